@@ -2,6 +2,8 @@
 from .events import (
     SecurityEvent,
     StoredEvent,
+    SummaryReport,
+    Heartbeat,
     MetricsResponse,
     AttackVolumeResponse,
     TimeSeriesPoint,
@@ -14,6 +16,8 @@ from .events import (
 __all__ = [
     "SecurityEvent",
     "StoredEvent",
+    "SummaryReport",
+    "Heartbeat",
     "MetricsResponse",
     "AttackVolumeResponse",
     "TimeSeriesPoint",
@@ -20,6 +20,7 @@ class Severity(str, Enum):
 class EventType(str, Enum):
     """Types of security events."""
     PRIVILEGED_CONTAINER = "PRIVILEGED_CONTAINER"
+    PRIVILEGED_INIT_CONTAINER = "PRIVILEGED_INIT_CONTAINER"
     DISALLOWED_REGISTRY = "DISALLOWED_REGISTRY"
     ROOT_USER = "ROOT_USER"
     HOST_NETWORK = "HOST_NETWORK"
@@ -43,11 +44,105 @@ class SecurityEvent(BaseModel):
     namespace: str = Field(..., description="Kubernetes namespace")
     container: Optional[str] = Field(None, description="Container name if applicable")
     image: Optional[str] = Field(None, description="Container image if applicable")
+    resolved_image_id: Optional[str] = Field(
+        None, alias="resolvedImageID", description="imageID the container runtime reports for the running container"
+    )
+    resolved_digest: Optional[str] = Field(
+        None, alias="resolvedDigest", description="Digest of the running image, e.g. sha256:..."
+    )
     reason: str = Field(..., description="Brief reason for the event")
+    reason_code: Optional[str] = Field(
+        None, alias="reasonCode", description="Stable code of the event type, e.g. KS-PRIV-001"
+    )
+    remediation: Optional[str] = Field(None, description="Short hint on how to fix the violation")
     action: str = Field(..., description="Action taken (TERMINATED, AUDIT, etc.)")
     policy_name: str = Field(..., alias="policyName", description="Name of the policy that triggered")
     node_name: Optional[str] = Field(None, alias="nodeName", description="Node where the pod runs")
     description: str = Field(..., description="Detailed description of the event")
+    captured_logs: Optional[dict[str, str]] = Field(
+        None, alias="capturedLogs", description="Tail of each container's logs read before termination"
+    )
+    containers: Optional[list[str]] = Field(
+        None, description="Containers of an event coalescing the same violation of many containers"
+    )
+    container_count: Optional[int] = Field(
+        None, alias="containerCount", description="Number of containers of a coalesced event"
+    )
+    cluster_upgrade_suspected: Optional[bool] = Field(
+        None, alias="clusterUpgradeSuspected", description="Violation only audited because a cluster upgrade was suspected"
+    )
+    
+    class Config:
+        populate_by_name = True
+
+
+class SummaryCount(BaseModel):
+    """Violation count for one policy, namespace and event type."""
+    
+    policy: str = Field(..., description="Policy name")
+    namespace: str = Field(..., description="Namespace")
+    event_type: str = Field(..., alias="eventType", description="Type of security event")
+    count: int = Field(..., description="Number of active violations")
+    
+    class Config:
+        populate_by_name = True
+
+
+class PodOffender(BaseModel):
+    """A pod ranked by its number of active violations."""
+    
+    pod_name: str = Field(..., alias="podName", description="Pod name")
+    namespace: str = Field(..., description="Namespace")
+    violations: int = Field(..., description="Number of active violations")
+    
+    class Config:
+        populate_by_name = True
+
+
+class NamespaceSummary(BaseModel):
+    """Violating pods compared with all pods in a namespace."""
+    
+    namespace: str = Field(..., description="Namespace")
+    pods: int = Field(..., description="Number of pods in the namespace")
+    violating_pods: int = Field(..., alias="violatingPods", description="Pods with active violations")
+    
+    class Config:
+        populate_by_name = True
+
+
+class SummaryReport(BaseModel):
+    """Periodic digest of active violations sent by the operator."""
+    
+    generated_at: str = Field(..., alias="generatedAt", description="ISO 8601 time the summary was built")
+    interval: str = Field(..., description="Reporting interval")
+    operator_version: Optional[str] = Field(None, alias="operatorVersion", description="Operator version")
+    total_violations: int = Field(..., alias="totalViolations", description="Active violations")
+    violating_pods: int = Field(..., alias="violatingPods", description="Pods with active violations")
+    total_pods: int = Field(0, alias="totalPods", description="Pods in the cluster")
+    counts: list[SummaryCount] = Field(default_factory=list, description="Counts by policy/namespace/type")
+    namespaces: list[NamespaceSummary] = Field(default_factory=list, description="Pod counts by namespace")
+    top_offending_pods: list[PodOffender] = Field(
+        default_factory=list, alias="topOffendingPods", description="Pods with the most violations"
+    )
+    
+    class Config:
+        populate_by_name = True
+
+
+class Heartbeat(BaseModel):
+    """Periodic liveness and inventory report sent by the operator's leader."""
+    
+    time: str = Field(..., description="ISO 8601 time the heartbeat was built")
+    interval: str = Field(..., description="Heartbeat interval")
+    operator_version: Optional[str] = Field(None, alias="operatorVersion", description="Operator version")
+    leader: str = Field(..., description="Identity of the leading operator replica")
+    policies: dict[str, int] = Field(default_factory=dict, description="ShieldPolicies by enforcement mode")
+    pods_evaluated: int = Field(0, alias="podsEvaluated", description="Pods evaluated since the previous heartbeat")
+    queue_depths: dict[str, int] = Field(default_factory=dict, alias="queueDepths", description="Work queue depth by controller")
+    buffered_events: dict[str, int] = Field(
+        default_factory=dict, alias="bufferedEvents", description="Events waiting for delivery by sink"
+    )
+    received_at: Optional[str] = Field(None, alias="receivedAt", description="Time the heartbeat was received by service")
     
     class Config:
         populate_by_name = True
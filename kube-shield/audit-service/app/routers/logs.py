@@ -9,6 +9,8 @@ from fastapi.responses import JSONResponse
 from ..models import (
     SecurityEvent,
     StoredEvent,
+    SummaryReport,
+    Heartbeat,
     MetricsResponse,
     AttackVolumeResponse,
     TimeSeriesPoint,
@@ -57,6 +59,54 @@ async def get_log_by_id(event_id: str) -> StoredEvent:
     return event
 
 
+@router.post("/summary", response_model=SummaryReport, status_code=201)
+async def create_summary(summary: SummaryReport) -> SummaryReport:
+    """
+    Receive a periodic violation summary from the operator.
+    
+    Only the latest summary is kept.
+    """
+    storage = get_log_storage()
+    storage.set_summary(summary)
+    return summary
+
+
+@router.get("/summary", response_model=SummaryReport)
+async def get_summary() -> SummaryReport:
+    """
+    Get the latest violation summary sent by the operator.
+    """
+    storage = get_log_storage()
+    summary = storage.get_summary()
+    if not summary:
+        raise HTTPException(status_code=404, detail="No summary received yet")
+    return summary
+
+
+@router.post("/heartbeat", response_model=Heartbeat, status_code=201)
+async def create_heartbeat(heartbeat: Heartbeat) -> Heartbeat:
+    """
+    Receive a periodic heartbeat from the operator's leader.
+    
+    Only the latest heartbeat is kept; its receivedAt tells how long the
+    operator has been silent.
+    """
+    storage = get_log_storage()
+    return storage.set_heartbeat(heartbeat)
+
+
+@router.get("/heartbeat", response_model=Heartbeat)
+async def get_heartbeat() -> Heartbeat:
+    """
+    Get the latest heartbeat sent by the operator.
+    """
+    storage = get_log_storage()
+    heartbeat = storage.get_heartbeat()
+    if not heartbeat:
+        raise HTTPException(status_code=404, detail="No heartbeat received yet")
+    return heartbeat
+
+
 @router.get("/metrics", response_model=MetricsResponse)
 async def get_metrics() -> MetricsResponse:
     """
@@ -110,6 +160,21 @@ async def legacy_create_log(event: SecurityEvent) -> StoredEvent:
     return stored_event
 
 
+@legacy_router.post("/summary", response_model=SummaryReport, status_code=201)
+async def legacy_create_summary(summary: SummaryReport) -> SummaryReport:
+    """Legacy endpoint: Receive a periodic violation summary."""
+    storage = get_log_storage()
+    storage.set_summary(summary)
+    return summary
+
+
+@legacy_router.post("/heartbeat", response_model=Heartbeat, status_code=201)
+async def legacy_create_heartbeat(heartbeat: Heartbeat) -> Heartbeat:
+    """Legacy endpoint: Receive a periodic heartbeat."""
+    storage = get_log_storage()
+    return storage.set_heartbeat(heartbeat)
+
+
 @legacy_router.get("/logs")
 async def legacy_get_logs(limit: int = 50) -> list[StoredEvent]:
     """Legacy endpoint: Get stored logs."""
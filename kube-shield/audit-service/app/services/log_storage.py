@@ -7,7 +7,7 @@ from collections import deque
 from datetime import datetime, timedelta
 from typing import Optional
 
-from ..models import SecurityEvent, StoredEvent
+from ..models import Heartbeat, SecurityEvent, StoredEvent, SummaryReport
 
 
 class LogStorage:
@@ -18,6 +18,8 @@ class LogStorage:
         self._lock = threading.Lock()
         self._max_logs = max_logs
         self._time_series: deque[tuple[datetime, int]] = deque(maxlen=720)  # 1 hour at 5s intervals
+        self._latest_summary: Optional[SummaryReport] = None
+        self._latest_heartbeat: Optional[Heartbeat] = None
         
     def add(self, event: SecurityEvent, source: str = "operator") -> StoredEvent:
         """Add a new event to storage."""
@@ -81,6 +83,28 @@ class LogStorage:
                     return event
         return None
     
+    def set_summary(self, summary: SummaryReport) -> None:
+        """Store the latest summary report from the operator."""
+        with self._lock:
+            self._latest_summary = summary
+    
+    def get_summary(self) -> Optional[SummaryReport]:
+        """Get the latest summary report, if any."""
+        with self._lock:
+            return self._latest_summary
+    
+    def set_heartbeat(self, heartbeat: Heartbeat) -> Heartbeat:
+        """Store the latest heartbeat from the operator, stamped with its arrival time."""
+        heartbeat = heartbeat.model_copy(update={"received_at": datetime.utcnow().isoformat() + "Z"})
+        with self._lock:
+            self._latest_heartbeat = heartbeat
+        return heartbeat
+    
+    def get_heartbeat(self) -> Optional[Heartbeat]:
+        """Get the latest heartbeat, if any."""
+        with self._lock:
+            return self._latest_heartbeat
+    
     def count(self) -> int:
         """Get total number of stored events."""
         with self._lock:
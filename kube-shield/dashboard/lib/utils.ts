@@ -110,6 +110,8 @@ export function getActionColor(action: string): string {
       return 'text-rose-500';
     case 'BLOCKED':
       return 'text-orange-500';
+    case 'WARN':
+      return 'text-yellow-400';
     case 'AUDIT':
       return 'text-amber-500';
     default:
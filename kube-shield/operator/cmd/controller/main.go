@@ -1,20 +1,52 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	wgpolicyv1alpha2 "github.com/kubeshield/operator/pkg/apis/wgpolicyk8s/v1alpha2"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/celrules"
 	"github.com/kubeshield/operator/pkg/config"
 	"github.com/kubeshield/operator/pkg/controller"
+	"github.com/kubeshield/operator/pkg/decision"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/heartbeat"
+	"github.com/kubeshield/operator/pkg/history"
+	"github.com/kubeshield/operator/pkg/interop"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/nscoverage"
+	"github.com/kubeshield/operator/pkg/policyreport"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/reporter"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/scoring"
+	"github.com/kubeshield/operator/pkg/state"
+	"github.com/kubeshield/operator/pkg/status"
+	"github.com/kubeshield/operator/pkg/tracing"
+	"github.com/kubeshield/operator/pkg/upgrade"
+	"github.com/kubeshield/operator/pkg/version"
 )
 
 var (
@@ -25,6 +57,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(shieldv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(wgpolicyv1alpha2.AddToScheme(scheme))
 }
 
 func main() {
@@ -32,65 +65,583 @@ func main() {
 
 	// Parse flags for override
 	var metricsAddr string
+	var metricsSecure bool
 	var probeAddr string
+	var statusAddr string
 	var enableLeaderElection bool
 	var auditServiceURL string
+	var printVersion bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", cfg.MetricsAddr, "The address the metric endpoint binds to.")
+	flag.BoolVar(&metricsSecure, "metrics-secure", cfg.MetricsSecure, "Serve metrics over HTTPS and authorize scrape requests.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", cfg.ProbeAddr, "The address the probe endpoint binds to.")
+	flag.StringVar(&statusAddr, "status-bind-address", cfg.StatusAddr, "The address the status endpoints bind to. Empty disables them.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", cfg.EnableLeaderElection, "Enable leader election for controller manager.")
 	flag.StringVar(&auditServiceURL, "audit-service-url", cfg.AuditServiceURL, "The URL of the audit service to send events to.")
+	flag.BoolVar(&printVersion, "version", false, "Print the operator version and exit.")
 
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
+
+	// The environment sets the defaults of the matching zap flags, which still win
+	if err := flag.Set("zap-encoder", cfg.LogFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid LOG_FORMAT %q: %v\n", cfg.LogFormat, err)
+		os.Exit(1)
+	}
+	if err := flag.Set("zap-time-encoding", cfg.LogTimestampFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid LOG_TIMESTAMP_FORMAT %q: %v\n", cfg.LogTimestampFormat, err)
+		os.Exit(1)
+	}
 	flag.Parse()
 
+	if printVersion {
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	setupLog.Info("Starting Kube-Shield Operator",
+		"version", version.Version,
+		"commit", version.Commit,
+		"buildDate", version.BuildDate,
 		"metricsAddr", metricsAddr,
 		"probeAddr", probeAddr,
+		"statusAddr", statusAddr,
 		"enableLeaderElection", enableLeaderElection,
 		"auditServiceURL", auditServiceURL,
+		"tracingEndpoint", cfg.TracingEndpoint,
+		"defaultEnforcementMode", cfg.DefaultEnforcementMode,
+		"evaluationPhase", cfg.EvaluationPhase,
 	)
 
+	if err := shieldv1alpha1.SetDefaultEnforcementMode(cfg.DefaultEnforcementMode); err != nil {
+		setupLog.Error(err, "invalid DEFAULT_ENFORCEMENT_MODE")
+		os.Exit(1)
+	}
+	if err := shieldv1alpha1.SetDefaultEvaluationPhase(cfg.EvaluationPhase); err != nil {
+		setupLog.Error(err, "invalid EVALUATION_PHASE")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingEndpoint, cfg.TracingSampleRatio, cfg.TracingInsecure)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	// Plain HTTP stays the default; secure serving adds TLS plus TokenReview/SubjectAccessReview
+	// based authn/authz so the endpoint can be scraped like kube-rbac-proxy protected targets
+	metricsOptions := metricsserver.Options{
+		BindAddress: metricsAddr,
+	}
+	if metricsSecure {
+		metricsOptions.SecureServing = true
+		metricsOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
+		if cfg.MetricsCertDir != "" {
+			metricsOptions.CertDir = cfg.MetricsCertDir
+			setupLog.Info("Serving metrics over HTTPS with authn/authz", "certDir", cfg.MetricsCertDir)
+		} else {
+			setupLog.Info("Serving metrics over HTTPS with authn/authz using a self-signed certificate")
+		}
+	} else {
+		setupLog.Info("Serving metrics over plain HTTP without authentication")
+	}
+
+	// Exemplars are only carried by OpenMetrics, which the default endpoint does not serve
+	if cfg.MetricsExemplars {
+		metrics.EnableExemplars()
+		metricsOptions.ExtraHandlers = map[string]http.Handler{metrics.OpenMetricsPath: metrics.OpenMetricsHandler()}
+		setupLog.Info("Serving metrics with exemplars", "path", metrics.OpenMetricsPath, "tracing", cfg.TracingEndpoint != "")
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
-		},
+		Scheme:                 scheme,
+		Metrics:                metricsOptions,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       cfg.LeaderElectionID,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    cfg.WebhookPort,
+			CertDir: cfg.WebhookCertDir,
+		}),
+		// Only image allowlists are read from the cache; the operator's own
+		// ConfigMaps are read through the API reader
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}: {Label: labels.SelectorFromSet(labels.Set{shieldv1alpha1.ImageAllowlistLabel: "true"})},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
 
+	// Track the violations currently present in the cluster
+	violationStore := state.NewMemoryStore()
+	if err := metrics.RegisterViolationStore(violationStore); err != nil {
+		setupLog.Error(err, "unable to register violation metrics")
+		os.Exit(1)
+	}
+
+	// Compliance scores are derived from the current violations
+	scoreWeights, err := scoring.ParseWeights(cfg.ComplianceScoreWeights)
+	if err != nil {
+		setupLog.Error(err, "invalid COMPLIANCE_SCORE_WEIGHTS")
+		os.Exit(1)
+	}
+	if err := schedule.AddRunnable(mgr, scoring.NewUpdater(violationStore, mgr.GetClient(), scoreWeights)); err != nil {
+		setupLog.Error(err, "unable to add compliance score updater")
+		os.Exit(1)
+	}
+
+	// Custom CEL rules are compiled once and shared by both controllers
+	ruleCompiler, err := celrules.NewCompiler()
+	if err != nil {
+		setupLog.Error(err, "unable to create CEL rule compiler")
+		os.Exit(1)
+	}
+
+	auditProxy, err := audit.ParseProxyURL(cfg.AuditProxyURL, cfg.AuditProxyUsername, cfg.AuditProxyPassword)
+	if err != nil {
+		setupLog.Error(err, "invalid AUDIT_PROXY_URL")
+		os.Exit(1)
+	}
+	if auditProxy != nil {
+		setupLog.Info("Sending audit traffic through proxy", "proxy", auditProxy.Redacted(), "noProxy", cfg.AuditNoProxy)
+	}
+	auditHTTPClient := audit.NewHTTPClient(audit.HTTPOptions{
+		Timeout:             cfg.AuditTimeout,
+		MaxIdleConnsPerHost: cfg.AuditMaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.AuditIdleConnTimeout,
+		UseEnvProxy:         cfg.AuditUseEnvProxy,
+		Proxy:               auditProxy,
+		NoProxy:             cfg.AuditNoProxy,
+	})
+
+	// Tell proxy and egress problems apart from a silent audit service early on
+	if cfg.AuditConnectivityCheck && auditServiceURL != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.AuditTimeout)
+			defer cancel()
+			status, err := audit.CheckConnectivity(ctx, auditHTTPClient, auditServiceURL)
+			if err != nil {
+				metrics.AuditReachable.Set(0)
+				setupLog.Error(err, "Audit service unreachable", "url", auditServiceURL)
+				return
+			}
+			metrics.AuditReachable.Set(1)
+			setupLog.Info("Audit service reachable", "url", auditServiceURL, "status", status)
+		}()
+	}
+
+	// Deliver security events to every configured sink
+	if err := audit.ValidateSinkNames(cfg.AuditSinks); err != nil {
+		setupLog.Error(err, "invalid AUDIT_SINKS")
+		os.Exit(1)
+	}
+	if cfg.AuditProtocol != audit.SinkHTTP && cfg.AuditProtocol != audit.SinkGRPC {
+		setupLog.Error(fmt.Errorf("unknown audit protocol %q, expected %q or %q", cfg.AuditProtocol, audit.SinkHTTP, audit.SinkGRPC), "invalid AUDIT_PROTOCOL")
+		os.Exit(1)
+	}
+	var auditSinks []audit.Sink
+	for _, name := range cfg.AuditSinks {
+		switch name {
+		case audit.SinkHTTP:
+			// The audit service can be reached over gRPC instead of JSON
+			if cfg.AuditProtocol == audit.SinkGRPC {
+				grpcSink, err := audit.NewGRPCSink(audit.GRPCOptions{
+					Target:   cfg.AuditGRPCTarget,
+					Timeout:  cfg.AuditTimeout,
+					Insecure: cfg.AuditGRPCInsecure,
+					CAFile:   cfg.AuditGRPCCAFile,
+					CertFile: cfg.AuditGRPCCertFile,
+					KeyFile:  cfg.AuditGRPCKeyFile,
+				})
+				if err != nil {
+					setupLog.Error(err, "unable to create gRPC audit sink")
+					os.Exit(1)
+				}
+				if err := mgr.Add(grpcSink); err != nil {
+					setupLog.Error(err, "unable to add gRPC audit sink")
+					os.Exit(1)
+				}
+				auditSinks = append(auditSinks, grpcSink)
+				continue
+			}
+			if auditServiceURL == "" {
+				setupLog.Info("Audit service URL not configured, skipping the http audit sink")
+				continue
+			}
+			auditSinks = append(auditSinks, audit.NewHTTPSink(auditServiceURL, auditHTTPClient))
+		case audit.SinkParquet:
+			parquetSink, err := audit.NewParquetSink(audit.ParquetOptions{
+				Dir:            cfg.AuditParquetDir,
+				FlushInterval:  cfg.AuditParquetFlushInterval,
+				RotateInterval: cfg.AuditParquetRotateInterval,
+				MaxFileBytes:   int64(cfg.AuditParquetMaxFileMB) * 1024 * 1024,
+			})
+			if err != nil {
+				setupLog.Error(err, "unable to create parquet audit sink")
+				os.Exit(1)
+			}
+			if err := mgr.Add(parquetSink); err != nil {
+				setupLog.Error(err, "unable to add parquet audit sink")
+				os.Exit(1)
+			}
+			auditSinks = append(auditSinks, parquetSink)
+		case audit.SinkAWS:
+			awsSink, err := audit.NewAWSSink(context.Background(), audit.AWSOptions{
+				TargetARN:   cfg.AuditAWSTargetARN,
+				Timeout:     cfg.AuditTimeout,
+				MaxAttempts: cfg.AuditAWSMaxAttempts,
+			})
+			if err != nil {
+				setupLog.Error(err, "unable to create AWS audit sink")
+				os.Exit(1)
+			}
+			auditSinks = append(auditSinks, awsSink)
+		}
+	}
+	setupLog.Info("Audit sinks", "sinks", cfg.AuditSinks)
+
+	// Keep recent events queryable on the status endpoints
+	var recentEvents *audit.RecentEvents
+	if statusAddr != "" && cfg.RecentEventsLimit > 0 {
+		recentEvents = audit.NewRecentEvents(cfg.RecentEventsLimit, cfg.RecentEventsRetention)
+		auditSinks = append(auditSinks, recentEvents)
+	}
+
+	// Optionally thin out low-severity events in noisy clusters
+	sampleRates, err := audit.ParseSampleRates(cfg.AuditSampleRates)
+	if err != nil {
+		setupLog.Error(err, "invalid AUDIT_SAMPLE_RATES")
+		os.Exit(1)
+	}
+	if len(sampleRates) > 0 {
+		setupLog.Info("Sampling security events", "rates", sampleRates)
+	}
+	sanitizer := audit.Sanitizer{Redact: cfg.RedactSecrets, MaxDescriptionLength: cfg.MaxDescriptionLength}
+	if cfg.AuditRateLimit > 0 {
+		setupLog.Info("Rate limiting audit events per policy and namespace", "perMinute", cfg.AuditRateLimit, "burst", cfg.AuditRateBurst)
+	}
+
+	// Never terminate the operator itself or the configured protected workloads
+	protectedPatterns := append([]string{}, cfg.ProtectedWorkloads...)
+	// Without the downward API, as with go run, only PROTECTED_WORKLOADS protect it
+	if cfg.OperatorNamespace == "" || cfg.OperatorPodName == "" {
+		setupLog.Info("POD_NAMESPACE and POD_NAME not set, the operator cannot recognize and protect its own pods")
+	} else {
+		selfPatterns, err := protection.SelfPatterns(context.Background(), mgr.GetAPIReader(), cfg.OperatorNamespace, cfg.OperatorPodName)
+		if err != nil {
+			setupLog.Error(err, "unable to fully resolve the operator's own workload, protecting what is known")
+		}
+		protectedPatterns = append(protectedPatterns, selfPatterns...)
+	}
+	protector, err := protection.NewProtector(protectedPatterns)
+	if err != nil {
+		setupLog.Error(err, "invalid PROTECTED_WORKLOADS")
+		os.Exit(1)
+	}
+	setupLog.Info("Protected workloads", "patterns", protector.Patterns())
+
+	// System namespaces are either skipped or only ever audited
+	systemNamespaces, err := protection.NewSystemNamespaces(cfg.SystemNamespaces, cfg.SystemNamespaceMode, cfg.BypassNamespaces)
+	if err != nil {
+		setupLog.Error(err, "invalid SYSTEM_NAMESPACES or SYSTEM_NAMESPACE_MODE")
+		os.Exit(1)
+	}
+	setupLog.Info("System namespaces", "patterns", systemNamespaces.Patterns(), "mode", systemNamespaces.Mode(), "bypassed", systemNamespaces.BypassPatterns())
+
+	// Optionally mirror the current violations into PolicyReports
+	if cfg.PolicyReports {
+		writer := policyreport.NewWriter(violationStore, mgr.GetClient(), mgr.GetAPIReader(), systemNamespaces, cfg.PolicyReportInterval)
+		if err := schedule.Add(mgr, writer.Job()); err != nil {
+			setupLog.Error(err, "unable to add PolicyReport writer")
+			os.Exit(1)
+		}
+	}
+
+	// Surface namespaces that no policy protects
+	if cfg.CoverageInterval > 0 {
+		var coverageSinks []audit.Sink
+		if cfg.CoverageEvents {
+			coverageSinks = auditSinks
+		}
+		tracker := nscoverage.NewTracker(mgr.GetClient(), systemNamespaces, coverageSinks, cfg.CoverageInterval)
+		tracker.Sanitizer = sanitizer
+		if err := schedule.Add(mgr, tracker.Job()); err != nil {
+			setupLog.Error(err, "unable to add namespace coverage tracker")
+			os.Exit(1)
+		}
+	}
+
+	// Keep the in-cluster enforcement history bounded
+	if cfg.EnforcementRecords && cfg.RecordPruneInterval > 0 {
+		pruner := history.NewPruner(mgr.GetClient(), mgr.GetAPIReader(), cfg.RecordMaxAge,
+			cfg.RecordMaxPerNamespace, cfg.RecordPruneInterval)
+		if err := schedule.Add(mgr, pruner.Job()); err != nil {
+			setupLog.Error(err, "unable to add enforcement record pruner")
+			os.Exit(1)
+		}
+	}
+
+	// Notice trimmed RBAC before enforcement fails on it
+	if cfg.RBACCheckInterval > 0 {
+		checker := controller.NewPermissionChecker(mgr.GetClient(), mgr.GetAPIReader(), cfg.RBACCheckInterval, controller.PermissionFeatures{
+			Quarantine:        cfg.Quarantine,
+			RespectPDB:        cfg.RespectPDB,
+			OperatorNamespace: cfg.OperatorNamespace,
+			DefaultPolicy:     cfg.CreateDefaultPolicy,
+			SharedCounters:    cfg.StateBackend == state.BackendConfigMap,
+		})
+		if err := schedule.Add(mgr, checker.Job()); err != nil {
+			setupLog.Error(err, "unable to add RBAC permission checker")
+			os.Exit(1)
+		}
+	}
+
+	// Optionally let an external decision point confirm every termination
+	var decisionClient *decision.Client
+	if cfg.DecisionHookURL != "" {
+		decisionClient = decision.NewClient(cfg.DecisionHookURL, audit.NewHTTPClient(audit.HTTPOptions{
+			Timeout:             cfg.DecisionHookTimeout,
+			MaxIdleConnsPerHost: cfg.AuditMaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.AuditIdleConnTimeout,
+			UseEnvProxy:         cfg.AuditUseEnvProxy,
+		}), cfg.DecisionHookFailOpen)
+		setupLog.Info("Consulting external decision point before terminations",
+			"url", cfg.DecisionHookURL,
+			"timeout", cfg.DecisionHookTimeout,
+			"failOpen", cfg.DecisionHookFailOpen,
+		)
+	}
+
+	// Policies without a deletionPropagation of their own fall back to the operator's
+	if err := controller.ValidateDeletionPropagation(cfg.DeletionPropagation); err != nil {
+		setupLog.Error(err, "invalid DELETION_PROPAGATION")
+		os.Exit(1)
+	}
+
+	// Termination counters are shared between replicas only when kept in a ConfigMap
+	var counters state.Counters
+	switch cfg.StateBackend {
+	case state.BackendMemory:
+		counters = state.NewMemoryCounters()
+	case state.BackendConfigMap:
+		if cfg.OperatorNamespace == "" {
+			setupLog.Error(nil, "STATE_BACKEND=configmap requires POD_NAMESPACE")
+			os.Exit(1)
+		}
+		counters = state.NewConfigMapCounters(mgr.GetClient(), mgr.GetAPIReader(), cfg.OperatorNamespace, cfg.StateConfigMap)
+		setupLog.Info("Sharing termination counters", "configMap", cfg.OperatorNamespace+"/"+cfg.StateConfigMap)
+	default:
+		setupLog.Error(nil, "unknown STATE_BACKEND", "backend", cfg.StateBackend)
+		os.Exit(1)
+	}
+
+	// A window that is not positive would start over on every termination and
+	// silently lift the limit
+	if cfg.MaxTerminationsPerOwner > 0 && cfg.TerminationThrottleWindow <= 0 {
+		setupLog.Error(fmt.Errorf("window must be positive, got %s", cfg.TerminationThrottleWindow), "invalid TERMINATION_THROTTLE_WINDOW")
+		os.Exit(1)
+	}
+
+	// Pods are evaluated by the Pod controller and by policy simulations
+	podEvaluator := evaluator.New(ruleCompiler)
+	podEvaluator.PublicRegistries = cfg.PublicRegistries
+	imageAllowlists := controller.NewImageAllowlists(mgr.GetClient(), ctrl.Log.WithName("image-allowlists"))
+	podEvaluator.ImageAllowlists = imageAllowlists
+
 	// Create and register the Pod controller
 	podReconciler := controller.NewPodReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
-		auditServiceURL,
+		mgr.GetAPIReader(),
+		auditSinks,
+		mgr.GetEventRecorderFor("kube-shield-operator"),
+		violationStore,
+		podEvaluator,
+		protector,
+		systemNamespaces,
+		decisionClient,
+		controller.PodReconcilerOptions{
+			EnforcementFailureThreshold: cfg.EnforcementFailureThreshold,
+			EvaluationBudget:            cfg.EvaluationBudget,
+			PolicyEvaluationTimeout:     cfg.PolicyEvaluationTimeout,
+			RecoverPanics:               cfg.RecoverPanics,
+			SkipDrainingNodes:           cfg.SkipDrainingNodes,
+			NodeReevaluationLimit:       cfg.NodeReevaluationLimit,
+			RespectPDB:                  cfg.RespectPDB,
+			Quarantine:                  cfg.Quarantine,
+			AnnotateOwners:              cfg.AnnotateOwners,
+			SampleRates:                 sampleRates,
+			Sanitizer:                   sanitizer,
+			AuditRateLimit:              cfg.AuditRateLimit,
+			AuditRateBurst:              cfg.AuditRateBurst,
+			MaxTerminationsPerOwner:     cfg.MaxTerminationsPerOwner,
+			TerminationThrottleWindow:   cfg.TerminationThrottleWindow,
+			DeletionPropagation:         cfg.DeletionPropagation,
+			CoalesceThreshold:           cfg.EventCoalesceThreshold,
+			EnforcementRecords:          cfg.EnforcementRecords,
+		},
 	)
+	// Optionally downgrade what Gatekeeper or Kyverno already report, e.g. during a migration
+	if cfg.DeduplicateExternalEngines {
+		podReconciler.Duplicates = interop.NewDetector(mgr.GetAPIReader())
+		setupLog.Info("Downgrading violations already reported by Gatekeeper or Kyverno PolicyReports")
+	}
+	podReconciler.Allowlists = imageAllowlists
+	// Container logs are streamed from the pods/log subresource, which the
+	// controller-runtime client cannot read
+	logsClient, err := corev1client.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create pod logs client")
+		os.Exit(1)
+	}
+	podReconciler.Logs = logsClient
+	podReconciler.AlertClient = auditHTTPClient
+	podReconciler.Counters = counters
+
+	// Optionally step aside while the cluster seems to be upgrading
+	if cfg.UpgradeDetection {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to create discovery client")
+			os.Exit(1)
+		}
+		controlPlane := func() (string, error) {
+			info, err := discoveryClient.ServerVersion()
+			if err != nil {
+				return "", err
+			}
+			return info.GitVersion, nil
+		}
+		detector := upgrade.NewDetector(mgr.GetClient(), controlPlane, cfg.UpgradeUnavailablePercent, cfg.UpgradeVersionSkew, cfg.UpgradeCooldown, cfg.UpgradeMaxNodeSuspicion)
+		if err := schedule.AddRunnable(mgr, detector); err != nil {
+			setupLog.Error(err, "unable to add cluster upgrade detector")
+			os.Exit(1)
+		}
+		podReconciler.Upgrades = detector
+	}
 	if err := podReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create Pod controller")
 		os.Exit(1)
 	}
 
+	// Optionally check pods and workloads at admission. Pods are only warned about
+	// under Warn policies; workloads with a disallowed image are denied under Enforce.
+	if cfg.WebhookEnabled {
+		controller.NewPodValidator(podReconciler).SetupWebhookWithManager(mgr)
+		controller.NewWorkloadValidator(podReconciler).SetupWebhookWithManager(mgr)
+		setupLog.Info("Serving the pod admission webhook", "path", controller.PodWebhookPath, "port", cfg.WebhookPort)
+		setupLog.Info("Serving the workload admission webhook", "path", controller.WorkloadWebhookPath, "port", cfg.WebhookPort)
+	}
+
 	// Create and register the ShieldPolicy controller
 	policyReconciler := controller.NewShieldPolicyReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
+		mgr.GetAPIReader(),
+		ruleCompiler,
+		podEvaluator,
 	)
+	policyReconciler.ImageAllowlists = imageAllowlists
+	policyReconciler.System = systemNamespaces
 	if err := policyReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create ShieldPolicy controller")
 		os.Exit(1)
 	}
 
+	// Optionally bootstrap a default Audit-mode policy covering all namespaces
+	if cfg.CreateDefaultPolicy {
+		defaultPolicyReconciler := controller.NewDefaultPolicyReconciler(mgr.GetClient(), mgr.GetAPIReader(), cfg.OperatorNamespace)
+		if err := defaultPolicyReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create default ShieldPolicy controller")
+			os.Exit(1)
+		}
+		setupLog.Info("Managing the default ShieldPolicy", "name", controller.DefaultPolicyName)
+	} else if err := schedule.Add(mgr, controller.ReleaseDefaultPolicyJob(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to add default ShieldPolicy release job")
+		os.Exit(1)
+	}
+
+	// Create and register the ShieldExemption controller
+	exemptionReconciler := controller.NewShieldExemptionReconciler(mgr.GetClient(), mgr.GetScheme())
+	if err := exemptionReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create ShieldExemption controller")
+		os.Exit(1)
+	}
+
+	// Send periodic violation summaries if configured
+	if cfg.ReportInterval > 0 {
+		reportURL := auditServiceURL
+		if cfg.ReportDestination == reporter.DestinationSlack {
+			if err := reporter.ValidateSlackWebhookURL(cfg.ReportSlackWebhookURL); err != nil {
+				setupLog.Error(err, "invalid REPORT_SLACK_WEBHOOK_URL")
+				os.Exit(1)
+			}
+			reportURL = cfg.ReportSlackWebhookURL
+		}
+		summaryReporter := reporter.NewSummaryReporter(
+			violationStore,
+			mgr.GetAPIReader(),
+			cfg.ListPageSize,
+			auditHTTPClient,
+			cfg.ReportInterval,
+			cfg.ReportDestination,
+			reportURL,
+			cfg.ReportTopN,
+		)
+		if err := schedule.Add(mgr, summaryReporter.Job()); err != nil {
+			setupLog.Error(err, "unable to add summary reporter")
+			os.Exit(1)
+		}
+		setupLog.Info("Sending scheduled summary reports", "interval", cfg.ReportInterval, "destination", cfg.ReportDestination)
+	}
+
+	// Let the audit service tell a quiet cluster from a dead operator
+	if cfg.HeartbeatInterval > 0 && auditServiceURL != "" {
+		identity := cfg.OperatorPodName
+		if identity == "" {
+			identity, _ = os.Hostname()
+		}
+		sender := heartbeat.NewSender(mgr.GetClient(), auditHTTPClient, auditServiceURL, cfg.HeartbeatInterval, identity, auditSinks)
+		if err := schedule.Add(mgr, sender.Job()); err != nil {
+			setupLog.Error(err, "unable to add heartbeat sender")
+			os.Exit(1)
+		}
+		setupLog.Info("Sending heartbeats", "interval", cfg.HeartbeatInterval, "identity", identity)
+	}
+
+	// Serve the compliance report and other status endpoints
+	if statusAddr != "" {
+		statusServer := status.NewServer(statusAddr)
+		if cfg.StatusAuth {
+			filter, err := filters.WithAuthenticationAndAuthorization(mgr.GetConfig(), mgr.GetHTTPClient())
+			if err != nil {
+				setupLog.Error(err, "unable to set up status endpoint authentication")
+				os.Exit(1)
+			}
+			statusServer.Filter = filter
+		} else {
+			setupLog.Info("Serving status endpoints without authentication")
+		}
+		// Violations and recent events are only collected by the leader
+		statusServer.HandleLeaderOnly("/report", mgr.Elected(), reporter.ReportHandler(mgr.GetClient(), violationStore, scoreWeights))
+		statusServer.Handle("/preview", controller.PreviewHandler(mgr.GetClient(), mgr.GetAPIReader(), podEvaluator, systemNamespaces))
+		if recentEvents != nil {
+			statusServer.HandleLeaderOnly("/events", mgr.Elected(), reporter.EventsHandler(recentEvents))
+		}
+		if err := mgr.Add(statusServer); err != nil {
+			setupLog.Error(err, "unable to add status server")
+			os.Exit(1)
+		}
+	}
+
 	// Add health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -102,8 +653,17 @@ func main() {
 	}
 
 	setupLog.Info("Starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
+	runErr := mgr.Start(ctrl.SetupSignalHandler())
+
+	// Flush any buffered spans before exiting
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "problem shutting down tracing")
+	}
+
+	if runErr != nil {
+		setupLog.Error(runErr, "problem running manager")
 		os.Exit(1)
 	}
 }
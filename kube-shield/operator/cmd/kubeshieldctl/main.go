@@ -0,0 +1,184 @@
+// kubeshieldctl answers questions about the operator's policies against a live
+// cluster, using the same code the operator decides with.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/config"
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(shieldv1alpha1.AddToScheme(scheme))
+}
+
+const usage = `Usage: kubeshieldctl <command> [flags]
+
+Commands:
+  effective   Show the policies, checks and exemptions that apply to namespaces
+  rules       List the event types with their reason codes and remediation hints
+  version     Print the version
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "effective":
+		if err := effective(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	case "rules":
+		if err := rules(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	case "version":
+		fmt.Println(version.Version)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// effective prints the effective rule set of each namespace given, as one YAML
+// document per namespace or a JSON array. The operator's settings that change the
+// outcome are read from the same environment variables as the operator, and can be
+// overridden with flags.
+func effective(args []string, out io.Writer) error {
+	cfg := config.NewConfig()
+	flags := flag.NewFlagSet("effective", flag.ExitOnError)
+	namespaces := flags.String("namespace", "", "Comma-separated namespaces to show")
+	output := flags.String("o", "yaml", "Output format: yaml or json")
+	defaultMode := flags.String("default-enforcement-mode", cfg.DefaultEnforcementMode, "The operator's DEFAULT_ENFORCEMENT_MODE")
+	systemNamespaces := flags.String("system-namespaces", strings.Join(cfg.SystemNamespaces, ","), "The operator's SYSTEM_NAMESPACES")
+	systemMode := flags.String("system-namespace-mode", cfg.SystemNamespaceMode, "The operator's SYSTEM_NAMESPACE_MODE")
+	bypassNamespaces := flags.String("bypass-namespaces", strings.Join(cfg.BypassNamespaces, ","), "The operator's BYPASS_NAMESPACES")
+	_ = flags.Parse(args)
+
+	if *namespaces == "" {
+		return fmt.Errorf("--namespace is required")
+	}
+	if *output != "yaml" && *output != "json" {
+		return fmt.Errorf("unknown output format %q, expected yaml or json", *output)
+	}
+	if err := shieldv1alpha1.SetDefaultEnforcementMode(*defaultMode); err != nil {
+		return err
+	}
+	system, err := protection.NewSystemNamespaces(strings.Split(*systemNamespaces, ","), *systemMode, strings.Split(*bypassNamespaces, ","))
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := c.List(ctx, policies); err != nil {
+		return fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+
+	var results []engine.Effective
+	now := time.Now()
+	for _, name := range strings.Split(*namespaces, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		namespace := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+			return fmt.Errorf("reading namespace %s: %w", name, err)
+		}
+		exemptions := &shieldv1alpha1.ShieldExemptionList{}
+		if err := c.List(ctx, exemptions, client.InNamespace(name)); err != nil {
+			return fmt.Errorf("listing ShieldExemptions in %s: %w", name, err)
+		}
+		results = append(results, engine.Compute(namespace, policies.Items, exemptions.Items, system, now))
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	for i, result := range results {
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rules prints the rule catalog, as a table or as JSON or YAML
+func rules(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("rules", flag.ExitOnError)
+	output := flags.String("o", "table", "Output format: table, yaml or json")
+	_ = flags.Parse(args)
+
+	catalog := engine.Catalog()
+	switch *output {
+	case "table":
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tEVENT TYPE\tSEVERITY\tREMEDIATION")
+		for _, rule := range catalog {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", rule.Code, rule.EventType, rule.Severity, rule.Remediation)
+		}
+		return w.Flush()
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(catalog)
+	case "yaml":
+		data, err := yaml.Marshal(catalog)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, yaml or json", *output)
+	}
+}
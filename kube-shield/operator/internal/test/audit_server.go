@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// FakeAuditServer stands in for the audit service. It records every SecurityEvent
+// posted to /log so tests can assert on what the operator reported.
+type FakeAuditServer struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	events  []audit.SecurityEvent
+	arrived chan struct{}
+}
+
+// NewFakeAuditServer starts a FakeAuditServer on a local port. Close it when done.
+func NewFakeAuditServer() *FakeAuditServer {
+	s := &FakeAuditServer{arrived: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/log", s.handleLog)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// URL is the base URL to configure as the operator's audit service
+func (s *FakeAuditServer) URL() string {
+	return s.server.URL
+}
+
+// Close shuts the server down
+func (s *FakeAuditServer) Close() {
+	s.server.Close()
+}
+
+func (s *FakeAuditServer) handleLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var event audit.SecurityEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	// Wake every waiter, they re-scan the events they have not seen yet
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Events returns a copy of every event received so far
+func (s *FakeAuditServer) Events() []audit.SecurityEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]audit.SecurityEvent{}, s.events...)
+}
+
+// Reset forgets the events received so far
+func (s *FakeAuditServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+}
+
+// WaitFor blocks until an event matching match has been received, including one
+// received before the call, or timeout elapses
+func (s *FakeAuditServer) WaitFor(ctx context.Context, timeout time.Duration, match func(audit.SecurityEvent) bool) (audit.SecurityEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	seen := 0
+	for {
+		s.mu.Lock()
+		if seen > len(s.events) {
+			// Reset was called while waiting
+			seen = 0
+		}
+		events, arrived := s.events[seen:], s.arrived
+		seen = len(s.events)
+		s.mu.Unlock()
+
+		for _, event := range events {
+			if match(event) {
+				return event, nil
+			}
+		}
+
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return audit.SecurityEvent{}, fmt.Errorf("no matching security event received: %w", ctx.Err())
+		}
+	}
+}
+
+// WaitForEvent waits for an event of eventType about the pod namespace/name
+func (s *FakeAuditServer) WaitForEvent(ctx context.Context, timeout time.Duration, namespace, name, eventType string) (audit.SecurityEvent, error) {
+	return s.WaitFor(ctx, timeout, EventFor(namespace, name, eventType))
+}
+
+// EventFor matches events of eventType about the pod namespace/name
+func EventFor(namespace, name, eventType string) func(audit.SecurityEvent) bool {
+	return func(event audit.SecurityEvent) bool {
+		return event.Namespace == namespace && event.PodName == name && event.EventType == eventType
+	}
+}
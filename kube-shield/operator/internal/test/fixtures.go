@@ -0,0 +1,127 @@
+package test
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// CompliantImage is an image every fixture policy allows
+const CompliantImage = "docker.io/library/nginx:1.25"
+
+// PodOption customizes a pod fixture
+type PodOption func(*corev1.Pod)
+
+// CompliantPod builds a single-container pod that violates none of the built-in checks
+func CompliantPod(namespace, name string, opts ...PodOption) *corev1.Pod {
+	nonRoot := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{"app": name},
+		},
+		Spec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: &nonRoot,
+			},
+			Containers: []corev1.Container{{
+				Name:  "app",
+				Image: CompliantImage,
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(pod)
+	}
+	return pod
+}
+
+// PrivilegedPod builds a pod whose only container runs privileged
+func PrivilegedPod(namespace, name string, opts ...PodOption) *corev1.Pod {
+	return CompliantPod(namespace, name, append([]PodOption{WithPrivileged()}, opts...)...)
+}
+
+// WithImage sets the image of every container
+func WithImage(image string) PodOption {
+	return func(pod *corev1.Pod) {
+		for i := range pod.Spec.Containers {
+			pod.Spec.Containers[i].Image = image
+		}
+	}
+}
+
+// WithPrivileged makes every container privileged
+func WithPrivileged() PodOption {
+	return func(pod *corev1.Pod) {
+		privileged := true
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].SecurityContext == nil {
+				pod.Spec.Containers[i].SecurityContext = &corev1.SecurityContext{}
+			}
+			pod.Spec.Containers[i].SecurityContext.Privileged = &privileged
+		}
+	}
+}
+
+// WithLabels adds labels to the pod
+func WithLabels(labels map[string]string) PodOption {
+	return func(pod *corev1.Pod) {
+		for key, value := range labels {
+			pod.Labels[key] = value
+		}
+	}
+}
+
+// WithAnnotations adds annotations to the pod
+func WithAnnotations(annotations map[string]string) PodOption {
+	return func(pod *corev1.Pod) {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		for key, value := range annotations {
+			pod.Annotations[key] = value
+		}
+	}
+}
+
+// PolicyOption customizes a ShieldPolicy fixture
+type PolicyOption func(*shieldv1alpha1.ShieldPolicy)
+
+// Policy builds a ShieldPolicy that blocks privileged containers in Enforce mode
+func Policy(name string, opts ...PolicyOption) *shieldv1alpha1.ShieldPolicy {
+	blockPrivileged := true
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			BlockPrivileged: &blockPrivileged,
+			EnforcementMode: shieldv1alpha1.EnforcementModeEnforce,
+		},
+	}
+	for _, opt := range opts {
+		opt(policy)
+	}
+	return policy
+}
+
+// WithEnforcementMode sets the policy's enforcement mode
+func WithEnforcementMode(mode string) PolicyOption {
+	return func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Spec.EnforcementMode = mode
+	}
+}
+
+// WithAllowedRegistries sets the registries the policy allows
+func WithAllowedRegistries(registries ...string) PolicyOption {
+	return func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Spec.AllowedRegistries = registries
+	}
+}
+
+// WithTargetNamespaces limits the policy to the given namespaces
+func WithTargetNamespaces(namespaces ...string) PolicyOption {
+	return func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Spec.TargetNamespaces = namespaces
+	}
+}
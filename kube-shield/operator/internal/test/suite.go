@@ -0,0 +1,226 @@
+// Package test is the integration test harness. It runs the operator's reconcilers
+// against a real API server started by envtest, with a FakeAuditServer receiving the
+// security events, and provides fixtures for violating and compliant pods.
+//
+// envtest needs the control plane binaries; `make test-integration` downloads them
+// and points KUBEBUILDER_ASSETS at them.
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/celrules"
+	"github.com/kubeshield/operator/pkg/config"
+	"github.com/kubeshield/operator/pkg/controller"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+// pollInterval is how often the Await helpers re-read the API server
+const pollInterval = 100 * time.Millisecond
+
+// Suite is a running API server with the Pod and ShieldPolicy reconcilers attached
+type Suite struct {
+	// Client talks to the API server directly, bypassing the manager's cache
+	Client client.Client
+
+	// Audit receives the security events the operator sends
+	Audit *FakeAuditServer
+
+	// Violations is the violation store shared by the reconcilers
+	Violations state.Store
+
+	env    *envtest.Environment
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// crdDirectory locates the CRD manifests relative to this file, so the suite works
+// from whichever package the tests run in
+func crdDirectory() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "k8s", "crds")
+}
+
+// Start starts envtest with the operator's CRDs installed and runs both reconcilers
+// against it. cfg supplies the reconciler options; nil uses the defaults.
+func Start(cfg *config.Config) (*Suite, error) {
+	if cfg == nil {
+		cfg = config.NewConfig()
+	}
+
+	scheme := k8sruntime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := shieldv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{crdDirectory()},
+		ErrorIfCRDPathMissing: true,
+	}
+	restConfig, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("starting envtest: %w", err)
+	}
+
+	s := &Suite{
+		Audit:      NewFakeAuditServer(),
+		Violations: state.NewMemoryStore(),
+		env:        env,
+		done:       make(chan error, 1),
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		return nil, s.abort(fmt.Errorf("creating manager: %w", err))
+	}
+	if s.Client, err = client.New(restConfig, client.Options{Scheme: scheme}); err != nil {
+		return nil, s.abort(fmt.Errorf("creating client: %w", err))
+	}
+
+	rules, err := celrules.NewCompiler()
+	if err != nil {
+		return nil, s.abort(err)
+	}
+	podEvaluator := evaluator.New(rules)
+	protector, err := protection.NewProtector(cfg.ProtectedWorkloads)
+	if err != nil {
+		return nil, s.abort(err)
+	}
+	systemNamespaces, err := protection.NewSystemNamespaces(cfg.SystemNamespaces, cfg.SystemNamespaceMode, cfg.BypassNamespaces)
+	if err != nil {
+		return nil, s.abort(err)
+	}
+
+	sinks := []audit.Sink{audit.NewHTTPSink(s.Audit.URL(), audit.NewHTTPClient(audit.HTTPOptions{
+		Timeout:             cfg.AuditTimeout,
+		MaxIdleConnsPerHost: cfg.AuditMaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.AuditIdleConnTimeout,
+	}))}
+
+	podReconciler := controller.NewPodReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetAPIReader(),
+		sinks,
+		mgr.GetEventRecorderFor("kube-shield-operator"),
+		s.Violations,
+		podEvaluator,
+		protector,
+		systemNamespaces,
+		nil,
+		controller.PodReconcilerOptions{
+			EnforcementFailureThreshold: cfg.EnforcementFailureThreshold,
+			EvaluationBudget:            cfg.EvaluationBudget,
+			PolicyEvaluationTimeout:     cfg.PolicyEvaluationTimeout,
+			SkipDrainingNodes:           cfg.SkipDrainingNodes,
+			RecoverPanics:               cfg.RecoverPanics,
+		},
+	)
+	if err := podReconciler.SetupWithManager(mgr); err != nil {
+		return nil, s.abort(fmt.Errorf("setting up Pod controller: %w", err))
+	}
+	policyReconciler := controller.NewShieldPolicyReconciler(mgr.GetClient(), mgr.GetScheme(), mgr.GetAPIReader(), rules, podEvaluator)
+	if err := policyReconciler.SetupWithManager(mgr); err != nil {
+		return nil, s.abort(fmt.Errorf("setting up ShieldPolicy controller: %w", err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		s.done <- mgr.Start(ctx)
+	}()
+	if !mgr.GetCache().WaitForCacheSync(ctx) {
+		return nil, s.abort(errors.New("manager cache did not sync"))
+	}
+	return s, nil
+}
+
+// abort tears down what Start brought up and returns err
+func (s *Suite) abort(err error) error {
+	s.Audit.Close()
+	if stopErr := s.env.Stop(); stopErr != nil {
+		return errors.Join(err, stopErr)
+	}
+	return err
+}
+
+// Stop stops the reconcilers, the API server and the audit server
+func (s *Suite) Stop() error {
+	s.cancel()
+	err := <-s.done
+	s.Audit.Close()
+	return errors.Join(err, s.env.Stop())
+}
+
+// CreateNamespace creates a namespace with the given labels
+func (s *Suite) CreateNamespace(ctx context.Context, name string, labels map[string]string) error {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	if err := s.Client.Create(ctx, namespace); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// ApplyPolicy creates the policy, or replaces the spec of an existing one
+func (s *Suite) ApplyPolicy(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) error {
+	existing := &shieldv1alpha1.ShieldPolicy{}
+	err := s.Client.Get(ctx, types.NamespacedName{Name: policy.Name}, existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		return s.Client.Create(ctx, policy)
+	case err != nil:
+		return err
+	}
+	existing.Spec = policy.Spec
+	return s.Client.Update(ctx, existing)
+}
+
+// AwaitPodDeleted waits for the pod to be gone from the API server. Pods are never
+// scheduled under envtest, so a terminated pod is removed right away.
+func (s *Suite) AwaitPodDeleted(ctx context.Context, timeout time.Duration, namespace, name string) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// AwaitPolicyStatus waits until check accepts the policy's status
+func (s *Suite) AwaitPolicyStatus(ctx context.Context, timeout time.Duration, name string, check func(shieldv1alpha1.ShieldPolicyStatus) bool) error {
+	return wait.PollUntilContextTimeout(ctx, pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		policy := &shieldv1alpha1.ShieldPolicy{}
+		if err := s.Client.Get(ctx, types.NamespacedName{Name: name}, policy); err != nil {
+			return false, err
+		}
+		return check(policy.Status), nil
+	})
+}
@@ -0,0 +1,83 @@
+package v1alpha1
+
+const (
+	// AnnotationPrefix is the prefix shared by all annotations understood by the operator
+	AnnotationPrefix = GroupName + "/"
+
+	// ExemptUntilAnnotation grants a pod a time-boxed exemption from all policies.
+	// The value is an RFC3339 timestamp; the exemption lapses once it has passed.
+	ExemptUntilAnnotation = AnnotationPrefix + "exempt-until"
+)
+
+const (
+	// QuarantineNowAnnotation set to "true" on a pod asks the operator to isolate it
+	// from the network right away, whether or not any policy applies to it
+	QuarantineNowAnnotation = AnnotationPrefix + "quarantine-now"
+
+	// QuarantinedLabel is set to "true" on isolated pods. The namespace's
+	// kube-shield-quarantine NetworkPolicy denies all traffic to and from them.
+	QuarantinedLabel = AnnotationPrefix + "quarantined"
+)
+
+const (
+	// ViolationsAnnotation is set by the operator on pods violating an audit-mode policy
+	// with AnnotateViolations enabled. The value is a JSON list of the violated rules.
+	ViolationsAnnotation = AnnotationPrefix + "violations"
+
+	// LastEvaluatedAnnotation records when ViolationsAnnotation was last written (RFC3339)
+	LastEvaluatedAnnotation = AnnotationPrefix + "last-evaluated"
+
+	// EvaluateAnnotation set to EvaluateNow asks the operator to re-evaluate the pod
+	// immediately and explain the outcome in EvaluationResultAnnotation.
+	// The operator removes it once the result is written.
+	EvaluateAnnotation = AnnotationPrefix + "evaluate"
+
+	// EvaluateNow is the value of EvaluateAnnotation that triggers a re-evaluation
+	EvaluateNow = "now"
+
+	// EvaluationResultAnnotation holds the JSON explanation of the last requested evaluation
+	EvaluationResultAnnotation = AnnotationPrefix + "evaluation-result"
+)
+
+const (
+	// LastEnforcementAnnotation is set by the operator on the top-level owner of a pod
+	// it terminates, e.g. its Deployment, to when the pod was terminated (RFC3339)
+	LastEnforcementAnnotation = AnnotationPrefix + "last-enforcement"
+
+	// LastEnforcementReasonAnnotation holds the violated rule and reason of the last
+	// termination, as "<rule>: <reason>"
+	LastEnforcementReasonAnnotation = AnnotationPrefix + "last-enforcement-reason"
+)
+
+const (
+	// AllowedRegistriesAnnotation on a pod lists, comma-separated, registries it may
+	// pull from in addition to a policy's AllowedRegistries, for policies with
+	// AllowAnnotationRegistryOverrides
+	AllowedRegistriesAnnotation = AnnotationPrefix + "allowed-registries"
+)
+
+const (
+	// ResetCountersAnnotation set to "true" on a ShieldPolicy asks the operator to reset
+	// its violation and termination counters. The operator removes it once done.
+	ResetCountersAnnotation = AnnotationPrefix + "reset-counters"
+)
+
+const (
+	// ManagedAnnotation set to "false" on the operator's default ShieldPolicy hands it over
+	// to the user: the operator stops applying it and does not recreate it once deleted
+	ManagedAnnotation = AnnotationPrefix + "managed"
+)
+
+const (
+	// SimulateAnnotation set to "true" on a ShieldPolicy makes it a dry run: the operator
+	// summarizes what the policy would find among the existing pods in its status, and
+	// neither enforces nor audits it. Removing the annotation activates the policy.
+	SimulateAnnotation = AnnotationPrefix + "simulate"
+)
+
+const (
+	// EffectivePoliciesAnnotation is set by the operator on the namespace's
+	// PolicyReport. It lists the policies that apply to the namespace with their
+	// effective modes, e.g. "baseline=Enforce,labels=Audit".
+	EffectivePoliciesAnnotation = AnnotationPrefix + "effective-policies"
+)
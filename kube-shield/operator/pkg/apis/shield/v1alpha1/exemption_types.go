@@ -0,0 +1,188 @@
+package v1alpha1
+
+import (
+	"path"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Exemption phases
+const (
+	// ExemptionPhaseActive marks an exemption that is currently applied
+	ExemptionPhaseActive = "Active"
+	// ExemptionPhaseExpired marks an exemption past its ExpiresAt
+	ExemptionPhaseExpired = "Expired"
+	// ExemptionPhaseInvalid marks an exemption whose spec cannot be applied
+	ExemptionPhaseInvalid = "Invalid"
+)
+
+// AllRules exempts every check when listed in ShieldExemptionSpec.Rules
+const AllRules = "*"
+
+// ShieldExemptionSpec defines an approved, time-boxed exception to ShieldPolicy checks
+type ShieldExemptionSpec struct {
+	// Match selects the pods the exemption covers. All given criteria must match.
+	// +kubebuilder:validation:Required
+	Match ExemptionMatch `json:"match"`
+
+	// Rules are the exempted checks, as listed by the compliance report
+	// (e.g. HOST_NETWORK or rule:<name>). "*" exempts every check.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:Required
+	Rules []string `json:"rules"`
+
+	// ExpiresAt is when the exemption stops applying
+	// +kubebuilder:validation:Required
+	ExpiresAt metav1.Time `json:"expiresAt"`
+
+	// Justification records why the exception was approved
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Justification string `json:"justification"`
+}
+
+// ExemptionMatch selects pods in the exemption's namespace
+type ExemptionMatch struct {
+	// PodSelector matches pod labels. Empty matches every pod.
+	// +kubebuilder:validation:Optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// Owner matches the pod's controlling owner
+	// +kubebuilder:validation:Optional
+	Owner *OwnerMatch `json:"owner,omitempty"`
+
+	// Images are glob patterns of container images (e.g. "registry.example.com/legacy/*").
+	// Container-level violations match on the violating container's image, pod-level
+	// violations when any container image matches.
+	// +kubebuilder:validation:Optional
+	Images []string `json:"images,omitempty"`
+}
+
+// OwnerMatch matches the controlling owner reference of a pod
+type OwnerMatch struct {
+	// Kind of the owner, e.g. ReplicaSet, StatefulSet, DaemonSet or Job
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// Name is a glob pattern of the owner name; empty matches any name
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+}
+
+// ShieldExemptionStatus defines the observed state of ShieldExemption
+type ShieldExemptionStatus struct {
+	// Phase is Active until ExpiresAt has passed, then Expired
+	// +kubebuilder:validation:Enum=Active;Expired;Invalid
+	Phase string `json:"phase,omitempty"`
+
+	// Message provides additional information about the current state
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this ShieldExemption
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=sx
+// +kubebuilder:printcolumn:name="Expires",type="string",JSONPath=".spec.expiresAt"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ShieldExemption is the Schema for the shieldexemptions API
+type ShieldExemption struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ShieldExemptionSpec   `json:"spec,omitempty"`
+	Status ShieldExemptionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ShieldExemptionList contains a list of ShieldExemption
+type ShieldExemptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ShieldExemption `json:"items"`
+}
+
+// IsActive returns true if the exemption has not expired at now. It is decided
+// from ExpiresAt alone so an exemption never outlives its expiry while its
+// status is still being updated.
+func (e *ShieldExemption) IsActive(now time.Time) bool {
+	return now.Before(e.Spec.ExpiresAt.Time)
+}
+
+// CoversRule returns true if the exemption lists check or all rules
+func (e *ShieldExemption) CoversRule(check string) bool {
+	for _, rule := range e.Spec.Rules {
+		if rule == AllRules || rule == check {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesPod returns true if the pod matches the exemption's selector and owner.
+// Invalid selectors match nothing.
+func (e *ShieldExemption) MatchesPod(pod *corev1.Pod) bool {
+	if pod.Namespace != e.Namespace {
+		return false
+	}
+
+	if e.Spec.Match.PodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(e.Spec.Match.PodSelector)
+		if err != nil {
+			return false
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			return false
+		}
+	}
+
+	if owner := e.Spec.Match.Owner; owner != nil {
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil || ref.Kind != owner.Kind {
+			return false
+		}
+		if owner.Name != "" {
+			if matched, _ := path.Match(owner.Name, ref.Name); !matched {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// MatchesImage returns true if image matches one of the exemption's image patterns,
+// or if the exemption does not restrict images. An empty image (a pod-level
+// violation) matches when any container of the pod does.
+func (e *ShieldExemption) MatchesImage(pod *corev1.Pod, image string) bool {
+	if len(e.Spec.Match.Images) == 0 {
+		return true
+	}
+	if image != "" {
+		return e.matchesImagePattern(image)
+	}
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if e.matchesImagePattern(container.Image) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesImagePattern matches image against the exemption's image globs
+func (e *ShieldExemption) matchesImagePattern(image string) bool {
+	for _, pattern := range e.Spec.Match.Images {
+		if matched, _ := path.Match(pattern, image); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package v1alpha1
+
+// Security profiles mirroring the Pod Security Standards
+const (
+	// ProfileBaseline prevents known privilege escalations
+	ProfileBaseline = "baseline"
+
+	// ProfileRestricted adds the current pod hardening best practices to ProfileBaseline
+	ProfileRestricted = "restricted"
+)
+
+// baselineChecks are the event types raised by the baseline profile on top of the
+// checks every policy runs
+var baselineChecks = []string{
+	"HOST_PID",
+	"HOST_IPC",
+	"HOST_PATH_VOLUME",
+	"HOST_PORT",
+	"ADDED_CAPABILITIES",
+	"APPARMOR_PROFILE",
+	"SELINUX_OPTIONS",
+	"PROC_MOUNT",
+	"SECCOMP_PROFILE",
+	"UNSAFE_SYSCTL",
+}
+
+// restrictedChecks are the event types the restricted profile adds to baselineChecks
+var restrictedChecks = []string{
+	"RESTRICTED_VOLUME_TYPE",
+	"PRIVILEGE_ESCALATION",
+	"RUN_AS_NON_ROOT",
+}
+
+// HasBaselineProfile returns true if the policy applies the baseline profile,
+// directly or as part of the restricted profile
+func (s *ShieldPolicy) HasBaselineProfile() bool {
+	return s.Spec.Profile == ProfileBaseline || s.Spec.Profile == ProfileRestricted
+}
+
+// HasRestrictedProfile returns true if the policy applies the restricted profile
+func (s *ShieldPolicy) HasRestrictedProfile() bool {
+	return s.Spec.Profile == ProfileRestricted
+}
+
+// profileChecks lists the event types the policy's profile enables
+func (s *ShieldPolicy) profileChecks() []string {
+	var checks []string
+	if s.HasBaselineProfile() {
+		checks = append(checks, baselineChecks...)
+	}
+	if s.HasRestrictedProfile() {
+		checks = append(checks, restrictedChecks...)
+	}
+	return checks
+}
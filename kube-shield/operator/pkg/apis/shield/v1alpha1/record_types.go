@@ -0,0 +1,75 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecordPolicyLabel is set on every ShieldEnforcementRecord to the policy whose
+// enforcement it records, for selecting the records of one policy
+const RecordPolicyLabel = "shield.kubeshield.io/policy"
+
+// ShieldEnforcementRecordSpec is what happened to a pod and why. Records are
+// written by the operator and never changed afterwards.
+type ShieldEnforcementRecordSpec struct {
+	// Timestamp is when the action was taken
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// PodName and PodUID identify the pod, which is usually gone by now
+	PodName string `json:"podName"`
+	PodUID  string `json:"podUID,omitempty"`
+
+	// NodeName is the node the pod ran on
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Policy is the ShieldPolicy that was enforced
+	Policy string `json:"policy"`
+
+	// EventType is the violated check, e.g. PRIVILEGED_CONTAINER
+	EventType string `json:"eventType"`
+
+	// Rule is the custom rule for CUSTOM_RULE_VIOLATION
+	Rule string `json:"rule,omitempty"`
+
+	// ReasonCode is the event type's stable code, e.g. KS-PRIV-001
+	ReasonCode string `json:"reasonCode,omitempty"`
+
+	// Container and Image are the violating container, for container checks
+	Container string `json:"container,omitempty"`
+	Image     string `json:"image,omitempty"`
+
+	// ResolvedDigest is the digest of the image the container actually ran, when
+	// its status reported one
+	ResolvedDigest string `json:"resolvedDigest,omitempty"`
+
+	// Reason is the human-readable reason of the violation
+	Reason string `json:"reason,omitempty"`
+
+	// Outcome is the action taken: TERMINATED, TERMINATION_FAILED or QUARANTINED
+	Outcome string `json:"outcome"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ser
+// +kubebuilder:printcolumn:name="Pod",type="string",JSONPath=".spec.podName"
+// +kubebuilder:printcolumn:name="Policy",type="string",JSONPath=".spec.policy"
+// +kubebuilder:printcolumn:name="Event",type="string",JSONPath=".spec.eventType"
+// +kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=".spec.outcome"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ShieldEnforcementRecord is the Schema for the shieldenforcementrecords API, an
+// in-cluster history of the operator's enforcement actions
+type ShieldEnforcementRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ShieldEnforcementRecordSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ShieldEnforcementRecordList contains a list of ShieldEnforcementRecord
+type ShieldEnforcementRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ShieldEnforcementRecord `json:"items"`
+}
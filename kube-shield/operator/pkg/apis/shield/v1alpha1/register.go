@@ -34,6 +34,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&ShieldPolicy{},
 		&ShieldPolicyList{},
+		&ShieldExemption{},
+		&ShieldExemptionList{},
+		&ShieldEnforcementRecord{},
+		&ShieldEnforcementRecordList{},
 	)
 	return nil
 }
@@ -4,34 +4,403 @@
 package v1alpha1
 
 import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Enforcement modes supported by ShieldPolicy
+const (
+	// EnforcementModeEnforce terminates pods that violate the policy
+	EnforcementModeEnforce = "Enforce"
+	// EnforcementModeWarn keeps violating pods running but warns their owners
+	// through admission warnings and Kubernetes Events on the pod
+	EnforcementModeWarn = "Warn"
+	// EnforcementModeAudit only reports violations
+	EnforcementModeAudit = "Audit"
+	// EnforcementModeDisabled turns the policy off
+	EnforcementModeDisabled = "Disabled"
 )
 
+// DefaultEnforcementMode is the mode applied at runtime to policies that leave
+// EnforcementMode empty. The CRD does not default the field, so an explicit
+// mode on the policy always wins over this operator-wide default.
+var DefaultEnforcementMode = EnforcementModeEnforce
+
+// SetDefaultEnforcementMode validates and installs the operator-wide default enforcement mode
+func SetDefaultEnforcementMode(mode string) error {
+	switch mode {
+	case EnforcementModeEnforce, EnforcementModeWarn, EnforcementModeAudit, EnforcementModeDisabled:
+		DefaultEnforcementMode = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid enforcement mode %q, must be one of %s, %s, %s or %s",
+			mode, EnforcementModeEnforce, EnforcementModeWarn, EnforcementModeAudit, EnforcementModeDisabled)
+	}
+}
+
+// Evaluation phases supported by ShieldPolicy
+const (
+	// EvaluationPhaseOnCreate evaluates pods as soon as they exist
+	EvaluationPhaseOnCreate = "OnCreate"
+	// EvaluationPhaseOnScheduled evaluates pods once they are bound to a node
+	EvaluationPhaseOnScheduled = "OnScheduled"
+	// EvaluationPhaseOnRunning evaluates pods once they are running
+	EvaluationPhaseOnRunning = "OnRunning"
+)
+
+// ImageAllowlistLabel must be set to "true" on ConfigMaps referenced by
+// AllowedImagesConfigMapRef. The operator only watches ConfigMaps carrying it.
+const ImageAllowlistLabel = GroupName + "/image-allowlist"
+
+// ImageAllowlistFailurePolicy values
+const (
+	// ImageAllowlistFailureIgnore skips the digest check without approved digests
+	ImageAllowlistFailureIgnore = "Ignore"
+	// ImageAllowlistFailureFail keeps checking against the inline digests alone
+	ImageAllowlistFailureFail = "Fail"
+)
+
+// OwnerKindNone is the MatchOwnerKinds entry matching pods without an owner
+const OwnerKindNone = "None"
+
+// DefaultEvaluationPhase is the phase applied to policies that leave EvaluationPhase empty
+var DefaultEvaluationPhase = EvaluationPhaseOnCreate
+
+// SetDefaultEvaluationPhase validates and installs the operator-wide default evaluation phase
+func SetDefaultEvaluationPhase(phase string) error {
+	switch phase {
+	case EvaluationPhaseOnCreate, EvaluationPhaseOnScheduled, EvaluationPhaseOnRunning:
+		DefaultEvaluationPhase = phase
+		return nil
+	default:
+		return fmt.Errorf("invalid evaluation phase %q, must be one of %s, %s or %s",
+			phase, EvaluationPhaseOnCreate, EvaluationPhaseOnScheduled, EvaluationPhaseOnRunning)
+	}
+}
+
 // ShieldPolicySpec defines the desired state of ShieldPolicy
 type ShieldPolicySpec struct {
-	// BlockPrivileged indicates whether privileged containers should be blocked and terminated
-	// +kubebuilder:validation:Required
-	BlockPrivileged bool `json:"blockPrivileged"`
+	// Profile applies a curated bundle of checks mirroring the Pod Security Standards.
+	// Individual fields such as BlockPrivileged take precedence over the profile.
+	// +kubebuilder:validation:Enum=baseline;restricted
+	// +kubebuilder:validation:Optional
+	Profile string `json:"profile,omitempty"`
+
+	// BlockPrivileged indicates whether privileged containers, and Windows HostProcess
+	// containers, should be blocked and terminated. When unset, it is enabled by a Profile.
+	// +kubebuilder:validation:Optional
+	BlockPrivileged *bool `json:"blockPrivileged,omitempty"`
+
+	// RequireDropAllCapabilities flags containers that do not drop ALL capabilities.
+	// When unset, it is enabled by the restricted Profile.
+	// +kubebuilder:validation:Optional
+	RequireDropAllCapabilities *bool `json:"requireDropAllCapabilities,omitempty"`
+
+	// BlockSharedProcessNamespace flags pods that share one process namespace between their containers
+	// +kubebuilder:validation:Optional
+	BlockSharedProcessNamespace bool `json:"blockSharedProcessNamespace,omitempty"`
 
 	// AllowedRegistries is a list of container registries that are allowed
 	// +kubebuilder:validation:Optional
 	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
 
-	// EnforcementMode specifies how the policy should be enforced
-	// +kubebuilder:validation:Enum=Enforce;Audit;Disabled
-	// +kubebuilder:default=Enforce
+	// StrictRegistryMatching stops "*" in AllowedRegistries from matching every
+	// registry, so only the registries listed by name are allowed
+	// +kubebuilder:validation:Optional
+	StrictRegistryMatching bool `json:"strictRegistryMatching,omitempty"`
+
+	// AllowAnnotationRegistryOverrides lets pods add registries to AllowedRegistries
+	// for themselves with AllowedRegistriesAnnotation. Every use is reported.
+	// +kubebuilder:validation:Optional
+	AllowAnnotationRegistryOverrides bool `json:"allowAnnotationRegistryOverrides,omitempty"`
+
+	// AllowedImageRepositories lists "registry/repository" glob patterns images must
+	// come from (e.g. "gcr.io/myproject/*"). A pattern also matches every repository
+	// nested below what it matches. Checked in addition to AllowedRegistries.
+	// +kubebuilder:validation:Optional
+	AllowedImageRepositories []string `json:"allowedImageRepositories,omitempty"`
+
+	// AllowedImageDigests lists the only image digests containers may run, e.g.
+	// "sha256:4a5e...", typically produced by CI. Combined with the digests of
+	// AllowedImagesConfigMapRef. Other images are reported as UNAPPROVED_IMAGE.
+	// +kubebuilder:validation:Optional
+	AllowedImageDigests []string `json:"allowedImageDigests,omitempty"`
+
+	// AllowedImagesConfigMapRef points at a ConfigMap whose keys are approved image
+	// digests, so CI can update the list without touching the policy. The ConfigMap
+	// must carry the ImageAllowlistLabel.
+	// +kubebuilder:validation:Optional
+	AllowedImagesConfigMapRef *ConfigMapReference `json:"allowedImagesConfigMapRef,omitempty"`
+
+	// ImageAllowlistFailurePolicy decides what happens while the ConfigMap of
+	// AllowedImagesConfigMapRef cannot be read. Ignore (the default) only checks the
+	// inline AllowedImageDigests, and skips the check if there are none. Fail checks
+	// against the inline digests alone, reporting every other image as UNAPPROVED_IMAGE.
+	// +kubebuilder:validation:Enum=Ignore;Fail
+	// +kubebuilder:validation:Optional
+	ImageAllowlistFailurePolicy string `json:"imageAllowlistFailurePolicy,omitempty"`
+
+	// RegistryAliases maps mirror prefixes to the registry they mirror, e.g.
+	// "mirror.internal:5000/docker.io": "docker.io". Images pulled through a mirror
+	// are checked against AllowedRegistries and AllowedImageRepositories as if they
+	// named the mirrored registry. The longest matching prefix wins.
+	// +kubebuilder:validation:Optional
+	RegistryAliases map[string]string `json:"registryAliases,omitempty"`
+
+	// RequireImagePullSecretFor lists registry patterns (e.g. "registry.example.com" or
+	// "*.azurecr.io") whose images must come with an imagePullSecret on the pod or its
+	// service account. Missing credentials are reported as MISSING_PULL_SECRET.
+	// +kubebuilder:validation:Optional
+	RequireImagePullSecretFor []string `json:"requireImagePullSecretFor,omitempty"`
+
+	// MaxEmptyDirMemoryMB is the largest sizeLimit, in MiB, allowed for memory-backed
+	// emptyDir volumes. Memory-backed emptyDirs without a sizeLimit are always reported,
+	// but pods are only terminated for them under Enforce while this is set.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	MaxEmptyDirMemoryMB int64 `json:"maxEmptyDirMemoryMB,omitempty"`
+
+	// RequireResourceRequests flags containers that do not request both CPU and
+	// memory, as MISSING_RESOURCE_REQUESTS
+	// +kubebuilder:validation:Optional
+	RequireResourceRequests bool `json:"requireResourceRequests,omitempty"`
+
+	// RequireResourceLimits flags containers that do not limit both CPU and memory,
+	// as MISSING_RESOURCE_LIMITS
+	// +kubebuilder:validation:Optional
+	RequireResourceLimits bool `json:"requireResourceLimits,omitempty"`
+
+	// EnforcementMode specifies how the policy should be enforced.
+	// When empty, the operator's DEFAULT_ENFORCEMENT_MODE applies.
+	// +kubebuilder:validation:Enum=Enforce;Warn;Audit;Disabled
+	// +kubebuilder:validation:Optional
 	EnforcementMode string `json:"enforcementMode,omitempty"`
 
-	// TargetNamespaces limits policy enforcement to specific namespaces
-	// If empty, applies to all namespaces except kube-system
+	// EvaluationPhase controls when pods are checked: as soon as they exist, once
+	// scheduled, or once running. When empty, the operator's EVALUATION_PHASE applies.
+	// +kubebuilder:validation:Enum=OnCreate;OnScheduled;OnRunning
+	// +kubebuilder:validation:Optional
+	EvaluationPhase string `json:"evaluationPhase,omitempty"`
+
+	// DeferBackOffPods keeps pods whose containers are stuck in ImagePullBackOff or
+	// CrashLoopBackOff instead of terminating them, since a replacement would only
+	// enter the same loop. Their violations are audited once.
+	// +kubebuilder:validation:Optional
+	DeferBackOffPods bool `json:"deferBackOffPods,omitempty"`
+
+	// EnforceDaemonSetPods terminates violating DaemonSet pods. They are only
+	// audited by default, since the DaemonSet recreates a deleted pod on the same
+	// node at once, to be terminated again.
+	// +kubebuilder:validation:Optional
+	EnforceDaemonSetPods bool `json:"enforceDaemonSetPods,omitempty"`
+
+	// EnforceOnExisting enforces the policy on pods created before it. Set to
+	// false to grandfather those pods: their violations are only audited until they
+	// are recreated, while newer pods are enforced. Defaults to true.
+	// +kubebuilder:validation:Optional
+	EnforceOnExisting *bool `json:"enforceOnExisting,omitempty"`
+
+	// AnnotateViolations makes an audit-mode policy record the rules a pod violates in
+	// the pod's shield.kubeshield.io/violations annotation, removed once it complies
+	// +kubebuilder:validation:Optional
+	AnnotateViolations bool `json:"annotateViolations,omitempty"`
+
+	// TargetNamespaces limits policy enforcement to specific namespaces.
+	// If empty, applies to all namespaces; system namespaces are handled by the
+	// operator's SYSTEM_NAMESPACES setting.
 	// +kubebuilder:validation:Optional
 	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
+	// NamespaceSelector limits the policy to namespaces whose labels match. It is
+	// combined with TargetNamespaces when both are set.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// NodeSelector limits the policy to pods scheduled on nodes carrying all of
+	// these labels. Pods not yet scheduled are not evaluated by such a policy.
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// MatchOwnerKinds limits the policy to pods whose top-level owner is of one of
+	// these kinds, e.g. Job or CronJob, following ReplicaSets to their Deployment
+	// and Jobs to their CronJob. OwnerKindNone matches pods without an owner.
+	// +kubebuilder:validation:Optional
+	MatchOwnerKinds []string `json:"matchOwnerKinds,omitempty"`
+
+	// SeverityOverrides changes the severity reported for built-in checks, keyed by
+	// event type (e.g. ROOT_USER: CRITICAL). Custom rules set their own severity.
+	// +kubebuilder:validation:Optional
+	SeverityOverrides map[string]Severity `json:"severityOverrides,omitempty"`
+
+	// Rules are custom checks written as CEL expressions evaluated against the pod
+	// +kubebuilder:validation:Optional
+	Rules []CELRule `json:"rules,omitempty"`
+
+	// GracePeriodAfterCreation is an observe-only period after the policy is created.
+	// Until it has elapsed the policy only audits, whatever its enforcement mode.
+	// +kubebuilder:validation:Optional
+	GracePeriodAfterCreation *metav1.Duration `json:"gracePeriodAfterCreation,omitempty"`
+
+	// CounterRetention resets ViolationsCount and TerminationsCount once this long has
+	// passed since the last reset (or since creation), e.g. 720h for monthly totals
+	// +kubebuilder:validation:Optional
+	CounterRetention *metav1.Duration `json:"counterRetention,omitempty"`
+
+	// DeletionPropagation is how the dependents of a terminated pod are deleted.
+	// Background (the default) deletes them after the pod, Foreground before it, and
+	// Orphan keeps them, e.g. to retain them for forensics.
+	// +kubebuilder:validation:Enum=Background;Foreground;Orphan
+	// +kubebuilder:validation:Optional
+	DeletionPropagation string `json:"deletionPropagation,omitempty"`
+
+	// CaptureLogsBeforeTermination attaches the tail of each container's logs to the
+	// security event of a pod before it is terminated, while they can still be read
+	// +kubebuilder:validation:Optional
+	CaptureLogsBeforeTermination *LogCapture `json:"captureLogsBeforeTermination,omitempty"`
+
+	// AlertWebhooks receive the policy's violations as JSON, in addition to the
+	// operator's audit sinks, so each policy can alert the team that owns it. Only
+	// https URLs are accepted.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=5
+	// +kubebuilder:validation:items:Pattern=`^https://[^/?#\s]+`
+	AlertWebhooks []string `json:"alertWebhooks,omitempty"`
+
+	// MaxPodLifetime is the longest a pod may run, counted from its start time.
+	// Older pods are reported as POD_LIFETIME_EXCEEDED, e.g. in batch namespaces
+	// where pods should be short-lived.
+	// +kubebuilder:validation:Optional
+	MaxPodLifetime *metav1.Duration `json:"maxPodLifetime,omitempty"`
+
+	// IncludeControlledPods applies MaxPodLifetime to pods owned by a controller
+	// (Deployments, Jobs, ...) as well, which are otherwise left out
+	// +kubebuilder:validation:Optional
+	IncludeControlledPods bool `json:"includeControlledPods,omitempty"`
+
+	// MaxPodAge is the longest a workload's pod may exist, counted from its creation,
+	// so that long-running pods are recycled and pick up patched images. Older pods
+	// are reported as POD_TOO_OLD and, in Enforce mode, evicted so that their
+	// PodDisruptionBudgets are respected. Pods of DaemonSets and pods without a
+	// controller are only audited, since evicting them does not recycle them.
+	// +kubebuilder:validation:Optional
+	MaxPodAge *metav1.Duration `json:"maxPodAge,omitempty"`
+
+	// ReevaluationInterval re-checks the pods the policy applies to this often,
+	// even when neither they nor the policies changed, so checks added by an
+	// operator upgrade or revoked signatures reach long-lived pods. Unset leaves
+	// pods alone until they or the policies change.
+	// +kubebuilder:validation:Optional
+	ReevaluationInterval *metav1.Duration `json:"reevaluationInterval,omitempty"`
+
+	// RequiredLabels are labels every pod must carry, such as owner, team or
+	// cost-center. An entry is a key, satisfied by any value, or key=value. Pods
+	// missing any are reported as MISSING_REQUIRED_LABEL.
+	// +kubebuilder:validation:Optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+
+	// BlockPublicRegistries flags images from public registries such as docker.io,
+	// ghcr.io or quay.io, for namespaces that must only run private images. The
+	// registries are the operator's PUBLIC_REGISTRIES. Reported as
+	// PUBLIC_REGISTRY_IMAGE.
+	// +kubebuilder:validation:Optional
+	BlockPublicRegistries bool `json:"blockPublicRegistries,omitempty"`
+
+	// DetectImageDrift flags running containers whose imageID, the image the
+	// container runtime actually pulled, comes from another registry or repository
+	// than the spec image, or has another digest than the one the spec pins.
+	// Reported as IMAGE_DRIFT.
+	// +kubebuilder:validation:Optional
+	DetectImageDrift bool `json:"detectImageDrift,omitempty"`
+
+	// RequireAntiAffinity flags replicated pods (of ReplicaSets, StatefulSets and
+	// ReplicationControllers) that neither require pod anti-affinity nor a
+	// DoNotSchedule topology spread, limiting the blast radius of a node compromise.
+	// Reported as MISSING_ANTIAFFINITY; never terminates pods.
+	// +kubebuilder:validation:Optional
+	RequireAntiAffinity bool `json:"requireAntiAffinity,omitempty"`
+}
+
+// ConfigMapReference names a ConfigMap in any namespace
+type ConfigMapReference struct {
+	// Namespace of the ConfigMap
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// Name of the ConfigMap
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// LogCapture bounds the container logs captured before a pod is terminated
+type LogCapture struct {
+	// TailLines is the number of most recent lines read from each container
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	// +kubebuilder:default=100
+	TailLines int64 `json:"tailLines,omitempty"`
+
+	// MaxBytes caps the log kept per container; older output is cut off first
+	// +kubebuilder:validation:Minimum=256
+	// +kubebuilder:validation:Maximum=16384
+	// +kubebuilder:default=4096
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+// CELRule is a custom check expressed in CEL. The pod is bound to the variable
+// "pod" and the rule is violated when the expression evaluates to true.
+type CELRule struct {
+	// Name identifies the rule in security events and status
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Expression is the CEL expression to evaluate, e.g. "pod.spec.hostPID == true"
+	// +kubebuilder:validation:Required
+	Expression string `json:"expression"`
+
+	// Severity is the severity reported when the rule is violated
+	// +kubebuilder:validation:Enum=CRITICAL;HIGH;MEDIUM;LOW;INFO
+	// +kubebuilder:default=MEDIUM
+	Severity string `json:"severity,omitempty"`
+
+	// Action controls what happens on violation: Enforce follows the policy's
+	// enforcement mode, Audit only reports the violation
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Audit
+	Action string `json:"action,omitempty"`
+}
+
+// Severity is the level a violation is reported with
+// +kubebuilder:validation:Enum=CRITICAL;HIGH;MEDIUM;LOW;INFO
+type Severity string
+
+// Severity levels, from most to least severe
+const (
+	SeverityCritical Severity = "CRITICAL"
+	SeverityHigh     Severity = "HIGH"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityLow      Severity = "LOW"
+	SeverityInfo     Severity = "INFO"
+)
+
+// IsValid reports whether the severity is one of the known levels
+func (s Severity) IsValid() bool {
+	switch s {
+	case SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo:
+		return true
+	}
+	return false
 }
 
 // ShieldPolicyStatus defines the observed state of ShieldPolicy
 type ShieldPolicyStatus struct {
 	// Phase represents the current phase of the ShieldPolicy
-	// +kubebuilder:validation:Enum=Active;Inactive;Error
+	// +kubebuilder:validation:Enum=Active;Inactive;Error;Simulated
 	Phase string `json:"phase,omitempty"`
 
 	// LastEnforcementTime is the last time the policy was enforced
@@ -43,6 +412,17 @@ type ShieldPolicyStatus struct {
 	// TerminationsCount is the total number of pods terminated due to violations
 	TerminationsCount int64 `json:"terminationsCount,omitempty"`
 
+	// ViolationsLast24h is the number of violations detected in the last 24 hours
+	ViolationsLast24h int64 `json:"violationsLast24h,omitempty"`
+
+	// TerminationsLast24h is the number of pods terminated in the last 24 hours
+	TerminationsLast24h int64 `json:"terminationsLast24h,omitempty"`
+
+	// RecentActivity counts violations and terminations per hour over the last 24
+	// hours, oldest first. The Last24h counters are its sums.
+	// +kubebuilder:validation:MaxItems=24
+	RecentActivity []ActivityBucket `json:"recentActivity,omitempty"`
+
 	// Conditions represent the latest available observations of the policy's current state
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
@@ -51,15 +431,82 @@ type ShieldPolicyStatus struct {
 
 	// Message provides additional information about the current state
 	Message string `json:"message,omitempty"`
+
+	// ComplianceScore is the severity-weighted score (0-100) of the violations this
+	// policy currently reports; it recovers as violations clear
+	ComplianceScore *int32 `json:"complianceScore,omitempty"`
+
+	// EnforcementMode is the mode the operator currently applies to the policy, after
+	// the operator default and any observe-only grace period are taken into account
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+
+	// LastReset records the totals the counters held when they were last reset
+	LastReset *CounterReset `json:"lastReset,omitempty"`
+
+	// Simulation summarizes the last dry run of the policy against the existing pods
+	Simulation *SimulationSummary `json:"simulation,omitempty"`
+}
+
+// ActivityBucket counts a policy's violations and terminations during one hour
+type ActivityBucket struct {
+	// Start is the beginning of the hour
+	Start metav1.Time `json:"start"`
+
+	// Violations is the number of violations detected during the hour
+	Violations int64 `json:"violations"`
+
+	// Terminations is the number of pods terminated during the hour
+	Terminations int64 `json:"terminations,omitempty"`
+}
+
+// SimulationSummary is what a simulated policy would find among the existing pods
+type SimulationSummary struct {
+	// Time is when the simulation finished
+	Time metav1.Time `json:"time"`
+
+	// ObservedGeneration is the policy generation that was simulated
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// PodsEvaluated is the number of pods in the policy's scope that were evaluated
+	PodsEvaluated int64 `json:"podsEvaluated"`
+
+	// ViolatingPods is the number of evaluated pods with at least one violation
+	ViolatingPods int64 `json:"violatingPods"`
+
+	// ViolationsByType counts the violations found per event type
+	ViolationsByType map[string]int64 `json:"violationsByType,omitempty"`
+
+	// ExamplePods names some of the violating pods as namespace/name
+	ExamplePods []string `json:"examplePods,omitempty"`
+}
+
+// CounterReset is a snapshot of a policy's counters taken when they were reset
+type CounterReset struct {
+	// Time is when the counters were reset
+	Time metav1.Time `json:"time"`
+
+	// Reason is why the counters were reset
+	// +kubebuilder:validation:Enum=Requested;Retention
+	Reason string `json:"reason"`
+
+	// ViolationsCount is the number of violations counted before the reset
+	ViolationsCount int64 `json:"violationsCount"`
+
+	// TerminationsCount is the number of terminations counted before the reset
+	TerminationsCount int64 `json:"terminationsCount"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=sp;shieldpolicy
 // +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.enforcementMode"
+// +kubebuilder:printcolumn:name="Effective",type="string",JSONPath=".status.enforcementMode"
+// +kubebuilder:printcolumn:name="Profile",type="string",JSONPath=".spec.profile"
 // +kubebuilder:printcolumn:name="Block Privileged",type="boolean",JSONPath=".spec.blockPrivileged"
 // +kubebuilder:printcolumn:name="Violations",type="integer",JSONPath=".status.violationsCount"
 // +kubebuilder:printcolumn:name="Terminations",type="integer",JSONPath=".status.terminationsCount"
+// +kubebuilder:printcolumn:name="Violations 24h",type="integer",JSONPath=".status.violationsLast24h",priority=1
+// +kubebuilder:printcolumn:name="Score",type="integer",JSONPath=".status.complianceScore"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -81,24 +528,237 @@ type ShieldPolicyList struct {
 	Items           []ShieldPolicy `json:"items"`
 }
 
-// IsEnforcing returns true if the policy is in enforcement mode
+// EffectiveEnforcementMode returns the policy's enforcement mode, falling back
+// to DefaultEnforcementMode when none is set. An enforcing policy that is still
+// within its GracePeriodAfterCreation is downgraded to audit.
+func (s *ShieldPolicy) EffectiveEnforcementMode() string {
+	mode := s.Spec.EnforcementMode
+	if mode == "" {
+		mode = DefaultEnforcementMode
+	}
+	if mode == EnforcementModeEnforce && s.GraceRemaining(time.Now()) > 0 {
+		return EnforcementModeAudit
+	}
+	return mode
+}
+
+// GraceRemaining returns how long the policy stays observe-only after its
+// creation, or zero once GracePeriodAfterCreation has elapsed or is not set
+func (s *ShieldPolicy) GraceRemaining(now time.Time) time.Duration {
+	if s.Spec.GracePeriodAfterCreation == nil || s.CreationTimestamp.IsZero() {
+		return 0
+	}
+	remaining := s.CreationTimestamp.Add(s.Spec.GracePeriodAfterCreation.Duration).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// IsEnforcing returns true if the policy is in enforcement mode. Warn is not enforcing.
 func (s *ShieldPolicy) IsEnforcing() bool {
-	return s.Spec.EnforcementMode == "" || s.Spec.EnforcementMode == "Enforce"
+	return s.EffectiveEnforcementMode() == EnforcementModeEnforce
+}
+
+// IsWarning returns true if the policy is in warn mode
+func (s *ShieldPolicy) IsWarning() bool {
+	return s.EffectiveEnforcementMode() == EnforcementModeWarn
+}
+
+// DeniesAdmission returns true if the workload admission webhook should reject
+// workloads whose pod template violates the policy
+func (s *ShieldPolicy) DeniesAdmission() bool {
+	return s.IsEnforcing()
+}
+
+// WarnsOnAdmission returns true if an admission webhook should admit pods violating
+// the policy but return admission warnings to the client
+func (s *ShieldPolicy) WarnsOnAdmission() bool {
+	return s.IsWarning()
 }
 
 // IsAuditing returns true if the policy is in audit mode
 func (s *ShieldPolicy) IsAuditing() bool {
-	return s.Spec.EnforcementMode == "Audit"
+	return s.EffectiveEnforcementMode() == EnforcementModeAudit
+}
+
+// IsSimulated returns true if the policy is a dry run requested with SimulateAnnotation
+func (s *ShieldPolicy) IsSimulated() bool {
+	return s.Annotations[SimulateAnnotation] == "true"
 }
 
 // IsDisabled returns true if the policy is disabled
 func (s *ShieldPolicy) IsDisabled() bool {
-	return s.Spec.EnforcementMode == "Disabled"
+	return s.EffectiveEnforcementMode() == EnforcementModeDisabled
+}
+
+// EffectiveEvaluationPhase returns the policy's EvaluationPhase, falling back to
+// DefaultEvaluationPhase when none is set
+func (s *ShieldPolicy) EffectiveEvaluationPhase() string {
+	if s.Spec.EvaluationPhase == "" {
+		return DefaultEvaluationPhase
+	}
+	return s.Spec.EvaluationPhase
+}
+
+// ShouldEvaluatePod returns true once a pod has reached the policy's evaluation phase
+func (s *ShieldPolicy) ShouldEvaluatePod(pod *corev1.Pod) bool {
+	switch s.EffectiveEvaluationPhase() {
+	case EvaluationPhaseOnScheduled:
+		return pod.Spec.NodeName != ""
+	case EvaluationPhaseOnRunning:
+		return pod.Status.Phase == corev1.PodRunning
+	default:
+		return true
+	}
+}
+
+// LifetimeExpiry returns when a pod outlives the policy's MaxPodLifetime, or false
+// if the limit does not apply to the pod or it has not started yet
+func (s *ShieldPolicy) LifetimeExpiry(pod *corev1.Pod) (time.Time, bool) {
+	if s.Spec.MaxPodLifetime == nil || pod.Status.StartTime == nil {
+		return time.Time{}, false
+	}
+	if !s.Spec.IncludeControlledPods && metav1.GetControllerOf(pod) != nil {
+		return time.Time{}, false
+	}
+	return pod.Status.StartTime.Add(s.Spec.MaxPodLifetime.Duration), true
+}
+
+// Grandfathers reports whether the policy only audits the pod because the pod was
+// created before the policy and EnforceOnExisting is false. Pods being admitted have
+// no creation timestamp yet and are never grandfathered.
+func (s *ShieldPolicy) Grandfathers(pod *corev1.Pod) bool {
+	if s.Spec.EnforceOnExisting == nil || *s.Spec.EnforceOnExisting || pod.CreationTimestamp.IsZero() {
+		return false
+	}
+	return pod.CreationTimestamp.Before(&s.CreationTimestamp)
+}
+
+// AgeExpiry returns when a pod outgrows the policy's MaxPodAge, or false if the
+// policy sets none or the pod has not been created yet
+func (s *ShieldPolicy) AgeExpiry(pod *corev1.Pod) (time.Time, bool) {
+	if s.Spec.MaxPodAge == nil || pod.CreationTimestamp.IsZero() {
+		return time.Time{}, false
+	}
+	return pod.CreationTimestamp.Add(s.Spec.MaxPodAge.Duration), true
 }
 
 // ShouldBlockPrivileged returns true if privileged containers should be blocked
 func (s *ShieldPolicy) ShouldBlockPrivileged() bool {
-	return s.Spec.BlockPrivileged && !s.IsDisabled()
+	return s.blocksPrivileged() && !s.IsDisabled()
+}
+
+// blocksPrivileged returns BlockPrivileged, falling back to the profile when it is unset
+func (s *ShieldPolicy) blocksPrivileged() bool {
+	if s.Spec.BlockPrivileged != nil {
+		return *s.Spec.BlockPrivileged
+	}
+	return s.HasBaselineProfile()
+}
+
+// RequiresDropAllCapabilities returns RequireDropAllCapabilities, falling back to the
+// profile when it is unset
+func (s *ShieldPolicy) RequiresDropAllCapabilities() bool {
+	if s.Spec.RequireDropAllCapabilities != nil {
+		return *s.Spec.RequireDropAllCapabilities
+	}
+	return s.HasRestrictedProfile()
+}
+
+// SeverityFor returns the severity the policy reports for a built-in check,
+// applying SeverityOverrides to the check's default. Unknown levels are ignored.
+func (s *ShieldPolicy) SeverityFor(eventType, defaultSeverity string) string {
+	if override, ok := s.Spec.SeverityOverrides[eventType]; ok && override.IsValid() {
+		return string(override)
+	}
+	return defaultSeverity
+}
+
+// ShouldApplyToNode checks if the policy's NodeSelector matches the labels of
+// the pod's node
+func (s *ShieldPolicy) ShouldApplyToNode(nodeLabels map[string]string) bool {
+	for key, value := range s.Spec.NodeSelector {
+		if actual, ok := nodeLabels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ShouldApplyToOwnerKind checks if the policy's MatchOwnerKinds admits pods whose
+// top-level owner is of the given kind, OwnerKindNone for pods without an owner
+func (s *ShieldPolicy) ShouldApplyToOwnerKind(kind string) bool {
+	if len(s.Spec.MatchOwnerKinds) == 0 {
+		return true
+	}
+	for _, match := range s.Spec.MatchOwnerKinds {
+		if match == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledChecks lists the event types the policy checks for. Custom rules are
+// listed as "rule:<name>".
+func (s *ShieldPolicy) EnabledChecks() []string {
+	checks := []string{"HOST_NETWORK", "ROOT_USER", "UNBOUNDED_TMPFS", "POD_MUTATED"}
+	if s.blocksPrivileged() {
+		checks = append(checks, "PRIVILEGED_CONTAINER", "PRIVILEGED_INIT_CONTAINER", "HOST_PROCESS")
+	}
+	checks = append(checks, s.profileChecks()...)
+	if s.RequiresDropAllCapabilities() {
+		checks = append(checks, "CAPABILITIES_NOT_DROPPED")
+	}
+	if s.Spec.BlockSharedProcessNamespace {
+		checks = append(checks, "SHARED_PROCESS_NAMESPACE")
+	}
+	if len(s.Spec.AllowedRegistries) > 0 {
+		checks = append(checks, "DISALLOWED_REGISTRY")
+	}
+	if len(s.Spec.AllowedImageRepositories) > 0 {
+		checks = append(checks, "DISALLOWED_REPOSITORY")
+	}
+	if len(s.Spec.AllowedImageDigests) > 0 || s.Spec.AllowedImagesConfigMapRef != nil {
+		checks = append(checks, "UNAPPROVED_IMAGE")
+	}
+	if s.Spec.BlockPublicRegistries {
+		checks = append(checks, "PUBLIC_REGISTRY_IMAGE")
+	}
+	if len(s.Spec.RequireImagePullSecretFor) > 0 {
+		checks = append(checks, "MISSING_PULL_SECRET")
+	}
+	if s.Spec.RequireResourceRequests {
+		checks = append(checks, "MISSING_RESOURCE_REQUESTS")
+	}
+	if s.Spec.RequireResourceLimits {
+		checks = append(checks, "MISSING_RESOURCE_LIMITS")
+	}
+	if s.Spec.MaxPodLifetime != nil {
+		checks = append(checks, "POD_LIFETIME_EXCEEDED")
+	}
+	if s.Spec.MaxPodAge != nil {
+		checks = append(checks, "POD_TOO_OLD")
+	}
+	if len(s.Spec.RequiredLabels) > 0 {
+		checks = append(checks, "MISSING_REQUIRED_LABEL")
+	}
+	if s.Spec.DetectImageDrift {
+		checks = append(checks, "IMAGE_DRIFT")
+	}
+	if s.Spec.RequireAntiAffinity {
+		checks = append(checks, "MISSING_ANTIAFFINITY")
+	}
+	for _, rule := range s.Spec.Rules {
+		checks = append(checks, CustomRuleCheck(rule.Name))
+	}
+	return checks
+}
+
+// CustomRuleCheck is the name under which EnabledChecks lists a custom rule
+func CustomRuleCheck(name string) string {
+	return "rule:" + name
 }
 
 // IsRegistryAllowed checks if a registry is in the allowed list
@@ -107,25 +767,74 @@ func (s *ShieldPolicy) IsRegistryAllowed(registry string) bool {
 		return true // No restriction if list is empty
 	}
 	for _, allowed := range s.Spec.AllowedRegistries {
-		if allowed == registry || allowed == "*" {
+		if RegistryEntryProblem(allowed) != "" {
+			continue
+		}
+		allowed = strings.TrimRight(allowed, "/")
+		if allowed == registry || (allowed == "*" && !s.Spec.StrictRegistryMatching) {
 			return true
 		}
 	}
 	return false
 }
 
-// ShouldApplyToNamespace checks if the policy should apply to a given namespace
-func (s *ShieldPolicy) ShouldApplyToNamespace(namespace string) bool {
-	// Never apply to kube-system
-	if namespace == "kube-system" {
+// RegistryEntryProblem explains why an AllowedRegistries entry can never match,
+// or returns an empty string for a usable entry. Trailing slashes are allowed and
+// ignored when matching.
+func RegistryEntryProblem(entry string) string {
+	switch {
+	case strings.TrimSpace(entry) == "":
+		return "is empty"
+	case strings.ContainsAny(entry, " \t\n"):
+		return "contains whitespace"
+	case strings.Contains(entry, "://"):
+		return "contains a URL scheme, list the registry host only"
+	}
+	return ""
+}
+
+// AllowsAnyRegistry returns true if "*" in AllowedRegistries lets every registry through
+func (s *ShieldPolicy) AllowsAnyRegistry() bool {
+	return !s.Spec.StrictRegistryMatching && containsString(s.Spec.AllowedRegistries, "*")
+}
+
+// IsRepositoryAllowed checks if an image repository ("registry/path/name") matches
+// AllowedImageRepositories, either as a whole or through one of its parent paths
+func (s *ShieldPolicy) IsRepositoryAllowed(repository string) bool {
+	if len(s.Spec.AllowedImageRepositories) == 0 {
+		return true // No restriction if list is empty
+	}
+	for _, pattern := range s.Spec.AllowedImageRepositories {
+		for prefix := repository; prefix != "."; prefix = path.Dir(prefix) {
+			if matched, err := path.Match(pattern, prefix); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ShouldApplyToNamespace checks if the policy targets a given namespace, whose labels
+// are matched against NamespaceSelector. System namespace exemptions are applied by
+// the operator, not by the policy.
+func (s *ShieldPolicy) ShouldApplyToNamespace(namespace string, namespaceLabels map[string]string) bool {
+	if len(s.Spec.TargetNamespaces) > 0 && !containsString(s.Spec.TargetNamespaces, namespace) {
 		return false
 	}
-	// If no target namespaces specified, apply to all (except kube-system)
-	if len(s.Spec.TargetNamespaces) == 0 {
+	if s.Spec.NamespaceSelector == nil {
 		return true
 	}
-	for _, ns := range s.Spec.TargetNamespaces {
-		if ns == namespace {
+	selector, err := metav1.LabelSelectorAsSelector(s.Spec.NamespaceSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(namespaceLabels))
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
 			return true
 		}
 	}
@@ -0,0 +1,34 @@
+package v1alpha1
+
+import "testing"
+
+func TestIsRepositoryAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		patterns   []string
+		repository string
+		want       bool
+	}{
+		{name: "no restriction", repository: "docker.io/library/nginx", want: true},
+		{name: "glob", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/myproject/app", want: true},
+		{name: "glob nested", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/myproject/team/app", want: true},
+		{name: "glob deeply nested", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/myproject/team/sub/app", want: true},
+		{name: "exact parent", patterns: []string{"gcr.io/myproject/team"}, repository: "gcr.io/myproject/team/app", want: true},
+		{name: "parent itself is not below the glob", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/myproject", want: false},
+		{name: "sibling project", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/otherproject/app", want: false},
+		{name: "project name prefix", patterns: []string{"gcr.io/myproject/*"}, repository: "gcr.io/myproject-evil/app", want: false},
+		{name: "path segment prefix", patterns: []string{"gcr.io/myproject/team"}, repository: "gcr.io/myproject/teammate/app", want: false},
+		{name: "other registry", patterns: []string{"gcr.io/myproject/*"}, repository: "ghcr.io/myproject/app", want: false},
+		{name: "registry with port", patterns: []string{"registry.example.com:5000/org/*"}, repository: "registry.example.com:5000/org/sub/app", want: true},
+		{name: "second pattern", patterns: []string{"gcr.io/myproject/*", "docker.io/library/*"}, repository: "docker.io/library/nginx", want: true},
+		{name: "invalid pattern", patterns: []string{"gcr.io/[myproject"}, repository: "gcr.io/[myproject", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &ShieldPolicy{Spec: ShieldPolicySpec{AllowedImageRepositories: tt.patterns}}
+			if got := policy.IsRepositoryAllowed(tt.repository); got != tt.want {
+				t.Errorf("IsRepositoryAllowed(%q) with %v = %t, want %t", tt.repository, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,298 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActivityBucket) DeepCopyInto(out *ActivityBucket) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActivityBucket.
+func (in *ActivityBucket) DeepCopy() *ActivityBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(ActivityBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELRule) DeepCopyInto(out *CELRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELRule.
+func (in *CELRule) DeepCopy() *CELRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CELRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CounterReset) DeepCopyInto(out *CounterReset) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CounterReset.
+func (in *CounterReset) DeepCopy() *CounterReset {
+	if in == nil {
+		return nil
+	}
+	out := new(CounterReset)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExemptionMatch) DeepCopyInto(out *ExemptionMatch) {
+	*out = *in
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Owner != nil {
+		in, out := &in.Owner, &out.Owner
+		*out = new(OwnerMatch)
+		**out = **in
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExemptionMatch.
+func (in *ExemptionMatch) DeepCopy() *ExemptionMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ExemptionMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogCapture) DeepCopyInto(out *LogCapture) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogCapture.
+func (in *LogCapture) DeepCopy() *LogCapture {
+	if in == nil {
+		return nil
+	}
+	out := new(LogCapture)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnerMatch) DeepCopyInto(out *OwnerMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnerMatch.
+func (in *OwnerMatch) DeepCopy() *OwnerMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnerMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldEnforcementRecord) DeepCopyInto(out *ShieldEnforcementRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldEnforcementRecord.
+func (in *ShieldEnforcementRecord) DeepCopy() *ShieldEnforcementRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldEnforcementRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ShieldEnforcementRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldEnforcementRecordList) DeepCopyInto(out *ShieldEnforcementRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ShieldEnforcementRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldEnforcementRecordList.
+func (in *ShieldEnforcementRecordList) DeepCopy() *ShieldEnforcementRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldEnforcementRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ShieldEnforcementRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldEnforcementRecordSpec) DeepCopyInto(out *ShieldEnforcementRecordSpec) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldEnforcementRecordSpec.
+func (in *ShieldEnforcementRecordSpec) DeepCopy() *ShieldEnforcementRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldEnforcementRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldExemption) DeepCopyInto(out *ShieldExemption) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldExemption.
+func (in *ShieldExemption) DeepCopy() *ShieldExemption {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldExemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ShieldExemption) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldExemptionList) DeepCopyInto(out *ShieldExemptionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ShieldExemption, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldExemptionList.
+func (in *ShieldExemptionList) DeepCopy() *ShieldExemptionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldExemptionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ShieldExemptionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldExemptionSpec) DeepCopyInto(out *ShieldExemptionSpec) {
+	*out = *in
+	in.Match.DeepCopyInto(&out.Match)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldExemptionSpec.
+func (in *ShieldExemptionSpec) DeepCopy() *ShieldExemptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldExemptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShieldExemptionStatus) DeepCopyInto(out *ShieldExemptionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldExemptionStatus.
+func (in *ShieldExemptionStatus) DeepCopy() *ShieldExemptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ShieldExemptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ShieldPolicy) DeepCopyInto(out *ShieldPolicy) {
 	*out = *in
@@ -71,16 +363,127 @@ func (in *ShieldPolicyList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ShieldPolicySpec) DeepCopyInto(out *ShieldPolicySpec) {
 	*out = *in
+	if in.BlockPrivileged != nil {
+		in, out := &in.BlockPrivileged, &out.BlockPrivileged
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RequireDropAllCapabilities != nil {
+		in, out := &in.RequireDropAllCapabilities, &out.RequireDropAllCapabilities
+		*out = new(bool)
+		**out = **in
+	}
 	if in.AllowedRegistries != nil {
 		in, out := &in.AllowedRegistries, &out.AllowedRegistries
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AllowedImageRepositories != nil {
+		in, out := &in.AllowedImageRepositories, &out.AllowedImageRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedImageDigests != nil {
+		in, out := &in.AllowedImageDigests, &out.AllowedImageDigests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedImagesConfigMapRef != nil {
+		in, out := &in.AllowedImagesConfigMapRef, &out.AllowedImagesConfigMapRef
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+	if in.RegistryAliases != nil {
+		in, out := &in.RegistryAliases, &out.RegistryAliases
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RequireImagePullSecretFor != nil {
+		in, out := &in.RequireImagePullSecretFor, &out.RequireImagePullSecretFor
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.TargetNamespaces != nil {
 		in, out := &in.TargetNamespaces, &out.TargetNamespaces
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MatchOwnerKinds != nil {
+		in, out := &in.MatchOwnerKinds, &out.MatchOwnerKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SeverityOverrides != nil {
+		in, out := &in.SeverityOverrides, &out.SeverityOverrides
+		*out = make(map[string]Severity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]CELRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.GracePeriodAfterCreation != nil {
+		in, out := &in.GracePeriodAfterCreation, &out.GracePeriodAfterCreation
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CounterRetention != nil {
+		in, out := &in.CounterRetention, &out.CounterRetention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CaptureLogsBeforeTermination != nil {
+		in, out := &in.CaptureLogsBeforeTermination, &out.CaptureLogsBeforeTermination
+		*out = new(LogCapture)
+		**out = **in
+	}
+	if in.AlertWebhooks != nil {
+		in, out := &in.AlertWebhooks, &out.AlertWebhooks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxPodLifetime != nil {
+		in, out := &in.MaxPodLifetime, &out.MaxPodLifetime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxPodAge != nil {
+		in, out := &in.MaxPodAge, &out.MaxPodAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RequiredLabels != nil {
+		in, out := &in.RequiredLabels, &out.RequiredLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReevaluationInterval != nil {
+		in, out := &in.ReevaluationInterval, &out.ReevaluationInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.EnforceOnExisting != nil {
+		in, out := &in.EnforceOnExisting, &out.EnforceOnExisting
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldPolicySpec.
@@ -100,6 +503,13 @@ func (in *ShieldPolicyStatus) DeepCopyInto(out *ShieldPolicyStatus) {
 		in, out := &in.LastEnforcementTime, &out.LastEnforcementTime
 		*out = (*in).DeepCopy()
 	}
+	if in.RecentActivity != nil {
+		in, out := &in.RecentActivity, &out.RecentActivity
+		*out = make([]ActivityBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -107,6 +517,21 @@ func (in *ShieldPolicyStatus) DeepCopyInto(out *ShieldPolicyStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ComplianceScore != nil {
+		in, out := &in.ComplianceScore, &out.ComplianceScore
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LastReset != nil {
+		in, out := &in.LastReset, &out.LastReset
+		*out = new(CounterReset)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Simulation != nil {
+		in, out := &in.Simulation, &out.Simulation
+		*out = new(SimulationSummary)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShieldPolicyStatus.
@@ -118,3 +543,31 @@ func (in *ShieldPolicyStatus) DeepCopy() *ShieldPolicyStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SimulationSummary) DeepCopyInto(out *SimulationSummary) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.ViolationsByType != nil {
+		in, out := &in.ViolationsByType, &out.ViolationsByType
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExamplePods != nil {
+		in, out := &in.ExamplePods, &out.ExamplePods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SimulationSummary.
+func (in *SimulationSummary) DeepCopy() *SimulationSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(SimulationSummary)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,3 @@
+// +k8s:deepcopy-gen=package
+// +groupName=wgpolicyk8s.io
+package v1alpha2
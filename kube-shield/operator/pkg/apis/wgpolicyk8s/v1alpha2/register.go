@@ -0,0 +1,36 @@
+// Package v1alpha2 contains the subset of the wgpolicyk8s.io v1alpha2 PolicyReport API
+// the operator writes. The CRD is owned by the Kubernetes Policy WG and must be
+// installed separately.
+package v1alpha2
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the group name used in this package
+	GroupName = "wgpolicyk8s.io"
+	// Version is the API version
+	Version = "v1alpha2"
+)
+
+var (
+	// SchemeGroupVersion is the group version used to register these objects
+	SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+
+	// AddToScheme adds the types in this group-version to the given scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+// addKnownTypes adds the list of known types to Scheme
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&PolicyReport{},
+		&PolicyReportList{},
+	)
+	return nil
+}
@@ -0,0 +1,80 @@
+package v1alpha2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyResult is the outcome of a policy rule for a resource
+type PolicyResult string
+
+// Policy results defined by the PolicyReport API
+const (
+	// StatusPass means the resource satisfies the rule
+	StatusPass PolicyResult = "pass"
+	// StatusFail means the resource violates the rule
+	StatusFail PolicyResult = "fail"
+	// StatusWarn means the rule raised a warning for the resource
+	StatusWarn PolicyResult = "warn"
+	// StatusError means the rule could not be evaluated
+	StatusError PolicyResult = "error"
+	// StatusSkip means the rule was not evaluated
+	StatusSkip PolicyResult = "skip"
+)
+
+// PolicySeverity is the severity of a failed rule
+type PolicySeverity string
+
+// Severities defined by the PolicyReport API
+const (
+	SeverityCritical PolicySeverity = "critical"
+	SeverityHigh     PolicySeverity = "high"
+	SeverityMedium   PolicySeverity = "medium"
+	SeverityLow      PolicySeverity = "low"
+	SeverityInfo     PolicySeverity = "info"
+)
+
+// PolicyReportSummary counts the results of a report by outcome
+type PolicyReportSummary struct {
+	Pass  int `json:"pass"`
+	Fail  int `json:"fail"`
+	Warn  int `json:"warn"`
+	Error int `json:"error"`
+	Skip  int `json:"skip"`
+}
+
+// PolicyReportResult is the result of one policy rule for one or more resources
+type PolicyReportResult struct {
+	Source     string                   `json:"source,omitempty"`
+	Policy     string                   `json:"policy"`
+	Rule       string                   `json:"rule,omitempty"`
+	Category   string                   `json:"category,omitempty"`
+	Severity   PolicySeverity           `json:"severity,omitempty"`
+	Timestamp  metav1.Timestamp         `json:"timestamp,omitempty"`
+	Result     PolicyResult             `json:"result,omitempty"`
+	Scored     bool                     `json:"scored,omitempty"`
+	Resources  []corev1.ObjectReference `json:"resources,omitempty"`
+	Message    string                   `json:"message,omitempty"`
+	Properties map[string]string        `json:"properties,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolicyReport holds the policy results of the resources in a namespace
+type PolicyReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Scope   *corev1.ObjectReference `json:"scope,omitempty"`
+	Summary PolicyReportSummary     `json:"summary,omitempty"`
+	Results []PolicyReportResult    `json:"results,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PolicyReportList contains a list of PolicyReport
+type PolicyReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PolicyReport `json:"items"`
+}
@@ -0,0 +1,5 @@
+// Package auditpb holds the Go code generated from proto/audit/v1/audit.proto, the
+// AuditService the grpc audit sink calls. Run "make proto" after editing the .proto.
+package auditpb
+
+//go:generate protoc -I ../../../proto --go_out=../../.. --go_opt=module=github.com/kubeshield/operator --go-grpc_out=../../.. --go-grpc_opt=module=github.com/kubeshield/operator audit/v1/audit.proto
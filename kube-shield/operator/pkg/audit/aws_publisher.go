@@ -0,0 +1,120 @@
+//go:build aws
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// awsMaxBackoff caps the delay between retries of a throttled publish
+const awsMaxBackoff = 5 * time.Second
+
+// newAWSPublisher creates the SNS or EventBridge publisher for target, with
+// credentials from the SDK's default chain
+func newAWSPublisher(ctx context.Context, target awsTarget, opts AWSOptions) (AWSPublisher, error) {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	// The standard retryer backs off and retries throttling errors and 5xx responses
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(target.Region),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = maxAttempts
+				o.MaxBackoff = awsMaxBackoff
+			})
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+
+	if target.Service == awsServiceSNS {
+		return &snsPublisher{client: sns.NewFromConfig(cfg), topicARN: target.ARN}, nil
+	}
+	return &eventBridgePublisher{client: eventbridge.NewFromConfig(cfg), busARN: target.ARN, maxAttempts: maxAttempts}, nil
+}
+
+// snsAPI is the part of the SNS client snsPublisher uses
+type snsAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// eventBridgeAPI is the part of the EventBridge client eventBridgePublisher uses
+type eventBridgeAPI interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// snsPublisher publishes events to an SNS topic. The event type and severity are
+// message attributes, so subscriptions can filter on them.
+type snsPublisher struct {
+	client   snsAPI
+	topicARN string
+}
+
+// Publish implements AWSPublisher
+func (p *snsPublisher) Publish(ctx context.Context, event SecurityEvent, payload []byte) error {
+	_, err := p.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(p.topicARN),
+		Message:  aws.String(string(payload)),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"eventType": {DataType: aws.String("String"), StringValue: aws.String(event.EventType)},
+			"severity":  {DataType: aws.String("String"), StringValue: aws.String(event.Severity)},
+		},
+	})
+	return err
+}
+
+// eventBridgePublisher puts events on an EventBridge bus
+type eventBridgePublisher struct {
+	client      eventBridgeAPI
+	busARN      string
+	maxAttempts int
+}
+
+// Publish implements AWSPublisher. PutEvents reports throttled entries in its
+// response rather than as an error, so the SDK does not retry them; they are retried
+// here with the same bounded backoff.
+func (p *eventBridgePublisher) Publish(ctx context.Context, _ SecurityEvent, payload []byte) error {
+	input := &eventbridge.PutEventsInput{
+		Entries: []eventbridgetypes.PutEventsRequestEntry{{
+			EventBusName: aws.String(p.busARN),
+			Source:       aws.String(awsEventSource),
+			DetailType:   aws.String(awsDetailType),
+			Detail:       aws.String(string(payload)),
+		}},
+	}
+
+	backoff := 200 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		out, err := p.client.PutEvents(ctx, input)
+		if err != nil {
+			return err
+		}
+		if out.FailedEntryCount == 0 || len(out.Entries) == 0 {
+			return nil
+		}
+		entry := out.Entries[0]
+		if aws.ToString(entry.ErrorCode) != "ThrottlingException" || attempt >= p.maxAttempts {
+			return fmt.Errorf("EventBridge rejected the event: %s %s", aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, awsMaxBackoff)
+	}
+}
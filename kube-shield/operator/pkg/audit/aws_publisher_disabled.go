@@ -0,0 +1,13 @@
+//go:build !aws
+
+package audit
+
+import (
+	"context"
+	"fmt"
+)
+
+// newAWSPublisher fails in builds without the aws build tag, which leave out the AWS SDK
+func newAWSPublisher(_ context.Context, _ awsTarget, _ AWSOptions) (AWSPublisher, error) {
+	return nil, fmt.Errorf("the %q audit sink needs an operator built with -tags aws", SinkAWS)
+}
@@ -0,0 +1,113 @@
+//go:build aws
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// fakeSNS records the messages published to it
+type fakeSNS struct {
+	inputs []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(_ context.Context, params *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &sns.PublishOutput{MessageId: aws.String("1")}, nil
+}
+
+// fakeEventBridge answers PutEvents with the queued responses, one per call
+type fakeEventBridge struct {
+	responses []*eventbridge.PutEventsOutput
+	inputs    []*eventbridge.PutEventsInput
+}
+
+func (f *fakeEventBridge) PutEvents(_ context.Context, params *eventbridge.PutEventsInput, _ ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if len(f.responses) == 0 {
+		return nil, errors.New("unexpected PutEvents call")
+	}
+	out := f.responses[0]
+	f.responses = f.responses[1:]
+	return out, nil
+}
+
+func failedEntry(code string) *eventbridge.PutEventsOutput {
+	return &eventbridge.PutEventsOutput{
+		FailedEntryCount: 1,
+		Entries:          []eventbridgetypes.PutEventsResultEntry{{ErrorCode: aws.String(code), ErrorMessage: aws.String(code)}},
+	}
+}
+
+func accepted() *eventbridge.PutEventsOutput {
+	return &eventbridge.PutEventsOutput{Entries: []eventbridgetypes.PutEventsResultEntry{{EventId: aws.String("1")}}}
+}
+
+func TestSNSPublisherSetsFilterAttributes(t *testing.T) {
+	client := &fakeSNS{}
+	publisher := &snsPublisher{client: client, topicARN: "arn:aws:sns:eu-west-1:123456789012:security"}
+
+	event := SecurityEvent{EventType: "PRIVILEGED_CONTAINER", Severity: "CRITICAL"}
+	if err := publisher.Publish(context.Background(), event, []byte(`{"eventType":"PRIVILEGED_CONTAINER"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.inputs) != 1 {
+		t.Fatalf("%d messages published, want 1", len(client.inputs))
+	}
+	input := client.inputs[0]
+	if aws.ToString(input.TopicArn) != publisher.topicARN || aws.ToString(input.Message) != `{"eventType":"PRIVILEGED_CONTAINER"}` {
+		t.Errorf("published %s to %s", aws.ToString(input.Message), aws.ToString(input.TopicArn))
+	}
+	for name, want := range map[string]string{"eventType": "PRIVILEGED_CONTAINER", "severity": "CRITICAL"} {
+		if got := aws.ToString(input.MessageAttributes[name].StringValue); got != want {
+			t.Errorf("attribute %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestEventBridgePublisherRetriesThrottledEntries(t *testing.T) {
+	client := &fakeEventBridge{responses: []*eventbridge.PutEventsOutput{failedEntry("ThrottlingException"), accepted()}}
+	publisher := &eventBridgePublisher{client: client, busARN: "arn:aws:events:eu-west-1:123456789012:event-bus/security", maxAttempts: 3}
+
+	if err := publisher.Publish(context.Background(), SecurityEvent{}, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.inputs) != 2 {
+		t.Fatalf("PutEvents called %d times, want 2", len(client.inputs))
+	}
+	entry := client.inputs[0].Entries[0]
+	if aws.ToString(entry.Source) != awsEventSource || aws.ToString(entry.DetailType) != awsDetailType || aws.ToString(entry.EventBusName) != publisher.busARN {
+		t.Errorf("entry = %+v, want the KubeShield source and detail type on the bus", entry)
+	}
+}
+
+func TestEventBridgePublisherGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []*eventbridge.PutEventsOutput
+		wantCalls int
+	}{
+		{name: "throttled past max attempts", responses: []*eventbridge.PutEventsOutput{failedEntry("ThrottlingException"), failedEntry("ThrottlingException")}, wantCalls: 2},
+		{name: "rejected entry", responses: []*eventbridge.PutEventsOutput{failedEntry("AccessDeniedException")}, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeEventBridge{responses: tt.responses}
+			publisher := &eventBridgePublisher{client: client, busARN: "arn:aws:events:eu-west-1:123456789012:event-bus/security", maxAttempts: 2}
+
+			if err := publisher.Publish(context.Background(), SecurityEvent{}, []byte(`{}`)); err == nil {
+				t.Fatal("Publish succeeded, want an error")
+			}
+			if len(client.inputs) != tt.wantCalls {
+				t.Errorf("PutEvents called %d times, want %d", len(client.inputs), tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+const (
+	// SinkAWS publishes events to an SNS topic or an EventBridge bus. The AWS SDK is
+	// only compiled in with the aws build tag.
+	SinkAWS = "aws"
+
+	// awsEventSource and awsDetailType identify KubeShield events on an EventBridge
+	// bus, for rules to match on
+	awsEventSource = "kubeshield.io"
+	awsDetailType  = "KubeShield Security Event"
+)
+
+// AWS services an AWSSink can publish to, taken from the target ARN
+const (
+	awsServiceSNS         = "sns"
+	awsServiceEventBridge = "events"
+)
+
+// AWSOptions configures an AWSSink
+type AWSOptions struct {
+	// TargetARN is an SNS topic (arn:aws:sns:...) or an EventBridge event bus
+	// (arn:aws:events:...:event-bus/name). Its region is the region published to.
+	TargetARN string
+
+	// Timeout is the deadline of each publish, including retries
+	Timeout time.Duration
+
+	// MaxAttempts bounds the attempts of a throttled or failing publish
+	MaxAttempts int
+}
+
+// AWSPublisher publishes one marshaled event to the target. Implementations retry
+// throttled calls themselves.
+type AWSPublisher interface {
+	Publish(ctx context.Context, event SecurityEvent, payload []byte) error
+}
+
+// AWSSink publishes security events to an SNS topic or EventBridge bus. Credentials
+// come from the SDK's default chain, which picks up IRSA (the service account's
+// eks.amazonaws.com/role-arn annotation) on EKS.
+type AWSSink struct {
+	TargetARN string
+	Timeout   time.Duration
+	Publisher AWSPublisher
+}
+
+// NewAWSSink creates an AWSSink. It fails on an invalid ARN, and always in builds
+// without the aws build tag.
+func NewAWSSink(ctx context.Context, opts AWSOptions) (*AWSSink, error) {
+	target, err := parseAWSTarget(opts.TargetARN)
+	if err != nil {
+		return nil, err
+	}
+	publisher, err := newAWSPublisher(ctx, target, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AWSSink{TargetARN: opts.TargetARN, Timeout: opts.Timeout, Publisher: publisher}, nil
+}
+
+// Name implements Sink
+func (s *AWSSink) Name() string {
+	return SinkAWS
+}
+
+// Send implements Sink
+func (s *AWSSink) Send(ctx context.Context, event SecurityEvent) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling security event: %w", err)
+	}
+
+	start := time.Now()
+	if err := s.Publisher.Publish(ctx, event, payload); err != nil {
+		metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeError).Observe(time.Since(start).Seconds())
+		return fmt.Errorf("publishing event to %s: %w", s.TargetARN, err)
+	}
+	metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeSuccess).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// awsTarget is the part of a target ARN the publisher needs
+type awsTarget struct {
+	ARN     string
+	Service string
+	Region  string
+}
+
+// parseAWSTarget checks that arn is an SNS topic or EventBridge bus ARN
+func parseAWSTarget(arn string) (awsTarget, error) {
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[3] == "" {
+		return awsTarget{}, fmt.Errorf("invalid AWS target ARN %q", arn)
+	}
+	target := awsTarget{ARN: arn, Service: parts[2], Region: parts[3]}
+	switch {
+	case target.Service == awsServiceSNS:
+	case target.Service == awsServiceEventBridge && strings.HasPrefix(parts[5], "event-bus/"):
+	default:
+		return awsTarget{}, fmt.Errorf("AWS target ARN %q is neither an SNS topic nor an EventBridge event bus", arn)
+	}
+	return target, nil
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePublisher records what an AWSSink publishes
+type fakePublisher struct {
+	err      error
+	events   []SecurityEvent
+	payloads [][]byte
+	deadline bool
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, event SecurityEvent, payload []byte) error {
+	_, f.deadline = ctx.Deadline()
+	f.events = append(f.events, event)
+	f.payloads = append(f.payloads, payload)
+	return f.err
+}
+
+func TestAWSSinkSend(t *testing.T) {
+	publisher := &fakePublisher{}
+	sink := &AWSSink{TargetARN: "arn:aws:sns:eu-west-1:123456789012:security", Timeout: time.Second, Publisher: publisher}
+
+	event := SecurityEvent{EventType: "HOST_NETWORK", Severity: "HIGH", PodName: "web"}
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 1 {
+		t.Fatalf("%d events published, want 1", len(publisher.events))
+	}
+	var published SecurityEvent
+	if err := json.Unmarshal(publisher.payloads[0], &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.EventType != "HOST_NETWORK" || published.PodName != "web" {
+		t.Errorf("payload = %s, want the marshaled event", publisher.payloads[0])
+	}
+	if !publisher.deadline {
+		t.Error("publish had no deadline, want the sink's Timeout")
+	}
+}
+
+func TestAWSSinkSendError(t *testing.T) {
+	sink := &AWSSink{TargetARN: "arn:aws:sns:eu-west-1:123456789012:security", Publisher: &fakePublisher{err: errors.New("AccessDenied")}}
+
+	err := sink.Send(context.Background(), SecurityEvent{EventType: "HOST_NETWORK"})
+	if err == nil || !strings.Contains(err.Error(), sink.TargetARN) || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Send = %v, want the target and the publish error", err)
+	}
+}
+
+func TestParseAWSTarget(t *testing.T) {
+	tests := []struct {
+		arn         string
+		wantService string
+		wantRegion  string
+		wantErr     bool
+	}{
+		{arn: "arn:aws:sns:eu-west-1:123456789012:security", wantService: awsServiceSNS, wantRegion: "eu-west-1"},
+		{arn: "arn:aws:events:us-east-1:123456789012:event-bus/security", wantService: awsServiceEventBridge, wantRegion: "us-east-1"},
+		{arn: "arn:aws-us-gov:sns:us-gov-west-1:123456789012:security", wantService: awsServiceSNS, wantRegion: "us-gov-west-1"},
+		{arn: "arn:aws:events:us-east-1:123456789012:rule/security", wantErr: true},
+		{arn: "arn:aws:sqs:us-east-1:123456789012:queue", wantErr: true},
+		{arn: "arn:aws:sns::123456789012:security", wantErr: true},
+		{arn: "not-an-arn", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			target, err := parseAWSTarget(tt.arn)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAWSTarget(%q) = %+v, want an error", tt.arn, target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Service != tt.wantService || target.Region != tt.wantRegion {
+				t.Errorf("parseAWSTarget(%q) = %+v, want %s in %s", tt.arn, target, tt.wantService, tt.wantRegion)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package audit contains the plumbing used to deliver security events to the audit service
+package audit
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+// HTTPOptions tunes the HTTP client used to reach the audit service
+type HTTPOptions struct {
+	// Timeout bounds each request, including reading the response
+	Timeout time.Duration
+
+	// MaxIdleConnsPerHost is the number of keep-alive connections kept per host
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is how long an idle keep-alive connection is kept open
+	IdleConnTimeout time.Duration
+
+	// UseEnvProxy routes requests through the proxy named by HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	UseEnvProxy bool
+
+	// Proxy, when set, routes requests through this proxy instead of the environment's
+	Proxy *url.URL
+
+	// NoProxy lists hosts, domains and CIDRs reached without Proxy
+	NoProxy []string
+}
+
+// NewHTTPClient builds an HTTP client for the audit service. Connections are
+// kept alive and reused across events, and HTTP/2 is negotiated when the
+// server supports it.
+func NewHTTPClient(opts HTTPOptions) *http.Client {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	switch {
+	case opts.Proxy != nil:
+		transport.Proxy = proxyFunc(opts.Proxy, opts.NoProxy)
+	case opts.UseEnvProxy:
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &userAgentTransport{
+			base:      transport,
+			userAgent: version.UserAgent(),
+		},
+	}
+}
+
+// userAgentTransport sets the operator's User-Agent on every outgoing request
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
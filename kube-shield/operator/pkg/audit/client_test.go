@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+// countingServer is an audit service that counts the connections opened to it
+type countingServer struct {
+	*httptest.Server
+	connections atomic.Int64
+	requests    atomic.Int64
+
+	mu         sync.Mutex
+	userAgents map[string]bool
+	protocols  map[string]bool
+}
+
+func newCountingServer(t *testing.T, tls bool) *countingServer {
+	t.Helper()
+	s := &countingServer{userAgents: make(map[string]bool), protocols: make(map[string]bool)}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		s.userAgents[r.UserAgent()] = true
+		s.protocols[r.Proto] = true
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.connections.Add(1)
+		}
+	}
+	if tls {
+		s.EnableHTTP2 = true
+		s.StartTLS()
+	} else {
+		s.Start()
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// sendConcurrently delivers events to sink from workers goroutines
+func sendConcurrently(t *testing.T, sink Sink, workers, events int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	var failed atomic.Int64
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < events/workers; i++ {
+				if err := sink.Send(context.Background(), SecurityEvent{EventType: "PRIVILEGED_CONTAINER"}); err != nil {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := failed.Load(); n > 0 {
+		t.Fatalf("%d of %d events failed", n, events)
+	}
+}
+
+func TestHTTPClientReusesConnections(t *testing.T) {
+	const workers, events = 8, 400
+	server := newCountingServer(t, false)
+	client := NewHTTPClient(HTTPOptions{Timeout: 5 * time.Second, MaxIdleConnsPerHost: workers, IdleConnTimeout: time.Minute})
+
+	sendConcurrently(t, NewHTTPSink(server.URL, client), workers, events)
+
+	if got := server.requests.Load(); got != events {
+		t.Errorf("server received %d requests, want %d", got, events)
+	}
+	// Every worker may open a connection, after that they are all reused
+	if got := server.connections.Load(); got > workers {
+		t.Errorf("%d connections opened for %d events from %d workers, want at most %d", got, events, workers, workers)
+	}
+	if want := version.UserAgent(); len(server.userAgents) != 1 || !server.userAgents[want] {
+		t.Errorf("User-Agents = %v, want only %q", server.userAgents, want)
+	}
+}
+
+func TestHTTPClientNegotiatesHTTP2(t *testing.T) {
+	const workers, events = 8, 200
+	server := newCountingServer(t, true)
+	client := NewHTTPClient(HTTPOptions{Timeout: 5 * time.Second, MaxIdleConnsPerHost: workers, IdleConnTimeout: time.Minute})
+	// Trust the test server's certificate
+	transport := client.Transport.(*userAgentTransport).base.(*http.Transport)
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	sendConcurrently(t, NewHTTPSink(server.URL, client), workers, events)
+
+	if len(server.protocols) != 1 || !server.protocols["HTTP/2.0"] {
+		t.Errorf("protocols = %v, want only HTTP/2.0", server.protocols)
+	}
+	// Only the first concurrent requests dial, the rest share their connections
+	if got := server.connections.Load(); got > workers {
+		t.Errorf("%d connections opened over HTTP/2 for %d events, want at most %d", got, events, workers)
+	}
+}
@@ -0,0 +1,92 @@
+package audit
+
+const (
+	// ActionTerminated marks violations that cause the pod to be deleted
+	ActionTerminated = "TERMINATED"
+
+	// ActionTerminationFailed marks violations whose pod could not be deleted
+	ActionTerminationFailed = "TERMINATION_FAILED"
+
+	// ActionWarn marks violations that are reported and surfaced as Events on the pod
+	ActionWarn = "WARN"
+
+	// ActionAudit marks violations that are only reported
+	ActionAudit = "AUDIT"
+
+	// ActionAllowed marks violations an external decision point allowed to keep running
+	ActionAllowed = "ALLOWED"
+
+	// ActionExempted marks the event raised when an exemption suppresses a violation
+	ActionExempted = "EXEMPTED"
+
+	// ActionQuarantined marks events whose pod was isolated from the network
+	ActionQuarantined = "QUARANTINED"
+
+	// ActionAuditStaticPod marks violations of static pods, which the kubelet would
+	// recreate if they were deleted, so they are only reported
+	ActionAuditStaticPod = "AUDIT_STATIC_POD"
+)
+
+const (
+	// EventTypeEnforcementFailed is reported when a pod could not be terminated
+	EventTypeEnforcementFailed = "ENFORCEMENT_FAILED"
+
+	// EventTypePDBBlocked is reported when a PodDisruptionBudget defers a termination
+	EventTypePDBBlocked = "PDB_BLOCKED"
+)
+
+// SecurityEvent represents a security event to be sent to the audit service
+type SecurityEvent struct {
+	Timestamp       string   `json:"timestamp"`
+	EventType       string   `json:"eventType"`
+	Severity        string   `json:"severity"`
+	PodName         string   `json:"podName"`
+	Namespace       string   `json:"namespace"`
+	Container       string   `json:"container,omitempty"`
+	Image           string   `json:"image,omitempty"`
+	ResolvedImageID string   `json:"resolvedImageID,omitempty"`
+	ResolvedDigest  string   `json:"resolvedDigest,omitempty"`
+	Reason          string   `json:"reason"`
+	ReasonCode      string   `json:"reasonCode,omitempty"`
+	Remediation     string   `json:"remediation,omitempty"`
+	Action          string   `json:"action"`
+	PolicyName      string   `json:"policyName"`
+	NodeName        string   `json:"nodeName,omitempty"`
+	Description     string   `json:"description"`
+	OperatorVersion string   `json:"operatorVersion,omitempty"`
+	Rule            string   `json:"rule,omitempty"`
+	ExemptedCheck   string   `json:"exemptedCheck,omitempty"`
+	Markers         []string `json:"markers,omitempty"`
+	NodeDraining    bool     `json:"nodeDraining,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	DuplicatedBy    string   `json:"duplicatedBy,omitempty"`
+
+	// Containers and ContainerCount are set instead of Container on an event
+	// coalescing the same violation of many containers of the pod
+	Containers     []string `json:"containers,omitempty"`
+	ContainerCount int      `json:"containerCount,omitempty"`
+
+	// ClusterUpgradeSuspected is set on violations only audited because the cluster
+	// or the pod's node seemed to be upgrading
+	ClusterUpgradeSuspected bool `json:"clusterUpgradeSuspected,omitempty"`
+
+	// CapturedLogs holds the tail of each container's logs, by container name,
+	// read just before the pod was terminated
+	CapturedLogs map[string]string `json:"capturedLogs,omitempty"`
+}
+
+// LogFields returns the key/value pairs that correlate log lines about the event
+// with the event itself, for logr's WithValues
+func (e SecurityEvent) LogFields() []interface{} {
+	fields := []interface{}{"policy", e.PolicyName, "eventType", e.EventType}
+	if e.Rule != "" {
+		fields = append(fields, "rule", e.Rule)
+	}
+	if e.Container != "" {
+		fields = append(fields, "container", e.Container)
+	}
+	if e.ContainerCount > 0 {
+		fields = append(fields, "containers", e.ContainerCount)
+	}
+	return fields
+}
@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/kubeshield/operator/pkg/audit/auditpb"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+const (
+	// SinkGRPC is the name of the GRPCSink, which replaces the http sink when
+	// AUDIT_PROTOCOL is grpc
+	SinkGRPC = "grpc"
+
+	// grpcServiceConfig retries calls the receiver could not take yet. Deadlines are
+	// set per call from GRPCOptions.Timeout and bound the retries too.
+	grpcServiceConfig = `{
+		"methodConfig": [{
+			"name": [{"service": "kubeshield.audit.v1.AuditService"}],
+			"retryPolicy": {
+				"maxAttempts": 4,
+				"initialBackoff": "0.2s",
+				"maxBackoff": "5s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE", "RESOURCE_EXHAUSTED"]
+			}
+		}]
+	}`
+)
+
+// GRPCOptions configures a GRPCSink
+type GRPCOptions struct {
+	// Target is the gRPC target of the audit endpoint, e.g. dns:///audit-bus:9443
+	Target string
+
+	// Timeout is the deadline of each call, including retries
+	Timeout time.Duration
+
+	// Insecure disables TLS, for in-cluster endpoints behind a service mesh
+	Insecure bool
+
+	// CAFile verifies the server certificate instead of the system roots
+	CAFile string
+
+	// CertFile and KeyFile are the client certificate presented for mTLS
+	CertFile string
+	KeyFile  string
+
+	// dialOptions are appended to the sink's own, e.g. to dial an in-memory listener
+	dialOptions []grpc.DialOption
+}
+
+// GRPCSink sends security events to an AuditService endpoint over gRPC. It is a
+// manager Runnable so its connection is closed when the operator stops.
+type GRPCSink struct {
+	Target  string
+	Timeout time.Duration
+
+	conn   *grpc.ClientConn
+	client auditpb.AuditServiceClient
+}
+
+// NewGRPCSink creates a GRPCSink. The connection is established lazily, so an
+// unavailable endpoint does not hold up startup.
+func NewGRPCSink(opts GRPCOptions) (*GRPCSink, error) {
+	if opts.Target == "" {
+		return nil, fmt.Errorf("gRPC audit target not set")
+	}
+
+	creds := insecure.NewCredentials()
+	if !opts.Insecure {
+		tlsConfig, err := grpcTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	// grpc.Dial does not connect before the first call without WithBlock. Its
+	// successor, grpc.NewClient, needs gRPC-Go 1.63.
+	dialOptions := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(grpcServiceConfig),
+	}, opts.dialOptions...)
+	conn, err := grpc.Dial(opts.Target, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to gRPC audit endpoint: %w", err)
+	}
+	return &GRPCSink{
+		Target:  opts.Target,
+		Timeout: opts.Timeout,
+		conn:    conn,
+		client:  auditpb.NewAuditServiceClient(conn),
+	}, nil
+}
+
+// grpcTLSConfig builds the TLS configuration, with a client certificate for mTLS
+// when one is configured
+func grpcTLSConfig(opts GRPCOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CAFile != "" {
+		ca, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading gRPC audit CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading gRPC audit client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Name implements Sink
+func (s *GRPCSink) Name() string {
+	return SinkGRPC
+}
+
+// Send implements Sink. Each event is sent as a batch of one.
+func (s *GRPCSink) Send(ctx context.Context, event SecurityEvent) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	batch := &auditpb.SecurityEventBatch{Events: []*auditpb.SecurityEvent{eventToProto(event)}}
+	if _, err := s.client.SendEvents(ctx, batch); err != nil {
+		metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeError).Observe(time.Since(start).Seconds())
+		return fmt.Errorf("sending event over gRPC: %w", err)
+	}
+	metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeSuccess).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// eventToProto converts an event to the SecurityEvent message of
+// proto/audit/v1/audit.proto
+func eventToProto(event SecurityEvent) *auditpb.SecurityEvent {
+	return &auditpb.SecurityEvent{
+		Timestamp:               event.Timestamp,
+		EventType:               event.EventType,
+		Severity:                event.Severity,
+		PodName:                 event.PodName,
+		Namespace:               event.Namespace,
+		Container:               event.Container,
+		Image:                   event.Image,
+		Reason:                  event.Reason,
+		Action:                  event.Action,
+		PolicyName:              event.PolicyName,
+		NodeName:                event.NodeName,
+		Description:             event.Description,
+		OperatorVersion:         event.OperatorVersion,
+		Rule:                    event.Rule,
+		ExemptedCheck:           event.ExemptedCheck,
+		Markers:                 event.Markers,
+		NodeDraining:            event.NodeDraining,
+		Error:                   event.Error,
+		DuplicatedBy:            event.DuplicatedBy,
+		CapturedLogs:            event.CapturedLogs,
+		ResolvedImageId:         event.ResolvedImageID,
+		Containers:              event.Containers,
+		ContainerCount:          uint32(event.ContainerCount),
+		ReasonCode:              event.ReasonCode,
+		Remediation:             event.Remediation,
+		ResolvedDigest:          event.ResolvedDigest,
+		ClusterUpgradeSuspected: event.ClusterUpgradeSuspected,
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica sends
+// events, so the sink must be closed on all of them.
+func (s *GRPCSink) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable, closing the connection once the manager stops
+func (s *GRPCSink) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return s.conn.Close()
+}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kubeshield/operator/pkg/audit/auditpb"
+)
+
+// fakeAuditService records the batches it receives and fails the first calls with
+// the configured errors
+type fakeAuditService struct {
+	auditpb.UnimplementedAuditServiceServer
+
+	mu       sync.Mutex
+	failures []error
+	calls    int
+	batches  []*auditpb.SecurityEventBatch
+}
+
+func (s *fakeAuditService) SendEvents(_ context.Context, batch *auditpb.SecurityEventBatch) (*auditpb.SendEventsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		return nil, err
+	}
+	s.batches = append(s.batches, batch)
+	return &auditpb.SendEventsResponse{Accepted: uint32(len(batch.Events))}, nil
+}
+
+// startAuditService serves service on an in-memory listener and returns a sink
+// connected to it
+func startAuditService(t *testing.T, service *fakeAuditService) *GRPCSink {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	auditpb.RegisterAuditServiceServer(server, service)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	sink, err := NewGRPCSink(GRPCOptions{
+		Target:   "passthrough:///bufnet",
+		Timeout:  5 * time.Second,
+		Insecure: true,
+		dialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sink.conn.Close() })
+	return sink
+}
+
+// fullEvent returns an event with every field set
+func fullEvent() SecurityEvent {
+	return SecurityEvent{
+		Timestamp:               "2024-05-01T12:00:00Z",
+		EventType:               "PRIVILEGED_CONTAINER",
+		Severity:                "CRITICAL",
+		PodName:                 "web-7d9f",
+		Namespace:               "production",
+		Container:               "app",
+		Image:                   "registry.example.com/app:v1",
+		ResolvedImageID:         "docker-pullable://registry.example.com/app@sha256:0123",
+		ResolvedDigest:          "sha256:0123",
+		Reason:                  "Container app runs privileged",
+		ReasonCode:              "KS-PRIV-001",
+		Remediation:             "drop privileged",
+		Action:                  ActionTerminated,
+		PolicyName:              "restricted",
+		NodeName:                "node-1",
+		Description:             "Pod web-7d9f violates policy restricted",
+		OperatorVersion:         "v1.2.3",
+		Rule:                    "no-privileged",
+		ExemptedCheck:           "HOST_NETWORK",
+		Markers:                 []string{"PDB_BLOCKED"},
+		NodeDraining:            true,
+		Error:                   "forbidden",
+		DuplicatedBy:            "baseline",
+		Containers:              []string{"app", "sidecar"},
+		ContainerCount:          2,
+		ClusterUpgradeSuspected: true,
+		CapturedLogs:            map[string]string{"app": "panic: boom"},
+	}
+}
+
+func TestEventToProtoSetsEveryField(t *testing.T) {
+	message := reflect.ValueOf(eventToProto(fullEvent())).Elem()
+	for i := 0; i < message.NumField(); i++ {
+		field := message.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if message.Field(i).IsZero() {
+			t.Errorf("auditpb.SecurityEvent.%s is not set from SecurityEvent", field.Name)
+		}
+	}
+}
+
+func TestGRPCSinkSendsEvent(t *testing.T) {
+	service := &fakeAuditService{}
+	sink := startAuditService(t, service)
+
+	event := fullEvent()
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(service.batches) != 1 || len(service.batches[0].Events) != 1 {
+		t.Fatalf("received %v, want one batch of one event", service.batches)
+	}
+	got := service.batches[0].Events[0]
+	if got.PodName != event.PodName || got.ResolvedImageId != event.ResolvedImageID ||
+		got.ContainerCount != 2 || !got.ClusterUpgradeSuspected ||
+		!reflect.DeepEqual(got.Markers, event.Markers) ||
+		!reflect.DeepEqual(got.CapturedLogs, event.CapturedLogs) {
+		t.Errorf("received %v, want the fields of %+v", got, event)
+	}
+}
+
+func TestGRPCSinkRetriesUnavailable(t *testing.T) {
+	service := &fakeAuditService{failures: []error{
+		status.Error(codes.Unavailable, "starting"),
+		status.Error(codes.ResourceExhausted, "busy"),
+	}}
+	sink := startAuditService(t, service)
+
+	if err := sink.Send(context.Background(), fullEvent()); err != nil {
+		t.Fatalf("Send after retryable errors = %v, want nil", err)
+	}
+	if service.calls != 3 || len(service.batches) != 1 {
+		t.Errorf("calls = %d, batches = %d, want 3 calls delivering 1 batch", service.calls, len(service.batches))
+	}
+}
+
+func TestGRPCSinkDoesNotRetryOtherErrors(t *testing.T) {
+	service := &fakeAuditService{failures: []error{status.Error(codes.InvalidArgument, "bad event")}}
+	sink := startAuditService(t, service)
+
+	err := sink.Send(context.Background(), fullEvent())
+	if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+		t.Fatalf("Send = %v, want InvalidArgument", err)
+	}
+	if service.calls != 1 {
+		t.Errorf("calls = %d, want 1", service.calls)
+	}
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// HTTPSink posts security events to the audit service's /log endpoint
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSink creates an HTTPSink for the audit service at url
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	return &HTTPSink{URL: url, Client: client}
+}
+
+// Name implements Sink
+func (s *HTTPSink) Name() string {
+	return SinkHTTP
+}
+
+// Send implements Sink
+func (s *HTTPSink) Send(ctx context.Context, event SecurityEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling security event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/log", s.URL), bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	// Let the audit service join the reconcile trace
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeError).Observe(time.Since(start).Seconds())
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the keep-alive connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	metrics.AuditPostDuration.WithLabelValues(outcome(resp.StatusCode)).Observe(time.Since(start).Seconds())
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("audit service returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+// outcome classifies an audit service response for the latency histogram
+func outcome(statusCode int) string {
+	switch {
+	case statusCode < 400:
+		return metrics.OutcomeSuccess
+	case statusCode == http.StatusTooManyRequests || statusCode >= 500:
+		return metrics.OutcomeRetryable
+	default:
+		return metrics.OutcomeError
+	}
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// sampleCount returns how many observations a histogram holds
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	if err := observer.(prometheus.Metric).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestHTTPSinkObservesOutcome(t *testing.T) {
+	tests := []struct {
+		status  int
+		outcome string
+		wantErr bool
+	}{
+		{status: http.StatusOK, outcome: metrics.OutcomeSuccess},
+		{status: http.StatusBadRequest, outcome: metrics.OutcomeError, wantErr: true},
+		{status: http.StatusTooManyRequests, outcome: metrics.OutcomeRetryable, wantErr: true},
+		{status: http.StatusServiceUnavailable, outcome: metrics.OutcomeRetryable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			sink := NewHTTPSink(server.URL, NewHTTPClient(HTTPOptions{Timeout: time.Second}))
+
+			histogram := metrics.AuditPostDuration.WithLabelValues(tt.outcome)
+			before := sampleCount(t, histogram)
+			err := sink.Send(context.Background(), SecurityEvent{EventType: "PRIVILEGED_CONTAINER"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send error = %v, want error %t", err, tt.wantErr)
+			}
+			if got := sampleCount(t, histogram) - before; got != 1 {
+				t.Errorf("%d %s observations, want 1", got, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkObservesUnreachableServiceAsError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	sink := NewHTTPSink(server.URL, NewHTTPClient(HTTPOptions{Timeout: time.Second}))
+
+	histogram := metrics.AuditPostDuration.WithLabelValues(metrics.OutcomeError)
+	before := sampleCount(t, histogram)
+	if err := sink.Send(context.Background(), SecurityEvent{}); err == nil {
+		t.Fatal("Send to a closed server succeeded")
+	}
+	if got := sampleCount(t, histogram) - before; got != 1 {
+		t.Errorf("%d error observations, want 1", got)
+	}
+}
@@ -0,0 +1,305 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/parquet-go/parquet-go"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// parquetSchemaVersion is stored in every file's key/value metadata and is bumped
+	// whenever a column is added to parquetRow
+	parquetSchemaVersion = "8"
+
+	// parquetRowGroupSize is the number of buffered rows that forces an early flush
+	parquetRowGroupSize = 1000
+
+	// parquetMaxBufferedRows bounds memory while the volume cannot be written to
+	parquetMaxBufferedRows = 50 * parquetRowGroupSize
+)
+
+// parquetRow is the Parquet schema of a SecurityEvent. Columns are only ever added,
+// as optional fields, and never renamed or retyped, so files written by older
+// operators can be read together with newer ones (e.g. Spark's mergeSchema).
+type parquetRow struct {
+	Timestamp       time.Time         `parquet:"timestamp,timestamp(millisecond)"`
+	EventType       string            `parquet:"event_type,dict"`
+	Severity        string            `parquet:"severity,dict"`
+	PodName         string            `parquet:"pod_name"`
+	Namespace       string            `parquet:"namespace,dict"`
+	Container       string            `parquet:"container,optional"`
+	Image           string            `parquet:"image,optional"`
+	ResolvedImageID string            `parquet:"resolved_image_id,optional"`
+	Reason          string            `parquet:"reason"`
+	Action          string            `parquet:"action,dict"`
+	PolicyName      string            `parquet:"policy_name,dict"`
+	NodeName        string            `parquet:"node_name,optional"`
+	Description     string            `parquet:"description"`
+	OperatorVersion string            `parquet:"operator_version,optional"`
+	Rule            string            `parquet:"rule,optional"`
+	ExemptedCheck   string            `parquet:"exempted_check,optional"`
+	Markers         []string          `parquet:"markers,list"`
+	NodeDraining    bool              `parquet:"node_draining,optional"`
+	Error           string            `parquet:"error,optional"`
+	DuplicatedBy    string            `parquet:"duplicated_by,optional"`
+	CapturedLogs    map[string]string `parquet:"captured_logs,optional"`
+	Containers      []string          `parquet:"containers,optional,list"`
+	ContainerCount  int32             `parquet:"container_count,optional"`
+	ReasonCode      string            `parquet:"reason_code,optional,dict"`
+	Remediation     string            `parquet:"remediation,optional"`
+	ResolvedDigest  string            `parquet:"resolved_digest,optional"`
+	UpgradeSuspect  bool              `parquet:"cluster_upgrade_suspected,optional"`
+}
+
+// newParquetRow converts a SecurityEvent to its Parquet row
+func newParquetRow(event SecurityEvent) parquetRow {
+	timestamp, err := time.Parse(time.RFC3339, event.Timestamp)
+	if err != nil {
+		timestamp = time.Now()
+	}
+	return parquetRow{
+		Timestamp:       timestamp.UTC(),
+		EventType:       event.EventType,
+		Severity:        event.Severity,
+		PodName:         event.PodName,
+		Namespace:       event.Namespace,
+		Container:       event.Container,
+		Image:           event.Image,
+		ResolvedImageID: event.ResolvedImageID,
+		Reason:          event.Reason,
+		Action:          event.Action,
+		PolicyName:      event.PolicyName,
+		NodeName:        event.NodeName,
+		Description:     event.Description,
+		OperatorVersion: event.OperatorVersion,
+		Rule:            event.Rule,
+		ExemptedCheck:   event.ExemptedCheck,
+		Markers:         event.Markers,
+		Error:           event.Error,
+		NodeDraining:    event.NodeDraining,
+		DuplicatedBy:    event.DuplicatedBy,
+		CapturedLogs:    event.CapturedLogs,
+		Containers:      event.Containers,
+		ContainerCount:  int32(event.ContainerCount),
+		ReasonCode:      event.ReasonCode,
+		Remediation:     event.Remediation,
+		ResolvedDigest:  event.ResolvedDigest,
+		UpgradeSuspect:  event.ClusterUpgradeSuspected,
+	}
+}
+
+// ParquetOptions configures a ParquetSink
+type ParquetOptions struct {
+	// Dir is the directory files are written to, partitioned as date=YYYY-MM-DD
+	Dir string
+
+	// FlushInterval is how often buffered events are written out as a row group
+	FlushInterval time.Duration
+
+	// RotateInterval is the longest a file stays open before it is finalized
+	RotateInterval time.Duration
+
+	// MaxFileBytes finalizes a file once it has grown past this size
+	MaxFileBytes int64
+}
+
+// ParquetSink buffers security events and writes them to Parquet files on a mounted
+// volume. Files are written under a hidden name and renamed once finalized, so
+// readers only ever see complete files. It is a manager Runnable that flushes
+// periodically and finalizes the open file on shutdown.
+type ParquetSink struct {
+	opts   ParquetOptions
+	prefix string
+
+	mu      sync.Mutex
+	buffer  []parquetRow
+	file    *os.File
+	counter *countingWriter
+	writer  *parquet.GenericWriter[parquetRow]
+	path    string
+	opened  time.Time
+	seq     int
+}
+
+// NewParquetSink creates a ParquetSink, making sure its directory is writable
+func NewParquetSink(opts ParquetOptions) (*ParquetSink, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating parquet directory: %w", err)
+	}
+
+	// Keep files of concurrent replicas apart
+	prefix, err := os.Hostname()
+	if err != nil || prefix == "" {
+		prefix = "kubeshield"
+	}
+	return &ParquetSink{opts: opts, prefix: prefix}, nil
+}
+
+// Name implements Sink
+func (s *ParquetSink) Name() string {
+	return SinkParquet
+}
+
+// Send implements Sink. Events are buffered and written on the next flush.
+func (s *ParquetSink) Send(_ context.Context, event SecurityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buffer) >= parquetMaxBufferedRows {
+		return fmt.Errorf("parquet buffer full (%d events), dropping event", len(s.buffer))
+	}
+	s.buffer = append(s.buffer, newParquetRow(event))
+	if len(s.buffer) >= parquetRowGroupSize {
+		return s.flushLocked(time.Now())
+	}
+	return nil
+}
+
+// Buffered returns the number of events waiting for the next flush
+func (s *ParquetSink) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buffer)
+}
+
+// Start implements manager.Runnable
+func (s *ParquetSink) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("parquet-sink")
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if err := s.flushLocked(time.Now()); err != nil {
+				logger.Error(err, "Failed to flush security events on shutdown")
+			}
+			if err := s.closeLocked(); err != nil {
+				logger.Error(err, "Failed to finalize parquet file on shutdown")
+			}
+			return nil
+		case <-ticker.C:
+			s.flush(logger)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica
+// flushes the events it produced itself.
+func (s *ParquetSink) NeedLeaderElection() bool {
+	return false
+}
+
+// flush writes buffered events and logs failures
+func (s *ParquetSink) flush(logger logr.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.flushLocked(time.Now()); err != nil {
+		logger.Error(err, "Failed to flush security events", "buffered", len(s.buffer))
+	}
+}
+
+// flushLocked writes the buffer as one row group and rotates the file when it is
+// too old or too large. On failure the buffer is kept for the next attempt.
+func (s *ParquetSink) flushLocked(now time.Time) error {
+	if len(s.buffer) > 0 {
+		if s.writer == nil {
+			if err := s.openLocked(now); err != nil {
+				return err
+			}
+		}
+		if _, err := s.writer.Write(s.buffer); err != nil {
+			// Finish the file with the row groups already in it and retry into a new one
+			return errors.Join(fmt.Errorf("writing parquet rows: %w", err), s.abandonLocked())
+		}
+		if err := s.writer.Flush(); err != nil {
+			return errors.Join(fmt.Errorf("flushing parquet row group: %w", err), s.abandonLocked())
+		}
+		s.buffer = s.buffer[:0]
+	}
+
+	if s.writer != nil && (s.counter.n >= s.opts.MaxFileBytes || now.Sub(s.opened) >= s.opts.RotateInterval) {
+		return s.closeLocked()
+	}
+	return nil
+}
+
+// openLocked starts a new in-progress file in today's partition
+func (s *ParquetSink) openLocked(now time.Time) error {
+	partition := filepath.Join(s.opts.Dir, "date="+now.UTC().Format("2006-01-02"))
+	if err := os.MkdirAll(partition, 0o755); err != nil {
+		return fmt.Errorf("creating parquet partition: %w", err)
+	}
+
+	s.seq++
+	s.path = filepath.Join(partition, fmt.Sprintf("events-%s-%d-%d.parquet", s.prefix, now.Unix(), s.seq))
+	file, err := os.Create(inProgressPath(s.path))
+	if err != nil {
+		return fmt.Errorf("creating parquet file: %w", err)
+	}
+
+	s.file = file
+	s.counter = &countingWriter{w: file}
+	s.writer = parquet.NewGenericWriter[parquetRow](s.counter,
+		parquet.Compression(&parquet.Snappy),
+		parquet.KeyValueMetadata("kubeshield.schema.version", parquetSchemaVersion),
+	)
+	s.opened = now
+	return nil
+}
+
+// closeLocked finalizes the open file and makes it visible to readers
+func (s *ParquetSink) closeLocked() error {
+	if s.writer == nil {
+		return nil
+	}
+	defer func() {
+		s.writer, s.file, s.counter = nil, nil, nil
+	}()
+
+	err := errors.Join(s.writer.Close(), s.file.Close())
+	if err != nil {
+		return fmt.Errorf("finalizing parquet file: %w", err)
+	}
+	return os.Rename(inProgressPath(s.path), s.path)
+}
+
+// abandonLocked stops writing to the open file after a failed write. The file is
+// finalized so the row groups flushed before are published; the failed rows stay
+// buffered and may end up in both files if the writer got part of them out. A file
+// that cannot be finalized either is left under its in-progress name for recovery.
+func (s *ParquetSink) abandonLocked() error {
+	if err := s.closeLocked(); err != nil {
+		return fmt.Errorf("%w, left as %s", err, inProgressPath(s.path))
+	}
+	return nil
+}
+
+// inProgressPath is the hidden name a file is written under until it is finalized
+func inProgressPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".inprogress")
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseProxyURL parses the proxy audit traffic is sent through. username and
+// password, typically read from a Secret, take precedence over credentials embedded
+// in raw and are sent as Proxy-Authorization.
+func ParseProxyURL(raw, username, password string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	proxy, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxy.Scheme != "http" && proxy.Scheme != "https" || proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q, expected http(s)://host:port", proxy.Redacted())
+	}
+	if username != "" {
+		proxy.User = url.UserPassword(username, password)
+	}
+	return proxy, nil
+}
+
+// proxyFunc routes requests through proxy unless their host matches noProxy
+func proxyFunc(proxy *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+}
+
+// bypassProxy reports whether host is reached directly. Entries follow NO_PROXY:
+// "*" matches every host, a CIDR matches IP addresses in it, and a domain matches
+// itself and its subdomains, with or without a leading dot.
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(entry, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckConnectivity sends a HEAD request to the audit service through client, and so
+// through its proxy, and returns the response status. Any HTTP response, even an
+// error status, proves the service is reachable; only transport failures are errors.
+func CheckConnectivity(ctx context.Context, client *http.Client, serviceURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, serviceURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
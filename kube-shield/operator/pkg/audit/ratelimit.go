@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// EventTypeSuppressed is the summary raised once a rate limited policy and namespace
+// may report again, counting the events dropped in the meantime
+const EventTypeSuppressed = "AUDIT_EVENTS_SUPPRESSED"
+
+// rateLimitedActions are the repeat observations a RateLimiter may drop. Terminations,
+// failed terminations and quarantines are always delivered.
+var rateLimitedActions = map[string]bool{
+	ActionAudit:          true,
+	ActionWarn:           true,
+	ActionAuditStaticPod: true,
+}
+
+// unlimitedEventTypes are reported with one of the rateLimitedActions but tell of a
+// termination that was deferred or failed, so they are always delivered too
+var unlimitedEventTypes = map[string]bool{
+	EventTypeEnforcementFailed: true,
+	EventTypePDBBlocked:        true,
+}
+
+// rateLimitKey identifies a token bucket
+type rateLimitKey struct {
+	policy    string
+	namespace string
+}
+
+// tokenBucket holds the events a policy may still report in a namespace
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+
+	// suppressed counts the events dropped since the last summary, the first
+	// of them at since
+	suppressed int
+	since      time.Time
+}
+
+// RateLimiter bounds the audit events of every policy in every namespace with a
+// token bucket, so a crash-looping pod or one noisy namespace cannot drown out the
+// rest. Each bucket holds up to Burst events and refills at PerMinute events per
+// minute. It is safe for concurrent use.
+type RateLimiter struct {
+	PerMinute float64
+	Burst     int
+
+	mu      sync.Mutex
+	buckets map[rateLimitKey]*tokenBucket
+}
+
+// NewRateLimiter creates a RateLimiter. A burst below 1 is raised to 1.
+func NewRateLimiter(perMinute float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		PerMinute: perMinute,
+		Burst:     burst,
+		buckets:   make(map[rateLimitKey]*tokenBucket),
+	}
+}
+
+// Allow reports whether event may be delivered at now, taking a token from its
+// bucket. Dropped events are counted for the next summary.
+func (l *RateLimiter) Allow(event SecurityEvent, now time.Time) bool {
+	if !rateLimitedActions[event.Action] || unlimitedEventTypes[event.EventType] {
+		return true
+	}
+	key := rateLimitKey{policy: event.PolicyName, namespace: event.Namespace}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+	l.refill(bucket, now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true
+	}
+
+	if bucket.suppressed == 0 {
+		bucket.since = now
+	}
+	bucket.suppressed++
+	metrics.SuppressedAuditEvents.WithLabelValues(key.policy, key.namespace).Inc()
+	return false
+}
+
+// Summaries returns an AUDIT_EVENTS_SUPPRESSED event for every bucket that dropped
+// events and has refilled by now, and starts counting those buckets again. The
+// summary takes a token like any other event. Buckets that are full and have
+// nothing to report are forgotten.
+func (l *RateLimiter) Summaries(now time.Time) []SecurityEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var summaries []SecurityEvent
+	for key, bucket := range l.buckets {
+		l.refill(bucket, now)
+		switch {
+		case bucket.suppressed > 0 && bucket.tokens >= 1:
+			bucket.tokens--
+			summaries = append(summaries, suppressedEvent(key, bucket.suppressed, bucket.since, now))
+			bucket.suppressed = 0
+		case bucket.suppressed == 0 && bucket.tokens >= float64(l.Burst):
+			delete(l.buckets, key)
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Namespace != summaries[j].Namespace {
+			return summaries[i].Namespace < summaries[j].Namespace
+		}
+		return summaries[i].PolicyName < summaries[j].PolicyName
+	})
+	return summaries
+}
+
+// refill adds the tokens earned since the bucket was last looked at
+func (l *RateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Minutes() * l.PerMinute
+		if bucket.tokens > float64(l.Burst) {
+			bucket.tokens = float64(l.Burst)
+		}
+	}
+	bucket.last = now
+}
+
+// suppressedEvent builds the summary of the events dropped for key
+func suppressedEvent(key rateLimitKey, suppressed int, since, now time.Time) SecurityEvent {
+	return SecurityEvent{
+		Timestamp: now.UTC().Format(time.RFC3339),
+		EventType: EventTypeSuppressed,
+		Severity:  "INFO",
+		Namespace: key.namespace,
+		Reason: fmt.Sprintf("Suppressed %d audit events of policy %s in namespace %s since %s",
+			suppressed, key.policy, key.namespace, since.UTC().Format(time.RFC3339)),
+		Action:      ActionAudit,
+		PolicyName:  key.policy,
+		Description: fmt.Sprintf("Audit events of this policy in the namespace exceeded the rate limit; %d were not delivered", suppressed),
+	}
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterDropsRepeatAudits(t *testing.T) {
+	limiter := NewRateLimiter(1, 2)
+	now := time.Now()
+	event := SecurityEvent{EventType: "PRIVILEGED_CONTAINER", Action: ActionAudit, PolicyName: "restricted", Namespace: "production"}
+
+	for i := 0; i < 2; i++ {
+		if !limiter.Allow(event, now) {
+			t.Fatalf("event #%d within the burst was dropped", i+1)
+		}
+	}
+	if limiter.Allow(event, now) {
+		t.Fatal("event past the burst was delivered")
+	}
+
+	summaries := limiter.Summaries(now.Add(time.Minute))
+	if len(summaries) != 1 || summaries[0].EventType != EventTypeSuppressed {
+		t.Fatalf("summaries = %v, want one %s", summaries, EventTypeSuppressed)
+	}
+}
+
+func TestRateLimiterNeverDropsTerminationEvents(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	now := time.Now()
+	exhaust := SecurityEvent{EventType: "PRIVILEGED_CONTAINER", Action: ActionAudit, PolicyName: "restricted", Namespace: "production"}
+	limiter.Allow(exhaust, now)
+
+	for _, event := range []SecurityEvent{
+		{EventType: "PRIVILEGED_CONTAINER", Action: ActionTerminated},
+		{EventType: "PRIVILEGED_CONTAINER", Action: ActionTerminationFailed},
+		{EventType: EventTypePDBBlocked, Action: ActionAudit},
+		{EventType: EventTypeEnforcementFailed, Action: ActionAudit},
+	} {
+		event.PolicyName, event.Namespace = "restricted", "production"
+		for i := 0; i < 3; i++ {
+			if !limiter.Allow(event, now) {
+				t.Fatalf("%s %s event was dropped", event.EventType, event.Action)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentSinkName is the name of the RecentEvents sink in logs and traces
+const recentSinkName = "recent"
+
+// RecentEvents is a Sink keeping the most recent security events in memory, bounded
+// by count and age, for the status endpoints to query. It is safe for concurrent use.
+type RecentEvents struct {
+	MaxEvents int
+	MaxAge    time.Duration
+
+	mu     sync.RWMutex
+	events []recentEvent
+}
+
+// recentEvent is a stored event with the time it happened and the time it was
+// stored. Events are stored in order of receipt, not necessarily of their timestamps.
+type recentEvent struct {
+	event     SecurityEvent
+	timestamp time.Time
+	received  time.Time
+}
+
+// EventQuery selects recent events. Empty fields match every event.
+type EventQuery struct {
+	Namespace string
+	Policy    string
+	Severity  string
+	Since     time.Time
+	Until     time.Time
+
+	// Limit caps the number of events returned, newest first (0 = no limit)
+	Limit int
+}
+
+// NewRecentEvents creates a RecentEvents keeping at most maxEvents events no older
+// than maxAge. A zero maxAge keeps events until they are pushed out by newer ones.
+func NewRecentEvents(maxEvents int, maxAge time.Duration) *RecentEvents {
+	return &RecentEvents{
+		MaxEvents: maxEvents,
+		MaxAge:    maxAge,
+	}
+}
+
+// Name implements Sink
+func (r *RecentEvents) Name() string {
+	return recentSinkName
+}
+
+// Send implements Sink. The oldest events are dropped once MaxEvents is reached.
+func (r *RecentEvents) Send(_ context.Context, event SecurityEvent) error {
+	r.add(event, time.Now())
+	return nil
+}
+
+// add stores an event received at now
+func (r *RecentEvents) add(event SecurityEvent, now time.Time) {
+	timestamp := now
+	if parsed, err := time.Parse(time.RFC3339, event.Timestamp); err == nil {
+		timestamp = parsed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recentEvent{event: event, timestamp: timestamp, received: now})
+	if r.MaxEvents > 0 && len(r.events) > r.MaxEvents {
+		// Copy rather than reslice so the dropped events can be collected
+		r.events = append([]recentEvent(nil), r.events[len(r.events)-r.MaxEvents:]...)
+	}
+	r.expire(now)
+}
+
+// expire drops events stored more than MaxAge ago. Events are appended as they are
+// received, so they are dropped from the front; their own timestamps may be out of
+// order, e.g. for events delayed by a retry.
+func (r *RecentEvents) expire(now time.Time) {
+	if r.MaxAge <= 0 {
+		return
+	}
+	cutoff := now.Add(-r.MaxAge)
+	i := 0
+	for i < len(r.events) && r.events[i].received.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		r.events = append([]recentEvent(nil), r.events[i:]...)
+	}
+}
+
+// Query returns the stored events matching q, newest first
+func (r *RecentEvents) Query(q EventQuery) []SecurityEvent {
+	return r.query(q, time.Now())
+}
+
+// query returns the events matching q that have not expired at now
+func (r *RecentEvents) query(q EventQuery, now time.Time) []SecurityEvent {
+	var cutoff time.Time
+	if r.MaxAge > 0 {
+		cutoff = now.Add(-r.MaxAge)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := []SecurityEvent{}
+	for i := len(r.events) - 1; i >= 0; i-- {
+		stored := r.events[i]
+		if stored.received.Before(cutoff) || !q.matches(stored) {
+			continue
+		}
+		matches = append(matches, stored.event)
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+	}
+	return matches
+}
+
+// matches returns true if a stored event satisfies every set field of the query
+func (q EventQuery) matches(stored recentEvent) bool {
+	event := stored.event
+	switch {
+	case q.Namespace != "" && event.Namespace != q.Namespace:
+		return false
+	case q.Policy != "" && event.PolicyName != q.Policy:
+		return false
+	case q.Severity != "" && !strings.EqualFold(event.Severity, q.Severity):
+		return false
+	case !q.Since.IsZero() && stored.timestamp.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && stored.timestamp.After(q.Until):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func eventNames(events []SecurityEvent) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.PodName)
+	}
+	return names
+}
+
+func expectEvents(t *testing.T, got []SecurityEvent, want ...string) {
+	t.Helper()
+	names := eventNames(got)
+	if len(names) != len(want) {
+		t.Fatalf("events = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("events = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestRecentEventsMaxEvents(t *testing.T) {
+	recent := NewRecentEvents(2, 0)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"first", "second", "third"} {
+		recent.add(SecurityEvent{PodName: name}, now)
+	}
+	expectEvents(t, recent.query(EventQuery{}, now), "third", "second")
+}
+
+func TestRecentEventsExpireByReceipt(t *testing.T) {
+	recent := NewRecentEvents(0, time.Hour)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// A delayed event arrives with a timestamp older than the one before it
+	recent.add(SecurityEvent{PodName: "old", Timestamp: start.Format(time.RFC3339)}, start)
+	recent.add(SecurityEvent{PodName: "fresh", Timestamp: start.Add(50 * time.Minute).Format(time.RFC3339)}, start.Add(50*time.Minute))
+	recent.add(SecurityEvent{PodName: "delayed", Timestamp: start.Add(-2 * time.Hour).Format(time.RFC3339)}, start.Add(55*time.Minute))
+
+	// The delayed event was only just stored, so it is kept until an hour after receipt
+	now := start.Add(70 * time.Minute)
+	recent.add(SecurityEvent{PodName: "latest"}, now)
+	expectEvents(t, recent.query(EventQuery{}, now), "latest", "delayed", "fresh")
+
+	now = start.Add(2 * time.Hour)
+	recent.add(SecurityEvent{PodName: "last"}, now)
+	expectEvents(t, recent.query(EventQuery{}, now), "last", "latest")
+}
+
+func TestRecentEventsQueryHidesExpired(t *testing.T) {
+	recent := NewRecentEvents(0, time.Hour)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent.add(SecurityEvent{PodName: "old"}, start)
+	recent.add(SecurityEvent{PodName: "new"}, start.Add(30*time.Minute))
+
+	// Nothing was sent since, but the old event is past MaxAge
+	expectEvents(t, recent.query(EventQuery{}, start.Add(61*time.Minute)), "new")
+}
+
+func TestRecentEventsQuery(t *testing.T) {
+	recent := NewRecentEvents(0, 0)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	events := []SecurityEvent{
+		{PodName: "a", Namespace: "prod", PolicyName: "restricted", Severity: "HIGH", Timestamp: start.Format(time.RFC3339)},
+		{PodName: "b", Namespace: "dev", PolicyName: "restricted", Severity: "LOW", Timestamp: start.Add(time.Minute).Format(time.RFC3339)},
+		{PodName: "c", Namespace: "prod", PolicyName: "baseline", Severity: "high", Timestamp: start.Add(2 * time.Minute).Format(time.RFC3339)},
+		// Delivered late: stored last, but happened first
+		{PodName: "d", Namespace: "prod", PolicyName: "restricted", Severity: "HIGH", Timestamp: start.Add(-time.Minute).Format(time.RFC3339)},
+	}
+	for _, event := range events {
+		recent.add(event, start.Add(3*time.Minute))
+	}
+	now := start.Add(3 * time.Minute)
+
+	tests := []struct {
+		name  string
+		query EventQuery
+		want  []string
+	}{
+		{name: "all newest stored first", query: EventQuery{}, want: []string{"d", "c", "b", "a"}},
+		{name: "namespace", query: EventQuery{Namespace: "prod"}, want: []string{"d", "c", "a"}},
+		{name: "policy", query: EventQuery{Policy: "restricted"}, want: []string{"d", "b", "a"}},
+		{name: "severity ignores case", query: EventQuery{Severity: "HIGH"}, want: []string{"d", "c", "a"}},
+		{name: "since uses the event time", query: EventQuery{Since: start}, want: []string{"c", "b", "a"}},
+		{name: "until uses the event time", query: EventQuery{Until: start}, want: []string{"d", "a"}},
+		{name: "limit", query: EventQuery{Namespace: "prod", Limit: 2}, want: []string{"d", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectEvents(t, recent.query(tt.query, now), tt.want...)
+		})
+	}
+}
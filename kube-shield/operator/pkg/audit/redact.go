@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// redacted replaces every value that looks like a credential
+const redacted = "[REDACTED]"
+
+// secretPattern matches a credential and replaces it, keeping what tells the reader
+// which setting was masked
+type secretPattern struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// secretPatterns match credentials that end up in event text, e.g. through image
+// references, custom rule expressions or captured logs
+var secretPatterns = []secretPattern{
+	// key=value and key: value pairs whose key names a credential, e.g. DB_PASSWORD=x.
+	// A key inside an image path or name, such as org/secret:v1, is left alone.
+	{regexp.MustCompile(`(?im)((?:^|[^/A-Za-z0-9.-])(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?|auth)["']?\s*[=:]\s*["']?)[^\s"',;&]+`), "${1}" + redacted},
+	// user:password@ in URLs
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}" + redacted + "@"},
+	// Authorization header values
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + redacted},
+	// AWS access key IDs
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), redacted},
+	// GitHub tokens
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), redacted},
+	// Slack tokens
+	{regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), redacted},
+	// JSON Web Tokens
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), redacted},
+	// PEM private keys
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`), redacted},
+}
+
+// credentialName matches annotation and label names that name a credential, e.g.
+// example.com/db-password
+var credentialName = regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?|auth)`)
+
+// Sanitizer prepares events before they leave the operator: it masks values that
+// look like credentials and caps the description. The zero value changes nothing.
+type Sanitizer struct {
+	// Redact masks credentials in the event's free text, image and captured logs
+	Redact bool
+
+	// MaxDescriptionLength caps Description in bytes (0 = unlimited)
+	MaxDescriptionLength int
+}
+
+// Apply sanitizes event in place
+func (s Sanitizer) Apply(event *SecurityEvent) {
+	if s.Redact {
+		event.Image = Redact(event.Image)
+		event.Reason = Redact(event.Reason)
+		event.Description = Redact(event.Description)
+		event.Error = Redact(event.Error)
+		if len(event.CapturedLogs) > 0 {
+			logs := make(map[string]string, len(event.CapturedLogs))
+			for container, tail := range event.CapturedLogs {
+				logs[container] = Redact(tail)
+			}
+			event.CapturedLogs = logs
+		}
+	}
+	if s.MaxDescriptionLength > 0 {
+		event.Description = Truncate(event.Description, s.MaxDescriptionLength)
+	}
+}
+
+// Text masks credentials in text that is shown outside the operator without being
+// part of an event, e.g. Kubernetes Events, records or admission responses
+func (s Sanitizer) Text(text string) string {
+	if !s.Redact {
+		return text
+	}
+	return Redact(text)
+}
+
+// Annotations returns the annotations that may be shown outside the operator. With
+// Redact set, those whose name names a credential or whose value contains one are
+// omitted rather than masked, since a partial value is rarely useful to a reader.
+func (s Sanitizer) Annotations(annotations map[string]string) map[string]string {
+	if !s.Redact || len(annotations) == 0 {
+		return annotations
+	}
+	kept := make(map[string]string, len(annotations))
+	for name, value := range annotations {
+		if credentialName.MatchString(name) || Redact(value) != value {
+			continue
+		}
+		kept[name] = value
+	}
+	return kept
+}
+
+// Redact masks the values in text that look like passwords, tokens or keys
+func Redact(text string) string {
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
+
+// Truncate caps text at max bytes without splitting a character, marking the cut
+// with "..."
+func Truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max - len("...")
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
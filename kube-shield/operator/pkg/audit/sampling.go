@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SampleRates is the fraction of security events delivered per severity, from 0
+// (drop all) to 1 (keep all). Severities without a rate are always delivered, and
+// HIGH and CRITICAL events are never sampled.
+type SampleRates map[string]float64
+
+// unsampledSeverities are always delivered, whatever the configured rates
+var unsampledSeverities = map[string]bool{"CRITICAL": true, "HIGH": true}
+
+// ParseSampleRates parses "SEVERITY=rate" pairs such as "LOW=0.1"
+func ParseSampleRates(pairs []string) (SampleRates, error) {
+	rates := make(SampleRates, len(pairs))
+	for _, pair := range pairs {
+		severity, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sample rate %q, expected SEVERITY=rate", pair)
+		}
+		severity = strings.ToUpper(strings.TrimSpace(severity))
+		if unsampledSeverities[severity] {
+			return nil, fmt.Errorf("invalid sample rate %q, %s events are always kept", pair, severity)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid sample rate %q, rate must be between 0 and 1", pair)
+		}
+		rates[severity] = rate
+	}
+	return rates, nil
+}
+
+// Keep decides whether an event is delivered. The decision only depends on the pod,
+// policy and event type, so the same violation of the same pod is either always
+// reported or never, instead of flapping between reconciles.
+func (r SampleRates) Keep(event SecurityEvent) bool {
+	if unsampledSeverities[event.Severity] {
+		return true
+	}
+	rate, ok := r[event.Severity]
+	if !ok || rate >= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	for _, part := range []string{event.Namespace, event.PodName, event.PolicyName, event.EventType} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return float64(h.Sum64())/float64(math.MaxUint64) < rate
+}
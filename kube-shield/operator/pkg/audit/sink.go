@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Sink names accepted in AUDIT_SINKS
+const (
+	// SinkHTTP posts events to the audit service
+	SinkHTTP = "http"
+
+	// SinkParquet writes events to Parquet files on a mounted volume
+	SinkParquet = "parquet"
+)
+
+// Sink delivers security events to one destination
+type Sink interface {
+	// Name identifies the sink in logs and traces
+	Name() string
+
+	// Send delivers one event. Implementations must be safe for concurrent use.
+	Send(ctx context.Context, event SecurityEvent) error
+}
+
+// ValidateSinkNames rejects sink names the operator does not know
+func ValidateSinkNames(names []string) error {
+	for _, name := range names {
+		switch name {
+		case SinkHTTP, SinkParquet, SinkAWS:
+		default:
+			return fmt.Errorf("unknown audit sink %q, expected %q, %q or %q", name, SinkHTTP, SinkParquet, SinkAWS)
+		}
+	}
+	return nil
+}
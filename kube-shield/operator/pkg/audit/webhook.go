@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ValidateWebhookURL checks that a policy's alert webhook is an https URL and does
+// not name a loopback, link-local or unspecified address, such as the cloud
+// metadata endpoint. Policy authors choose these URLs, and the operator posts
+// event data to them with its own network identity.
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" {
+		return errors.New("webhook URL must use https")
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("webhook URL has no host")
+	}
+	if host == "localhost" {
+		return errors.New("webhook URL must not point at localhost")
+	}
+	if ip := net.ParseIP(host); ip != nil &&
+		(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()) {
+		return fmt.Errorf("webhook URL must not point at %s", ip)
+	}
+	return nil
+}
+
+// WebhookSink posts security events as JSON to an alerting webhook, such as a team's
+// chat or paging integration configured in a policy's AlertWebhooks
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a WebhookSink posting to url
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{URL: url, Client: client}
+}
+
+// Name implements Sink. Webhook URLs often embed tokens, so only the host is shown.
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.Destination()
+}
+
+// Destination is the webhook's host, used to label metrics without leaking its path
+func (s *WebhookSink) Destination() string {
+	parsed, err := url.Parse(s.URL)
+	if err != nil || parsed.Host == "" {
+		return "invalid"
+	}
+	return parsed.Host
+}
+
+// Send implements Sink
+func (s *WebhookSink) Send(ctx context.Context, event SecurityEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling security event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("creating HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
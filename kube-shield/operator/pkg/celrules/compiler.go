@@ -0,0 +1,121 @@
+// Package celrules compiles and evaluates the custom CEL rules declared in ShieldPolicies
+package celrules
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PodVariable is the name the pod is bound to inside rule expressions
+const PodVariable = "pod"
+
+// Compiler compiles CEL expressions once and caches the resulting programs per
+// policy. Only the programs of a policy's latest generation are kept, so edited and
+// deleted rules do not accumulate.
+type Compiler struct {
+	env *cel.Env
+
+	mu       sync.Mutex
+	policies map[string]*policyPrograms
+}
+
+// policyPrograms are the cached programs of one generation of a policy, by expression
+type policyPrograms struct {
+	generation int64
+	programs   map[string]compiled
+}
+
+// compiled is the cached outcome of compiling an expression
+type compiled struct {
+	program cel.Program
+	err     error
+}
+
+// NewCompiler creates a Compiler whose environment exposes the pod as a dynamic object
+func NewCompiler() (*Compiler, error) {
+	env, err := cel.NewEnv(cel.Variable(PodVariable, cel.DynType))
+	if err != nil {
+		return nil, err
+	}
+	return &Compiler{
+		env:      env,
+		policies: make(map[string]*policyPrograms),
+	}, nil
+}
+
+// Compile returns the program for an expression of the given policy generation,
+// compiling it on first use. Compile errors are cached as well so invalid rules are
+// not recompiled on every pod. A newer generation evicts the programs of the older
+// one; expressions of an outdated generation are compiled without being cached.
+func (c *Compiler) Compile(policy string, generation int64, expression string) (cel.Program, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.policies[policy]
+	switch {
+	case ok && generation < entry.generation:
+		result := c.compile(expression)
+		return result.program, result.err
+	case !ok || generation > entry.generation:
+		entry = &policyPrograms{generation: generation, programs: make(map[string]compiled)}
+		c.policies[policy] = entry
+	}
+
+	if result, ok := entry.programs[expression]; ok {
+		return result.program, result.err
+	}
+
+	result := c.compile(expression)
+	entry.programs[expression] = result
+	return result.program, result.err
+}
+
+// Forget drops the programs of a deleted policy
+func (c *Compiler) Forget(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.policies, policy)
+}
+
+// compile parses, type-checks and plans a single expression
+func (c *Compiler) compile(expression string) compiled {
+	ast, issues := c.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return compiled{err: issues.Err()}
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return compiled{err: fmt.Errorf("expression must evaluate to a bool, got %s", ast.OutputType())}
+	}
+
+	program, err := c.env.Program(ast)
+	if err != nil {
+		return compiled{err: err}
+	}
+	return compiled{program: program}
+}
+
+// PodInput converts a pod into the activation passed to Evaluate
+func PodInput(pod *corev1.Pod) (map[string]interface{}, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{PodVariable: obj}, nil
+}
+
+// Evaluate runs a compiled program against input and reports whether the rule matched
+func Evaluate(program cel.Program, input map[string]interface{}) (bool, error) {
+	out, _, err := program.Eval(input)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %T, expected bool", out.Value())
+	}
+	return matched, nil
+}
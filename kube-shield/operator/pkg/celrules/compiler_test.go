@@ -0,0 +1,65 @@
+package celrules
+
+import "testing"
+
+func TestCompilerKeepsLatestGeneration(t *testing.T) {
+	c, err := NewCompiler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := func(policy string) int {
+		if entry, ok := c.policies[policy]; ok {
+			return len(entry.programs)
+		}
+		return 0
+	}
+
+	for _, expression := range []string{"pod.spec.hostNetwork == true", "pod.spec.hostPID == true"} {
+		if _, err := c.Compile("restricted", 1, expression); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Compile("restricted", 1, "pod.spec.hostIPC"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cached("restricted"); got != 3 {
+		t.Fatalf("%d programs cached, want 3", got)
+	}
+
+	// Editing the policy evicts the programs of its previous generation
+	if _, err := c.Compile("restricted", 2, "pod.spec.hostNetwork == true"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cached("restricted"); got != 1 {
+		t.Errorf("%d programs cached after an edit, want 1", got)
+	}
+
+	// An evaluation still holding the old generation does not bring it back
+	if _, err := c.Compile("restricted", 1, "pod.spec.hostPID == true"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.policies["restricted"].generation; got != 2 {
+		t.Errorf("cached generation = %d, want 2", got)
+	}
+	if got := cached("restricted"); got != 1 {
+		t.Errorf("%d programs cached after compiling an old generation, want 1", got)
+	}
+
+	c.Forget("restricted")
+	if _, ok := c.policies["restricted"]; ok {
+		t.Error("programs of a deleted policy are still cached")
+	}
+}
+
+func TestCompilerCachesErrors(t *testing.T) {
+	c, err := NewCompiler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Compile("restricted", 1, "pod.spec.containers.size()"); err == nil {
+		t.Fatal("non-bool expression compiled")
+	}
+	if _, err := c.Compile("restricted", 1, "pod.spec.containers.size()"); err == nil {
+		t.Error("cached compile error was lost")
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/kubeshield/operator/pkg/evaluator"
 )
 
 // Config holds all configuration for the operator
@@ -11,9 +14,32 @@ type Config struct {
 	// MetricsAddr is the address the metric endpoint binds to
 	MetricsAddr string
 
+	// MetricsSecure serves metrics over HTTPS with authentication and authorization of scrape requests
+	MetricsSecure bool
+
+	// MetricsCertDir holds tls.crt/tls.key for the metrics endpoint (empty = self-signed certificate)
+	MetricsCertDir string
+
+	// MetricsExemplars attaches trace IDs as exemplars to the violation counters and serves them in OpenMetrics format
+	MetricsExemplars bool
+
 	// ProbeAddr is the address the probe endpoint binds to
 	ProbeAddr string
 
+	// StatusAddr is the address the status endpoints (e.g. /report) bind to, empty to disable
+	StatusAddr string
+
+	// StatusAuth authenticates and authorizes requests to the status endpoints like
+	// those to secure metrics, with TokenReviews and SubjectAccessReviews
+	StatusAuth bool
+
+	// RecentEventsLimit is the number of recent security events kept in memory for
+	// the /events status endpoint (0 = disabled)
+	RecentEventsLimit int
+
+	// RecentEventsRetention is how long events stay queryable on /events
+	RecentEventsRetention time.Duration
+
 	// EnableLeaderElection enables leader election for controller manager
 	EnableLeaderElection bool
 
@@ -23,6 +49,250 @@ type Config struct {
 	// AuditServiceURL is the URL of the audit service to send events to
 	AuditServiceURL string
 
+	// AuditTimeout bounds each request to the audit service
+	AuditTimeout time.Duration
+
+	// AuditMaxIdleConnsPerHost is the number of keep-alive connections kept to the audit service
+	AuditMaxIdleConnsPerHost int
+
+	// AuditIdleConnTimeout is how long idle connections to the audit service are kept open
+	AuditIdleConnTimeout time.Duration
+
+	// AuditUseEnvProxy routes audit traffic through the proxy from HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	AuditUseEnvProxy bool
+
+	// AuditProxyURL routes audit traffic through this proxy, overriding the environment's
+	AuditProxyURL string
+
+	// AuditProxyUsername and AuditProxyPassword authenticate to AuditProxyURL
+	AuditProxyUsername string
+	AuditProxyPassword string
+
+	// AuditNoProxy lists hosts, domains and CIDRs reached without AuditProxyURL
+	AuditNoProxy []string
+
+	// AuditProtocol is how events reach the audit service: "http" (JSON) or "grpc"
+	AuditProtocol string
+
+	// AuditGRPCTarget is the gRPC target events are sent to when AuditProtocol is grpc
+	AuditGRPCTarget string
+
+	// AuditGRPCInsecure disables TLS on the gRPC connection
+	AuditGRPCInsecure bool
+
+	// AuditGRPCCAFile, AuditGRPCCertFile and AuditGRPCKeyFile configure (m)TLS on the gRPC connection
+	AuditGRPCCAFile   string
+	AuditGRPCCertFile string
+	AuditGRPCKeyFile  string
+
+	// AuditConnectivityCheck probes the audit service through the proxy at startup
+	AuditConnectivityCheck bool
+
+	// AuditSinks lists where security events are delivered: "http" (the audit service), "parquet" and/or "aws"
+	AuditSinks []string
+
+	// AuditSampleRates are "SEVERITY=rate" fractions of events delivered; HIGH and CRITICAL are always kept
+	AuditSampleRates []string
+
+	// MaxDescriptionLength caps the description of security events in bytes (0 = unlimited)
+	MaxDescriptionLength int
+
+	// RedactSecrets masks values resembling passwords, tokens and keys in security events
+	RedactSecrets bool
+
+	// AuditRateLimit is the audit events per minute each policy may report per namespace; terminations are never limited (0 = unlimited)
+	AuditRateLimit float64
+
+	// AuditRateBurst is the number of audit events a policy may report at once per namespace before AuditRateLimit applies
+	AuditRateBurst int
+
+	// EventCoalesceThreshold is the number of a pod's containers violating the same rule above which they are reported as one event (0 = never)
+	EventCoalesceThreshold int
+
+	// AuditParquetDir is the mounted directory the parquet sink writes to
+	AuditParquetDir string
+
+	// AuditParquetFlushInterval is how often the parquet sink writes buffered events
+	AuditParquetFlushInterval time.Duration
+
+	// AuditParquetRotateInterval is the longest a parquet file stays open before it is finalized
+	AuditParquetRotateInterval time.Duration
+
+	// AuditParquetMaxFileMB finalizes a parquet file once it grows past this size
+	AuditParquetMaxFileMB int
+
+	// AuditAWSTargetARN is the SNS topic or EventBridge bus the aws sink publishes to
+	AuditAWSTargetARN string
+
+	// AuditAWSMaxAttempts bounds the attempts of a throttled or failing AWS publish
+	AuditAWSMaxAttempts int
+
+	// DecisionHookURL is the external decision endpoint consulted before terminating a pod, empty to disable
+	DecisionHookURL string
+
+	// DecisionHookTimeout bounds each request to the decision endpoint
+	DecisionHookTimeout time.Duration
+
+	// DecisionHookFailOpen keeps pods running when the decision endpoint is unavailable
+	DecisionHookFailOpen bool
+
+	// DefaultEnforcementMode applies to policies that leave EnforcementMode empty
+	DefaultEnforcementMode string
+
+	// EvaluationPhase applies to policies that leave EvaluationPhase empty (OnCreate, OnScheduled or OnRunning)
+	EvaluationPhase string
+
+	// CreateDefaultPolicy bootstraps the "kubeshield-default" Audit-mode ShieldPolicy
+	CreateDefaultPolicy bool
+
+	// HeartbeatInterval is how often the leader sends a heartbeat to the audit service (0 = disabled)
+	HeartbeatInterval time.Duration
+
+	// ReportInterval is how often a violation summary is sent (0 = disabled)
+	ReportInterval time.Duration
+
+	// ReportDestination selects where summaries are sent: audit or slack
+	ReportDestination string
+
+	// ReportSlackWebhookURL is the Slack incoming webhook used when ReportDestination is slack
+	ReportSlackWebhookURL string
+
+	// ReportTopN is the number of top offending pods included in each summary
+	ReportTopN int
+
+	// OperatorNamespace is the namespace the operator runs in, from the downward API
+	OperatorNamespace string
+
+	// OperatorPodName is the name of the operator's own pod, from the downward API
+	OperatorPodName string
+
+	// PublicRegistries are the registry globs blockPublicRegistries flags
+	PublicRegistries []string
+
+	// SystemNamespaces are namespace globs treated as cluster system namespaces
+	SystemNamespaces []string
+
+	// SystemNamespaceMode is how pods in system namespaces are treated (skip or audit-only)
+	SystemNamespaceMode string
+
+	// BypassNamespaces are namespace globs whose pods are never evaluated by any policy
+	BypassNamespaces []string
+
+	// ComplianceScoreWeights are "SEVERITY=weight" penalties per active violation used for compliance scores
+	ComplianceScoreWeights []string
+
+	// PolicyReports publishes violations as wgpolicyk8s.io PolicyReports (the CRD must be installed)
+	PolicyReports bool
+
+	// PolicyReportInterval is how often the PolicyReports are rewritten
+	PolicyReportInterval time.Duration
+
+	// CoverageInterval is how often namespaces without an applicable policy are looked for (0 = disabled)
+	CoverageInterval time.Duration
+
+	// RBACCheckInterval is how often the operator's own RBAC permissions are reviewed (0 = disabled)
+	RBACCheckInterval time.Duration
+
+	// EnforcementRecords keeps a ShieldEnforcementRecord of every termination and quarantine
+	EnforcementRecords bool
+
+	// RecordMaxAge is how long ShieldEnforcementRecords are kept (0 = forever)
+	RecordMaxAge time.Duration
+
+	// RecordMaxPerNamespace caps the ShieldEnforcementRecords kept per namespace (0 = unlimited)
+	RecordMaxPerNamespace int
+
+	// RecordPruneInterval is how often expired ShieldEnforcementRecords are deleted
+	RecordPruneInterval time.Duration
+
+	// CoverageEvents reports new namespaces without an applicable policy as UNCOVERED_NAMESPACE
+	CoverageEvents bool
+
+	// ProtectedWorkloads are "namespace/name" glob patterns of pods that are never terminated
+	ProtectedWorkloads []string
+
+	// ListPageSize is the page size used for explicit, uncached List calls
+	ListPageSize int64
+
+	// EnforcementFailureThreshold is the number of consecutive failed terminations of a pod
+	// after which its policy gets the EnforcementDegraded condition
+	EnforcementFailureThreshold int
+
+	// DeletionPropagation is how dependents of terminated pods are deleted when the
+	// policy does not say: Background, Foreground, Orphan or empty for the API default
+	DeletionPropagation string
+
+	// MaxTerminationsPerOwner caps the pods of one controller terminated per
+	// TerminationThrottleWindow (0 = unlimited)
+	MaxTerminationsPerOwner int
+
+	// TerminationThrottleWindow is the window MaxTerminationsPerOwner counts over,
+	// which must be positive when a limit is set
+	TerminationThrottleWindow time.Duration
+
+	// StateBackend keeps shared counters in "memory" or in a "configmap"
+	StateBackend string
+
+	// StateConfigMap is the ConfigMap in the operator namespace holding shared counters
+	StateConfigMap string
+
+	// EvaluationBudget bounds the time one pod reconcile spends evaluating policies (0 = unbounded)
+	EvaluationBudget time.Duration
+
+	// PolicyEvaluationTimeout bounds the time a single policy may take to evaluate a pod (0 = unbounded)
+	PolicyEvaluationTimeout time.Duration
+
+	// RecoverPanics turns a panicking pod reconcile into an error that requeues the pod instead of crashing
+	RecoverPanics bool
+
+	// RespectPDB defers terminations a PodDisruptionBudget does not allow. Off by default
+	// so upgrading does not change when existing installs terminate pods.
+	RespectPDB bool
+
+	// Quarantine isolates pods annotated with quarantine-now behind a deny-all NetworkPolicy.
+	// Off by default, as it has the operator create NetworkPolicies.
+	Quarantine bool
+
+	// AnnotateOwners annotates the top-level owner of a terminated pod with the reason and
+	// sends it an Event. Off by default, as it patches workloads the operator did not before.
+	AnnotateOwners bool
+
+	// UpgradeDetection only audits violations while a rolling cluster upgrade is suspected
+	UpgradeDetection bool
+
+	// UpgradeUnavailablePercent is the share of NotReady or cordoned nodes above which
+	// a cluster upgrade is suspected (0 = never)
+	UpgradeUnavailablePercent int
+
+	// UpgradeVersionSkew is the kubelet minor version distance from the control plane
+	// beyond which a node is suspected to be upgrading (0 = never)
+	UpgradeVersionSkew int
+
+	// UpgradeCooldown is how long the nodes must stay under the threshold before a
+	// suspected upgrade is considered over
+	UpgradeCooldown time.Duration
+
+	// UpgradeMaxNodeSuspicion is the longest a node is left alone as upgrading
+	UpgradeMaxNodeSuspicion time.Duration
+
+	// SkipDrainingNodes only audits violations of pods on cordoned or autoscaler-removed nodes
+	SkipDrainingNodes bool
+
+	// NodeReevaluationLimit caps the pods re-evaluated when a node's labels change (0 = unlimited)
+	NodeReevaluationLimit int
+
+	// DeduplicateExternalEngines downgrades violations Gatekeeper or Kyverno already report to LOW
+	DeduplicateExternalEngines bool
+
+	// WebhookEnabled serves the validating admission webhook for pods
+	WebhookEnabled bool
+
+	// WebhookPort is the port the admission webhook listens on
+	WebhookPort int
+
+	// WebhookCertDir holds tls.crt/tls.key for the admission webhook (empty = controller-runtime default)
+	WebhookCertDir string
+
 	// SyncPeriod is how often the controller re-syncs all resources
 	SyncPeriod time.Duration
 
@@ -31,19 +301,127 @@ type Config struct {
 
 	// LogLevel sets the log verbosity
 	LogLevel int
+
+	// LogFormat is the log encoding, "console" or "json"; --zap-encoder overrides it
+	LogFormat string
+
+	// LogTimestampFormat is how log timestamps are written: rfc3339, rfc3339nano,
+	// iso8601, epoch, millis or nano; --zap-time-encoding overrides it
+	LogTimestampFormat string
+
+	// TracingEndpoint is the OTLP/gRPC endpoint spans are exported to (empty = tracing disabled)
+	TracingEndpoint string
+
+	// TracingSampleRatio is the fraction of root spans that are sampled when tracing is enabled
+	TracingSampleRatio float64
+
+	// TracingInsecure disables TLS when connecting to the tracing endpoint
+	TracingInsecure bool
 }
 
 // NewConfig creates a new Config with default values
 func NewConfig() *Config {
 	return &Config{
-		MetricsAddr:          getEnvOrDefault("METRICS_ADDR", ":8080"),
-		ProbeAddr:            getEnvOrDefault("PROBE_ADDR", ":8081"),
-		EnableLeaderElection: getEnvBoolOrDefault("ENABLE_LEADER_ELECTION", false),
-		LeaderElectionID:     getEnvOrDefault("LEADER_ELECTION_ID", "kubeshield-operator-lock"),
-		AuditServiceURL:      getEnvOrDefault("AUDIT_SERVICE_URL", "http://audit-service:8000"),
-		SyncPeriod:           getEnvDurationOrDefault("SYNC_PERIOD", 10*time.Minute),
-		Namespace:            os.Getenv("WATCH_NAMESPACE"),
-		LogLevel:             getEnvIntOrDefault("LOG_LEVEL", 0),
+		MetricsAddr:                 getEnvOrDefault("METRICS_ADDR", ":8080"),
+		MetricsSecure:               getEnvBoolOrDefault("METRICS_SECURE", false),
+		MetricsCertDir:              os.Getenv("METRICS_CERT_DIR"),
+		MetricsExemplars:            getEnvBoolOrDefault("METRICS_EXEMPLARS", false),
+		ProbeAddr:                   getEnvOrDefault("PROBE_ADDR", ":8081"),
+		StatusAddr:                  getEnvOrDefault("STATUS_ADDR", ":8082"),
+		StatusAuth:                  getEnvBoolOrDefault("STATUS_AUTH", true),
+		RecentEventsLimit:           getEnvIntOrDefault("RECENT_EVENTS_LIMIT", 1000),
+		RecentEventsRetention:       getEnvDurationOrDefault("RECENT_EVENTS_RETENTION", time.Hour),
+		EnableLeaderElection:        getEnvBoolOrDefault("ENABLE_LEADER_ELECTION", false),
+		LeaderElectionID:            getEnvOrDefault("LEADER_ELECTION_ID", "kubeshield-operator-lock"),
+		AuditServiceURL:             getEnvOrDefault("AUDIT_SERVICE_URL", "http://audit-service:8000"),
+		AuditTimeout:                getEnvDurationOrDefault("AUDIT_TIMEOUT", 10*time.Second),
+		AuditMaxIdleConnsPerHost:    getEnvIntOrDefault("AUDIT_MAX_IDLE_CONNS_PER_HOST", 32),
+		AuditIdleConnTimeout:        getEnvDurationOrDefault("AUDIT_IDLE_CONN_TIMEOUT", 90*time.Second),
+		AuditUseEnvProxy:            getEnvBoolOrDefault("AUDIT_USE_ENV_PROXY", true),
+		AuditProxyURL:               os.Getenv("AUDIT_PROXY_URL"),
+		AuditProxyUsername:          os.Getenv("AUDIT_PROXY_USERNAME"),
+		AuditProxyPassword:          os.Getenv("AUDIT_PROXY_PASSWORD"),
+		AuditNoProxy:                getEnvListOrDefault("AUDIT_NO_PROXY", nil),
+		AuditConnectivityCheck:      getEnvBoolOrDefault("AUDIT_CONNECTIVITY_CHECK", true),
+		AuditProtocol:               getEnvOrDefault("AUDIT_PROTOCOL", "http"),
+		AuditGRPCTarget:             os.Getenv("AUDIT_GRPC_TARGET"),
+		AuditGRPCInsecure:           getEnvBoolOrDefault("AUDIT_GRPC_INSECURE", false),
+		AuditGRPCCAFile:             os.Getenv("AUDIT_GRPC_CA_FILE"),
+		AuditGRPCCertFile:           os.Getenv("AUDIT_GRPC_CERT_FILE"),
+		AuditGRPCKeyFile:            os.Getenv("AUDIT_GRPC_KEY_FILE"),
+		AuditSinks:                  getEnvListOrDefault("AUDIT_SINKS", []string{"http"}),
+		AuditSampleRates:            getEnvListOrDefault("AUDIT_SAMPLE_RATES", nil),
+		MaxDescriptionLength:        getEnvIntOrDefault("MAX_DESCRIPTION_LENGTH", 1024),
+		RedactSecrets:               getEnvBoolOrDefault("REDACT_EVENT_SECRETS", true),
+		AuditRateLimit:              getEnvFloatOrDefault("AUDIT_RATE_LIMIT", 0),
+		AuditRateBurst:              getEnvIntOrDefault("AUDIT_RATE_BURST", 20),
+		EventCoalesceThreshold:      getEnvIntOrDefault("EVENT_COALESCE_THRESHOLD", 5),
+		AuditParquetDir:             getEnvOrDefault("AUDIT_PARQUET_DIR", "/var/lib/kubeshield/audit"),
+		AuditParquetFlushInterval:   getEnvDurationOrDefault("AUDIT_PARQUET_FLUSH_INTERVAL", 30*time.Second),
+		AuditParquetRotateInterval:  getEnvDurationOrDefault("AUDIT_PARQUET_ROTATE_INTERVAL", time.Hour),
+		AuditParquetMaxFileMB:       getEnvIntOrDefault("AUDIT_PARQUET_MAX_FILE_MB", 128),
+		AuditAWSTargetARN:           os.Getenv("AUDIT_AWS_TARGET_ARN"),
+		AuditAWSMaxAttempts:         getEnvIntOrDefault("AUDIT_AWS_MAX_ATTEMPTS", 5),
+		DecisionHookURL:             getEnvOrDefault("DECISION_HOOK_URL", ""),
+		DecisionHookTimeout:         getEnvDurationOrDefault("DECISION_HOOK_TIMEOUT", 2*time.Second),
+		DecisionHookFailOpen:        getEnvBoolOrDefault("DECISION_HOOK_FAIL_OPEN", true),
+		DefaultEnforcementMode:      getEnvOrDefault("DEFAULT_ENFORCEMENT_MODE", "Enforce"),
+		EvaluationPhase:             getEnvOrDefault("EVALUATION_PHASE", "OnCreate"),
+		CreateDefaultPolicy:         getEnvBoolOrDefault("CREATE_DEFAULT_POLICY", false),
+		HeartbeatInterval:           getEnvDurationOrDefault("HEARTBEAT_INTERVAL", 5*time.Minute),
+		ReportInterval:              getEnvDurationOrDefault("REPORT_INTERVAL", 0),
+		ReportDestination:           getEnvOrDefault("REPORT_DESTINATION", "audit"),
+		ReportSlackWebhookURL:       os.Getenv("REPORT_SLACK_WEBHOOK_URL"),
+		ReportTopN:                  getEnvIntOrDefault("REPORT_TOP_N", 10),
+		OperatorNamespace:           os.Getenv("POD_NAMESPACE"),
+		OperatorPodName:             os.Getenv("POD_NAME"),
+		PublicRegistries:            getEnvListOrDefault("PUBLIC_REGISTRIES", evaluator.DefaultPublicRegistries),
+		SystemNamespaces:            getEnvListOrDefault("SYSTEM_NAMESPACES", []string{"kube-system", "kube-node-lease", "kube-public"}),
+		SystemNamespaceMode:         getEnvOrDefault("SYSTEM_NAMESPACE_MODE", "skip"),
+		BypassNamespaces:            getEnvListOrDefault("BYPASS_NAMESPACES", nil),
+		ComplianceScoreWeights:      getEnvListOrDefault("COMPLIANCE_SCORE_WEIGHTS", nil),
+		PolicyReports:               getEnvBoolOrDefault("POLICY_REPORTS", false),
+		PolicyReportInterval:        getEnvDurationOrDefault("POLICY_REPORT_INTERVAL", time.Minute),
+		CoverageInterval:            getEnvDurationOrDefault("COVERAGE_INTERVAL", 5*time.Minute),
+		CoverageEvents:              getEnvBoolOrDefault("COVERAGE_EVENTS", false),
+		EnforcementRecords:          getEnvBoolOrDefault("ENFORCEMENT_RECORDS", false),
+		RecordMaxAge:                getEnvDurationOrDefault("ENFORCEMENT_RECORD_MAX_AGE", 90*24*time.Hour),
+		RecordMaxPerNamespace:       getEnvIntOrDefault("ENFORCEMENT_RECORD_MAX_PER_NAMESPACE", 1000),
+		RecordPruneInterval:         getEnvDurationOrDefault("ENFORCEMENT_RECORD_PRUNE_INTERVAL", time.Hour),
+		RBACCheckInterval:           getEnvDurationOrDefault("RBAC_CHECK_INTERVAL", 10*time.Minute),
+		ProtectedWorkloads:          getEnvListOrDefault("PROTECTED_WORKLOADS", []string{"kube-system/*"}),
+		ListPageSize:                int64(getEnvIntOrDefault("LIST_PAGE_SIZE", 500)),
+		EnforcementFailureThreshold: getEnvIntOrDefault("ENFORCEMENT_FAILURE_THRESHOLD", 3),
+		DeletionPropagation:         getEnvOrDefault("DELETION_PROPAGATION", ""),
+		MaxTerminationsPerOwner:     getEnvIntOrDefault("MAX_TERMINATIONS_PER_OWNER", 0),
+		TerminationThrottleWindow:   getEnvDurationOrDefault("TERMINATION_THROTTLE_WINDOW", time.Hour),
+		StateBackend:                getEnvOrDefault("STATE_BACKEND", "memory"),
+		StateConfigMap:              getEnvOrDefault("STATE_CONFIGMAP", "kube-shield-state"),
+		EvaluationBudget:            getEnvDurationOrDefault("EVALUATION_BUDGET", 10*time.Second),
+		PolicyEvaluationTimeout:     getEnvDurationOrDefault("POLICY_EVALUATION_TIMEOUT", 2*time.Second),
+		RecoverPanics:               getEnvBoolOrDefault("RECOVER_PANICS", true),
+		RespectPDB:                  getEnvBoolOrDefault("RESPECT_PDB", false),
+		Quarantine:                  getEnvBoolOrDefault("QUARANTINE_ENABLED", false),
+		AnnotateOwners:              getEnvBoolOrDefault("ANNOTATE_OWNERS", false),
+		SkipDrainingNodes:           getEnvBoolOrDefault("SKIP_DRAINING_NODES", true),
+		NodeReevaluationLimit:       getEnvIntOrDefault("NODE_REEVALUATION_LIMIT", 250),
+		UpgradeDetection:            getEnvBoolOrDefault("UPGRADE_DETECTION", false),
+		UpgradeUnavailablePercent:   getEnvIntOrDefault("UPGRADE_UNAVAILABLE_NODES_PERCENT", 20),
+		UpgradeVersionSkew:          getEnvIntOrDefault("UPGRADE_VERSION_SKEW", 1),
+		UpgradeCooldown:             getEnvDurationOrDefault("UPGRADE_COOLDOWN", 10*time.Minute),
+		UpgradeMaxNodeSuspicion:     getEnvDurationOrDefault("UPGRADE_MAX_NODE_SUSPICION", time.Hour),
+		DeduplicateExternalEngines:  getEnvBoolOrDefault("DEDUPLICATE_EXTERNAL_ENGINES", false),
+		WebhookEnabled:              getEnvBoolOrDefault("WEBHOOK_ENABLED", false),
+		WebhookPort:                 getEnvIntOrDefault("WEBHOOK_PORT", 9443),
+		WebhookCertDir:              os.Getenv("WEBHOOK_CERT_DIR"),
+		SyncPeriod:                  getEnvDurationOrDefault("SYNC_PERIOD", 10*time.Minute),
+		Namespace:                   os.Getenv("WATCH_NAMESPACE"),
+		LogLevel:                    getEnvIntOrDefault("LOG_LEVEL", 0),
+		LogFormat:                   getEnvOrDefault("LOG_FORMAT", "console"),
+		LogTimestampFormat:          getEnvOrDefault("LOG_TIMESTAMP_FORMAT", "rfc3339"),
+		TracingEndpoint:             os.Getenv("OTLP_ENDPOINT"),
+		TracingSampleRatio:          getEnvFloatOrDefault("TRACING_SAMPLE_RATIO", 0.1),
+		TracingInsecure:             getEnvBoolOrDefault("TRACING_INSECURE", false),
 	}
 }
 
@@ -55,6 +433,21 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvListOrDefault returns the comma-separated values of an environment variable or a default
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // getEnvBoolOrDefault returns the boolean value of an environment variable or a default
 func getEnvBoolOrDefault(key string, defaultValue bool) bool {
 	value := os.Getenv(key)
@@ -81,6 +474,19 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	return i
 }
 
+// getEnvFloatOrDefault returns the float value of an environment variable or a default
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
 // getEnvDurationOrDefault returns the duration value of an environment variable or a default
 func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
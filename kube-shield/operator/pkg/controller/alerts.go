@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+const (
+	// alertQueueSize bounds the alerts waiting for delivery. Alerts arriving while it
+	// is full are dropped and counted, so a slow webhook never holds up enforcement.
+	alertQueueSize = 256
+
+	// alertDedupWindow is how long the same violation is not alerted again to the
+	// same webhook, e.g. while a pod is re-evaluated on every status update
+	alertDedupWindow = 10 * time.Minute
+)
+
+// alert is a violation waiting to be posted to one webhook
+type alert struct {
+	url   string
+	event audit.SecurityEvent
+}
+
+// key identifies the alert for deduplication
+func (a alert) key() string {
+	return a.url + "|" + a.event.Namespace + "/" + a.event.PodName + "|" + a.event.PolicyName + "|" + a.event.EventType + "|" + a.event.Container
+}
+
+// alertDispatcher posts the alerts of policies' AlertWebhooks in the background, so
+// reconciles only enqueue them
+type alertDispatcher struct {
+	client *http.Client
+	queue  chan alert
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// newAlertDispatcher creates an alertDispatcher posting through client
+func newAlertDispatcher(client *http.Client) *alertDispatcher {
+	return &alertDispatcher{
+		client: client,
+		queue:  make(chan alert, alertQueueSize),
+		sent:   make(map[string]time.Time),
+	}
+}
+
+// enqueue queues event for delivery to url. Alerts sent within alertDedupWindow are
+// skipped, and a full queue drops the alert.
+func (d *alertDispatcher) enqueue(logger logr.Logger, url string, event audit.SecurityEvent) {
+	a := alert{url: url, event: event}
+	webhook := audit.NewWebhookSink(url, d.client)
+	if !d.due(a.key(), time.Now()) {
+		metrics.AlertsSkipped.WithLabelValues(webhook.Destination(), "duplicate").Inc()
+		return
+	}
+	select {
+	case d.queue <- a:
+	default:
+		metrics.AlertsSkipped.WithLabelValues(webhook.Destination(), "queue_full").Inc()
+		logger.Info("Alert queue full, dropping alert", "destination", webhook.Destination())
+	}
+}
+
+// due records that the alert is sent at now, unless it was within alertDedupWindow.
+// Expired entries are dropped on the way, keeping the map bounded by the window.
+func (d *alertDispatcher) due(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if sentAt, ok := d.sent[key]; ok && now.Sub(sentAt) < alertDedupWindow {
+		return false
+	}
+	for k, sentAt := range d.sent {
+		if now.Sub(sentAt) >= alertDedupWindow {
+			delete(d.sent, k)
+		}
+	}
+	d.sent[key] = now
+	return true
+}
+
+// Start implements manager.Runnable, delivering queued alerts until ctx is done
+func (d *alertDispatcher) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("alerts")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case a := <-d.queue:
+			webhook := audit.NewWebhookSink(a.url, d.client)
+			if err := webhook.Send(ctx, a.event); err != nil {
+				metrics.AlertWebhookFailures.WithLabelValues(webhook.Destination()).Inc()
+				logger.Info("Failed to deliver alert", "destination", webhook.Destination(), "error", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// annotationPatchInterval is the minimum time between two annotation patches of the same pod
+const annotationPatchInterval = time.Minute
+
+// patchLimiter rate limits annotation patches per pod
+type patchLimiter struct {
+	mu   sync.Mutex
+	last map[types.NamespacedName]time.Time
+}
+
+// newPatchLimiter creates an empty patchLimiter
+func newPatchLimiter() *patchLimiter {
+	return &patchLimiter{last: make(map[types.NamespacedName]time.Time)}
+}
+
+// reserve returns zero and records the attempt if the pod may be patched now,
+// otherwise it returns how long to wait before the next patch
+func (l *patchLimiter) reserve(pod types.NamespacedName, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if wait := l.last[pod].Add(annotationPatchInterval).Sub(now); wait > 0 {
+		return wait
+	}
+	l.last[pod] = now
+	return 0
+}
+
+// forget drops the rate limit state of a pod
+func (l *patchLimiter) forget(pod types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.last, pod)
+}
+
+// annotatedViolations returns the sorted, de-duplicated rules violated under
+// audit-mode policies that ask for violations to be annotated on the pod
+func annotatedViolations(evaluations []policyEvaluation) []string {
+	seen := make(map[string]bool)
+	var rules []string
+	for _, evaluation := range evaluations {
+		if !evaluation.policy.Spec.AnnotateViolations || !evaluation.policy.IsAuditing() {
+			continue
+		}
+		for _, violation := range evaluation.violations {
+			rule := violation.EventType
+			if violation.Rule != "" {
+				rule = violation.Rule
+			}
+			if !seen[rule] {
+				seen[rule] = true
+				rules = append(rules, rule)
+			}
+		}
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// syncViolationAnnotations brings the pod's violation annotations in line with the
+// rules it currently violates. Only our own keys are touched through a merge patch,
+// and nothing is written when the annotation is already current. When the pod was
+// patched too recently, it returns how long to wait before trying again. A failed
+// patch is returned so the caller requeues, and does not count against the limit.
+func (r *PodReconciler) syncViolationAnnotations(
+	ctx context.Context,
+	logger logr.Logger,
+	pod *corev1.Pod,
+	rules []string,
+) (time.Duration, error) {
+	current, annotated := pod.Annotations[shieldv1alpha1.ViolationsAnnotation]
+
+	var desired interface{}
+	if len(rules) > 0 {
+		encoded, err := json.Marshal(rules)
+		if err != nil {
+			logger.Error(err, "Failed to encode violation annotation")
+			return 0, nil
+		}
+		if annotated && current == string(encoded) {
+			return 0, nil
+		}
+		desired = string(encoded)
+	} else if !annotated {
+		return 0, nil
+	}
+
+	podKey := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	now := time.Now()
+	if wait := r.annotationPatches.reserve(podKey, now); wait > 0 {
+		logger.V(1).Info("Deferring violation annotation update", "retryAfter", wait)
+		return wait, nil
+	}
+
+	// A nil value removes the key in a JSON merge patch
+	var lastEvaluated interface{}
+	if desired != nil {
+		lastEvaluated = now.UTC().Format(time.RFC3339)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				shieldv1alpha1.ViolationsAnnotation:    desired,
+				shieldv1alpha1.LastEvaluatedAnnotation: lastEvaluated,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to encode violation annotation patch")
+		return 0, nil
+	}
+
+	if err := r.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		r.annotationPatches.forget(podKey)
+		return 0, err
+	}
+	logger.V(1).Info("Updated violation annotations", "rules", rules)
+	return 0, nil
+}
+
+// ignoreOwnAnnotationUpdates filters out pod updates that only changed the
+// annotations written by the operator, so annotating a pod does not trigger
+// another evaluation of it. Requests for an evaluation always pass.
+func ignoreOwnAnnotationUpdates() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+			if newPod.Annotations[shieldv1alpha1.EvaluateAnnotation] == shieldv1alpha1.EvaluateNow {
+				return true
+			}
+			// Attached ephemeral containers are checked for POD_MUTATED
+			if len(newPod.Spec.EphemeralContainers) != len(oldPod.Spec.EphemeralContainers) {
+				return true
+			}
+			return !equality.Semantic.DeepEqual(withoutOwnAnnotations(oldPod), withoutOwnAnnotations(newPod))
+		},
+	}
+}
+
+// withoutOwnAnnotations returns a copy of pod without the operator-managed
+// annotations and the metadata that changes on every write
+func withoutOwnAnnotations(pod *corev1.Pod) *corev1.Pod {
+	stripped := pod.DeepCopy()
+	stripped.ResourceVersion = ""
+	stripped.ManagedFields = nil
+	delete(stripped.Annotations, shieldv1alpha1.ViolationsAnnotation)
+	delete(stripped.Annotations, shieldv1alpha1.LastEvaluatedAnnotation)
+	delete(stripped.Annotations, shieldv1alpha1.EvaluateAnnotation)
+	delete(stripped.Annotations, shieldv1alpha1.EvaluationResultAnnotation)
+	if len(stripped.Annotations) == 0 {
+		stripped.Annotations = nil
+	}
+	return stripped
+}
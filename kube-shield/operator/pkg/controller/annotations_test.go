@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestReconcileRequeuesFailedAnnotationPatch(t *testing.T) {
+	ctx := context.Background()
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			EnforcementMode:    shieldv1alpha1.EnforcementModeAudit,
+			AnnotateViolations: true,
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newTestPodReconciler(t, policy, pod)
+
+	// The API server is unavailable for the first patch only
+	patches := 0
+	r.Client = interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			if patches == 1 {
+				return errors.NewServiceUnavailable("etcd is unavailable")
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+
+	result, err := r.Reconcile(ctx, podRequest(pod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Requeue && result.RequeueAfter <= 0 {
+		t.Fatalf("failed annotation patch returned %+v, want a requeue", result)
+	}
+
+	// The retry is neither skipped as unchanged nor held back by the patch rate limit
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if patches != 2 {
+		t.Errorf("%d patches, want the failed one retried", patches)
+	}
+	if err := c.Get(ctx, podRequest(pod).NamespacedName, pod); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pod.Annotations[shieldv1alpha1.ViolationsAnnotation], `["HOST_NETWORK"]`; got != want {
+		t.Errorf("violations annotation = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BackOffMarker is added to violations whose termination was skipped because the pod
+// was stuck in a back-off loop
+const BackOffMarker = "POD_BACKOFF"
+
+// backOffReasons are the container waiting reasons of a pod that cannot start and is
+// retried by the kubelet, where deleting the pod only restarts the cycle
+var backOffReasons = map[string]bool{
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+	"CrashLoopBackOff": true,
+}
+
+// backOffReason returns the waiting reason of the first container stuck in a
+// back-off loop, or "" when none is
+func backOffReason(pod *corev1.Pod) string {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting != nil && backOffReasons[status.State.Waiting.Reason] {
+			return status.State.Waiting.Reason
+		}
+	}
+	return ""
+}
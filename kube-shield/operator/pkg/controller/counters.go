@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+const (
+	// counterResetRequested marks a reset asked for with the reset-counters annotation
+	counterResetRequested = "Requested"
+
+	// counterResetRetention marks a reset triggered by CounterRetention
+	counterResetRetention = "Retention"
+
+	// activityWindow is the period covered by the Last24h counters
+	activityWindow = 24 * time.Hour
+
+	// activityBucket is the granularity of status.recentActivity
+	activityBucket = time.Hour
+)
+
+// recordActivity counts a violation, and a termination if there was one, in the
+// bucket of the current hour and refreshes the windowed counters
+func recordActivity(status *shieldv1alpha1.ShieldPolicyStatus, now time.Time, terminated bool) {
+	start := now.Truncate(activityBucket)
+	buckets := status.RecentActivity
+	if n := len(buckets); n == 0 || buckets[n-1].Start.Time.Before(start) {
+		buckets = append(buckets, shieldv1alpha1.ActivityBucket{Start: metav1.NewTime(start)})
+	}
+	last := &buckets[len(buckets)-1]
+	last.Violations++
+	if terminated {
+		last.Terminations++
+	}
+	status.RecentActivity = buckets
+	rollActivity(status, now)
+}
+
+// rollActivity drops the buckets that left the window and recomputes the windowed
+// counters from the rest. It returns the time until the oldest remaining bucket
+// leaves the window, or zero when there is none.
+func rollActivity(status *shieldv1alpha1.ShieldPolicyStatus, now time.Time) time.Duration {
+	cutoff := now.Add(-activityWindow)
+	kept := status.RecentActivity[:0]
+	var violations, terminations int64
+	for _, bucket := range status.RecentActivity {
+		// A bucket is in the window until its whole hour has left it
+		if !bucket.Start.Time.Add(activityBucket).After(cutoff) {
+			continue
+		}
+		kept = append(kept, bucket)
+		violations += bucket.Violations
+		terminations += bucket.Terminations
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	status.RecentActivity = kept
+	status.ViolationsLast24h = violations
+	status.TerminationsLast24h = terminations
+
+	if len(kept) == 0 {
+		return 0
+	}
+	return kept[0].Start.Time.Add(activityBucket).Sub(cutoff)
+}
+
+// rollRecentActivity keeps the windowed counters current while no new violations
+// come in, and returns the time until they next change
+func (r *ShieldPolicyReconciler) rollRecentActivity(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) (time.Duration, error) {
+	var next time.Duration
+	err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		next = rollActivity(&policy.Status, time.Now())
+	})
+	return next, err
+}
+
+// resetCounters zeroes the policy's counters when the reset-counters annotation asks for
+// it or CounterRetention has elapsed, keeping the previous totals in status.lastReset.
+// The status is written with an update, so a reset never silently drops an increment
+// made by the pod controller in the meantime: one of the two writes conflicts and is
+// retried on a re-read policy. It returns the time until the next retention reset.
+func (r *ShieldPolicyReconciler) resetCounters(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) (time.Duration, error) {
+	now := time.Now()
+
+	if policy.Annotations[shieldv1alpha1.ResetCountersAnnotation] == "true" {
+		if err := r.snapshotCounters(ctx, policy, counterResetRequested, now); err != nil {
+			return 0, err
+		}
+
+		// Only drop the request once the reset is persisted
+		patch := client.MergeFrom(policy.DeepCopy())
+		delete(policy.Annotations, shieldv1alpha1.ResetCountersAnnotation)
+		if err := r.Patch(ctx, policy, patch); err != nil {
+			return 0, err
+		}
+	}
+
+	if policy.Spec.CounterRetention == nil || policy.Spec.CounterRetention.Duration <= 0 {
+		return 0, nil
+	}
+
+	since := policy.CreationTimestamp.Time
+	if policy.Status.LastReset != nil {
+		since = policy.Status.LastReset.Time.Time
+	}
+	remaining := since.Add(policy.Spec.CounterRetention.Duration).Sub(now)
+	if remaining > 0 {
+		return remaining, nil
+	}
+
+	if err := r.snapshotCounters(ctx, policy, counterResetRetention, now); err != nil {
+		return 0, err
+	}
+	return policy.Spec.CounterRetention.Duration, nil
+}
+
+// snapshotCounters records the policy's current totals in status.lastReset and zeroes them
+func (r *ShieldPolicyReconciler) snapshotCounters(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy, reason string, now time.Time) error {
+	return writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Status.LastReset = &shieldv1alpha1.CounterReset{
+			Time:              metav1.NewTime(now),
+			Reason:            reason,
+			ViolationsCount:   policy.Status.ViolationsCount,
+			TerminationsCount: policy.Status.TerminationsCount,
+		}
+		policy.Status.Message = fmt.Sprintf("Counters reset at %s (previously %d violations, %d terminations)",
+			now.UTC().Format(time.RFC3339), policy.Status.ViolationsCount, policy.Status.TerminationsCount)
+		policy.Status.ViolationsCount = 0
+		policy.Status.TerminationsCount = 0
+	})
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// conditionEvaluationSlow is set on a policy whose evaluation of a pod overran its timeout
+const conditionEvaluationSlow = "EvaluationSlow"
+
+// reportSlowEvaluation records a policy evaluation that was abandoned because it ran out
+// of time and marks the policy EvaluationSlow. budgetSpent tells whether the reconcile's
+// budget rather than the policy's own timeout cut it short.
+func (r *PodReconciler) reportSlowEvaluation(
+	ctx context.Context,
+	logger logr.Logger,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	budgetSpent bool,
+) {
+	scope, limit := metrics.TimeoutScopePolicy, r.Options.PolicyEvaluationTimeout
+	if budgetSpent {
+		scope, limit = metrics.TimeoutScopeReconcile, r.Options.EvaluationBudget
+	}
+	metrics.EvaluationTimeouts.WithLabelValues(policy.Name, scope).Inc()
+	logger.Info("Policy evaluation timed out, requeueing", "policy", policy.Name, "scope", scope, "limit", limit.String())
+
+	err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+			Type:               conditionEvaluationSlow,
+			Status:             metav1.ConditionTrue,
+			Reason:             "EvaluationTimedOut",
+			Message:            fmt.Sprintf("Evaluating pod %s/%s exceeded the %s %s limit", pod.Namespace, pod.Name, scope, limit),
+			ObservedGeneration: policy.Generation,
+		})
+	})
+	if err != nil {
+		logger.Error(err, "Failed to mark ShieldPolicy as slow")
+	}
+}
+
+// clearEvaluationSlow marks a slow policy as healthy again once it evaluates a pod in time
+func (r *PodReconciler) clearEvaluationSlow(ctx context.Context, logger logr.Logger, policy *shieldv1alpha1.ShieldPolicy) {
+	if !meta.IsStatusConditionTrue(policy.Status.Conditions, conditionEvaluationSlow) {
+		return
+	}
+	err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+			Type:               conditionEvaluationSlow,
+			Status:             metav1.ConditionFalse,
+			Reason:             "EvaluationCompleted",
+			Message:            "Policy evaluations complete within their timeout",
+			ObservedGeneration: policy.Generation,
+		})
+	})
+	if err != nil {
+		logger.Error(err, "Failed to clear EvaluationSlow on ShieldPolicy")
+	}
+}
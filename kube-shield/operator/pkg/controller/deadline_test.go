@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// slowAllowlist makes every digest allowlist lookup outlast the context it runs in
+type slowAllowlist struct{ lookups *atomic.Int32 }
+
+func (s slowAllowlist) ApprovedDigests(ctx context.Context, _ shieldv1alpha1.ConfigMapReference) (map[string]bool, error) {
+	s.lookups.Add(1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func slowPolicy(name string) *shieldv1alpha1.ShieldPolicy {
+	return &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID("uid-" + name)},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			EnforcementMode:           shieldv1alpha1.EnforcementModeEnforce,
+			AllowedImagesConfigMapRef: &shieldv1alpha1.ConfigMapReference{Namespace: "kube-shield", Name: "approved-images"},
+		},
+	}
+}
+
+// A spent evaluation budget leaves the remaining policies for the requeue, but what
+// the policies evaluated in time decided is still enforced
+func TestReconcileStopsAtEvaluationBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+	}{
+		{name: "termination", mode: shieldv1alpha1.EnforcementModeEnforce},
+		{name: "audit event", mode: shieldv1alpha1.EnforcementModeAudit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			// Policies are evaluated in name order: the first one decides the outcome,
+			// the second one spends the budget and the third is never started
+			decided := &shieldv1alpha1.ShieldPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "a-host-network", UID: "uid-a-host-network"},
+				Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: tt.mode},
+			}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+				Spec: corev1.PodSpec{
+					HostNetwork: true,
+					Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			}
+			r, c := newTestPodReconciler(t, decided, slowPolicy("b-slow"), slowPolicy("c-unstarted"), pod)
+			lookups := &atomic.Int32{}
+			r.Evaluator.ImageAllowlists = slowAllowlist{lookups: lookups}
+			r.Options.EvaluationBudget = 100 * time.Millisecond
+
+			result, err := r.Reconcile(ctx, podRequest(pod))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := lookups.Load(); n != 1 {
+				t.Errorf("%d policies looked up their allowlist, want only the one evaluated before the budget ran out", n)
+			}
+			slow := &shieldv1alpha1.ShieldPolicy{}
+			if err := c.Get(ctx, client.ObjectKey{Name: "b-slow"}, slow); err != nil {
+				t.Fatal(err)
+			}
+			if !meta.IsStatusConditionTrue(slow.Status.Conditions, conditionEvaluationSlow) {
+				t.Errorf("conditions = %+v, want %s on the policy that ran out of time", slow.Status.Conditions, conditionEvaluationSlow)
+			}
+
+			// A terminated pod has nothing left to evaluate, a pod that is kept is
+			// requeued for the policies left over
+			err = c.Get(ctx, podRequest(pod).NamespacedName, &corev1.Pod{})
+			if tt.mode == shieldv1alpha1.EnforcementModeEnforce {
+				if !errors.IsNotFound(err) {
+					t.Errorf("pod after its violation was decided in time: err = %v, want it terminated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("audited pod: %v", err)
+			}
+			if !result.Requeue {
+				t.Errorf("result = %+v, want a requeue for the remaining policies", result)
+			}
+			events := r.Sinks[0].(*audit.RecentEvents).Query(audit.EventQuery{})
+			if len(events) != 1 || events[0].EventType != "HOST_NETWORK" || events[0].PolicyName != decided.Name {
+				t.Errorf("events = %+v, want the HOST_NETWORK violation of %s", events, decided.Name)
+			}
+		})
+	}
+}
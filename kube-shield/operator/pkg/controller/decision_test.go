@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/decision"
+)
+
+func TestDecideLogsCause(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		failOpen   bool
+		wantAction string
+		wantLog    string
+	}{
+		{
+			name: "answer",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"decision":"allow","reason":"break-glass"}`))
+			},
+			wantAction: audit.ActionAllowed,
+			wantLog:    "External decision point overrode termination",
+		},
+		{
+			name:       "error failing open",
+			handler:    func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			failOpen:   true,
+			wantAction: audit.ActionAudit,
+			wantLog:    "External decision point failed, failing open and not terminating",
+		},
+		{
+			name:       "error failing closed",
+			handler:    func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantAction: audit.ActionTerminated,
+			wantLog:    "External decision point failed, failing closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			r := &PodReconciler{Decisions: decision.NewClient(server.URL, &http.Client{Timeout: time.Second}, tt.failOpen)}
+
+			var lines []string
+			logger := funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{})
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+			violation := audit.SecurityEvent{EventType: "HOST_NETWORK", Action: audit.ActionTerminated}
+
+			got := r.decide(context.Background(), logger, pod, violation)
+			if got.Action != tt.wantAction {
+				t.Errorf("Action = %s, want %s", got.Action, tt.wantAction)
+			}
+			logged := strings.Join(lines, "\n")
+			if !strings.Contains(logged, tt.wantLog) {
+				t.Errorf("logged:\n%s\nwant %q", logged, tt.wantLog)
+			}
+			if tt.wantAction != audit.ActionAllowed && strings.Contains(logged, "overrode termination") {
+				t.Errorf("logged:\n%s\nwant no override reported for a fallback", logged)
+			}
+		})
+	}
+}
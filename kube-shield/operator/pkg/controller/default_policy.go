@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/schedule"
+)
+
+const (
+	// DefaultPolicyName is the name of the ShieldPolicy bootstrapped by CREATE_DEFAULT_POLICY
+	DefaultPolicyName = "kubeshield-default"
+
+	// defaultPolicyFieldManager owns the fields of the default policy the operator applies
+	defaultPolicyFieldManager = "kubeshield-operator"
+
+	// defaultPolicyFinalizer lets the operator see the default policy's last annotations
+	// before it is gone, to remember whether it was handed over to the user
+	defaultPolicyFinalizer = shieldv1alpha1.GroupName + "/default-policy"
+
+	// defaultPolicyOptOut is the ConfigMap in the operator's namespace recording that the
+	// user deleted the default policy after setting ManagedAnnotation to "false"
+	defaultPolicyOptOut = "kubeshield-default-policy-opt-out"
+)
+
+// DefaultPolicyReconciler keeps the operator's default ShieldPolicy in place. Fields
+// are applied with server-side apply and never forced, so a field the user edited
+// belongs to them and is left alone.
+type DefaultPolicyReconciler struct {
+	client.Client
+	APIReader client.Reader
+
+	// Namespace is where the opt-out ConfigMap is kept; when empty the opt-out only
+	// lasts until the operator restarts
+	Namespace string
+
+	mu       sync.Mutex
+	optedOut bool
+}
+
+// NewDefaultPolicyReconciler creates a new DefaultPolicyReconciler
+func NewDefaultPolicyReconciler(client client.Client, apiReader client.Reader, namespace string) *DefaultPolicyReconciler {
+	return &DefaultPolicyReconciler{
+		Client:    client,
+		APIReader: apiReader,
+		Namespace: namespace,
+	}
+}
+
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldpolicies,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldpolicies/finalizers,verbs=update
+
+// The opt-out ConfigMap is read and created in the operator's own namespace, set by
+// POD_NAMESPACE, through the namespaced kube-shield-operator Role, which has to be
+// installed in that namespace.
+
+// Reconcile restores the default policy after it was changed or deleted
+func (r *DefaultPolicyReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	return ctrl.Result{}, r.ensure(ctx)
+}
+
+// startupJob creates the default policy once the replica is leader, since a missing
+// policy produces no event for Reconcile
+func (r *DefaultPolicyReconciler) startupJob() schedule.Job {
+	return schedule.Job{
+		Name: "default-policy",
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if err := r.ensure(ctx); err != nil {
+				logger.Error(err, "Failed to create the default ShieldPolicy", "shieldpolicy", DefaultPolicyName)
+			}
+		},
+	}
+}
+
+// ensure applies the default policy unless the user took it over or opted out
+func (r *DefaultPolicyReconciler) ensure(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("shieldpolicy", DefaultPolicyName)
+
+	policy := &shieldv1alpha1.ShieldPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy)
+	switch {
+	case errors.IsNotFound(err):
+		optedOut, err := r.isOptedOut(ctx)
+		if err != nil || optedOut {
+			return err
+		}
+		logger.Info("Creating the default ShieldPolicy")
+		return r.apply(ctx, logger)
+	case err != nil:
+		return err
+	}
+
+	handedOver := policy.Annotations[shieldv1alpha1.ManagedAnnotation] == "false"
+	if !policy.DeletionTimestamp.IsZero() {
+		if handedOver {
+			if err := r.recordOptOut(ctx); err != nil {
+				return err
+			}
+			logger.Info("Default ShieldPolicy deleted after being handed over, it will not be recreated")
+		}
+		return removeDefaultPolicyFinalizer(ctx, r.Client, policy)
+	}
+	if handedOver {
+		return nil
+	}
+	return r.apply(ctx, logger)
+}
+
+// apply server-side applies the operator's fields of the default policy. A conflict
+// means the user changed one of them, which is respected rather than overwritten.
+func (r *DefaultPolicyReconciler) apply(ctx context.Context, logger logr.Logger) error {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": shieldv1alpha1.SchemeGroupVersion.String(),
+		"kind":       "ShieldPolicy",
+		"metadata": map[string]interface{}{
+			"name":       DefaultPolicyName,
+			"finalizers": []interface{}{defaultPolicyFinalizer},
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/managed-by": defaultPolicyFieldManager,
+			},
+		},
+		"spec": map[string]interface{}{
+			"blockPrivileged": true,
+			"enforcementMode": shieldv1alpha1.EnforcementModeAudit,
+		},
+	}}
+
+	err := r.Patch(ctx, policy, client.Apply, client.FieldOwner(defaultPolicyFieldManager))
+	if errors.IsConflict(err) {
+		logger.Info("Default ShieldPolicy was edited, leaving the user's changes in place", "conflict", err.Error())
+		return nil
+	}
+	return err
+}
+
+// removeDefaultPolicyFinalizer removes the operator's finalizer from policy, if set
+func removeDefaultPolicyFinalizer(ctx context.Context, c client.Client, policy *shieldv1alpha1.ShieldPolicy) error {
+	patch := client.MergeFrom(policy.DeepCopy())
+	if !controllerutil.RemoveFinalizer(policy, defaultPolicyFinalizer) {
+		return nil
+	}
+	return c.Patch(ctx, policy, patch)
+}
+
+// ReleaseDefaultPolicyJob removes the finalizer a run with CREATE_DEFAULT_POLICY=true
+// left on the default policy, so it can be deleted once the operator no longer
+// manages it
+func ReleaseDefaultPolicyJob(c client.Client) schedule.Job {
+	return schedule.Job{
+		Name: "default-policy-release",
+		Run: func(ctx context.Context, logger logr.Logger) {
+			policy := &shieldv1alpha1.ShieldPolicy{}
+			if err := c.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy); err != nil {
+				if !errors.IsNotFound(err) {
+					logger.Error(err, "Failed to read the default ShieldPolicy", "shieldpolicy", DefaultPolicyName)
+				}
+				return
+			}
+			if !controllerutil.ContainsFinalizer(policy, defaultPolicyFinalizer) {
+				return
+			}
+			if err := removeDefaultPolicyFinalizer(ctx, c, policy); err != nil {
+				logger.Error(err, "Failed to remove the finalizer of the default ShieldPolicy", "shieldpolicy", DefaultPolicyName)
+				return
+			}
+			logger.Info("Released the default ShieldPolicy, CREATE_DEFAULT_POLICY is off", "shieldpolicy", DefaultPolicyName)
+		},
+	}
+}
+
+// isOptedOut reports whether the user deleted the default policy after handing it over
+func (r *DefaultPolicyReconciler) isOptedOut(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.optedOut || r.Namespace == "" {
+		return r.optedOut, nil
+	}
+
+	// Read directly, the operator does not watch ConfigMaps
+	err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: defaultPolicyOptOut}, &corev1.ConfigMap{})
+	switch {
+	case errors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	r.optedOut = true
+	return true, nil
+}
+
+// recordOptOut remembers that the default policy must not be recreated
+func (r *DefaultPolicyReconciler) recordOptOut(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.optedOut = true
+	if r.Namespace == "" {
+		return nil
+	}
+
+	optOut := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: r.Namespace, Name: defaultPolicyOptOut},
+		Data: map[string]string{
+			"reason": "The default ShieldPolicy was deleted with " + shieldv1alpha1.ManagedAnnotation + "=false. Delete this ConfigMap and restart the operator to have it recreated.",
+		},
+	}
+	if err := r.Create(ctx, optOut); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *DefaultPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := schedule.Add(mgr, r.startupJob()); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("defaultpolicy").
+		For(&shieldv1alpha1.ShieldPolicy{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return object.GetName() == DefaultPolicyName
+		}))).
+		Complete(r)
+}
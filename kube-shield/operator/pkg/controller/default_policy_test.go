@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// applyRecorder stands in for server-side apply, which the fake client does not
+// support, recording the applied objects and optionally answering with a conflict
+type applyRecorder struct {
+	applied  []*unstructured.Unstructured
+	conflict bool
+}
+
+func (a *applyRecorder) funcs() interceptor.Funcs {
+	return interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			a.applied = append(a.applied, obj.(*unstructured.Unstructured))
+			if a.conflict {
+				return errors.NewConflict(schema.GroupResource{Group: shieldv1alpha1.GroupName, Resource: "shieldpolicies"}, obj.GetName(), nil)
+			}
+			return nil
+		},
+	}
+}
+
+func newDefaultPolicyReconciler(t *testing.T, recorder *applyRecorder, namespace string, objects ...client.Object) (*DefaultPolicyReconciler, client.Client) {
+	t.Helper()
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objects...).WithInterceptorFuncs(recorder.funcs()).Build()
+	return NewDefaultPolicyReconciler(c, c, namespace), c
+}
+
+func defaultPolicy(annotations map[string]string) *shieldv1alpha1.ShieldPolicy {
+	return &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:        DefaultPolicyName,
+		Annotations: annotations,
+		Finalizers:  []string{defaultPolicyFinalizer},
+	}}
+}
+
+func TestDefaultPolicyCreated(t *testing.T) {
+	recorder := &applyRecorder{}
+	r, _ := newDefaultPolicyReconciler(t, recorder, "kube-shield")
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.applied) != 1 {
+		t.Fatalf("default policy applied %d times, want 1", len(recorder.applied))
+	}
+	applied := recorder.applied[0]
+	if mode, _, _ := unstructured.NestedString(applied.Object, "spec", "enforcementMode"); mode != shieldv1alpha1.EnforcementModeAudit {
+		t.Errorf("enforcementMode = %q, want %s", mode, shieldv1alpha1.EnforcementModeAudit)
+	}
+	if blocked, _, _ := unstructured.NestedBool(applied.Object, "spec", "blockPrivileged"); !blocked {
+		t.Error("blockPrivileged is not applied")
+	}
+	if finalizers := applied.GetFinalizers(); len(finalizers) != 1 || finalizers[0] != defaultPolicyFinalizer {
+		t.Errorf("finalizers = %v, want %s", finalizers, defaultPolicyFinalizer)
+	}
+}
+
+func TestDefaultPolicyEditsAreKept(t *testing.T) {
+	recorder := &applyRecorder{conflict: true}
+	r, _ := newDefaultPolicyReconciler(t, recorder, "kube-shield", defaultPolicy(nil))
+
+	// A conflict means the user owns a field now, which is not an error
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{}); err != nil {
+		t.Fatalf("Reconcile after a user edit = %v, want no error", err)
+	}
+	if len(recorder.applied) != 1 {
+		t.Fatalf("default policy applied %d times, want 1", len(recorder.applied))
+	}
+}
+
+func TestHandedOverDefaultPolicyLeftAlone(t *testing.T) {
+	recorder := &applyRecorder{}
+	r, _ := newDefaultPolicyReconciler(t, recorder, "kube-shield", defaultPolicy(map[string]string{shieldv1alpha1.ManagedAnnotation: "false"}))
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.applied) != 0 {
+		t.Errorf("handed over default policy applied %d times, want 0", len(recorder.applied))
+	}
+}
+
+func TestHandedOverDefaultPolicyDeletedStaysDeleted(t *testing.T) {
+	ctx := context.Background()
+	recorder := &applyRecorder{}
+	r, c := newDefaultPolicyReconciler(t, recorder, "kube-shield", defaultPolicy(map[string]string{shieldv1alpha1.ManagedAnnotation: "false"}))
+
+	policy := &shieldv1alpha1.ShieldPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy); !errors.IsNotFound(err) {
+		t.Fatalf("default policy still there after its finalizer was due to be removed: %v", err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "kube-shield", Name: defaultPolicyOptOut}, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("opt-out ConfigMap was not created: %v", err)
+	}
+
+	// Neither this replica nor a restarted operator recreates it
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDefaultPolicyReconciler(c, c, "kube-shield").Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.applied) != 0 {
+		t.Errorf("opted out default policy applied %d times, want 0", len(recorder.applied))
+	}
+}
+
+func TestManagedDefaultPolicyDeletedIsRecreated(t *testing.T) {
+	ctx := context.Background()
+	recorder := &applyRecorder{}
+	r, c := newDefaultPolicyReconciler(t, recorder, "kube-shield", defaultPolicy(nil))
+
+	policy := &shieldv1alpha1.ShieldPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "kube-shield", Name: defaultPolicyOptOut}, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+		t.Fatalf("opt-out ConfigMap recorded for a policy that was not handed over: %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.applied) != 1 {
+		t.Errorf("deleted default policy applied %d times, want it recreated once", len(recorder.applied))
+	}
+}
+
+func TestReleaseDefaultPolicyJob(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(defaultPolicy(nil)).Build()
+
+	ReleaseDefaultPolicyJob(c).Run(ctx, ctrl.Log)
+
+	policy := &shieldv1alpha1.ShieldPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Name: DefaultPolicyName}, policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Finalizers) != 0 {
+		t.Errorf("finalizers = %v, want none", policy.Finalizers)
+	}
+}
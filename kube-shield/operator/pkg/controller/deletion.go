@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/codes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/tracing"
+)
+
+// deletePod terminates a violating pod immediately, propagating the deletion as the
+// policy asks
+func (r *PodReconciler) deletePod(ctx context.Context, pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DeletePod")
+	defer span.End()
+
+	err := r.Delete(ctx, pod, deleteOptions(policy, r.Options.DeletionPropagation)...)
+	if err != nil && !errors.IsNotFound(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// deleteOptions returns how a violating pod is deleted under a policy: immediately,
+// with the policy's propagation of the deletion to dependents, or fallback when the
+// policy sets none
+func deleteOptions(policy *shieldv1alpha1.ShieldPolicy, fallback string) []client.DeleteOption {
+	opts := []client.DeleteOption{client.GracePeriodSeconds(0)}
+	propagation := policy.Spec.DeletionPropagation
+	if propagation == "" {
+		propagation = fallback
+	}
+	if propagation != "" {
+		opts = append(opts, client.PropagationPolicy(metav1.DeletionPropagation(propagation)))
+	}
+	return opts
+}
+
+// ValidateDeletionPropagation checks a DELETION_PROPAGATION value, which takes the
+// same values as a policy's deletionPropagation, or is empty for the API default
+func ValidateDeletionPropagation(propagation string) error {
+	switch metav1.DeletionPropagation(propagation) {
+	case "", metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan:
+		return nil
+	}
+	return fmt.Errorf("invalid deletion propagation %q, must be %s, %s or %s", propagation,
+		metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan)
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestValidateDeletionPropagation(t *testing.T) {
+	for _, propagation := range []string{"", "Background", "Foreground", "Orphan"} {
+		if err := ValidateDeletionPropagation(propagation); err != nil {
+			t.Errorf("ValidateDeletionPropagation(%q) = %v, want nil", propagation, err)
+		}
+	}
+	for _, propagation := range []string{"orphan", "Cascade"} {
+		if err := ValidateDeletionPropagation(propagation); err == nil {
+			t.Errorf("ValidateDeletionPropagation(%q) = nil, want an error", propagation)
+		}
+	}
+}
+
+func TestDeleteOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		fallback string
+		want     metav1.DeletionPropagation
+	}{
+		{name: "API default"},
+		{name: "fallback", fallback: "Foreground", want: metav1.DeletePropagationForeground},
+		{name: "policy wins", policy: "Orphan", fallback: "Foreground", want: metav1.DeletePropagationOrphan},
+		{name: "orphan fallback", fallback: "Orphan", want: metav1.DeletePropagationOrphan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &shieldv1alpha1.ShieldPolicy{Spec: shieldv1alpha1.ShieldPolicySpec{DeletionPropagation: tt.policy}}
+			opts := (&client.DeleteOptions{}).ApplyOptions(deleteOptions(policy, tt.fallback))
+
+			if opts.GracePeriodSeconds == nil || *opts.GracePeriodSeconds != 0 {
+				t.Errorf("GracePeriodSeconds = %v, want 0", opts.GracePeriodSeconds)
+			}
+			var got metav1.DeletionPropagation
+			if opts.PropagationPolicy != nil {
+				got = *opts.PropagationPolicy
+			}
+			if got != tt.want {
+				t.Errorf("PropagationPolicy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+const (
+	// PDBBlockedMarker is added to violations whose termination was deferred because
+	// a PodDisruptionBudget does not allow the disruption
+	PDBBlockedMarker = "PDB_BLOCKED"
+
+	// PDBUnknownMarker is added to violations whose termination was deferred because
+	// the PodDisruptionBudgets of the namespace could not be read
+	PDBUnknownMarker = "PDB_UNKNOWN"
+)
+
+// deferredTerminations remembers why the termination of each violation of a pod was
+// last deferred: the name of the blocking PodDisruptionBudget, or "" when budgets
+// could not be read. The retries every evictionRetry report a deferral only when
+// its cause changed.
+type deferredTerminations struct {
+	mu     sync.Mutex
+	causes map[types.NamespacedName]map[state.Key]string
+}
+
+// newDeferredTerminations creates an empty deferredTerminations
+func newDeferredTerminations() *deferredTerminations {
+	return &deferredTerminations{causes: make(map[types.NamespacedName]map[state.Key]string)}
+}
+
+// deferred records that the violation's termination was deferred for cause and returns
+// true if the previous attempt was deferred for the same cause
+func (d *deferredTerminations) deferred(pod types.NamespacedName, key state.Key, cause string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	causes, ok := d.causes[pod]
+	if !ok {
+		causes = make(map[state.Key]string)
+		d.causes[pod] = causes
+	}
+	previous, repeated := causes[key]
+	causes[key] = cause
+	return repeated && previous == cause
+}
+
+// forget drops what was recorded for a pod
+func (d *deferredTerminations) forget(pod types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.causes, pod)
+}
+
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=list
+
+// blockingDisruptionBudget returns the name of a PodDisruptionBudget that deleting
+// the pod would breach, or "" when none would. Like the Eviction API, a pod that is
+// not ready does not count against its budgets. Budgets are read from the API
+// server, so a termination right after another sees the updated allowance.
+func (r *PodReconciler) blockingDisruptionBudget(ctx context.Context, pod *corev1.Pod) (string, error) {
+	if !podReady(pod) {
+		return "", nil
+	}
+
+	budgets := &policyv1.PodDisruptionBudgetList{}
+	if err := r.APIReader.List(ctx, budgets, client.InNamespace(pod.Namespace)); err != nil {
+		return "", fmt.Errorf("listing PodDisruptionBudgets: %w", err)
+	}
+	for i := range budgets.Items {
+		budget := &budgets.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if budget.Status.DisruptionsAllowed < 1 {
+			return budget.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// podReady returns true if the pod's Ready condition is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// deferForBudget downgrades a termination the PodDisruptionBudget would not allow to
+// an audit and reports PDB_BLOCKED. It returns true as well if the previous attempt
+// was blocked by the same budget, which has then already been reported.
+func (r *PodReconciler) deferForBudget(
+	ctx context.Context,
+	logger logr.Logger,
+	pod *corev1.Pod,
+	violation audit.SecurityEvent,
+	budget string,
+) (audit.SecurityEvent, bool) {
+	violation.Action = audit.ActionAudit
+	violation.Markers = append(violation.Markers, PDBBlockedMarker)
+	key := state.Key{Policy: violation.PolicyName, EventType: violation.EventType, Rule: violation.Rule}
+	if r.deferrals.deferred(client.ObjectKeyFromObject(pod), key, budget) {
+		logger.V(1).Info("Termination still blocked by a PodDisruptionBudget", "podDisruptionBudget", budget, "retryAfter", evictionRetry)
+		return violation, true
+	}
+
+	logger.Info("Termination blocked by a PodDisruptionBudget", "podDisruptionBudget", budget, "retryAfter", evictionRetry)
+	r.sendSecurityEvent(ctx, logger, audit.SecurityEvent{
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		EventType:       audit.EventTypePDBBlocked,
+		Severity:        violation.Severity,
+		PodName:         pod.Name,
+		Namespace:       pod.Namespace,
+		Container:       violation.Container,
+		Image:           violation.Image,
+		ResolvedImageID: violation.ResolvedImageID,
+		ResolvedDigest:  violation.ResolvedDigest,
+		Reason:          fmt.Sprintf("Termination after %s deferred by PodDisruptionBudget %s", violation.EventType, budget),
+		Action:          audit.ActionAudit,
+		PolicyName:      violation.PolicyName,
+		NodeName:        pod.Spec.NodeName,
+		Description:     fmt.Sprintf("Pod '%s' violates policy '%s' but PodDisruptionBudget '%s' allows no disruption; termination is retried in %s", pod.Name, violation.PolicyName, budget, evictionRetry),
+	})
+	return violation, false
+}
+
+// deferForUnknownBudgets downgrades a termination to an audit when the
+// PodDisruptionBudgets that might forbid it cannot be read. Like deferForBudget,
+// it returns true if the previous attempt was deferred for the same reason.
+func (r *PodReconciler) deferForUnknownBudgets(logger logr.Logger, pod *corev1.Pod, violation audit.SecurityEvent, err error) (audit.SecurityEvent, bool) {
+	violation.Action = audit.ActionAudit
+	violation.Markers = append(violation.Markers, PDBUnknownMarker)
+	key := state.Key{Policy: violation.PolicyName, EventType: violation.EventType, Rule: violation.Rule}
+	repeated := r.deferrals.deferred(client.ObjectKeyFromObject(pod), key, "")
+	if !repeated {
+		logger.Error(err, "Failed to check PodDisruptionBudgets, deferring termination", "retryAfter", evictionRetry)
+	}
+	return violation, repeated
+}
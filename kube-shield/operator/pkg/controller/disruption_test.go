@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+func TestDeferredTerminationsReportCauseChangesOnly(t *testing.T) {
+	deferrals := newDeferredTerminations()
+	pod := types.NamespacedName{Namespace: "production", Name: "web"}
+	key := state.Key{Policy: "restricted", EventType: "PRIVILEGED_CONTAINER"}
+
+	steps := []struct {
+		cause        string
+		wantRepeated bool
+	}{
+		{cause: "web-pdb", wantRepeated: false},
+		{cause: "web-pdb", wantRepeated: true},
+		{cause: "", wantRepeated: false},
+		{cause: "", wantRepeated: true},
+		{cause: "web-pdb", wantRepeated: false},
+	}
+	for i, step := range steps {
+		if repeated := deferrals.deferred(pod, key, step.cause); repeated != step.wantRepeated {
+			t.Errorf("attempt %d deferred by %q: repeated = %t, want %t", i+1, step.cause, repeated, step.wantRepeated)
+		}
+	}
+
+	deferrals.forget(pod)
+	if deferrals.deferred(pod, key, "web-pdb") {
+		t.Error("deferral after forget reported as repeated")
+	}
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+const (
+	// conditionEnforcementDegraded is set on a policy whose terminations keep failing
+	conditionEnforcementDegraded = "EnforcementDegraded"
+
+	// conditionImageAllowlistAvailable reports whether a policy's allowlist ConfigMap can be read
+	conditionImageAllowlistAvailable = "ImageAllowlistAvailable"
+
+	// enforcementBackoffBase is the requeue delay after the first failed termination
+	enforcementBackoffBase = 5 * time.Second
+
+	// enforcementBackoffMax caps the requeue delay between termination attempts
+	enforcementBackoffMax = 5 * time.Minute
+)
+
+// failureTracker counts consecutive failures per pod
+type failureTracker struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// newFailureTracker creates an empty failureTracker
+func newFailureTracker() *failureTracker {
+	return &failureTracker{failures: make(map[types.NamespacedName]int)}
+}
+
+// record registers a failure and returns the number of consecutive failures
+func (t *failureTracker) record(pod types.NamespacedName) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failures[pod]++
+	return t.failures[pod]
+}
+
+// reset clears the failures recorded for a pod
+func (t *failureTracker) reset(pod types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, pod)
+}
+
+// enforcementBackoff returns the exponential requeue delay after the given number of failures
+func enforcementBackoff(failures int) time.Duration {
+	delay := enforcementBackoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= enforcementBackoffMax {
+			return enforcementBackoffMax
+		}
+	}
+	return delay
+}
+
+// handleEnforcementFailure reports a failed termination and schedules the next attempt
+// after a jittered backoff. After EnforcementFailureThreshold consecutive failures the
+// policy is marked degraded. A forbidden termination marks it degraded right away and
+// is only retried after forbiddenRetry, since only an RBAC fix can make it succeed.
+func (r *PodReconciler) handleEnforcementFailure(
+	ctx context.Context,
+	logger logr.Logger,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	violation audit.SecurityEvent,
+	deleteErr error,
+) ctrl.Result {
+	podKey := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	failures := r.failures.record(podKey)
+	metrics.Inc(ctx, metrics.EnforcementFailures.WithLabelValues(policy.Name, pod.Namespace))
+
+	r.sendSecurityEvent(ctx, logger, audit.SecurityEvent{
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		EventType:       audit.EventTypeEnforcementFailed,
+		Severity:        "CRITICAL",
+		PodName:         pod.Name,
+		Namespace:       pod.Namespace,
+		Container:       violation.Container,
+		Image:           violation.Image,
+		ResolvedImageID: violation.ResolvedImageID,
+		ResolvedDigest:  violation.ResolvedDigest,
+		Containers:      violation.Containers,
+		Reason:          fmt.Sprintf("Failed to terminate pod after %s", violation.EventType),
+		Action:          audit.ActionTerminationFailed,
+		PolicyName:      policy.Name,
+		NodeName:        pod.Spec.NodeName,
+		Description:     fmt.Sprintf("Pod '%s' violates policy '%s' but could not be terminated (attempt %d)", pod.Name, policy.Name, failures),
+		Error:           deleteErr.Error(),
+	})
+	// Only the first failure of a run is recorded, not every retry
+	if failures == 1 {
+		failed := violation
+		failed.Action = audit.ActionTerminationFailed
+		r.recordEnforcement(ctx, logger, pod, failed)
+	}
+
+	forbidden := classifyError(deleteErr) == errorForbidden
+	if failures >= r.Options.EnforcementFailureThreshold || forbidden {
+		reason, message := "TerminationFailing", fmt.Sprintf("Pod %s could not be terminated %d times in a row: %v", podKey, failures, deleteErr)
+		if forbidden {
+			reason, message = "TerminationForbidden", fmt.Sprintf("The operator is not allowed to terminate pod %s: %v", podKey, deleteErr)
+		}
+		err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+				Type:               conditionEnforcementDegraded,
+				Status:             metav1.ConditionTrue,
+				Reason:             reason,
+				Message:            message,
+				ObservedGeneration: policy.Generation,
+			})
+		})
+		if err != nil {
+			logger.Error(err, "Failed to mark ShieldPolicy as degraded")
+		}
+	}
+	if forbidden {
+		backoff := wait.Jitter(forbiddenRetry, retryJitter)
+		logger.Info("Retrying termination once the operator's RBAC may allow it", "requeueAfter", backoff)
+		return ctrl.Result{RequeueAfter: backoff}
+	}
+
+	backoff := jitteredBackoff(failures)
+	logger.Info("Retrying termination after backoff", "failures", failures, "requeueAfter", backoff)
+	return ctrl.Result{RequeueAfter: backoff}
+}
+
+// clearEnforcementDegraded marks a degraded policy as healthy again after a successful termination.
+// The caller is responsible for persisting the status.
+func clearEnforcementDegraded(policy *shieldv1alpha1.ShieldPolicy) {
+	if !meta.IsStatusConditionTrue(policy.Status.Conditions, conditionEnforcementDegraded) {
+		return
+	}
+	meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+		Type:               conditionEnforcementDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             "TerminationSucceeded",
+		Message:            "Violating pods are being terminated again",
+		ObservedGeneration: policy.Generation,
+	})
+}
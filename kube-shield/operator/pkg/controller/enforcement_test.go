@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+func TestEnforcementFailureRecordedOnce(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).Build()
+	r := &PodReconciler{
+		Client:    c,
+		APIReader: c,
+		Options:   PodReconcilerOptions{EnforcementRecords: true, EnforcementFailureThreshold: 10},
+		failures:  newFailureTracker(),
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	policy := &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}}
+	violation := audit.SecurityEvent{EventType: "PRIVILEGED_CONTAINER", PolicyName: policy.Name}
+
+	for i := 0; i < 3; i++ {
+		r.handleEnforcementFailure(ctx, logr.Discard(), pod, policy, violation, errors.New("connection refused"))
+	}
+
+	records := &shieldv1alpha1.ShieldEnforcementRecordList{}
+	if err := c.List(ctx, records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records.Items) != 1 {
+		t.Fatalf("%d enforcement records after 3 failed attempts, want 1", len(records.Items))
+	}
+	if outcome := records.Items[0].Spec.Outcome; outcome != audit.ActionTerminationFailed {
+		t.Errorf("record outcome = %s, want %s", outcome, audit.ActionTerminationFailed)
+	}
+
+	// A new run of failures after a success is recorded again
+	r.failures.reset(types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
+	r.handleEnforcementFailure(ctx, logr.Discard(), pod, policy, violation, errors.New("connection refused"))
+	if err := c.List(ctx, records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records.Items) != 2 {
+		t.Errorf("%d enforcement records after a new failure, want 2", len(records.Items))
+	}
+}
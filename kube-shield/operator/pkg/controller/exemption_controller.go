@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// ShieldExemptionReconciler keeps the phase of ShieldExemption objects current and
+// expires them when their ExpiresAt passes
+type ShieldExemptionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// NewShieldExemptionReconciler creates a new ShieldExemptionReconciler
+func NewShieldExemptionReconciler(client client.Client, scheme *runtime.Scheme) *ShieldExemptionReconciler {
+	return &ShieldExemptionReconciler{
+		Client: client,
+		Scheme: scheme,
+	}
+}
+
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldexemptions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldexemptions/status,verbs=get;update;patch
+
+// Reconcile updates the exemption's phase and requeues it for its expiry
+func (r *ShieldExemptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("shieldexemption", req.NamespacedName)
+
+	// Keep the active exemptions gauge in line, whatever happened to the object
+	defer r.refreshActiveExemptions(ctx, req.Namespace)
+
+	exemption := &shieldv1alpha1.ShieldExemption{}
+	if err := r.Get(ctx, req.NamespacedName, exemption); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch ShieldExemption")
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	phase, message := exemptionPhase(exemption, now)
+	if exemption.Status.Phase != phase || exemption.Status.Message != message ||
+		exemption.Status.ObservedGeneration != exemption.Generation {
+		exemption.Status.Phase = phase
+		exemption.Status.Message = message
+		exemption.Status.ObservedGeneration = exemption.Generation
+		if err := r.Status().Update(ctx, exemption); err != nil {
+			logger.Error(err, "Failed to update ShieldExemption status")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated ShieldExemption phase", "phase", phase)
+	}
+
+	if phase == shieldv1alpha1.ExemptionPhaseActive {
+		// Come back right after expiry to flip the phase
+		return ctrl.Result{RequeueAfter: exemption.Spec.ExpiresAt.Sub(now) + time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// exemptionPhase derives the phase and status message of an exemption at now
+func exemptionPhase(exemption *shieldv1alpha1.ShieldExemption, now time.Time) (string, string) {
+	if exemption.Spec.Match.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(exemption.Spec.Match.PodSelector); err != nil {
+			return shieldv1alpha1.ExemptionPhaseInvalid, fmt.Sprintf("Invalid pod selector: %v", err)
+		}
+	}
+	if !exemption.IsActive(now) {
+		return shieldv1alpha1.ExemptionPhaseExpired,
+			fmt.Sprintf("Expired at %s", exemption.Spec.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return shieldv1alpha1.ExemptionPhaseActive,
+		fmt.Sprintf("Exempting %d rule(s) until %s", len(exemption.Spec.Rules), exemption.Spec.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+// refreshActiveExemptions recomputes the active exemptions gauge of a namespace
+func (r *ShieldExemptionReconciler) refreshActiveExemptions(ctx context.Context, namespace string) {
+	exemptions := &shieldv1alpha1.ShieldExemptionList{}
+	if err := r.List(ctx, exemptions, client.InNamespace(namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ShieldExemptions for metrics")
+		return
+	}
+
+	now := time.Now()
+	active := 0
+	for i := range exemptions.Items {
+		if exemptions.Items[i].IsActive(now) {
+			active++
+		}
+	}
+	metrics.ActiveExemptions.WithLabelValues(namespace).Set(float64(active))
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ShieldExemptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&shieldv1alpha1.ShieldExemption{}).
+		Complete(r)
+}
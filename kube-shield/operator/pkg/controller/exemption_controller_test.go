@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func testExemption(expiresAt time.Time) *shieldv1alpha1.ShieldExemption {
+	return &shieldv1alpha1.ShieldExemption{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "migration", UID: "uid-migration"},
+		Spec: shieldv1alpha1.ShieldExemptionSpec{
+			Rules:         []string{shieldv1alpha1.AllRules},
+			ExpiresAt:     metav1.NewTime(expiresAt),
+			Justification: "Legacy agent until the migration completes",
+		},
+	}
+}
+
+func TestListActiveExemptionsAtExpiry(t *testing.T) {
+	expiresAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(testExemption(expiresAt)).Build()
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{name: "just before", now: expiresAt.Add(-time.Nanosecond), want: 1},
+		{name: "at expiry", now: expiresAt},
+		{name: "after", now: expiresAt.Add(time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, err := listActiveExemptions(context.Background(), c, "default", tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(active) != tt.want {
+				t.Errorf("%d active exemptions, want %d", len(active), tt.want)
+			}
+			if phase, _ := exemptionPhase(testExemption(expiresAt), tt.now); (phase == shieldv1alpha1.ExemptionPhaseActive) != (tt.want == 1) {
+				t.Errorf("phase = %s, want it to agree with the active list", phase)
+			}
+		})
+	}
+}
+
+func TestShieldExemptionReconcilerExpires(t *testing.T) {
+	ctx := context.Background()
+	active := testExemption(time.Now().Add(time.Hour))
+	expired := testExemption(time.Now().Add(-time.Second))
+	expired.Name, expired.UID = "expired", "uid-expired"
+	// Still marked Active from before its expiry
+	expired.Status.Phase = shieldv1alpha1.ExemptionPhaseActive
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(active, expired).
+		WithStatusSubresource(&shieldv1alpha1.ShieldExemption{}).
+		Build()
+	r := NewShieldExemptionReconciler(c, c.Scheme())
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(active)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter <= time.Hour-time.Minute || result.RequeueAfter > time.Hour+time.Second {
+		t.Errorf("active exemption requeued after %s, want right after its expiry in 1h", result.RequeueAfter)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(active), active); err != nil {
+		t.Fatal(err)
+	}
+	if active.Status.Phase != shieldv1alpha1.ExemptionPhaseActive {
+		t.Errorf("phase = %s, want %s", active.Status.Phase, shieldv1alpha1.ExemptionPhaseActive)
+	}
+
+	result, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(expired)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expired exemption requeued after %s, want no requeue", result.RequeueAfter)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(expired), expired); err != nil {
+		t.Fatal(err)
+	}
+	if expired.Status.Phase != shieldv1alpha1.ExemptionPhaseExpired {
+		t.Errorf("phase = %s, want %s", expired.Status.Phase, shieldv1alpha1.ExemptionPhaseExpired)
+	}
+}
+
+// An evaluation cached while an exemption was active must not outlive it, even
+// though neither the pod nor the exemption object changed
+func TestReconcileStopsExemptingAtExpiry(t *testing.T) {
+	ctx := context.Background()
+	// metav1.Time keeps whole seconds, so a sub-second expiry could already be past
+	expiresAt := time.Now().Truncate(time.Second).Add(2 * time.Second)
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeEnforce},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newTestPodReconciler(t, policy, pod, testExemption(expiresAt))
+
+	for i := 0; i < 2; i++ {
+		before := time.Now()
+		result, err := r.Reconcile(ctx, podRequest(pod))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Get(ctx, podRequest(pod).NamespacedName, &corev1.Pod{}); err != nil {
+			t.Fatalf("exempt pod: %v", err)
+		}
+		if i == 0 && (result.RequeueAfter <= 0 || result.RequeueAfter > expiresAt.Sub(before)+time.Second) {
+			t.Errorf("exempt pod requeued after %s, want right after the exemption expires", result.RequeueAfter)
+		}
+	}
+
+	time.Sleep(time.Until(expiresAt) + 10*time.Millisecond)
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, podRequest(pod).NamespacedName, &corev1.Pod{}); !errors.IsNotFound(err) {
+		t.Errorf("pod after its exemption expired: err = %v, want it terminated", err)
+	}
+}
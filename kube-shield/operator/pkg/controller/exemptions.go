@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// activeExemptions returns the exemptions in the pod's namespace that have not expired at now
+func (r *PodReconciler) activeExemptions(ctx context.Context, namespace string, now time.Time) ([]shieldv1alpha1.ShieldExemption, error) {
+	return listActiveExemptions(ctx, r.Client, namespace, now)
+}
+
+// listActiveExemptions returns the exemptions in namespace that have not expired at now
+func listActiveExemptions(ctx context.Context, c client.Reader, namespace string, now time.Time) ([]shieldv1alpha1.ShieldExemption, error) {
+	exemptions := &shieldv1alpha1.ShieldExemptionList{}
+	if err := c.List(ctx, exemptions, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var active []shieldv1alpha1.ShieldExemption
+	for _, exemption := range exemptions.Items {
+		if exemption.IsActive(now) {
+			active = append(active, exemption)
+		}
+	}
+	return active, nil
+}
+
+// exemptionSetVersion identifies the active exemptions so cached evaluations are
+// invalidated when one is created, changed or expires
+func exemptionSetVersion(exemptions []shieldv1alpha1.ShieldExemption) string {
+	parts := make([]string, 0, len(exemptions))
+	for _, exemption := range exemptions {
+		parts = append(parts, fmt.Sprintf("%s/%d", exemption.UID, exemption.Generation))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// applyExemptions removes the violations covered by an active exemption. It returns
+// the remaining violations, an EXEMPTION_APPLIED event for every suppressed one and
+// the earliest expiry among the exemptions used, so the pod can be re-checked then.
+func applyExemptions(
+	pod *corev1.Pod,
+	violations []audit.SecurityEvent,
+	exemptions []shieldv1alpha1.ShieldExemption,
+) ([]audit.SecurityEvent, []audit.SecurityEvent, time.Time) {
+	if len(exemptions) == 0 {
+		return violations, nil, time.Time{}
+	}
+
+	var remaining, applied []audit.SecurityEvent
+	var nextExpiry time.Time
+	for _, violation := range violations {
+		check := violation.EventType
+		if violation.Rule != "" {
+			check = shieldv1alpha1.CustomRuleCheck(violation.Rule)
+		}
+
+		exemption := findExemption(pod, check, violation.Image, exemptions)
+		if exemption == nil {
+			remaining = append(remaining, violation)
+			continue
+		}
+
+		expiresAt := exemption.Spec.ExpiresAt.Time
+		if nextExpiry.IsZero() || expiresAt.Before(nextExpiry) {
+			nextExpiry = expiresAt
+		}
+
+		event := violation
+		event.EventType = "EXEMPTION_APPLIED"
+		event.Severity = "INFO"
+		event.Action = audit.ActionExempted
+		event.ExemptedCheck = check
+		event.Reason = fmt.Sprintf("%s exempted by ShieldExemption %s/%s", check, exemption.Namespace, exemption.Name)
+		event.Description = fmt.Sprintf("Violation '%s' of policy '%s' is exempted until %s: %s",
+			check, violation.PolicyName, expiresAt.UTC().Format(time.RFC3339), exemption.Spec.Justification)
+		applied = append(applied, event)
+	}
+	return remaining, applied, nextExpiry
+}
+
+// findExemption returns the first exemption covering check on the pod, if any
+func findExemption(
+	pod *corev1.Pod,
+	check, image string,
+	exemptions []shieldv1alpha1.ShieldExemption,
+) *shieldv1alpha1.ShieldExemption {
+	for i := range exemptions {
+		exemption := &exemptions[i]
+		if exemption.CoversRule(check) && exemption.MatchesPod(pod) && exemption.MatchesImage(pod, image) {
+			return exemption
+		}
+	}
+	return nil
+}
+
+// podsForExemption maps a ShieldExemption to the pods it may cover, so they are
+// re-evaluated when the exemption is created, changed or deleted
+func (r *PodReconciler) podsForExemption(ctx context.Context, obj client.Object) []reconcile.Request {
+	exemption, ok := obj.(*shieldv1alpha1.ShieldExemption)
+	if !ok {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(exemption.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list pods for ShieldExemption", "shieldexemption", exemption.Name)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if exemption.MatchesPod(pod) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+			})
+		}
+	}
+	return requests
+}
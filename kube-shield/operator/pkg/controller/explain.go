@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// EvaluationResult explains how a pod fared against every ShieldPolicy
+type EvaluationResult struct {
+	EvaluatedAt string             `json:"evaluatedAt"`
+	Policies    []PolicyEvaluation `json:"policies"`
+}
+
+// PolicyEvaluation is the outcome of one policy for a pod
+type PolicyEvaluation struct {
+	Policy     string        `json:"policy"`
+	Mode       string        `json:"mode"`
+	Applies    bool          `json:"applies"`
+	SkipReason string        `json:"skipReason,omitempty"`
+	Checks     []CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of one check of a policy
+type CheckResult struct {
+	Check   string   `json:"check"`
+	Passed  bool     `json:"passed"`
+	Action  string   `json:"action,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// explainEvaluation builds the EvaluationResult of a pod from the policies that were
+// considered and the violations found by the applicable ones
+func explainEvaluation(
+	pod *corev1.Pod,
+	policies []shieldv1alpha1.ShieldPolicy,
+	evaluations []policyEvaluation,
+	namespaceLabels map[string]string,
+	nodeLabels map[string]string,
+	scheduled bool,
+	ownerKind string,
+	now time.Time,
+) EvaluationResult {
+	found := make(map[string]policyEvaluation, len(evaluations))
+	for _, evaluation := range evaluations {
+		found[evaluation.policy.Name] = evaluation
+	}
+
+	result := EvaluationResult{
+		EvaluatedAt: now.UTC().Format(time.RFC3339),
+		Policies:    make([]PolicyEvaluation, 0, len(policies)),
+	}
+	for i := range policies {
+		policy := &policies[i]
+		entry := PolicyEvaluation{
+			Policy: policy.Name,
+			Mode:   policy.EffectiveEnforcementMode(),
+		}
+
+		switch {
+		case !policy.ShouldApplyToNamespace(pod.Namespace, namespaceLabels):
+			entry.SkipReason = "namespace not targeted by the policy"
+		case !appliesToNode(policy, nodeLabels, scheduled):
+			entry.SkipReason = "node does not match the policy's nodeSelector"
+		case !policy.ShouldApplyToOwnerKind(ownerKind):
+			entry.SkipReason = fmt.Sprintf("pod's owner kind %s is not in the policy's matchOwnerKinds", ownerKind)
+		case policy.IsDisabled():
+			entry.SkipReason = "policy is disabled"
+		case policy.IsSimulated():
+			entry.SkipReason = "policy is only simulated"
+		case !policy.ShouldEvaluatePod(pod):
+			entry.SkipReason = fmt.Sprintf("pod has not reached the policy's evaluation phase (%s)", policy.EffectiveEvaluationPhase())
+		default:
+			entry.Applies = true
+			entry.Checks = explainChecks(policy, found[policy.Name])
+		}
+		result.Policies = append(result.Policies, entry)
+	}
+	return result
+}
+
+// explainChecks lists every check of a policy, failing the ones with violations.
+// Exempted checks pass with the exemption as their reason.
+func explainChecks(policy *shieldv1alpha1.ShieldPolicy, evaluation policyEvaluation) []CheckResult {
+	outcomes := make(map[string]*CheckResult)
+	record := func(events []audit.SecurityEvent, passed bool) {
+		for _, event := range events {
+			check := event.EventType
+			if event.Rule != "" {
+				check = shieldv1alpha1.CustomRuleCheck(event.Rule)
+			}
+			if event.ExemptedCheck != "" {
+				check = event.ExemptedCheck
+			}
+			result, ok := outcomes[check]
+			if !ok {
+				result = &CheckResult{Check: check, Passed: passed, Action: event.Action}
+				outcomes[check] = result
+			}
+			result.Reasons = append(result.Reasons, event.Description)
+		}
+	}
+	record(evaluation.violations, false)
+	record(evaluation.exempted, true)
+
+	var checks []CheckResult
+	for _, check := range policy.EnabledChecks() {
+		if result, ok := outcomes[check]; ok {
+			checks = append(checks, *result)
+			continue
+		}
+		checks = append(checks, CheckResult{Check: check, Passed: true})
+	}
+	return checks
+}
+
+// writeEvaluationResult stores the explanation on the pod and clears the trigger
+// annotation in the same merge patch, so the request is processed once
+func (r *PodReconciler) writeEvaluationResult(
+	ctx context.Context,
+	logger logr.Logger,
+	pod *corev1.Pod,
+	result EvaluationResult,
+) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		logger.Error(err, "Failed to encode evaluation result")
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				shieldv1alpha1.EvaluationResultAnnotation: string(encoded),
+				shieldv1alpha1.EvaluateAnnotation:         nil,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to encode evaluation result patch")
+		return
+	}
+
+	if err := r.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		logger.Error(err, "Failed to write evaluation result to pod")
+		return
+	}
+	logger.Info("Wrote requested evaluation result to pod")
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// ImageAllowlists reads the ConfigMaps policies reference in AllowedImagesConfigMapRef.
+// They are read through the manager's cache, which only holds ConfigMaps labeled
+// with ImageAllowlistLabel, and are parsed again only when their resourceVersion
+// changes.
+type ImageAllowlists struct {
+	reader client.Reader
+	logger logr.Logger
+
+	mu     sync.Mutex
+	parsed map[types.NamespacedName]parsedAllowlist
+	failed map[types.NamespacedName]string
+}
+
+// parsedAllowlist holds the digests of one ConfigMap revision
+type parsedAllowlist struct {
+	resourceVersion string
+	digests         map[string]bool
+}
+
+var _ evaluator.ImageAllowlistLookup = &ImageAllowlists{}
+
+// NewImageAllowlists creates an ImageAllowlists reading from the given cached reader
+func NewImageAllowlists(reader client.Reader, logger logr.Logger) *ImageAllowlists {
+	return &ImageAllowlists{
+		reader: reader,
+		logger: logger,
+		parsed: make(map[types.NamespacedName]parsedAllowlist),
+		failed: make(map[types.NamespacedName]string),
+	}
+}
+
+// ApprovedDigests returns the digests listed as keys of the referenced ConfigMap, in
+// Data or BinaryData. A ConfigMap that is missing or not labeled is reported once
+// per distinct error rather than on every evaluation.
+func (a *ImageAllowlists) ApprovedDigests(ctx context.Context, ref shieldv1alpha1.ConfigMapReference) (map[string]bool, error) {
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	cm := &corev1.ConfigMap{}
+	if err := a.read(ctx, key, cm); err != nil {
+		metrics.ImageAllowlistReadFailures.WithLabelValues(key.String()).Inc()
+		a.reportFailure(key, err)
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.failed, key)
+	if cached, ok := a.parsed[key]; ok && cached.resourceVersion == cm.ResourceVersion {
+		return cached.digests, nil
+	}
+
+	digests := make(map[string]bool, len(cm.Data)+len(cm.BinaryData))
+	for entry := range cm.Data {
+		digests[evaluator.NormalizeDigest(entry)] = true
+	}
+	for entry := range cm.BinaryData {
+		digests[evaluator.NormalizeDigest(entry)] = true
+	}
+	a.parsed[key] = parsedAllowlist{resourceVersion: cm.ResourceVersion, digests: digests}
+	return digests, nil
+}
+
+// Available returns an error describing why the referenced ConfigMap cannot be read,
+// or nil if it can
+func (a *ImageAllowlists) Available(ctx context.Context, ref shieldv1alpha1.ConfigMapReference) error {
+	return a.read(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &corev1.ConfigMap{})
+}
+
+// read gets an allowlist ConfigMap, explaining that only labeled ones are visible
+func (a *ImageAllowlists) read(ctx context.Context, key types.NamespacedName, cm *corev1.ConfigMap) error {
+	err := a.reader.Get(ctx, key, cm)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("ConfigMap %s not found or not labeled %s=true", key, shieldv1alpha1.ImageAllowlistLabel)
+	}
+	return err
+}
+
+// reportFailure logs a ConfigMap read error unless the same one was logged last time
+func (a *ImageAllowlists) reportFailure(key types.NamespacedName, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.parsed, key)
+	if a.failed[key] == err.Error() {
+		return
+	}
+	a.failed[key] = err.Error()
+	a.logger.Error(err, "Failed to read image allowlist; only the policy's inline digests apply", "configmap", key.String())
+}
+
+// Version identifies the revisions of the allowlists the policies reference so
+// cached evaluations are invalidated when one of them changes
+func (a *ImageAllowlists) Version(ctx context.Context, policies []shieldv1alpha1.ShieldPolicy) string {
+	if a == nil {
+		return ""
+	}
+	var parts []string
+	for i := range policies {
+		ref := policies[i].Spec.AllowedImagesConfigMapRef
+		if ref == nil {
+			continue
+		}
+		cm := &corev1.ConfigMap{}
+		resourceVersion := "missing"
+		if err := a.reader.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err == nil {
+			resourceVersion = cm.ResourceVersion
+		}
+		parts = append(parts, ref.Namespace+"/"+ref.Name+"="+resourceVersion)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// podsForAllowlist maps an allowlist ConfigMap to the pods of the namespaces the
+// policies referencing it apply to, so they are re-evaluated when digests are
+// added or removed
+func (r *PodReconciler) podsForAllowlist(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		logger.Error(err, "Failed to list ShieldPolicies for image allowlist", "configmap", client.ObjectKeyFromObject(obj).String())
+		return nil
+	}
+
+	var referencing []*shieldv1alpha1.ShieldPolicy
+	for i := range policies.Items {
+		ref := policies.Items[i].Spec.AllowedImagesConfigMapRef
+		if ref != nil && ref.Namespace == obj.GetNamespace() && ref.Name == obj.GetName() {
+			referencing = append(referencing, &policies.Items[i])
+		}
+	}
+	if len(referencing) == 0 {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		logger.Error(err, "Failed to list pods for image allowlist", "configmap", client.ObjectKeyFromObject(obj).String())
+		return nil
+	}
+
+	applies := make(map[string]bool)
+	var requests []reconcile.Request
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		selected, ok := applies[pod.Namespace]
+		if !ok {
+			selected = r.allowlistAppliesTo(ctx, referencing, pod.Namespace)
+			applies[pod.Namespace] = selected
+		}
+		if selected {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// allowlistAppliesTo returns true if any of the policies applies to the namespace
+func (r *PodReconciler) allowlistAppliesTo(ctx context.Context, policies []*shieldv1alpha1.ShieldPolicy, namespace string) bool {
+	if r.System.Skip(namespace) {
+		return false
+	}
+	var nsLabels map[string]string
+	fetched := false
+	for _, policy := range policies {
+		if policy.Spec.NamespaceSelector != nil && !fetched {
+			labels, err := namespaceLabels(ctx, r.Client, namespace)
+			if err != nil {
+				// Re-evaluate rather than miss a removed digest
+				return true
+			}
+			nsLabels, fetched = labels, true
+		}
+		if policy.ShouldApplyToNamespace(namespace, nsLabels) {
+			return true
+		}
+	}
+	return false
+}
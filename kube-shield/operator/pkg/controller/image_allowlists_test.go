@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/protection"
+)
+
+const approvedDigest = "sha256:4a1c4b21597c1b4415bdbecb28a3296c6b5e23ca4f9feeb599860a1dac6a0108"
+
+// newTestScheme returns a scheme with the core and ShieldPolicy types
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := shieldv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func allowlistConfigMap(digests ...string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kube-shield",
+			Name:      "approved-images",
+			Labels:    map[string]string{shieldv1alpha1.ImageAllowlistLabel: "true"},
+		},
+		Data: map[string]string{},
+	}
+	for _, digest := range digests {
+		cm.Data[digest] = ""
+	}
+	return cm
+}
+
+var allowlistRef = shieldv1alpha1.ConfigMapReference{Namespace: "kube-shield", Name: "approved-images"}
+
+func TestImageAllowlistsFollowConfigMapUpdates(t *testing.T) {
+	ctx := context.Background()
+	cm := allowlistConfigMap("sha256.4a1c4b21597c1b4415bdbecb28a3296c6b5e23ca4f9feeb599860a1dac6a0108")
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(cm).Build()
+	allowlists := NewImageAllowlists(c, logr.Discard())
+
+	digests, err := allowlists.ApprovedDigests(ctx, allowlistRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !digests[approvedDigest] {
+		t.Fatalf("digests = %v, want %s", digests, approvedDigest)
+	}
+
+	// Replacing the digest bumps the resourceVersion, so the cached parse is dropped
+	cm.Data = map[string]string{"sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef": ""}
+	if err := c.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	digests, err = allowlists.ApprovedDigests(ctx, allowlistRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digests[approvedDigest] {
+		t.Error("removed digest is still approved after the ConfigMap update")
+	}
+	if !digests["sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"] {
+		t.Errorf("digests = %v, want the added digest", digests)
+	}
+}
+
+func TestImageAllowlistsRecoverFromDeletion(t *testing.T) {
+	ctx := context.Background()
+	cm := allowlistConfigMap(approvedDigest[len("sha256:"):])
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(cm).Build()
+	allowlists := NewImageAllowlists(c, logr.Discard())
+	policies := []shieldv1alpha1.ShieldPolicy{{Spec: shieldv1alpha1.ShieldPolicySpec{AllowedImagesConfigMapRef: &allowlistRef}}}
+
+	if err := allowlists.Available(ctx, allowlistRef); err != nil {
+		t.Fatalf("Available = %v, want nil", err)
+	}
+
+	if err := c.Delete(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := allowlists.ApprovedDigests(ctx, allowlistRef); err == nil {
+		t.Fatal("ApprovedDigests of a deleted ConfigMap succeeded")
+	}
+	if err := allowlists.Available(ctx, allowlistRef); err == nil {
+		t.Fatal("Available of a deleted ConfigMap = nil")
+	}
+	if version := allowlists.Version(ctx, policies); version != "kube-shield/approved-images=missing" {
+		t.Errorf("Version after deletion = %q, want it marked missing", version)
+	}
+
+	recreated := allowlistConfigMap(approvedDigest)
+	if err := c.Create(ctx, recreated); err != nil {
+		t.Fatal(err)
+	}
+	digests, err := allowlists.ApprovedDigests(ctx, allowlistRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !digests[approvedDigest] {
+		t.Errorf("digests after recreation = %v, want %s", digests, approvedDigest)
+	}
+	if len(allowlists.failed) != 0 {
+		t.Error("failure of the deleted ConfigMap is still recorded after recreation")
+	}
+}
+
+func TestPodsForAllowlist(t *testing.T) {
+	ctx := context.Background()
+	pod := func(namespace, name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "approved-images"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			AllowedImagesConfigMapRef: &allowlistRef,
+			TargetNamespaces:          []string{"production"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(
+		policy,
+		pod("production", "web"),
+		pod("staging", "web"),
+		pod("kube-system", "coredns"),
+	).Build()
+	system, err := protection.NewSystemNamespaces([]string{"kube-system"}, protection.SystemNamespaceModeSkip, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &PodReconciler{Client: c, System: system}
+
+	requests := r.podsForAllowlist(ctx, allowlistConfigMap())
+	if len(requests) != 1 || requests[0].Namespace != "production" || requests[0].Name != "web" {
+		t.Errorf("requests = %v, want only production/web", requests)
+	}
+
+	other := allowlistConfigMap()
+	other.Name = "other"
+	if requests := r.podsForAllowlist(ctx, other); len(requests) != 0 {
+		t.Errorf("requests for an unreferenced ConfigMap = %v, want none", requests)
+	}
+}
+
+func TestReportImageAllowlist(t *testing.T) {
+	ctx := context.Background()
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "approved-images"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{AllowedImagesConfigMapRef: &allowlistRef},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTestScheme(t)).
+		WithObjects(policy).
+		WithStatusSubresource(&shieldv1alpha1.ShieldPolicy{}).
+		Build()
+	r := &ShieldPolicyReconciler{Client: c, APIReader: c, ImageAllowlists: NewImageAllowlists(c, logr.Discard())}
+
+	condition := func() *metav1.Condition {
+		t.Helper()
+		current := &shieldv1alpha1.ShieldPolicy{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(policy), current); err != nil {
+			t.Fatal(err)
+		}
+		*policy = *current
+		for i := range current.Status.Conditions {
+			if current.Status.Conditions[i].Type == conditionImageAllowlistAvailable {
+				return &current.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	if err := r.reportImageAllowlist(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if got := condition(); got == nil || got.Status != metav1.ConditionFalse {
+		t.Fatalf("condition with a missing ConfigMap = %v, want False", got)
+	}
+
+	if err := c.Create(ctx, allowlistConfigMap(approvedDigest)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reportImageAllowlist(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if got := condition(); got == nil || got.Status != metav1.ConditionTrue {
+		t.Fatalf("condition with the ConfigMap created = %v, want True", got)
+	}
+
+	policy.Spec.AllowedImagesConfigMapRef = nil
+	if err := r.reportImageAllowlist(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if got := condition(); got != nil {
+		t.Errorf("condition without a ConfigMap reference = %v, want it removed", got)
+	}
+}
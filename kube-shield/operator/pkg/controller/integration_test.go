@@ -0,0 +1,234 @@
+//go:build integration
+
+package controller_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeshield/operator/internal/test"
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/controller"
+)
+
+// awaitTimeout bounds every wait on the operator's reconcilers
+const awaitTimeout = 30 * time.Second
+
+// quietPeriod is how long a test watches for something that must not happen
+const quietPeriod = 3 * time.Second
+
+var suite *test.Suite
+
+func TestMain(m *testing.M) {
+	var err error
+	if suite, err = test.Start(nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := suite.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(code)
+}
+
+// setup creates the test's namespaces and applies the policy, waiting until the
+// ShieldPolicy controller has picked it up
+func setup(t *testing.T, policy *shieldv1alpha1.ShieldPolicy, namespaces ...string) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	for _, namespace := range namespaces {
+		if err := suite.CreateNamespace(ctx, namespace, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := suite.ApplyPolicy(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	err := suite.AwaitPolicyStatus(ctx, awaitTimeout, policy.Name, func(status shieldv1alpha1.ShieldPolicyStatus) bool {
+		return status.Phase != ""
+	})
+	if err != nil {
+		t.Fatalf("policy %s was not reconciled: %v", policy.Name, err)
+	}
+	return ctx
+}
+
+// createPod creates the pod and fails the test if it cannot
+func createPod(ctx context.Context, t *testing.T, pod *corev1.Pod) {
+	t.Helper()
+	if err := suite.Client.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectNoEvent fails the test if an event matching match arrives within quietPeriod
+func expectNoEvent(ctx context.Context, t *testing.T, match func(audit.SecurityEvent) bool) {
+	t.Helper()
+	if event, err := suite.Audit.WaitFor(ctx, quietPeriod, match); err == nil {
+		t.Errorf("unexpected security event %s about %s/%s", event.EventType, event.Namespace, event.PodName)
+	}
+}
+
+// expectPodKept fails the test if the pod is gone
+func expectPodKept(ctx context.Context, t *testing.T, namespace, name string) {
+	t.Helper()
+	if err := suite.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Pod{}); err != nil {
+		t.Errorf("pod %s/%s was not kept: %v", namespace, name, err)
+	}
+}
+
+func TestPrivilegedPodTerminatedUnderEnforce(t *testing.T) {
+	ctx := setup(t, test.Policy("it-enforce", test.WithTargetNamespaces("it-enforce")), "it-enforce")
+	createPod(ctx, t, test.PrivilegedPod("it-enforce", "privileged"))
+
+	event, err := suite.Audit.WaitForEvent(ctx, awaitTimeout, "it-enforce", "privileged", "PRIVILEGED_CONTAINER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Action != audit.ActionTerminated || event.PolicyName != "it-enforce" {
+		t.Errorf("event action %s by %s, want %s by it-enforce", event.Action, event.PolicyName, audit.ActionTerminated)
+	}
+	if err := suite.AwaitPodDeleted(ctx, awaitTimeout, "it-enforce", "privileged"); err != nil {
+		t.Fatalf("privileged pod was not terminated: %v", err)
+	}
+}
+
+func TestPrivilegedPodAuditedUnderAudit(t *testing.T) {
+	policy := test.Policy("it-audit",
+		test.WithEnforcementMode(shieldv1alpha1.EnforcementModeAudit),
+		test.WithTargetNamespaces("it-audit"),
+	)
+	ctx := setup(t, policy, "it-audit")
+	createPod(ctx, t, test.PrivilegedPod("it-audit", "privileged"))
+
+	event, err := suite.Audit.WaitForEvent(ctx, awaitTimeout, "it-audit", "privileged", "PRIVILEGED_CONTAINER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Action != audit.ActionAudit {
+		t.Errorf("event action = %s, want %s", event.Action, audit.ActionAudit)
+	}
+	// Give a wrongly issued termination the time to happen
+	time.Sleep(quietPeriod)
+	expectPodKept(ctx, t, "it-audit", "privileged")
+}
+
+func TestRegistryAllowlist(t *testing.T) {
+	policy := test.Policy("it-registries",
+		test.WithEnforcementMode(shieldv1alpha1.EnforcementModeAudit),
+		test.WithAllowedRegistries("registry.example.com"),
+		test.WithTargetNamespaces("it-registries"),
+	)
+	ctx := setup(t, policy, "it-registries")
+	createPod(ctx, t, test.CompliantPod("it-registries", "allowed", test.WithImage("registry.example.com/team/app:1.0")))
+	createPod(ctx, t, test.CompliantPod("it-registries", "disallowed"))
+
+	event, err := suite.Audit.WaitForEvent(ctx, awaitTimeout, "it-registries", "disallowed", "DISALLOWED_REGISTRY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Image != test.CompliantImage {
+		t.Errorf("event image = %s, want %s", event.Image, test.CompliantImage)
+	}
+	expectNoEvent(ctx, t, test.EventFor("it-registries", "allowed", "DISALLOWED_REGISTRY"))
+}
+
+func TestNamespaceTargeting(t *testing.T) {
+	ctx := setup(t, test.Policy("it-targeted", test.WithTargetNamespaces("it-targeted")), "it-targeted", "it-untargeted")
+	createPod(ctx, t, test.PrivilegedPod("it-untargeted", "privileged"))
+	createPod(ctx, t, test.PrivilegedPod("it-targeted", "privileged"))
+
+	if err := suite.AwaitPodDeleted(ctx, awaitTimeout, "it-targeted", "privileged"); err != nil {
+		t.Fatalf("privileged pod in the targeted namespace was not terminated: %v", err)
+	}
+	expectNoEvent(ctx, t, func(event audit.SecurityEvent) bool {
+		return event.Namespace == "it-untargeted" && event.PolicyName == "it-targeted"
+	})
+	expectPodKept(ctx, t, "it-untargeted", "privileged")
+}
+
+func TestStatusCounters(t *testing.T) {
+	ctx := setup(t, test.Policy("it-counters", test.WithTargetNamespaces("it-counters")), "it-counters")
+	for _, name := range []string{"first", "second"} {
+		createPod(ctx, t, test.PrivilegedPod("it-counters", name))
+	}
+	createPod(ctx, t, test.CompliantPod("it-counters", "compliant"))
+
+	err := suite.AwaitPolicyStatus(ctx, awaitTimeout, "it-counters", func(status shieldv1alpha1.ShieldPolicyStatus) bool {
+		return status.ViolationsCount == 2 && status.TerminationsCount == 2
+	})
+	if err != nil {
+		t.Fatalf("status counters did not reach 2 violations and 2 terminations: %v", err)
+	}
+	expectPodKept(ctx, t, "it-counters", "compliant")
+}
+
+func TestDefaultPolicyFieldOwnership(t *testing.T) {
+	ctx := context.Background()
+	r := controller.NewDefaultPolicyReconciler(suite.Client, suite.Client, "")
+	key := types.NamespacedName{Name: controller.DefaultPolicyName}
+	t.Cleanup(func() {
+		policy := &shieldv1alpha1.ShieldPolicy{}
+		if err := suite.Client.Get(ctx, key, policy); err != nil {
+			return
+		}
+		patch := client.MergeFrom(policy.DeepCopy())
+		policy.Finalizers = nil
+		_ = suite.Client.Patch(ctx, policy, patch)
+		_ = suite.Client.Delete(ctx, policy)
+	})
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	policy := &shieldv1alpha1.ShieldPolicy{}
+	if err := suite.Client.Get(ctx, key, policy); err != nil {
+		t.Fatalf("default policy was not created: %v", err)
+	}
+	if policy.Spec.EnforcementMode != shieldv1alpha1.EnforcementModeAudit {
+		t.Fatalf("enforcementMode = %s, want %s", policy.Spec.EnforcementMode, shieldv1alpha1.EnforcementModeAudit)
+	}
+
+	// The user takes over enforcementMode with their own field manager
+	policy.Spec.EnforcementMode = shieldv1alpha1.EnforcementModeEnforce
+	if err := suite.Client.Update(ctx, policy, client.FieldOwner("kubectl-edit")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatalf("Reconcile after a user edit = %v, want no error", err)
+	}
+	if err := suite.Client.Get(ctx, key, policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Spec.EnforcementMode != shieldv1alpha1.EnforcementModeEnforce {
+		t.Errorf("enforcementMode = %s after reconcile, want the user's %s kept", policy.Spec.EnforcementMode, shieldv1alpha1.EnforcementModeEnforce)
+	}
+
+	// Once handed over and deleted, it is not recreated
+	policy.Annotations = map[string]string{shieldv1alpha1.ManagedAnnotation: "false"}
+	if err := suite.Client.Update(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	if err := suite.Client.Delete(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := suite.Client.Get(ctx, key, policy); !apierrors.IsNotFound(err) {
+		t.Errorf("handed over default policy is still there or was recreated: %v", err)
+	}
+}
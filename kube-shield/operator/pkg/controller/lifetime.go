@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/tracing"
+)
+
+const (
+	// EvictionBlockedMarker is added to POD_TOO_OLD violations whose eviction a
+	// PodDisruptionBudget did not allow yet
+	EvictionBlockedMarker = "EVICTION_BLOCKED"
+
+	// evictionRetry is how long a pod whose eviction was blocked waits for the next try
+	evictionRetry = 5 * time.Minute
+)
+
+// podExpiries returns the times the pod outlives the policy's MaxPodLifetime and
+// outgrows its MaxPodAge, keyed by the check
+func podExpiries(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy) map[string]time.Time {
+	expiries := make(map[string]time.Time, 2)
+	if expiry, ok := policy.LifetimeExpiry(pod); ok {
+		expiries["lifetime"] = expiry
+	}
+	if expiry, ok := policy.AgeExpiry(pod); ok {
+		expiries["age"] = expiry
+	}
+	return expiries
+}
+
+// lifetimeVersion names the policies whose MaxPodLifetime or MaxPodAge the pod has
+// passed, so that a cached evaluation is invalidated once a limit passes
+func lifetimeVersion(pod *corev1.Pod, policies []shieldv1alpha1.ShieldPolicy, now time.Time) string {
+	var expired []string
+	for i := range policies {
+		for check, expiry := range podExpiries(pod, &policies[i]) {
+			if !now.Before(expiry) {
+				expired = append(expired, policies[i].Name+"/"+check)
+			}
+		}
+	}
+	sort.Strings(expired)
+	return strings.Join(expired, ",")
+}
+
+// nextLifetimeExpiry returns whichever comes first of next and the time the pod will
+// outlive the policy's MaxPodLifetime or outgrow its MaxPodAge. Limits already
+// passed are ignored.
+func nextLifetimeExpiry(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, now time.Time, next time.Time) time.Time {
+	for _, expiry := range podExpiries(pod, policy) {
+		if !expiry.After(now) {
+			continue
+		}
+		if next.IsZero() || expiry.Before(next) {
+			next = expiry
+		}
+	}
+	return next
+}
+
+// evictPod evicts a pod through the Eviction API, which refuses with 429 Too Many
+// Requests while a PodDisruptionBudget does not allow the disruption
+func (r *PodReconciler) evictPod(ctx context.Context, pod *corev1.Pod) error {
+	ctx, span := tracing.Tracer().Start(ctx, "EvictPod")
+	defer span.End()
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+	}
+	err := r.SubResource("eviction").Create(ctx, pod, eviction)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
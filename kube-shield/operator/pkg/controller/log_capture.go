@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+const (
+	// logCaptureTimeout bounds the time spent reading logs before a termination, so
+	// a slow kubelet cannot hold back enforcement
+	logCaptureTimeout = 10 * time.Second
+
+	// maxLogReadBytes is the most read from a single container, whatever its tail
+	// lines add up to. Only the last MaxBytes of it are kept.
+	maxLogReadBytes = 256 * 1024
+
+	// truncatedLogMarker starts logs that were cut to MaxBytes
+	truncatedLogMarker = "[truncated]\n"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+// captureLogsOnce captures the pod's logs when a policy asking for them would
+// terminate it, with the settings of the first such policy
+func (r *PodReconciler) captureLogsOnce(ctx context.Context, logger logr.Logger, pod *corev1.Pod, evaluations []policyEvaluation) map[string]string {
+	if r.Logs == nil {
+		return nil
+	}
+	for _, evaluation := range evaluations {
+		capture := evaluation.policy.Spec.CaptureLogsBeforeTermination
+		if capture == nil {
+			continue
+		}
+		for _, violation := range evaluation.violations {
+			if violation.Action == audit.ActionTerminated {
+				return r.captureLogs(ctx, logger, pod, capture)
+			}
+		}
+	}
+	return nil
+}
+
+// captureLogs reads the tail of each container's logs before the pod is terminated.
+// Containers whose logs cannot be read are left out; the termination goes ahead.
+func (r *PodReconciler) captureLogs(ctx context.Context, logger logr.Logger, pod *corev1.Pod, capture *shieldv1alpha1.LogCapture) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, logCaptureTimeout)
+	defer cancel()
+
+	tailLines := capture.TailLines
+	if tailLines <= 0 {
+		tailLines = 100
+	}
+	maxBytes := capture.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 4096
+	}
+
+	logs := make(map[string]string)
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		output, err := r.readLogs(ctx, pod, container.Name, tailLines)
+		if err != nil {
+			logger.V(1).Info("Failed to capture container logs before termination", "container", container.Name, "error", err.Error())
+			continue
+		}
+		if len(output) == 0 {
+			continue
+		}
+		if int64(len(output)) > maxBytes {
+			output = append([]byte(truncatedLogMarker), output[int64(len(output))-maxBytes:]...)
+		}
+		logs[container.Name] = string(output)
+	}
+	return logs
+}
+
+// readLogs reads the last tailLines lines of a container through the pods/log
+// subresource, stopping at maxLogReadBytes
+func (r *PodReconciler) readLogs(ctx context.Context, pod *corev1.Pod, container string, tailLines int64) ([]byte, error) {
+	stream, err := r.Logs.Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &tailLines,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	output, err := io.ReadAll(io.LimitReader(stream, maxLogReadBytes))
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+func TestEnforcementLogLineIsJSON(t *testing.T) {
+	var out bytes.Buffer
+	ctx := log.IntoContext(context.Background(), zap.New(zap.WriteTo(&out), zap.JSONEncoder()))
+
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeEnforce},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, _ := newTestPodReconciler(t, policy, pod)
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+
+	var line map[string]interface{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		if entry["msg"] == "Terminating pod due to policy violation" {
+			line = entry
+		}
+	}
+	if line == nil {
+		t.Fatalf("no termination logged in:\n%s", out.String())
+	}
+
+	for _, key := range []string{"ts", "level", "msg", "pod", "podUID", "policy", "eventType", "reason"} {
+		if _, ok := line[key]; !ok {
+			t.Errorf("termination log line has no %q field: %v", key, line)
+		}
+	}
+
+	// The fields join the line with the event delivered for it
+	events := r.Sinks[0].(*audit.RecentEvents).Query(audit.EventQuery{})
+	if len(events) != 1 {
+		t.Fatalf("%d events delivered, want 1", len(events))
+	}
+	if line["policy"] != events[0].PolicyName || line["eventType"] != events[0].EventType || line["podUID"] != string(pod.UID) {
+		t.Errorf("log line policy=%v eventType=%v podUID=%v, want %s %s %s",
+			line["policy"], line["eventType"], line["podUID"], events[0].PolicyName, events[0].EventType, pod.UID)
+	}
+}
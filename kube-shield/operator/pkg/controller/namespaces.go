@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// namespaceLabels returns the labels of a namespace. Like nodes, namespaces are read
+// as metadata only through the manager's cache, which the controller's namespace watch
+// keeps current, so selector checks never reach the API server.
+func namespaceLabels(ctx context.Context, reader client.Reader, name string) (map[string]string, error) {
+	namespace := &metav1.PartialObjectMetadata{}
+	namespace.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if err := reader.Get(ctx, types.NamespacedName{Name: name}, namespace); err != nil {
+		return nil, fmt.Errorf("fetching namespace %s: %w", name, err)
+	}
+	return namespace.Labels, nil
+}
+
+// needsNamespaceLabels returns true if any policy is scoped with a NamespaceSelector
+func needsNamespaceLabels(policies []shieldv1alpha1.ShieldPolicy) bool {
+	for i := range policies {
+		if policies[i].Spec.NamespaceSelector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceLabelsVersion identifies a namespace's labels so cached evaluations are
+// invalidated when they change
+func namespaceLabelsVersion(namespaceLabels map[string]string) string {
+	return labels.Set(namespaceLabels).String()
+}
+
+// podsForNamespace maps a namespace to its pods, so they are re-evaluated when the
+// namespace's labels change which policies select them. Without a policy using a
+// NamespaceSelector, namespace labels cannot change any outcome.
+func (r *PodReconciler) podsForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	if r.System.Skip(obj.GetName()) {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		logger.Error(err, "Failed to list ShieldPolicies for namespace", "namespace", obj.GetName())
+		return nil
+	}
+	if !needsNamespaceLabels(policies.Items) {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(obj.GetName())); err != nil {
+		logger.Error(err, "Failed to list pods for namespace", "namespace", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(pods.Items))
+	for i := range pods.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pods.Items[i].Namespace, Name: pods.Items[i].Name},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestPodsForNamespace(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "web"}}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	unscoped := &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}}
+	scoped := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-only"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policies []*shieldv1alpha1.ShieldPolicy
+		want     int
+	}{
+		{name: "no policies"},
+		{name: "no namespace selector", policies: []*shieldv1alpha1.ShieldPolicy{unscoped}},
+		{name: "namespace selector", policies: []*shieldv1alpha1.ShieldPolicy{unscoped, scoped}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, c := newTestPodReconciler(t, namespace, pod)
+			for _, policy := range tt.policies {
+				if err := c.Create(context.Background(), policy.DeepCopy()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := r.podsForNamespace(context.Background(), namespace); len(got) != tt.want {
+				t.Errorf("podsForNamespace = %v, want %d requests", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// toBeDeletedTaint is set by the cluster autoscaler on nodes it is about to remove
+const toBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+
+// podNodeNameField indexes the cached pods by the node they are scheduled on
+const podNodeNameField = "spec.nodeName"
+
+// scheduledNode returns the node a pod is scheduled on. Nodes are read through the
+// manager's cache, so lookups are served from an informer instead of the API server.
+// It returns nil for unscheduled pods and for nodes that no longer exist.
+func scheduledNode(ctx context.Context, reader client.Reader, pod *corev1.Pod) (*corev1.Node, error) {
+	if pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+
+	node := &corev1.Node{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching node %s: %w", pod.Spec.NodeName, err)
+	}
+	return node, nil
+}
+
+// nodeDraining returns true if a node is cordoned or about to be removed by the
+// cluster autoscaler, meaning its pods are already being evicted
+func nodeDraining(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == toBeDeletedTaint {
+			return true
+		}
+	}
+	return false
+}
+
+// needsNodeLabels returns true if any policy is scoped with a NodeSelector
+func needsNodeLabels(policies []shieldv1alpha1.ShieldPolicy) bool {
+	for i := range policies {
+		if len(policies[i].Spec.NodeSelector) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesToNode returns true if the policy applies given the pod's node labels.
+// Node-scoped policies never apply to pods that are not scheduled yet.
+func appliesToNode(policy *shieldv1alpha1.ShieldPolicy, labels map[string]string, scheduled bool) bool {
+	if len(policy.Spec.NodeSelector) == 0 {
+		return true
+	}
+	return scheduled && policy.ShouldApplyToNode(labels)
+}
+
+// nodeLabelsVersion identifies the labels of the pod's node so cached evaluations are
+// invalidated when they change. It is empty unless a policy is node-scoped.
+func nodeLabelsVersion(policies []shieldv1alpha1.ShieldPolicy, nodeLabels map[string]string) string {
+	if !needsNodeLabels(policies) {
+		return ""
+	}
+	return labels.Set(nodeLabels).String()
+}
+
+// podNodeName is the index function of podNodeNameField
+func podNodeName(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// nodeLabelsChanged only passes updates that change a node's labels. Node creations
+// are ignored as new nodes have no pods yet, and deletions as the pods of a removed
+// node are being deleted too.
+func nodeLabelsChanged() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !labels.Equals(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+		},
+	}
+}
+
+// podsForNode maps a node to the pods scheduled on it, so they are re-evaluated when
+// its labels change which node-scoped policies select them. Nothing is queued while
+// no policy is node-scoped, and at most NodeReevaluationLimit pods are queued per
+// change; the others are picked up on their next update or reevaluationInterval.
+func (r *PodReconciler) podsForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		logger.Error(err, "Failed to list ShieldPolicies for node", "node", obj.GetName())
+		return nil
+	}
+	if !needsNodeLabels(policies.Items) {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.MatchingFields{podNodeNameField: obj.GetName()}); err != nil {
+		logger.Error(err, "Failed to list pods for node", "node", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if r.System.Skip(pod.Namespace) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+		})
+	}
+
+	if limit := r.Options.NodeReevaluationLimit; limit > 0 && len(requests) > limit {
+		dropped := len(requests) - limit
+		metrics.NodeReevaluationsDropped.Add(float64(dropped))
+		logger.Info("Node labels changed on a node with more pods than NODE_REEVALUATION_LIMIT, not re-evaluating all of them",
+			"node", obj.GetName(), "pods", len(requests), "dropped", dropped)
+		requests = requests[:limit]
+	}
+	return requests
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// maxEnforcementReasonLength caps LastEnforcementReasonAnnotation, so a long custom
+// rule message does not bloat the owner's metadata
+const maxEnforcementReasonLength = 256
+
+// annotatedOwners are the top-level owners annotateOwner may patch. The operator
+// is granted patch on exactly these kinds.
+var annotatedOwners = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "Deployment"}:  true,
+	{Group: "apps", Kind: "StatefulSet"}: true,
+	{Group: "apps", Kind: "DaemonSet"}:   true,
+	{Group: "apps", Kind: "ReplicaSet"}:  true,
+	{Group: "batch", Kind: "Job"}:        true,
+	{Group: "batch", Kind: "CronJob"}:    true,
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=patch
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=patch
+
+// annotateOwner tells the developers of the pod's workload why it is about to be
+// terminated: its top-level owner gets LastEnforcementAnnotation and
+// LastEnforcementReasonAnnotation and a Warning Event. It is best-effort: pods
+// without an owner are skipped, and failures, such as a missing patch permission,
+// are logged and never hold up the termination.
+func (r *PodReconciler) annotateOwner(ctx context.Context, logger logr.Logger, pod *corev1.Pod, violation audit.SecurityEvent) {
+	if !r.Options.AnnotateOwners {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, recordTimeout)
+	defer cancel()
+
+	ref, err := r.owners.TopLevel(ctx, pod)
+	if err != nil {
+		logger.V(1).Info("Failed to resolve the pod's owner for annotation", "error", err.Error())
+		return
+	}
+	if ref == nil {
+		return
+	}
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	if !annotatedOwners[gvk.GroupKind()] {
+		return
+	}
+
+	rule := violation.Rule
+	if rule == "" {
+		rule = violation.EventType
+	}
+	reason := audit.Truncate(r.Options.Sanitizer.Text(rule+": "+violation.Reason), maxEnforcementReasonLength)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				shieldv1alpha1.LastEnforcementAnnotation:       time.Now().UTC().Format(time.RFC3339),
+				shieldv1alpha1.LastEnforcementReasonAnnotation: reason,
+			},
+		},
+	})
+	if err != nil {
+		logger.Error(err, "Failed to build owner annotation patch")
+		return
+	}
+
+	owner := &metav1.PartialObjectMetadata{}
+	owner.SetGroupVersionKind(gvk)
+	owner.SetNamespace(pod.Namespace)
+	owner.SetName(ref.Name)
+	owner.SetUID(ref.UID)
+	err = r.Patch(ctx, owner, client.RawPatch(types.MergePatchType, patch))
+	switch {
+	case errors.IsNotFound(err):
+		return
+	case errors.IsForbidden(err):
+		logger.V(1).Info("Not permitted to annotate the pod's owner", "owner", ref.Kind+"/"+ref.Name)
+	case err != nil:
+		logger.Info("Failed to annotate the pod's owner", "owner", ref.Kind+"/"+ref.Name, "error", err.Error())
+	}
+
+	// The Event only needs the owner's reference, so it is sent even if the patch failed
+	r.Recorder.Eventf(owner, corev1.EventTypeWarning, "PodTerminated",
+		"Pod %s terminated by ShieldPolicy %s: %s", pod.Name, violation.PolicyName, reason)
+}
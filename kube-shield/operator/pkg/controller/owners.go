@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/ownership"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get
+
+// ownerResolver finds a pod's top-level owner, e.g. the Deployment of a pod of a
+// ReplicaSet
+type ownerResolver struct {
+	*ownership.Resolver
+}
+
+// newOwnerResolver creates an ownerResolver reading owners as metadata through reader
+func newOwnerResolver(reader client.Reader) *ownerResolver {
+	return &ownerResolver{Resolver: ownership.NewResolver(reader)}
+}
+
+// topLevelKind returns the kind of the pod's top-level controller, or
+// OwnerKindNone when the pod has none
+func (o *ownerResolver) topLevelKind(ctx context.Context, pod *corev1.Pod) (string, error) {
+	ref, err := o.TopLevel(ctx, pod)
+	if err != nil {
+		return "", err
+	}
+	if ref == nil {
+		return shieldv1alpha1.OwnerKindNone, nil
+	}
+	return ref.Kind, nil
+}
+
+// needsOwnerKind returns true if any policy is scoped with MatchOwnerKinds
+func needsOwnerKind(policies []shieldv1alpha1.ShieldPolicy) bool {
+	for i := range policies {
+		if len(policies[i].Spec.MatchOwnerKinds) > 0 {
+			return true
+		}
+	}
+	return false
+}
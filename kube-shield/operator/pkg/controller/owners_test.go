@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestTopLevelKind(t *testing.T) {
+	controller := true
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "backup-28391040", UID: "job-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", UID: "cronjob-uid", Controller: &controller}},
+	}}
+	owners := newOwnerResolver(fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(job).Build())
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "backup-28391040-x7k2p",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: job.Name, UID: job.UID, Controller: &controller}},
+	}}
+	kind, err := owners.topLevelKind(context.Background(), pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != "CronJob" {
+		t.Errorf("topLevelKind = %s, want CronJob", kind)
+	}
+
+	kind, err = owners.topLevelKind(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bare"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != shieldv1alpha1.OwnerKindNone {
+		t.Errorf("topLevelKind of a bare pod = %s, want %s", kind, shieldv1alpha1.OwnerKindNone)
+	}
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// podControllerName labels the Pod controller's panics
+const podControllerName = "pod"
+
+// recoverPanic is deferred by Reconcile. It turns a panic into an error, so the pod is
+// requeued with backoff and the other pods keep being reconciled.
+func (r *PodReconciler) recoverPanic(ctx context.Context, req ctrl.Request, err *error) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	logger := log.FromContext(ctx).WithValues("pod", req.NamespacedName)
+	*err = r.reportPanic(logger, value, debug.Stack())
+}
+
+// reportPanic logs a panic with its stack and counts it, and returns the error the
+// reconcile ends with. With RecoverPanics off it panics again once reported.
+func (r *PodReconciler) reportPanic(logger logr.Logger, value interface{}, stack []byte, keysAndValues ...interface{}) error {
+	metrics.ReconcilePanics.WithLabelValues(podControllerName).Inc()
+	err := fmt.Errorf("reconcile panicked: %v", value)
+	logger.Error(err, "Recovered from panic while reconciling pod", append(keysAndValues, "stack", string(stack))...)
+	if !r.Options.RecoverPanics {
+		panic(value)
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	r := &PodReconciler{Options: PodReconcilerOptions{RecoverPanics: true}}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	reconcile := func() (err error) {
+		defer r.recoverPanic(context.Background(), req, &err)
+		panic("nil map")
+	}
+	if err := reconcile(); err == nil {
+		t.Fatal("panicking reconcile returned no error")
+	}
+}
+
+func TestRecoverPanicDisabled(t *testing.T) {
+	r := &PodReconciler{}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	defer func() {
+		if value := recover(); value != "nil map" {
+			t.Errorf("recovered %v, want the original panic", value)
+		}
+	}()
+	func() (err error) {
+		defer r.recoverPanic(context.Background(), req, &err)
+		panic("nil map")
+	}()
+	t.Error("panic was swallowed with RecoverPanics off")
+}
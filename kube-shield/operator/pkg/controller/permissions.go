@@ -0,0 +1,313 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/schedule"
+)
+
+// reasonPermissionMissing is the EnforcementDegraded reason set by the
+// PermissionChecker, and the only one it clears again
+const reasonPermissionMissing = "PermissionMissing"
+
+// Permission is an API access the operator relies on
+type Permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+
+	// Namespace limits the permission to one namespace; empty means cluster-wide
+	Namespace string
+
+	// Feature is what stops working without the permission
+	Feature string
+}
+
+// String returns the permission as "verb resource[/subresource][.group][ in namespace]"
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Namespace != "" {
+		resource += " in " + p.Namespace
+	}
+	return p.Verb + " " + resource
+}
+
+var (
+	// permissionDeletePods is needed to terminate pods of Enforce policies
+	permissionDeletePods = Permission{Resource: "pods", Verb: "delete", Feature: "terminating violating pods"}
+
+	// permissionEvictPods is needed to evict pods older than MaxPodAge
+	permissionEvictPods = Permission{Resource: "pods", Subresource: "eviction", Verb: "create", Feature: "evicting pods older than maxPodAge"}
+)
+
+// basePermissions are the accesses every installation needs, a subset of the
+// operator's ClusterRole whose loss is otherwise only noticed at runtime
+var basePermissions = []Permission{
+	{Resource: "pods", Verb: "list", Feature: "watching pods"},
+	{Resource: "pods", Verb: "watch", Feature: "watching pods"},
+	permissionDeletePods,
+	{Resource: "pods", Verb: "patch", Feature: "violation annotations and quarantine labels"},
+	{Group: shieldv1alpha1.GroupName, Resource: "shieldpolicies", Subresource: "status", Verb: "update", Feature: "policy status and conditions"},
+	{Resource: "events", Verb: "create", Feature: "Warn mode events"},
+}
+
+// PermissionFeatures are the optional features whose permissions a PermissionChecker
+// reviews on top of basePermissions
+type PermissionFeatures struct {
+	// Quarantine creates NetworkPolicies isolating quarantined pods
+	Quarantine bool
+
+	// RespectPDB lists PodDisruptionBudgets before terminations
+	RespectPDB bool
+
+	// OperatorNamespace is where DefaultPolicy and SharedCounters keep their ConfigMaps
+	OperatorNamespace string
+
+	// DefaultPolicy records the opt-out of the default policy in a ConfigMap
+	DefaultPolicy bool
+
+	// SharedCounters keeps the termination counters in a ConfigMap
+	SharedCounters bool
+}
+
+// RequiredPermissions returns the permissions to review for the enabled features.
+// Evicting pods is not among them, as it depends on the policies.
+func RequiredPermissions(features PermissionFeatures) []Permission {
+	permissions := append([]Permission(nil), basePermissions...)
+	if features.Quarantine {
+		permissions = append(permissions, Permission{Group: "networking.k8s.io", Resource: "networkpolicies", Verb: "create", Feature: "emergency quarantine"})
+	}
+	if features.RespectPDB {
+		permissions = append(permissions, Permission{Group: "policy", Resource: "poddisruptionbudgets", Verb: "list", Feature: "honoring PodDisruptionBudgets before terminations"})
+	}
+
+	var stateFeatures []string
+	verbs := []string{"get", "create"}
+	if features.DefaultPolicy {
+		stateFeatures = append(stateFeatures, "remembering the default policy opt-out")
+	}
+	if features.SharedCounters {
+		stateFeatures = append(stateFeatures, "termination counters shared between replicas")
+		verbs = append(verbs, "update")
+	}
+	if len(stateFeatures) > 0 && features.OperatorNamespace != "" {
+		for _, verb := range verbs {
+			permissions = append(permissions, Permission{
+				Resource:  "configmaps",
+				Verb:      verb,
+				Namespace: features.OperatorNamespace,
+				Feature:   strings.Join(stateFeatures, " and "),
+			})
+		}
+	}
+	return permissions
+}
+
+// PermissionChecker asks the API server every Interval,
+// through SelfSubjectAccessReviews, whether the operator still holds the permissions
+// it needs. Cluster admins sometimes trim the ClusterRole, which otherwise surfaces
+// as confusing failures much later. Missing permissions are logged and counted in
+// kubeshield_rbac_missing_permissions, and policies whose enforcement they make
+// impossible get the EnforcementDegraded condition until the permission is back.
+// It runs as a Job on the leader.
+type PermissionChecker struct {
+	Client    client.Client
+	APIReader client.Reader
+	Interval  time.Duration
+
+	// Permissions are reviewed on every check, besides evicting pods while a policy
+	// sets MaxPodAge
+	Permissions []Permission
+
+	// missing is the result of the previous check, to log only changes
+	missing map[Permission]bool
+}
+
+// NewPermissionChecker creates a PermissionChecker reviewing the permissions of the
+// enabled features
+func NewPermissionChecker(c client.Client, apiReader client.Reader, interval time.Duration, features PermissionFeatures) *PermissionChecker {
+	return &PermissionChecker{
+		Client:      c,
+		APIReader:   apiReader,
+		Interval:    interval,
+		Permissions: RequiredPermissions(features),
+	}
+}
+
+// Job returns the leader-only job checking the permissions every Interval
+func (c *PermissionChecker) Job() schedule.Job {
+	return schedule.Job{
+		Name:      "rbac-check",
+		Interval:  c.Interval,
+		Immediate: true,
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if err := c.check(ctx, logger); err != nil {
+				logger.Error(err, "Failed to check the operator's RBAC permissions")
+			}
+		},
+	}
+}
+
+// check reviews every required permission and updates the metric and policies
+func (c *PermissionChecker) check(ctx context.Context, logger logr.Logger) error {
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := c.Client.List(ctx, policies); err != nil {
+		return fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+	required := c.Permissions
+	if evictsPods(policies.Items) {
+		required = append(required[:len(required):len(required)], permissionEvictPods)
+	}
+
+	missing := make(map[Permission]bool)
+	for _, permission := range required {
+		allowed, err := c.allowed(ctx, permission)
+		if err != nil {
+			return fmt.Errorf("reviewing %s: %w", permission, err)
+		}
+		if !allowed {
+			missing[permission] = true
+		}
+	}
+	metrics.RBACMissingPermissions.Set(float64(len(missing)))
+
+	if c.missing == nil || !samePermissions(c.missing, missing) {
+		logPermissions(logger, required, missing)
+	}
+	c.missing = missing
+
+	c.markPolicies(ctx, logger, policies.Items, missing)
+	return nil
+}
+
+// evictsPods returns true if an enabled policy evicts pods older than MaxPodAge
+func evictsPods(policies []shieldv1alpha1.ShieldPolicy) bool {
+	for i := range policies {
+		if !policies[i].IsDisabled() && policies[i].Spec.MaxPodAge != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed asks the API server whether the operator may use the permission cluster-wide
+func (c *PermissionChecker) allowed(ctx context.Context, permission Permission) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       permission.Group,
+				Resource:    permission.Resource,
+				Subresource: permission.Subresource,
+				Verb:        permission.Verb,
+				Namespace:   permission.Namespace,
+			},
+		},
+	}
+	if err := c.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// logPermissions logs one line per required permission and a summary, a table of
+// what is granted and what is missing
+func logPermissions(logger logr.Logger, required []Permission, missing map[Permission]bool) {
+	var names []string
+	for _, permission := range required {
+		granted := !missing[permission]
+		logger.Info("RBAC permission", "permission", permission.String(), "granted", granted, "neededFor", permission.Feature)
+		if !granted {
+			names = append(names, permission.String())
+		}
+	}
+	if len(names) == 0 {
+		logger.Info("All required RBAC permissions are granted", "checked", len(required))
+		return
+	}
+	logger.Info("The operator's ClusterRole is missing permissions, some features will fail",
+		"missing", strings.Join(names, ", "),
+		"granted", len(required)-len(names),
+	)
+}
+
+// samePermissions reports whether two sets of missing permissions are equal
+func samePermissions(a, b map[Permission]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for permission := range a {
+		if !b[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// impossibleEnforcement returns the permission a policy cannot enforce without, or
+// false if its enforcement is possible
+func impossibleEnforcement(policy *shieldv1alpha1.ShieldPolicy, missing map[Permission]bool) (Permission, bool) {
+	if policy.IsDisabled() || evaluator.ActionFor(policy) != audit.ActionTerminated {
+		return Permission{}, false
+	}
+	if missing[permissionDeletePods] {
+		return permissionDeletePods, true
+	}
+	if policy.Spec.MaxPodAge != nil && missing[permissionEvictPods] {
+		return permissionEvictPods, true
+	}
+	return Permission{}, false
+}
+
+// markPolicies sets EnforcementDegraded on the policies a missing permission keeps
+// from enforcing, and clears it once the permission is back. Degradations from
+// failed terminations are left to the Pod controller.
+func (c *PermissionChecker) markPolicies(ctx context.Context, logger logr.Logger, policies []shieldv1alpha1.ShieldPolicy, missing map[Permission]bool) {
+	for i := range policies {
+		policy := &policies[i]
+		permission, impossible := impossibleEnforcement(policy, missing)
+		current := meta.FindStatusCondition(policy.Status.Conditions, conditionEnforcementDegraded)
+		ours := current != nil && current.Status == metav1.ConditionTrue && current.Reason == reasonPermissionMissing
+		if !impossible && !ours {
+			continue
+		}
+
+		err := writePolicyStatus(ctx, c.Client, c.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			condition := metav1.Condition{
+				Type:               conditionEnforcementDegraded,
+				Status:             metav1.ConditionFalse,
+				Reason:             "PermissionRestored",
+				Message:            "The operator has the permissions this policy's enforcement needs again",
+				ObservedGeneration: policy.Generation,
+			}
+			if impossible {
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = reasonPermissionMissing
+				condition.Message = fmt.Sprintf("The operator's ClusterRole does not allow %s, needed for %s", permission, permission.Feature)
+			}
+			meta.SetStatusCondition(&policy.Status.Conditions, condition)
+		})
+		if err != nil {
+			logger.Error(err, "Failed to update EnforcementDegraded condition", "policy", policy.Name)
+		}
+	}
+}
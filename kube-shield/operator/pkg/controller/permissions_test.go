@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestRequiredPermissionsFollowFeatures(t *testing.T) {
+	has := func(permissions []Permission, name string) bool {
+		for _, permission := range permissions {
+			if permission.String() == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	base := RequiredPermissions(PermissionFeatures{})
+	for _, name := range []string{
+		"create networkpolicies.networking.k8s.io",
+		"list poddisruptionbudgets.policy",
+		"create pods/eviction",
+		"get configmaps in kube-shield",
+	} {
+		if has(base, name) {
+			t.Errorf("%s is checked with every feature disabled", name)
+		}
+	}
+	if !has(base, "delete pods") {
+		t.Error("delete pods is not checked")
+	}
+
+	all := RequiredPermissions(PermissionFeatures{
+		Quarantine:        true,
+		RespectPDB:        true,
+		OperatorNamespace: "kube-shield",
+		DefaultPolicy:     true,
+		SharedCounters:    true,
+	})
+	for _, name := range []string{
+		"create networkpolicies.networking.k8s.io",
+		"list poddisruptionbudgets.policy",
+		"get configmaps in kube-shield",
+		"create configmaps in kube-shield",
+		"update configmaps in kube-shield",
+	} {
+		if !has(all, name) {
+			t.Errorf("%s is not checked with every feature enabled", name)
+		}
+	}
+
+	defaultPolicyOnly := RequiredPermissions(PermissionFeatures{OperatorNamespace: "kube-shield", DefaultPolicy: true})
+	if has(defaultPolicyOnly, "update configmaps in kube-shield") {
+		t.Error("update configmaps is checked without shared counters")
+	}
+}
+
+func TestEvictsPods(t *testing.T) {
+	maxAge := metav1.Duration{}
+	policies := []shieldv1alpha1.ShieldPolicy{{}}
+	if evictsPods(policies) {
+		t.Error("evictsPods without maxPodAge = true")
+	}
+	policies = append(policies, shieldv1alpha1.ShieldPolicy{Spec: shieldv1alpha1.ShieldPolicySpec{MaxPodAge: &maxAge}})
+	if !evictsPods(policies) {
+		t.Error("evictsPods with maxPodAge = false")
+	}
+}
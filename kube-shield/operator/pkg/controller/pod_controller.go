@@ -1,76 +1,245 @@
 package controller
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/decision"
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/interop"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/state"
+	"github.com/kubeshield/operator/pkg/tracing"
+	"github.com/kubeshield/operator/pkg/upgrade"
+	"github.com/kubeshield/operator/pkg/version"
 )
 
+// evaluationPhaseRequeue is how often a pod waiting to reach a policy's evaluation
+// phase is re-checked in case its update was missed
+const evaluationPhaseRequeue = 30 * time.Second
+
+// deferredTerminationRetry is how long a pod whose termination was held back by a
+// transient node condition, a suspected upgrade or a drain, waits for the next try
+const deferredTerminationRetry = 2 * time.Minute
+
 // PodReconciler reconciles Pod objects based on ShieldPolicy configurations
 type PodReconciler struct {
 	client.Client
-	Scheme          *runtime.Scheme
-	AuditServiceURL string
-	HTTPClient      *http.Client
+	Scheme      *runtime.Scheme
+	APIReader   client.Reader
+	Sinks       []audit.Sink
+	Recorder    record.EventRecorder
+	Violations  state.Store
+	Evaluations *state.EvaluationCache
+	Evaluator   *evaluator.Evaluator
+	Protector   *protection.Protector
+	System      *protection.SystemNamespaces
+	Decisions   *decision.Client
+	Options     PodReconcilerOptions
+
+	// Duplicates, when set, downgrades violations Gatekeeper or Kyverno already report
+	Duplicates *interop.Detector
+
+	// Logs reads container logs for policies that capture them before termination
+	Logs corev1client.PodsGetter
+
+	// AlertClient posts violations to the AlertWebhooks of policies; nil disables them
+	AlertClient *http.Client
+
+	// Upgrades, when set, suspects cluster upgrades during which terminations are
+	// only audited
+	Upgrades *upgrade.Detector
+
+	// Allowlists, when set, resolves the ConfigMaps of AllowedImagesConfigMapRef so
+	// pods are re-evaluated when they change
+	Allowlists *ImageAllowlists
+
+	// Counters hold the terminations per owner, shared between replicas when backed
+	// by a ConfigMap
+	Counters state.Counters
+
+	failures          *failureTracker
+	retries           *failureTracker
+	deferrals         *deferredTerminations
+	annotationPatches *patchLimiter
+	owners            *ownerResolver
+	auditLimiter      *audit.RateLimiter
+	alerts            *alertDispatcher
 }
 
-// SecurityEvent represents a security event to be sent to the audit service
-type SecurityEvent struct {
-	Timestamp   string `json:"timestamp"`
-	EventType   string `json:"eventType"`
-	Severity    string `json:"severity"`
-	PodName     string `json:"podName"`
-	Namespace   string `json:"namespace"`
-	Container   string `json:"container,omitempty"`
-	Image       string `json:"image,omitempty"`
-	Reason      string `json:"reason"`
-	Action      string `json:"action"`
-	PolicyName  string `json:"policyName"`
-	NodeName    string `json:"nodeName,omitempty"`
-	Description string `json:"description"`
+// PodReconcilerOptions holds the tunables of a PodReconciler
+type PodReconcilerOptions struct {
+	// EnforcementFailureThreshold is the number of consecutive failed terminations
+	// of a pod after which its policy is marked EnforcementDegraded
+	EnforcementFailureThreshold int
+
+	// EvaluationBudget bounds the time one reconcile spends evaluating policies.
+	// Policies left over when it runs out are evaluated on a requeue.
+	EvaluationBudget time.Duration
+
+	// PolicyEvaluationTimeout bounds the time a single policy may take to evaluate a pod
+	PolicyEvaluationTimeout time.Duration
+
+	// SkipDrainingNodes only audits violations of pods on cordoned nodes or nodes the
+	// cluster autoscaler is removing, instead of terminating them
+	SkipDrainingNodes bool
+
+	// SampleRates thins out security events of low severities before delivery
+	SampleRates audit.SampleRates
+
+	// Sanitizer masks credentials in and caps the description of every event and
+	// alert before delivery
+	Sanitizer audit.Sanitizer
+
+	// AuditRateLimit is the audit events per minute each policy may report per
+	// namespace, beyond AuditRateBurst; terminations are never limited (0 = unlimited)
+	AuditRateLimit float64
+
+	// AuditRateBurst is the number of audit events a policy may report at once per namespace
+	AuditRateBurst int
+
+	// MaxTerminationsPerOwner caps the pods of one controller terminated within
+	// TerminationThrottleWindow; further violations are only audited (0 = no cap)
+	MaxTerminationsPerOwner int
+
+	// TerminationThrottleWindow is the window MaxTerminationsPerOwner applies to
+	TerminationThrottleWindow time.Duration
+
+	// DeletionPropagation is used for policies without a deletionPropagation of their
+	// own; empty leaves it to the API server
+	DeletionPropagation string
+
+	// RecoverPanics turns a panicking reconcile into an error that requeues the pod,
+	// instead of letting it crash the operator
+	RecoverPanics bool
+
+	// CoalesceThreshold is the number of containers of a pod violating the same rule
+	// of a policy above which they are reported as one event (0 = never)
+	CoalesceThreshold int
+
+	// RespectPDB defers terminations a PodDisruptionBudget does not allow and
+	// reports PDB_BLOCKED instead
+	RespectPDB bool
+
+	// Quarantine isolates pods carrying QuarantineNowAnnotation
+	Quarantine bool
+
+	// AnnotateOwners records the reason of a termination on the pod's top-level
+	// owner, as annotations and an Event
+	AnnotateOwners bool
+
+	// EnforcementRecords keeps a ShieldEnforcementRecord of every termination and
+	// quarantine in the pod's namespace
+	EnforcementRecords bool
+
+	// NodeReevaluationLimit caps the pods re-queued when the labels of their node
+	// change (0 = unlimited)
+	NodeReevaluationLimit int
 }
 
 // NewPodReconciler creates a new PodReconciler with dependency injection
 func NewPodReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
-	auditServiceURL string,
+	apiReader client.Reader,
+	sinks []audit.Sink,
+	recorder record.EventRecorder,
+	violations state.Store,
+	podEvaluator *evaluator.Evaluator,
+	protector *protection.Protector,
+	system *protection.SystemNamespaces,
+	decisions *decision.Client,
+	opts PodReconcilerOptions,
 ) *PodReconciler {
 	return &PodReconciler{
-		Client:          client,
-		Scheme:          scheme,
-		AuditServiceURL: auditServiceURL,
-		HTTPClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		Client:            client,
+		Scheme:            scheme,
+		APIReader:         apiReader,
+		Sinks:             sinks,
+		Recorder:          recorder,
+		Violations:        violations,
+		Evaluations:       state.NewEvaluationCache(),
+		Evaluator:         podEvaluator,
+		Protector:         protector,
+		System:            system,
+		Decisions:         decisions,
+		Options:           opts,
+		failures:          newFailureTracker(),
+		retries:           newFailureTracker(),
+		deferrals:         newDeferredTerminations(),
+		annotationPatches: newPatchLimiter(),
+		owners:            newOwnerResolver(apiReader),
+		auditLimiter:      newAuditLimiter(opts),
+	}
+}
+
+// newAuditLimiter creates the audit rate limiter, nil when AuditRateLimit is unset
+func newAuditLimiter(opts PodReconcilerOptions) *audit.RateLimiter {
+	if opts.AuditRateLimit <= 0 {
+		return nil
 	}
+	return audit.NewRateLimiter(opts.AuditRateLimit, opts.AuditRateBurst)
 }
 
-// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldexemptions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldpolicies,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldpolicies/status,verbs=get;update;patch
 
 // Reconcile implements the reconciliation loop for Pods
-func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer r.recoverPanic(ctx, req, &err)
+	return r.reconcile(ctx, req)
+}
+
+// reconcile evaluates a pod against the policies and enforces the outcome
+func (r *PodReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("pod", req.NamespacedName)
 
-	// Skip kube-system namespace
-	if req.Namespace == "kube-system" {
+	ctx, span := tracing.Tracer().Start(ctx, "PodReconciler.Reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.pod.name", req.Name),
+	))
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.ReconcileDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// Skip system namespaces unless they are configured to be audited
+	if r.System.Skip(req.Namespace) {
 		return ctrl.Result{}, nil
 	}
 
@@ -78,215 +247,671 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	pod := &corev1.Pod{}
 	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
 		if errors.IsNotFound(err) {
-			// Pod was deleted, nothing to do
+			// Pod was deleted, drop its violations from the current state
+			r.Violations.Forget(req.NamespacedName)
+			r.Evaluations.Forget(req.NamespacedName)
+			r.failures.reset(req.NamespacedName)
+			r.retries.reset(req.NamespacedName)
+			r.deferrals.forget(req.NamespacedName)
+			r.annotationPatches.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
-		logger.Error(err, "Failed to fetch Pod")
-		return ctrl.Result{}, err
+		return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to fetch Pod"), nil
 	}
+	logger = logger.WithValues("podUID", pod.UID)
 
 	// Skip pods that are already terminating
 	if pod.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
 
-	// Skip pods in terminal phases
+	// Isolate pods responders flagged, before and regardless of any policy
+	if r.Options.Quarantine && quarantineRequested(pod) {
+		if err := r.handleQuarantineRequest(ctx, logger, pod); err != nil {
+			return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to quarantine pod"), nil
+		}
+	}
+
+	// Skip pods in terminal phases, they no longer count as violating
 	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		r.Violations.Forget(req.NamespacedName)
+		r.Evaluations.Forget(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
+	// Honor time-boxed exemptions, re-checking the pod once the exemption lapses
+	if until, ok := exemptUntil(logger, pod); ok {
+		logger.V(1).Info("Pod is exempt from policy enforcement", "exemptUntil", until.Format(time.RFC3339))
+		r.Violations.Forget(req.NamespacedName)
+		return ctrl.Result{RequeueAfter: time.Until(until)}, nil
+	}
+
 	// Fetch all ShieldPolicies
 	policies := &shieldv1alpha1.ShieldPolicyList{}
 	if err := r.List(ctx, policies); err != nil {
-		logger.Error(err, "Failed to list ShieldPolicies")
-		return ctrl.Result{}, err
+		return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to list ShieldPolicies"), nil
+	}
+
+	// Approved exceptions are decided from their expiry, not their status phase
+	now := time.Now()
+	exemptions, err := r.activeExemptions(ctx, pod.Namespace, now)
+	if err != nil {
+		return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to list ShieldExemptions"), nil
+	}
+
+	// Resolve the namespace's labels from the cache for namespace-scoped policies
+	var nsLabels map[string]string
+	if needsNamespaceLabels(policies.Items) {
+		nsLabels, err = namespaceLabels(ctx, r.Client, pod.Namespace)
+		if err != nil {
+			return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to resolve namespace labels"), nil
+		}
+	}
+
+	// Resolve the pod's node once for node-scoped policies, drain detection and
+	// pods that leave their OS to the node
+	var labels map[string]string
+	var scheduled, draining bool
+	if needsNodeLabels(policies.Items) || r.Options.SkipDrainingNodes || evaluator.PodOS(pod) == "" && pod.Spec.NodeName != "" {
+		node, err := scheduledNode(ctx, r.Client, pod)
+		if err != nil {
+			return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to resolve pod node"), nil
+		}
+		if node != nil {
+			labels, scheduled = node.Labels, true
+			draining = r.Options.SkipDrainingNodes && nodeDraining(node)
+		} else if pod.Spec.NodeName != "" {
+			// The node is already gone, its pods are being cleaned up
+			draining = r.Options.SkipDrainingNodes
+		}
+	}
+
+	// Skip pods that have not changed since they were last evaluated against the same policies
+	policyVersion := policySetVersion(policies.Items) + "|" + exemptionSetVersion(exemptions) + "|" + namespaceLabelsVersion(nsLabels) +
+		"|" + lifetimeVersion(pod, policies.Items, now) + "|" + r.Allowlists.Version(ctx, policies.Items) + "|" + nodeLabelsVersion(policies.Items, labels)
+	fresh := r.Evaluations.Fresh(req.NamespacedName, pod.UID, pod.ResourceVersion, policyVersion)
+
+	// Unchanged pods are still re-checked periodically when a policy asks for it
+	reevaluation := reevaluationInterval(pod, policies.Items, nsLabels)
+	var periodic bool
+	if fresh && reevaluation > 0 {
+		evaluatedAt := r.Evaluations.EvaluatedAt(req.NamespacedName, pod.UID)
+		if now.Sub(evaluatedAt) < reevaluation {
+			metrics.EvaluationCacheLookups.WithLabelValues("hit").Inc()
+			return ctrl.Result{RequeueAfter: nextReevaluation(reevaluation, evaluatedAt, now)}, nil
+		}
+		fresh, periodic = false, true
+		metrics.PeriodicReevaluations.Inc()
+		logger.V(1).Info("Re-evaluating unchanged pod", "interval", reevaluation.String())
+	}
+	if fresh {
+		metrics.EvaluationCacheLookups.WithLabelValues("hit").Inc()
+		logger.V(1).Info("Pod unchanged since last evaluation, skipping checks")
+		return ctrl.Result{}, nil
+	}
+	metrics.EvaluationCacheLookups.WithLabelValues("miss").Inc()
+	metrics.PodsEvaluated.Inc()
+
+	// Ephemeral containers attached since the last evaluation, e.g. with kubectl debug
+	containers := evaluator.ContainerNames(pod)
+	attached := evaluator.AttachedContainers(pod, r.Evaluations.Containers(req.NamespacedName, pod.UID))
+	if len(attached) > 0 {
+		logger.Info("Containers attached to pod after admission", "containers", attached)
+	}
+
+	// Resolve the pod's top-level owner for policies scoped to workload kinds
+	var ownerKind string
+	if needsOwnerKind(policies.Items) {
+		if ownerKind, err = r.owners.topLevelKind(ctx, pod); err != nil {
+			return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to resolve pod owner"), nil
+		}
+	}
+
+	// Apply the Windows checks to Windows pods that do not declare their OS
+	evalPod := evaluator.WithNodeOS(pod, labels)
+
+	// Bound the time spent evaluating so one slow policy cannot stall the queue
+	budgetCtx := ctx
+	if r.Options.EvaluationBudget > 0 {
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithTimeout(ctx, r.Options.EvaluationBudget)
+		defer cancel()
 	}
 
 	// Check pod against all applicable policies
-	for _, policy := range policies.Items {
-		if !policy.ShouldApplyToNamespace(pod.Namespace) {
+	var evaluations []policyEvaluation
+	var current []state.Violation
+	var nextExpiry time.Time
+	var deferred []string
+	var waiting bool
+	var duplicates map[string]string
+	accounts := newServiceAccountCache(r.APIReader, logger)
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if !engine.AppliesToNamespace(policy, pod.Namespace, nsLabels) {
+			continue
+		}
+
+		if !appliesToNode(policy, labels, scheduled) {
+			continue
+		}
+
+		if !policy.ShouldApplyToOwnerKind(ownerKind) {
+			continue
+		}
+
+		// Wait until the pod reaches the phase the policy evaluates it in
+		if !policy.ShouldEvaluatePod(pod) {
+			waiting = true
 			continue
 		}
 
-		if policy.IsDisabled() {
+		// Leave the remaining policies for the requeue once the budget is spent
+		if budgetCtx.Err() != nil {
+			deferred = append(deferred, policy.Name)
 			continue
 		}
 
 		// Check for violations
-		violations := r.checkPodViolations(ctx, logger, pod, &policy)
+		evalCtx, evalSpan := tracing.Tracer().Start(budgetCtx, "EvaluatePolicy", trace.WithAttributes(
+			attribute.String("kubeshield.policy", policy.Name),
+		))
+		violations, err := r.Evaluator.EvaluateWithin(evalCtx, evalPod, policy, accounts, r.Options.PolicyEvaluationTimeout)
+		if err != nil {
+			evalSpan.RecordError(err)
+			evalSpan.SetStatus(codes.Error, err.Error())
+			evalSpan.End()
+			if panicErr, ok := err.(*evaluator.PanicError); ok {
+				return ctrl.Result{}, r.reportPanic(logger, panicErr.Value, panicErr.Stack, "policy", policy.Name)
+			}
+			deferred = append(deferred, policy.Name)
+			r.reportSlowEvaluation(ctx, logger, pod, policy, budgetCtx.Err() != nil)
+			continue
+		}
+		r.clearEvaluationSlow(ctx, logger, policy)
+		// Attached containers are reported once per pod, by the first policy evaluated
+		violations = append(violations, evaluator.MutationViolations(pod, policy, attached)...)
+		attached = nil
+		violations, overridden := evaluator.ApplyRegistryOverrides(pod, policy, violations)
+		violations, exempted, expiry := applyExemptions(pod, violations, exemptions)
+		exempted = append(overridden, exempted...)
+		if len(violations) > 0 && r.Duplicates != nil {
+			if duplicates == nil {
+				if duplicates, err = r.Duplicates.Duplicates(ctx, pod); err != nil {
+					logger.Error(err, "Failed to read PolicyReports of other engines, reporting violations at full severity")
+					duplicates = map[string]string{}
+				}
+			}
+			interop.Downgrade(violations, duplicates)
+		}
+		violations = evaluator.Coalesce(violations, r.Options.CoalesceThreshold)
+		evalSpan.SetAttributes(attribute.Int("kubeshield.violations", len(violations)))
+		evalSpan.End()
+		if !expiry.IsZero() && (nextExpiry.IsZero() || expiry.Before(nextExpiry)) {
+			nextExpiry = expiry
+		}
+		nextExpiry = nextLifetimeExpiry(pod, policy, now, nextExpiry)
+		if len(violations) == 0 && len(exempted) == 0 {
+			continue
+		}
 
+		evaluations = append(evaluations, policyEvaluation{policy: policy, violations: violations, exempted: exempted})
 		for _, violation := range violations {
-			// Send event to audit service
-			r.sendSecurityEvent(ctx, logger, violation)
+			current = append(current, state.Violation{
+				Key:      state.Key{Policy: policy.Name, EventType: violation.EventType, Rule: violation.Rule},
+				Severity: violation.Severity,
+			})
+		}
+	}
 
-			// If enforcing, terminate the pod
-			if policy.IsEnforcing() {
-				logger.Info("Terminating pod due to policy violation",
-					"pod", pod.Name,
-					"namespace", pod.Namespace,
-					"policy", policy.Name,
+	// Pods stuck in a back-off loop are audited once, periodic re-checks only report
+	// what changed and pod owners are warned once per violation, note what was
+	// already reported
+	backOff := backOffReason(pod)
+	reported := make(map[state.Key]bool)
+	for _, violation := range current {
+		key := violation.Key
+		key.PodUID = pod.UID
+		if _, ok := r.Violations.Get(key); ok {
+			reported[key] = true
+		}
+	}
+
+	// Record what the pod currently violates before acting on it. Policies left for
+	// the requeue keep what they reported last, so their FirstSeen survives.
+	if len(deferred) > 0 {
+		current = append(current, deferredViolations(r.Violations.Pod(req.NamespacedName, pod.UID), deferred)...)
+	}
+	r.Violations.Record(req.NamespacedName, pod.UID, current)
+
+	// Explain the outcome on the pod when asked to
+	if pod.Annotations[shieldv1alpha1.EvaluateAnnotation] == shieldv1alpha1.EvaluateNow {
+		r.writeEvaluationResult(ctx, logger, pod, explainEvaluation(pod, policies.Items, evaluations, nsLabels, labels, scheduled, ownerKind, time.Now()))
+	}
+
+	// Protected pods are never terminated, whatever the policy says
+	protected, protectedBy := r.Protector.IsProtected(pod)
+
+	// Pods in system namespaces are at most audited
+	auditOnly := r.System.AuditOnly(pod.Namespace)
+
+	// Pods whose eviction or termination a PodDisruptionBudget held back are tried
+	// again later
+	var evictionBlocked bool
+
+	// Pods on a node that seems to be upgrading are at most audited
+	upgrading := r.Upgrades != nil && r.Upgrades.Suspected(pod.Spec.NodeName)
+
+	// Pods whose termination a transient node condition held back are tried again
+	// once it may have passed
+	var terminationDeferred bool
+
+	// Deleting a static pod's mirror or a DaemonSet pod only brings it back
+	mirror, daemonSet := isMirrorPod(pod), isDaemonSetPod(pod)
+
+	// Keep the logs for the investigation, they go away with the pod. They are read
+	// once, before any termination, and attached to every termination asking for them.
+	capturedLogs := r.captureLogsOnce(ctx, logger, pod, evaluations)
+
+	for _, evaluation := range evaluations {
+		policy := evaluation.policy
+
+		// Report every exemption that was used
+		for _, event := range evaluation.exempted {
+			r.sendSecurityEvent(ctx, logger, event)
+		}
+
+		for _, violation := range evaluation.violations {
+			// Enforcement log lines carry the fields SIEM queries join events on
+			logger := logger.WithValues(violation.LogFields()...)
+
+			if protected && violation.Action == audit.ActionTerminated {
+				logger.Info("Not terminating protected pod",
 					"reason", violation.Reason,
+					"protectedBy", protectedBy,
 				)
+				violation.Action = audit.ActionAudit
+				violation.Markers = append(violation.Markers, protection.Marker)
+			}
+			if auditOnly && (violation.Action == audit.ActionTerminated || violation.Action == audit.ActionWarn) {
+				violation.Action = audit.ActionAudit
+				violation.Markers = append(violation.Markers, protection.SystemNamespaceMarker)
+			}
+			if mirror {
+				metrics.Inc(ctx, metrics.StaticPodViolations.WithLabelValues(policy.Name, violation.EventType))
+				if violation.Action == audit.ActionTerminated {
+					violation.Action = audit.ActionAuditStaticPod
+				}
+			}
+			if daemonSet && !policy.Spec.EnforceDaemonSetPods && violation.Action == audit.ActionTerminated {
+				violation.Action = audit.ActionAudit
+				violation.Markers = append(violation.Markers, DaemonSetMarker)
+			}
+
+			// Leave system agents briefly running unexpected images during a rolling
+			// upgrade alone instead of slowing it down
+			if upgrading {
+				violation.ClusterUpgradeSuspected = true
+				if violation.Action == audit.ActionTerminated {
+					violation.Action = audit.ActionAudit
+					metrics.Inc(ctx, metrics.UpgradeSkips.WithLabelValues(policy.Name))
+					terminationDeferred = true
+				}
+			}
+
+			// Leave pods on draining nodes to the drain instead of racing it
+			if draining {
+				violation.NodeDraining = true
+				if violation.Action == audit.ActionTerminated {
+					violation.Action = audit.ActionAudit
+					metrics.Inc(ctx, metrics.DrainingNodeSkips.WithLabelValues(policy.Name))
+					terminationDeferred = true
+				}
+			}
+
+			// Deleting a pod stuck in a back-off loop only restarts the loop
+			if backOff != "" && policy.Spec.DeferBackOffPods {
+				if reported[state.Key{PodUID: pod.UID, Policy: policy.Name, EventType: violation.EventType, Rule: violation.Rule}] {
+					continue
+				}
+				if violation.Action == audit.ActionTerminated {
+					logger.Info("Not terminating pod stuck in back-off", "reason", backOff)
+					violation.Action = audit.ActionAudit
+					violation.Markers = append(violation.Markers, BackOffMarker)
+					metrics.Inc(ctx, metrics.BackOffSkips.WithLabelValues(policy.Name))
+				}
+			}
+
+			// Let the external decision point have the final say on terminations
+			if violation.Action == audit.ActionTerminated && r.Decisions != nil {
+				violation = r.decide(ctx, logger, pod, violation)
+			}
+
+			// Stop terminating the replacements of an owner in a loop. The termination
+			// is counted up front and given back below if it does not happen.
+			var reserved bool
+			if violation.Action == audit.ActionTerminated {
+				var allowed bool
+				if allowed, reserved = r.reserveTermination(ctx, logger, pod); !allowed {
+					logger.Info("Not terminating pod, its owner reached the termination limit", "limit", r.Options.MaxTerminationsPerOwner)
+					violation = throttle(violation)
+				}
+			}
+
+			if violation.Action == audit.ActionTerminated && policy.Spec.CaptureLogsBeforeTermination != nil {
+				violation.CapturedLogs = capturedLogs
+			}
+
+			// Old pods are evicted rather than deleted so their PodDisruptionBudget has
+			// a say; a refused eviction is reported as an audit instead
+			evicting := violation.Action == audit.ActionTerminated && violation.EventType == evaluator.PodTooOld
+			var evictErr error
+			if evicting {
+				if evictErr = r.evictPod(ctx, pod); errors.IsTooManyRequests(evictErr) {
+					logger.Info("Eviction of old pod blocked by a PodDisruptionBudget", "retryAfter", evictionRetry)
+					violation.Action = audit.ActionAudit
+					violation.Markers = append(violation.Markers, EvictionBlockedMarker)
+					evictionBlocked = true
+				}
+			}
+
+			// With RespectPDB, other terminations honor PodDisruptionBudgets too.
+			// Budgets that cannot be read hold the termination back as well, as they
+			// might forbid it.
+			var stillDeferred bool
+			if violation.Action == audit.ActionTerminated && !evicting && r.Options.RespectPDB {
+				budget, err := r.blockingDisruptionBudget(ctx, pod)
+				switch {
+				case err != nil:
+					violation, stillDeferred = r.deferForUnknownBudgets(logger, pod, violation, err)
+					evictionBlocked = true
+				case budget != "":
+					if violation, stillDeferred = r.deferForBudget(ctx, logger, pod, violation, budget); !stillDeferred {
+						metrics.Inc(ctx, metrics.PDBBlockedTerminations.WithLabelValues(policy.Name))
+					}
+					evictionBlocked = true
+				}
+			}
+
+			if reserved && violation.Action != audit.ActionTerminated {
+				r.releaseTermination(ctx, logger, pod)
+			}
 
-				// Delete the pod
-				if err := r.Delete(ctx, pod, client.GracePeriodSeconds(0)); err != nil {
-					if !errors.IsNotFound(err) {
+			// Neither a periodic re-check nor the retry of a termination deferred
+			// for the same reason as before reports unchanged violations again
+			if stillDeferred || periodic && violation.Action != audit.ActionTerminated &&
+				reported[state.Key{PodUID: pod.UID, Policy: policy.Name, EventType: violation.EventType, Rule: violation.Rule}] {
+				continue
+			}
+
+			// Send event to audit service and the teams the policy alerts
+			metrics.Inc(ctx, metrics.Violations.WithLabelValues(policy.Name, violation.EventType, violation.Action))
+			r.sendSecurityEvent(ctx, logger, violation)
+			r.sendAlerts(logger, policy, violation)
+
+			// If the violation is enforced, terminate the pod
+			if violation.Action == audit.ActionTerminated {
+				logger.Info("Terminating pod due to policy violation", "reason", violation.Reason)
+				r.annotateOwner(ctx, logger, pod, violation)
+
+				// Delete the pod, backing off and reporting when that keeps failing
+				err := evictErr
+				if !evicting {
+					err = r.deletePod(ctx, pod, policy)
+				}
+				if err != nil {
+					switch classifyError(err) {
+					case errorDone:
+						// Someone else removed it first
+					case errorRetry:
+						if reserved {
+							r.releaseTermination(ctx, logger, pod)
+						}
+						return ctrl.Result{Requeue: true}, nil
+					default:
+						if reserved {
+							r.releaseTermination(ctx, logger, pod)
+						}
 						logger.Error(err, "Failed to delete violating pod")
-						return ctrl.Result{}, err
+						return r.handleEnforcementFailure(ctx, logger, pod, policy, violation, err), nil
 					}
 				}
+				r.failures.reset(req.NamespacedName)
+				r.deferrals.forget(req.NamespacedName)
+				r.recordEnforcement(ctx, logger, pod, violation)
 
 				// Update policy status
-				r.updatePolicyStatus(ctx, logger, &policy, true)
+				r.updatePolicyStatus(ctx, logger, policy, true)
 
 				return ctrl.Result{}, nil
 			}
 
+			// In warn mode, let the pod's owners see the violation. Updates to the pod
+			// re-evaluate it, the Event is only recorded when the violation is new.
+			if violation.Action == audit.ActionWarn && !reported[state.Key{PodUID: pod.UID, Policy: policy.Name, EventType: violation.EventType, Rule: violation.Rule}] {
+				r.Recorder.Eventf(pod, corev1.EventTypeWarning, "PolicyViolation",
+					"%s violates ShieldPolicy %s: %s", pod.Name, policy.Name, r.Options.Sanitizer.Text(violation.Reason))
+			}
+
 			// If auditing, just log and update status
-			r.updatePolicyStatus(ctx, logger, &policy, false)
+			r.updatePolicyStatus(ctx, logger, policy, false)
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// Let developers see audit-mode violations on the pod itself. A deferred or failed
+	// patch leaves the evaluation uncached so the retry is not skipped.
+	wait, err := r.syncViolationAnnotations(ctx, logger, pod, annotatedViolations(evaluations))
+	if err != nil {
+		return r.requeueOnError(ctx, logger, req.NamespacedName, err, "Failed to annotate pod with violations"), nil
+	}
+	if wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// Try blocked evictions and terminations again without caching the evaluation, which would skip them
+	if evictionBlocked {
+		return ctrl.Result{RequeueAfter: evictionRetry}, nil
+	}
+	if terminationDeferred {
+		return ctrl.Result{RequeueAfter: deferredTerminationRetry}, nil
+	}
+
+	// Finish the policies that ran out of time on another pass. Enforcement decided
+	// above has already run, and the partial result is not cached.
+	if len(deferred) > 0 {
+		logger.Info("Evaluation incomplete, requeueing remaining policies", "policies", deferred)
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	r.retries.reset(req.NamespacedName)
+	r.Evaluations.Remember(req.NamespacedName, pod.UID, pod.ResourceVersion, policyVersion, containers)
+
+	// Re-check the pod as soon as an exemption it relies on expires or it outlives
+	// a policy's MaxPodLifetime or MaxPodAge
+	var requeueAfter time.Duration
+	if !nextExpiry.IsZero() {
+		requeueAfter = time.Until(nextExpiry) + time.Second
+	}
+
+	// Phase changes update the pod and re-trigger it, poll as a fallback only
+	if waiting && (requeueAfter == 0 || requeueAfter > evaluationPhaseRequeue) {
+		requeueAfter = evaluationPhaseRequeue
+	}
+
+	// Come back for the next periodic re-check
+	if reevaluation > 0 {
+		if next := nextReevaluation(reevaluation, now, now); requeueAfter == 0 || next < requeueAfter {
+			requeueAfter = next
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-// checkPodViolations checks a pod against a policy and returns any violations
-func (r *PodReconciler) checkPodViolations(
+// decide asks the external decision point whether a violating pod may be terminated
+// and returns the violation with its action adjusted to the decision
+func (r *PodReconciler) decide(
 	ctx context.Context,
 	logger logr.Logger,
 	pod *corev1.Pod,
-	policy *shieldv1alpha1.ShieldPolicy,
-) []SecurityEvent {
-	var violations []SecurityEvent
-	now := time.Now().UTC().Format(time.RFC3339)
-
-	// Pod-level checks (host network)
-	if pod.Spec.HostNetwork {
-		violations = append(violations, SecurityEvent{
-			Timestamp:   now,
-			EventType:   "HOST_NETWORK",
-			Severity:    "HIGH",
-			PodName:     pod.Name,
-			Namespace:   pod.Namespace,
-			Reason:      "Pod using host network",
-			Action:      "AUDIT",
-			PolicyName:  policy.Name,
-			NodeName:    pod.Spec.NodeName,
-			Description: fmt.Sprintf("Pod '%s' is using host network which can bypass network policies", pod.Name),
-		})
-	}
-
-	// Check all containers (including init containers)
-	allContainers := append(pod.Spec.Containers, pod.Spec.InitContainers...)
-
-	for _, container := range allContainers {
-		// Check for privileged containers
-		if policy.ShouldBlockPrivileged() {
-			if container.SecurityContext != nil &&
-				container.SecurityContext.Privileged != nil &&
-				*container.SecurityContext.Privileged {
-
-				violations = append(violations, SecurityEvent{
-					Timestamp:   now,
-					EventType:   "PRIVILEGED_CONTAINER",
-					Severity:    "CRITICAL",
-					PodName:     pod.Name,
-					Namespace:   pod.Namespace,
-					Container:   container.Name,
-					Image:       container.Image,
-					Reason:      "Privileged container detected",
-					Action:      r.getActionString(policy),
-					PolicyName:  policy.Name,
-					NodeName:    pod.Spec.NodeName,
-					Description: fmt.Sprintf("Container '%s' is running in privileged mode which violates policy '%s'", container.Name, policy.Name),
-				})
-			}
-		}
+	violation audit.SecurityEvent,
+) audit.SecurityEvent {
+	ctx, span := tracing.Tracer().Start(ctx, "DecisionHook.Decide")
+	defer span.End()
+
+	// The decision point is outside the operator, so it gets what the audit sinks get
+	event := violation
+	r.Options.Sanitizer.Apply(&event)
+	resp, err := r.Decisions.Decide(ctx, decision.Request{
+		Event: event,
+		Pod:   decision.NewPodContext(pod, r.Options.Sanitizer),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("kubeshield.decision", resp.Decision))
 
-		// Check for disallowed registries
-		if len(policy.Spec.AllowedRegistries) > 0 {
-			registry := extractRegistry(container.Image)
-			if !policy.IsRegistryAllowed(registry) {
-				violations = append(violations, SecurityEvent{
-					Timestamp:   now,
-					EventType:   "DISALLOWED_REGISTRY",
-					Severity:    "HIGH",
-					PodName:     pod.Name,
-					Namespace:   pod.Namespace,
-					Container:   container.Name,
-					Image:       container.Image,
-					Reason:      fmt.Sprintf("Image from disallowed registry: %s", registry),
-					Action:      r.getActionString(policy),
-					PolicyName:  policy.Name,
-					NodeName:    pod.Spec.NodeName,
-					Description: fmt.Sprintf("Container '%s' uses image from registry '%s' which is not in the allowed list", container.Name, registry),
-				})
-			}
+	switch resp.Decision {
+	case decision.Deny:
+		if err != nil {
+			logger.Error(err, "External decision point failed, failing closed", "decision", resp.Decision)
 		}
+		return violation
+	case decision.Allow:
+		violation.Action = audit.ActionAllowed
+	default:
+		violation.Action = audit.ActionAudit
+	}
 
-		// Check for root user
-		if container.SecurityContext != nil {
-			if container.SecurityContext.RunAsUser != nil && *container.SecurityContext.RunAsUser == 0 {
-				violations = append(violations, SecurityEvent{
-					Timestamp:   now,
-					EventType:   "ROOT_USER",
-					Severity:    "HIGH",
-					PodName:     pod.Name,
-					Namespace:   pod.Namespace,
-					Container:   container.Name,
-					Image:       container.Image,
-					Reason:      "Container running as root user",
-					Action:      "AUDIT",
-					PolicyName:  policy.Name,
-					NodeName:    pod.Spec.NodeName,
-					Description: fmt.Sprintf("Container '%s' is configured to run as root (UID 0)", container.Name),
-				})
-			}
-		}
+	// Only an answer of the decision point overrides the termination, a fallback
+	// applies because it gave none
+	if err != nil {
+		logger.Error(err, "External decision point failed, failing open and not terminating", "decision", resp.Decision)
+	} else {
+		logger.Info("External decision point overrode termination",
+			"decision", resp.Decision,
+			"reason", resp.Reason,
+		)
 	}
+	violation.Markers = append(violation.Markers, decision.Marker)
+	return violation
+}
 
-	return violations
+// policyEvaluation holds the violations a single policy found on a pod
+type policyEvaluation struct {
+	policy     *shieldv1alpha1.ShieldPolicy
+	violations []audit.SecurityEvent
+	exempted   []audit.SecurityEvent
 }
 
-// getActionString returns the action string based on policy mode
-func (r *PodReconciler) getActionString(policy *shieldv1alpha1.ShieldPolicy) string {
-	if policy.IsEnforcing() {
-		return "TERMINATED"
+// policySetVersion identifies the current set of policy specs. It changes whenever a
+// policy is created, deleted or has its spec updated, invalidating cached evaluations.
+func policySetVersion(policies []shieldv1alpha1.ShieldPolicy) string {
+	parts := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		parts = append(parts, fmt.Sprintf("%s/%d/%s/%t", policy.UID, policy.Generation, policy.EffectiveEnforcementMode(), policy.IsSimulated()))
 	}
-	return "AUDIT"
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
 }
 
-// sendSecurityEvent sends a security event to the audit service
-func (r *PodReconciler) sendSecurityEvent(ctx context.Context, logger logr.Logger, event SecurityEvent) {
-	if r.AuditServiceURL == "" {
-		logger.V(1).Info("Audit service URL not configured, skipping event notification")
-		return
+// deferredViolations returns the stored violations raised by the deferred policies
+func deferredViolations(stored []state.Violation, deferred []string) []state.Violation {
+	var kept []state.Violation
+	for _, violation := range stored {
+		if slices.Contains(deferred, violation.Policy) {
+			kept = append(kept, violation)
+		}
 	}
+	return kept
+}
 
-	payload, err := json.Marshal(event)
+// exemptUntil returns the expiry of the pod's exemption annotation if it is still in effect.
+// Expired or malformed exemptions are ignored and logged.
+func exemptUntil(logger logr.Logger, pod *corev1.Pod) (time.Time, bool) {
+	value, ok := pod.Annotations[shieldv1alpha1.ExemptUntilAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	until, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		logger.Error(err, "Failed to marshal security event")
+		logger.Info("Ignoring malformed exemption annotation",
+			"annotation", shieldv1alpha1.ExemptUntilAnnotation,
+			"value", value,
+			"error", err.Error(),
+		)
+		return time.Time{}, false
+	}
+
+	if !until.After(time.Now()) {
+		logger.Info("Ignoring expired exemption annotation",
+			"annotation", shieldv1alpha1.ExemptUntilAnnotation,
+			"expiredAt", until.Format(time.RFC3339),
+		)
+		return time.Time{}, false
+	}
+
+	return until, true
+}
+
+// sendSecurityEvent delivers a security event to every configured audit sink
+func (r *PodReconciler) sendSecurityEvent(ctx context.Context, logger logr.Logger, event audit.SecurityEvent) {
+	if len(r.Sinks) == 0 {
+		logger.V(1).Info("No audit sinks configured, skipping event notification")
 		return
 	}
 
-	url := fmt.Sprintf("%s/log", r.AuditServiceURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
-	if err != nil {
-		logger.Error(err, "Failed to create HTTP request")
+	// Enforcement still happens, only the report is dropped
+	if !r.Options.SampleRates.Keep(event) {
+		metrics.SampledOutEvents.WithLabelValues(event.Severity).Inc()
 		return
 	}
+	if r.auditLimiter != nil && !r.auditLimiter.Allow(event, time.Now()) {
+		return
+	}
+	r.deliverSecurityEvent(ctx, logger, event)
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// deliverSecurityEvent hands an event to every sink, bypassing sampling and rate limits
+func (r *PodReconciler) deliverSecurityEvent(ctx context.Context, logger logr.Logger, event audit.SecurityEvent) {
+	r.Options.Sanitizer.Apply(&event)
+	event.OperatorVersion = version.Version
+	engine.Classify(&event)
+	for _, sink := range r.Sinks {
+		sinkCtx, span := tracing.Tracer().Start(ctx, "AuditSink.Deliver", trace.WithAttributes(
+			attribute.String("kubeshield.event_type", event.EventType),
+			attribute.String("kubeshield.sink", sink.Name()),
+		))
+		if err := sink.Send(sinkCtx, event); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			logger.Info("Failed to deliver security event", "sink", sink.Name(), "error", err.Error())
+		}
+		span.End()
+	}
+}
 
-	resp, err := r.HTTPClient.Do(req)
-	if err != nil {
-		logger.V(1).Info("Failed to send event to audit service", "error", err.Error())
+// sendAlerts queues a violation for each of the policy's AlertWebhooks. Delivery
+// happens in the background; a failing destination is logged and counted without
+// affecting the others. URLs that are not https or point at the operator's own
+// host or the cloud metadata endpoint are refused.
+func (r *PodReconciler) sendAlerts(logger logr.Logger, policy *shieldv1alpha1.ShieldPolicy, event audit.SecurityEvent) {
+	if r.alerts == nil || len(policy.Spec.AlertWebhooks) == 0 {
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		logger.Info("Audit service returned error", "status", resp.StatusCode)
+	r.Options.Sanitizer.Apply(&event)
+	event.OperatorVersion = version.Version
+	engine.Classify(&event)
+	for _, url := range policy.Spec.AlertWebhooks {
+		if err := audit.ValidateWebhookURL(url); err != nil {
+			destination := audit.NewWebhookSink(url, nil).Destination()
+			metrics.AlertsSkipped.WithLabelValues(destination, "invalid_url").Inc()
+			logger.Info("Refusing alert webhook", "destination", destination, "error", err.Error())
+			continue
+		}
+		r.alerts.enqueue(logger, url, event)
 	}
 }
 
@@ -298,42 +923,70 @@ func (r *PodReconciler) updatePolicyStatus(
 	wasTerminated bool,
 ) {
 	now := metav1.Now()
-	policy.Status.LastEnforcementTime = &now
-	policy.Status.ViolationsCount++
-	policy.Status.Phase = "Active"
-
-	if wasTerminated {
-		policy.Status.TerminationsCount++
-		policy.Status.Message = fmt.Sprintf("Last termination at %s", now.Format(time.RFC3339))
-	}
-
-	if err := r.Status().Update(ctx, policy); err != nil {
+	err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Status.LastEnforcementTime = &now
+		policy.Status.ViolationsCount++
+		recordActivity(&policy.Status, now.Time, wasTerminated)
+		policy.Status.Phase = "Active"
+
+		if wasTerminated {
+			policy.Status.TerminationsCount++
+			policy.Status.Message = fmt.Sprintf("Last termination at %s", now.Format(time.RFC3339))
+			clearEnforcementDegraded(policy)
+		}
+	})
+	if err != nil {
 		logger.Error(err, "Failed to update ShieldPolicy status")
 	}
 }
 
-// extractRegistry extracts the registry from a container image
-func extractRegistry(image string) string {
-	// Handle images without explicit registry (default to docker.io)
-	if !strings.Contains(image, "/") {
-		return "docker.io"
-	}
-
-	parts := strings.Split(image, "/")
-	firstPart := parts[0]
-
-	// Check if first part looks like a registry (contains . or :)
-	if strings.Contains(firstPart, ".") || strings.Contains(firstPart, ":") {
-		return firstPart
+// suppressionSummaryInterval is how often rate limited policies are checked for a
+// refilled bucket to report their suppressed events
+const suppressionSummaryInterval = 10 * time.Second
+
+// suppressionSummaries returns the job delivering the AUDIT_EVENTS_SUPPRESSED
+// summaries of the audit rate limiter
+func (r *PodReconciler) suppressionSummaries() schedule.Job {
+	return schedule.Job{
+		Name:     "audit-rate-limit",
+		Interval: suppressionSummaryInterval,
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if len(r.Sinks) == 0 {
+				return
+			}
+			for _, summary := range r.auditLimiter.Summaries(time.Now()) {
+				r.deliverSecurityEvent(ctx, logger.WithValues(summary.LogFields()...), summary)
+			}
+		},
 	}
-
-	// Otherwise, it's a docker.io library image
-	return "docker.io"
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
-		Complete(r)
+	if r.auditLimiter != nil {
+		if err := schedule.Add(mgr, r.suppressionSummaries()); err != nil {
+			return err
+		}
+	}
+	if r.AlertClient != nil {
+		r.alerts = newAlertDispatcher(r.AlertClient)
+		if err := schedule.AddRunnable(mgr, r.alerts); err != nil {
+			return err
+		}
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField, podNodeName); err != nil {
+		return err
+	}
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}, builder.WithPredicates(ignoreOwnAnnotationUpdates())).
+		Watches(&shieldv1alpha1.ShieldExemption{}, handler.EnqueueRequestsFromMapFunc(r.podsForExemption)).
+		WatchesMetadata(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.podsForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.podsForNode),
+			builder.WithPredicates(nodeLabelsChanged()))
+	if r.Allowlists != nil {
+		// The manager's cache only holds ConfigMaps labeled as image allowlists
+		bldr = bldr.Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.podsForAllowlist))
+	}
+	return bldr.Complete(r)
 }
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/celrules"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+// newTestPodReconciler builds a PodReconciler on a fake client holding objs, with
+// an in-memory audit sink and the default options
+func newTestPodReconciler(t *testing.T, objs ...client.Object) (*PodReconciler, client.Client) {
+	t.Helper()
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&shieldv1alpha1.ShieldPolicy{}).
+		Build()
+	protector, err := protection.NewProtector(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system, err := protection.NewSystemNamespaces(nil, protection.SystemNamespaceModeSkip, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewPodReconciler(c, c.Scheme(), c, []audit.Sink{audit.NewRecentEvents(10, 0)}, record.NewFakeRecorder(10),
+		state.NewMemoryStore(), evaluator.New(nil), protector, system, nil, PodReconcilerOptions{})
+	return r, c
+}
+
+func podRequest(pod *corev1.Pod) ctrl.Request {
+	return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}}
+}
+
+func TestReconcileSkipsUnchangedPods(t *testing.T) {
+	ctx := context.Background()
+	exporter := recordSpans(t)
+	evaluations := func() int {
+		var n int
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "EvaluatePolicy" {
+				n++
+			}
+		}
+		return n
+	}
+
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeEnforce},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.25"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newTestPodReconciler(t, policy, pod)
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconcile()
+	if got := evaluations(); got != 1 {
+		t.Fatalf("%d evaluations after the first reconcile, want 1", got)
+	}
+	reconcile()
+	if got := evaluations(); got != 1 {
+		t.Errorf("%d evaluations after reconciling an unchanged pod, want 1", got)
+	}
+
+	// An updated pod is evaluated again
+	if err := c.Get(ctx, podRequest(pod).NamespacedName, pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.Labels = map[string]string{"app": "web"}
+	if err := c.Update(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if got := evaluations(); got != 2 {
+		t.Errorf("%d evaluations after a pod update, want 2", got)
+	}
+
+	// So is every pod once a policy changes
+	if err := c.Get(ctx, client.ObjectKeyFromObject(policy), policy); err != nil {
+		t.Fatal(err)
+	}
+	policy.Spec.EnforcementMode = shieldv1alpha1.EnforcementModeAudit
+	if err := c.Update(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if got := evaluations(); got != 3 {
+		t.Errorf("%d evaluations after a policy change, want 3", got)
+	}
+	reconcile()
+	if got := evaluations(); got != 3 {
+		t.Errorf("%d evaluations after reconciling again, want 3", got)
+	}
+}
+
+func TestReconcileObservesDuration(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.25"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, _ := newTestPodReconciler(t, pod)
+
+	count := func() uint64 {
+		metric := &dto.Metric{}
+		if err := metrics.ReconcileDuration.Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetHistogram().GetSampleCount()
+	}
+	before := count()
+	if _, err := r.Reconcile(context.Background(), podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if got := count() - before; got != 1 {
+		t.Errorf("%d reconcile duration observations, want 1", got)
+	}
+}
+
+func TestReconcileWarnsOncePerViolation(t *testing.T) {
+	ctx := context.Background()
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeWarn},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newTestPodReconciler(t, policy, pod)
+	events := r.Recorder.(*record.FakeRecorder).Events
+
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d Events after the first evaluation, want 1", len(events))
+	}
+	<-events
+
+	// Updates re-evaluate the pod without warning about the same violation again
+	for i := 0; i < 3; i++ {
+		if err := c.Get(ctx, podRequest(pod).NamespacedName, pod); err != nil {
+			t.Fatal(err)
+		}
+		pod.Labels = map[string]string{"revision": strconv.Itoa(i)}
+		if err := c.Update(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 0 {
+		t.Errorf("%d Events after re-evaluating an unchanged violation, want 0", len(events))
+	}
+
+	// A violation the pod no longer has is warned about again when it comes back
+	r.Violations.Forget(podRequest(pod).NamespacedName)
+	r.Evaluations.Forget(podRequest(pod).NamespacedName)
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("%d Events for a new violation, want 1", len(events))
+	}
+}
+
+func TestReconcileTracksCustomRulesSeparately(t *testing.T) {
+	ctx := context.Background()
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", UID: "uid-restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			EnforcementMode: shieldv1alpha1.EnforcementModeWarn,
+			Rules: []shieldv1alpha1.CELRule{
+				{Name: "no-host-pid", Expression: "pod.spec.hostPID == true", Action: "Enforce"},
+				{Name: "no-debug", Expression: "has(pod.metadata.labels) && 'debug' in pod.metadata.labels", Action: "Enforce"},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostPID:    true,
+			Containers: []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, c := newTestPodReconciler(t, policy, pod)
+	compiler, err := celrules.NewCompiler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Evaluator = evaluator.New(compiler)
+	events := r.Recorder.(*record.FakeRecorder).Events
+
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d Events after the first rule matched, want 1", len(events))
+	}
+	<-events
+
+	// A second rule of the same policy is a violation of its own
+	if err := c.Get(ctx, podRequest(pod).NamespacedName, pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.Labels = map[string]string{"debug": "true"}
+	if err := c.Update(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d Events after the second rule matched, want 1", len(events))
+	}
+	if event := <-events; !strings.Contains(event, "no-debug") {
+		t.Errorf("Event %q does not name the second rule", event)
+	}
+
+	for _, rule := range []string{"no-host-pid", "no-debug"} {
+		key := state.Key{PodUID: pod.UID, Policy: policy.Name, EventType: "CUSTOM_RULE_VIOLATION", Rule: rule}
+		if _, ok := r.Violations.Get(key); !ok {
+			t.Errorf("No violation of rule %s recorded", rule)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// PodWebhookPath is where the validating admission webhook for pods is served
+const PodWebhookPath = "/validate-v1-pod"
+
+// Decisions of the pod admission webhook
+const (
+	admissionAllowed = "allowed"
+	admissionWarned  = "warned"
+	admissionDenied  = "denied"
+	admissionError   = "error"
+)
+
+// PodValidator is the validating admission webhook for pods. It applies the same
+// checks, exemptions and protections as the PodReconciler before a pod exists, and
+// admits violating pods of Warn policies with admission warnings that kubectl shows
+// to the user. It never denies a pod: Enforce and Audit policies are left to the
+// PodReconciler.
+type PodValidator struct {
+	reconciler *PodReconciler
+}
+
+// NewPodValidator creates a PodValidator sharing the PodReconciler's configuration
+func NewPodValidator(reconciler *PodReconciler) *PodValidator {
+	return &PodValidator{reconciler: reconciler}
+}
+
+// +kubebuilder:webhook:path=/validate-v1-pod,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=vpod.kubeshield.io,admissionReviewVersions=v1
+
+// Handle implements admission.Handler
+func (v *PodValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx).WithValues("pod", req.Namespace+"/"+req.Name)
+
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		metrics.AdmissionDecisions.WithLabelValues(admissionError).Inc()
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Pods created from a template only get their name and namespace from the request
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+	if pod.Name == "" {
+		pod.Name = req.Name
+	}
+	if pod.Name == "" {
+		pod.Name = pod.GenerateName
+	}
+
+	_, warnings, err := v.review(ctx, pod, nil, false)
+	if err != nil {
+		logger.Error(err, "Failed to review pod")
+		metrics.AdmissionDecisions.WithLabelValues(admissionError).Inc()
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if len(warnings) > 0 {
+		metrics.AdmissionDecisions.WithLabelValues(admissionWarned).Inc()
+		return admission.Allowed("").WithWarnings(warnings...)
+	}
+	metrics.AdmissionDecisions.WithLabelValues(admissionAllowed).Inc()
+	return admission.Allowed("")
+}
+
+// review evaluates the pod against every applicable policy and returns the violations
+// that deny it and those that only warn. Enforce policies are only reviewed when
+// enforce is set, otherwise just Warn policies are. When checks is set, only
+// violations of the event types it accepts count.
+func (v *PodValidator) review(ctx context.Context, pod *corev1.Pod, checks func(eventType string) bool, enforce bool) ([]string, []string, error) {
+	r := v.reconciler
+	logger := log.FromContext(ctx)
+
+	if r.System.Skip(pod.Namespace) {
+		return nil, nil, nil
+	}
+	if _, ok := exemptUntil(logger, pod); ok {
+		return nil, nil, nil
+	}
+
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, nil, fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+	exemptions, err := r.activeExemptions(ctx, pod.Namespace, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing ShieldExemptions: %w", err)
+	}
+	var nsLabels map[string]string
+	if needsNamespaceLabels(policies.Items) {
+		if nsLabels, err = namespaceLabels(ctx, r.Client, pod.Namespace); err != nil {
+			return nil, nil, fmt.Errorf("resolving namespace labels: %w", err)
+		}
+	}
+
+	var ownerKind string
+	if needsOwnerKind(policies.Items) {
+		if ownerKind, err = r.owners.topLevelKind(ctx, pod); err != nil {
+			return nil, nil, fmt.Errorf("resolving pod owner: %w", err)
+		}
+	}
+
+	// Protected pods, system namespaces and static pods are never blocked, at most
+	// warned about. Rejecting a mirror pod would only hide the static pod the kubelet
+	// runs anyway.
+	protected, _ := r.Protector.IsProtected(pod)
+	mayDeny := !protected && !r.System.AuditOnly(pod.Namespace) && !isMirrorPod(pod)
+
+	var denials, warnings []string
+	accounts := newServiceAccountCache(r.APIReader, logger)
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		// Node-scoped policies cannot apply before the pod is scheduled
+		if !engine.AppliesToNamespace(policy, pod.Namespace, nsLabels) || len(policy.Spec.NodeSelector) > 0 {
+			continue
+		}
+		if !policy.ShouldApplyToOwnerKind(ownerKind) {
+			continue
+		}
+		if !(enforce && policy.DeniesAdmission() || policy.WarnsOnAdmission()) {
+			continue
+		}
+		// Policies evaluating pods once scheduled or running leave them to the reconciler
+		if !policy.ShouldEvaluatePod(pod) {
+			continue
+		}
+
+		violations, err := r.Evaluator.EvaluateWithin(ctx, pod, policy, accounts, r.Options.PolicyEvaluationTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("evaluating ShieldPolicy %s: %w", policy.Name, err)
+		}
+		violations, _ = evaluator.ApplyRegistryOverrides(pod, policy, violations)
+		violations, _, _ = applyExemptions(pod, violations, exemptions)
+		for _, violation := range violations {
+			if checks != nil && !checks(violation.EventType) {
+				continue
+			}
+			finding := fmt.Sprintf("ShieldPolicy %s: %s", policy.Name, violationSummary(violation, r.Options.Sanitizer))
+			switch {
+			case violation.Action == audit.ActionTerminated && mayDeny:
+				denials = append(denials, finding)
+			case violation.Action == audit.ActionTerminated || violation.Action == audit.ActionWarn:
+				warnings = append(warnings, finding)
+			}
+		}
+	}
+	return denials, warnings, nil
+}
+
+// violationSummary describes a violation in one line for admission responses, which
+// the client shows the user as is
+func violationSummary(violation audit.SecurityEvent, sanitizer audit.Sanitizer) string {
+	reason := sanitizer.Text(violation.Reason)
+	if violation.Container != "" {
+		return fmt.Sprintf("%s in container %s (%s)", violation.EventType, violation.Container, reason)
+	}
+	return fmt.Sprintf("%s (%s)", violation.EventType, reason)
+}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server
+func (v *PodValidator) SetupWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(PodWebhookPath, &webhook.Admission{Handler: v})
+}
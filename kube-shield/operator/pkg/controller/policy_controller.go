@@ -2,32 +2,65 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/celrules"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/version"
 )
 
 // ShieldPolicyReconciler reconciles ShieldPolicy objects
 type ShieldPolicyReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme    *runtime.Scheme
+	APIReader client.Reader
+	Rules     *celrules.Compiler
+	Evaluator *evaluator.Evaluator
+
+	// ImageAllowlists reads the ConfigMaps of AllowedImagesConfigMapRef, to report
+	// in the ImageAllowlistAvailable condition whether they can be read
+	ImageAllowlists *ImageAllowlists
+
+	// System, when set, leaves the namespaces the pod controller skips out of simulations
+	System *protection.SystemNamespaces
+
+	simulations *simulationRuns
 }
 
 // NewShieldPolicyReconciler creates a new ShieldPolicyReconciler
 func NewShieldPolicyReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
+	apiReader client.Reader,
+	rules *celrules.Compiler,
+	podEvaluator *evaluator.Evaluator,
 ) *ShieldPolicyReconciler {
 	return &ShieldPolicyReconciler{
-		Client: client,
-		Scheme: scheme,
+		Client:      client,
+		Scheme:      scheme,
+		APIReader:   apiReader,
+		Rules:       rules,
+		Evaluator:   podEvaluator,
+		simulations: newSimulationRuns(),
 	}
 }
 
@@ -45,6 +78,9 @@ func (r *ShieldPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if errors.IsNotFound(err) {
 			// Policy was deleted
 			logger.Info("ShieldPolicy resource not found, ignoring since object must be deleted")
+			if r.Rules != nil {
+				r.Rules.Forget(req.Name)
+			}
 			return ctrl.Result{}, nil
 		}
 		logger.Error(err, "Failed to fetch ShieldPolicy")
@@ -53,48 +89,59 @@ func (r *ShieldPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	// Initialize status if not set
 	if policy.Status.Phase == "" {
-		policy.Status.Phase = "Active"
-		policy.Status.ObservedGeneration = policy.Generation
-		policy.Status.Message = "Policy is active and enforcing"
-
-		// Set initial condition
-		condition := metav1.Condition{
-			Type:               "Ready",
-			Status:             metav1.ConditionTrue,
-			Reason:             "PolicyActive",
-			Message:            "ShieldPolicy is active and monitoring pods",
-			LastTransitionTime: metav1.Now(),
-		}
-		policy.Status.Conditions = []metav1.Condition{condition}
+		err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			policy.Status.Phase = "Active"
+			policy.Status.ObservedGeneration = policy.Generation
+			policy.Status.EnforcementMode = policy.EffectiveEnforcementMode()
+			policy.Status.Message = fmt.Sprintf("Policy is active in %s mode (operator %s)", policy.Status.EnforcementMode, version.Version)
 
-		if err := r.Status().Update(ctx, policy); err != nil {
+			// Set initial condition
+			condition := metav1.Condition{
+				Type:               "Ready",
+				Status:             metav1.ConditionTrue,
+				Reason:             "PolicyActive",
+				Message:            fmt.Sprintf("ShieldPolicy is active and monitoring pods in %s mode", policy.Status.EnforcementMode),
+				ObservedGeneration: policy.Generation,
+				LastTransitionTime: metav1.Now(),
+			}
+			meta.SetStatusCondition(&policy.Status.Conditions, condition)
+			r.validateRules(policy)
+			validateRegistries(policy)
+		})
+		if err != nil {
 			logger.Error(err, "Failed to update ShieldPolicy status")
 			return ctrl.Result{}, err
 		}
 
 		logger.Info("Initialized ShieldPolicy status",
 			"phase", policy.Status.Phase,
-			"blockPrivileged", policy.Spec.BlockPrivileged,
+			"profile", policy.Spec.Profile,
+			"blockPrivileged", policy.ShouldBlockPrivileged(),
 			"enforcementMode", policy.Spec.EnforcementMode,
 		)
 	}
 
 	// Check if generation changed
 	if policy.Generation != policy.Status.ObservedGeneration {
-		policy.Status.ObservedGeneration = policy.Generation
-		policy.Status.Message = "Policy configuration updated"
-
-		// Update condition
-		condition := metav1.Condition{
-			Type:               "Ready",
-			Status:             metav1.ConditionTrue,
-			Reason:             "PolicyUpdated",
-			Message:            "ShieldPolicy configuration was updated",
-			LastTransitionTime: metav1.Now(),
-		}
-		policy.Status.Conditions = []metav1.Condition{condition}
+		err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			policy.Status.ObservedGeneration = policy.Generation
+			policy.Status.EnforcementMode = policy.EffectiveEnforcementMode()
+			policy.Status.Message = "Policy configuration updated"
 
-		if err := r.Status().Update(ctx, policy); err != nil {
+			// Update condition
+			condition := metav1.Condition{
+				Type:               "Ready",
+				Status:             metav1.ConditionTrue,
+				Reason:             "PolicyUpdated",
+				Message:            fmt.Sprintf("ShieldPolicy configuration was updated, now in %s mode", policy.Status.EnforcementMode),
+				ObservedGeneration: policy.Generation,
+				LastTransitionTime: metav1.Now(),
+			}
+			meta.SetStatusCondition(&policy.Status.Conditions, condition)
+			r.validateRules(policy)
+			validateRegistries(policy)
+		})
+		if err != nil {
 			logger.Error(err, "Failed to update ShieldPolicy status after config change")
 			return ctrl.Result{}, err
 		}
@@ -102,13 +149,245 @@ func (r *ShieldPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		logger.Info("Updated ShieldPolicy status after configuration change")
 	}
 
-	// Requeue periodically to update status
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	// Keep the reported mode in line with the operator default and grace period
+	if policy.Status.EnforcementMode != policy.EffectiveEnforcementMode() {
+		err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			policy.Status.EnforcementMode = policy.EffectiveEnforcementMode()
+			validateRegistries(policy)
+		})
+		if err != nil {
+			logger.Error(err, "Failed to update ShieldPolicy enforcement mode")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Dry runs are summarized from the existing pods instead of being enforced
+	if policy.IsSimulated() {
+		if err := r.simulate(ctx, logger, policy); err != nil {
+			logger.Error(err, "Failed to start ShieldPolicy simulation")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	r.simulations.stop(policy.UID)
+	if policy.Status.Phase == phaseSimulated {
+		err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			policy.Status.Phase = "Active"
+			policy.Status.Message = fmt.Sprintf("Simulation ended, policy is active in %s mode", policy.EffectiveEnforcementMode())
+		})
+		if err != nil {
+			logger.Error(err, "Failed to activate simulated ShieldPolicy")
+			return ctrl.Result{}, err
+		}
+		logger.Info("Simulated ShieldPolicy activated")
+	}
+
+	// Spec and annotation changes trigger reconciles on their own, only come back
+	// for the next time-based transition
+	untilReset, err := r.resetCounters(ctx, policy)
+	if err != nil {
+		logger.Error(err, "Failed to reset ShieldPolicy counters")
+		return ctrl.Result{}, err
+	}
+	untilRollover, err := r.rollRecentActivity(ctx, policy)
+	if err != nil {
+		logger.Error(err, "Failed to roll ShieldPolicy activity window")
+		return ctrl.Result{}, err
+	}
+	remaining, err := r.reportGracePeriod(ctx, policy)
+	if err != nil {
+		logger.Error(err, "Failed to update ShieldPolicy grace period status")
+		return ctrl.Result{}, err
+	}
+	if err := r.reportImageAllowlist(ctx, policy); err != nil {
+		logger.Error(err, "Failed to update ShieldPolicy image allowlist condition")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: earliest(untilReset, untilRollover, remaining)}, nil
+}
+
+// earliest returns the shortest of the positive durations, or zero when there is none
+func earliest(durations ...time.Duration) time.Duration {
+	var next time.Duration
+	for _, d := range durations {
+		if d > 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+	return next
+}
+
+// reportGracePeriod surfaces when enforcement of an observe-only policy begins in its
+// status, and flips the ObserveOnly condition off once it has. The status names the
+// time rather than counting down to it, so it is written once and not on every
+// reconcile. It returns the remaining grace time, for the caller to requeue at.
+func (r *ShieldPolicyReconciler) reportGracePeriod(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) (time.Duration, error) {
+	now := time.Now()
+	remaining := policy.GraceRemaining(now)
+
+	if remaining > 0 {
+		enforceAt := policy.CreationTimestamp.Add(policy.Spec.GracePeriodAfterCreation.Duration)
+		return remaining, writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			policy.Status.Message = fmt.Sprintf("Observe-only grace period: enforcement begins at %s",
+				enforceAt.UTC().Format(time.RFC3339))
+			meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+				Type:               "ObserveOnly",
+				Status:             metav1.ConditionTrue,
+				Reason:             "GracePeriodAfterCreation",
+				Message:            fmt.Sprintf("Violations are only audited until %s", enforceAt.UTC().Format(time.RFC3339)),
+				ObservedGeneration: policy.Generation,
+			})
+		})
+	}
+
+	if !meta.IsStatusConditionTrue(policy.Status.Conditions, "ObserveOnly") {
+		return 0, nil
+	}
+	return 0, writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Status.Message = fmt.Sprintf("Grace period ended, policy is now in %s mode", policy.EffectiveEnforcementMode())
+		meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+			Type:               "ObserveOnly",
+			Status:             metav1.ConditionFalse,
+			Reason:             "GracePeriodEnded",
+			Message:            "The observe-only grace period has elapsed",
+			ObservedGeneration: policy.Generation,
+		})
+	})
+}
+
+// validateRules compiles the policy's CEL rules and records any compile errors
+// in the RulesValid condition and status message
+func (r *ShieldPolicyReconciler) validateRules(policy *shieldv1alpha1.ShieldPolicy) {
+	if len(policy.Spec.Rules) == 0 || r.Rules == nil {
+		meta.RemoveStatusCondition(&policy.Status.Conditions, "RulesValid")
+		return
+	}
+
+	var problems []string
+	for _, rule := range policy.Spec.Rules {
+		if _, err := r.Rules.Compile(policy.Name, policy.Generation, rule.Expression); err != nil {
+			problems = append(problems, fmt.Sprintf("rule '%s': %v", rule.Name, err))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               "RulesValid",
+		Status:             metav1.ConditionTrue,
+		Reason:             "RulesCompiled",
+		Message:            fmt.Sprintf("All %d custom rules compiled", len(policy.Spec.Rules)),
+		ObservedGeneration: policy.Generation,
+	}
+	if len(problems) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CompileError"
+		condition.Message = strings.Join(problems, "; ")
+		policy.Status.Message = fmt.Sprintf("%d custom rule(s) failed to compile and are ignored", len(problems))
+	}
+	meta.SetStatusCondition(&policy.Status.Conditions, condition)
+}
+
+// validateRegistries records in the RegistriesValid condition and status message
+// why the policy's AllowedRegistries are misconfigured: entries that can never match
+// and are ignored, or a "*" that makes an enforcing policy allow every registry
+func validateRegistries(policy *shieldv1alpha1.ShieldPolicy) {
+	if len(policy.Spec.AllowedRegistries) == 0 {
+		meta.RemoveStatusCondition(&policy.Status.Conditions, "RegistriesValid")
+		return
+	}
+
+	var problems []string
+	for _, entry := range policy.Spec.AllowedRegistries {
+		if problem := shieldv1alpha1.RegistryEntryProblem(entry); problem != "" {
+			problems = append(problems, fmt.Sprintf("allowedRegistries entry %q %s and is ignored", entry, problem))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               "RegistriesValid",
+		Status:             metav1.ConditionTrue,
+		Reason:             "RegistriesValid",
+		Message:            fmt.Sprintf("All %d allowed registries are usable", len(policy.Spec.AllowedRegistries)),
+		ObservedGeneration: policy.Generation,
+	}
+	switch {
+	case len(problems) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "InvalidEntry"
+		condition.Message = strings.Join(problems, "; ")
+		policy.Status.Message = "Misconfigured: " + condition.Message
+	case policy.AllowsAnyRegistry() && policy.IsEnforcing():
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "WildcardEnforced"
+		condition.Message = `allowedRegistries contains "*", which allows every registry although the policy is enforced; list the registries or set strictRegistryMatching`
+		policy.Status.Message = "Misconfigured: " + condition.Message
+	}
+	meta.SetStatusCondition(&policy.Status.Conditions, condition)
+}
+
+// reportImageAllowlist records in the ImageAllowlistAvailable condition whether the
+// ConfigMap of the policy's AllowedImagesConfigMapRef can be read
+func (r *ShieldPolicyReconciler) reportImageAllowlist(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) error {
+	ref := policy.Spec.AllowedImagesConfigMapRef
+	if r.ImageAllowlists == nil || ref == nil {
+		if meta.FindStatusCondition(policy.Status.Conditions, conditionImageAllowlistAvailable) == nil {
+			return nil
+		}
+		return writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			meta.RemoveStatusCondition(&policy.Status.Conditions, conditionImageAllowlistAvailable)
+		})
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionImageAllowlistAvailable,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ConfigMapRead",
+		Message:            fmt.Sprintf("Approved digests are read from ConfigMap %s/%s", ref.Namespace, ref.Name),
+		ObservedGeneration: policy.Generation,
+	}
+	if err := r.ImageAllowlists.Available(ctx, *ref); err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ConfigMapUnavailable"
+		if policy.Spec.ImageAllowlistFailurePolicy == shieldv1alpha1.ImageAllowlistFailureFail {
+			condition.Message = fmt.Sprintf("%v; images not in allowedImageDigests are reported as unapproved", err)
+		} else {
+			condition.Message = fmt.Sprintf("%v; only allowedImageDigests apply, and without them images are not checked", err)
+		}
+	}
+	return writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		meta.SetStatusCondition(&policy.Status.Conditions, condition)
+	})
+}
+
+// policiesForAllowlist maps an allowlist ConfigMap to the policies referencing it,
+// so their ImageAllowlistAvailable condition follows its creation and deletion
+func (r *ShieldPolicyReconciler) policiesForAllowlist(ctx context.Context, obj client.Object) []reconcile.Request {
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ShieldPolicies for image allowlist", "configmap", client.ObjectKeyFromObject(obj).String())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		ref := policy.Spec.AllowedImagesConfigMapRef
+		if ref != nil && ref.Namespace == obj.GetNamespace() && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: policy.Name}})
+		}
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *ShieldPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&shieldv1alpha1.ShieldPolicy{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		// Status writes do not bump the generation, so the controller is not woken by
+		// its own updates. Annotations carry requests such as reset-counters.
+		For(&shieldv1alpha1.ShieldPolicy{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		)))
+	if r.ImageAllowlists != nil {
+		b = b.Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.policiesForAllowlist),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: func(event.UpdateEvent) bool { return false }}))
+	}
+	return b.Complete(r)
 }
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// writeCounter counts every write a client makes, to objects and their subresources
+type writeCounter struct {
+	writes int
+}
+
+func (w *writeCounter) funcs() interceptor.Funcs {
+	return interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			w.writes++
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			w.writes++
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			w.writes++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			w.writes++
+			return c.Delete(ctx, obj, opts...)
+		},
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			w.writes++
+			return c.SubResource(subResource).Update(ctx, obj, opts...)
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			w.writes++
+			return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+		},
+	}
+}
+
+func TestShieldPolicyReconcileNoOpWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", Generation: 1},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeEnforce},
+	}
+	counter := &writeCounter{}
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(policy).
+		WithStatusSubresource(&shieldv1alpha1.ShieldPolicy{}).
+		WithInterceptorFuncs(counter.funcs()).
+		Build()
+	r := NewShieldPolicyReconciler(c, c.Scheme(), c, nil, nil)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)}
+
+	// The first reconcile initializes the status
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if counter.writes == 0 {
+		t.Fatal("first reconcile did not initialize the status")
+	}
+	if err := c.Get(ctx, req.NamespacedName, policy); err != nil {
+		t.Fatal(err)
+	}
+	ready := meta.FindStatusCondition(policy.Status.Conditions, "Ready")
+	if ready == nil || ready.ObservedGeneration != policy.Generation || policy.Status.ObservedGeneration != policy.Generation {
+		t.Errorf("status = %+v, want Ready observed at generation %d", policy.Status, policy.Generation)
+	}
+
+	// Nothing changed since, so nothing is written and nothing is scheduled
+	counter.writes = 0
+	for i := 0; i < 3; i++ {
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RequeueAfter != 0 || result.Requeue {
+			t.Errorf("no-op reconcile requeued with %+v, want no requeue", result)
+		}
+	}
+	if counter.writes != 0 {
+		t.Errorf("no-op reconciles made %d writes, want 0", counter.writes)
+	}
+
+	// Neither do counters the pod controller updated in the meantime
+	if err := c.Get(ctx, req.NamespacedName, policy); err != nil {
+		t.Fatal(err)
+	}
+	policy.Status.ViolationsCount++
+	if err := c.Status().Update(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+	counter.writes = 0
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if counter.writes != 0 {
+		t.Errorf("reconcile after a status-only change made %d writes, want 0", counter.writes)
+	}
+}
+
+func TestShieldPolicyReconcileGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", Generation: 1, CreationTimestamp: metav1.NewTime(created)},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			EnforcementMode:          shieldv1alpha1.EnforcementModeEnforce,
+			GracePeriodAfterCreation: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	counter := &writeCounter{}
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(policy).
+		WithStatusSubresource(&shieldv1alpha1.ShieldPolicy{}).
+		WithInterceptorFuncs(counter.funcs()).
+		Build()
+	r := NewShieldPolicyReconciler(c, c.Scheme(), c, nil, nil)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, policy); err != nil {
+		t.Fatal(err)
+	}
+	enforceAt := created.Add(time.Hour).UTC().Format(time.RFC3339)
+	if !strings.Contains(policy.Status.Message, enforceAt) {
+		t.Errorf("status message = %q, want it to name %s", policy.Status.Message, enforceAt)
+	}
+	if !meta.IsStatusConditionTrue(policy.Status.Conditions, "ObserveOnly") {
+		t.Error("ObserveOnly condition is not true during the grace period")
+	}
+
+	// Later reconciles leave the status alone and come back when the grace period ends
+	counter.writes = 0
+	before := time.Now()
+	result, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.writes != 0 {
+		t.Errorf("reconcile during the grace period made %d writes, want 0", counter.writes)
+	}
+	if remaining := created.Add(time.Hour).Sub(before); result.RequeueAfter > remaining || result.RequeueAfter < remaining-time.Minute {
+		t.Errorf("requeued after %s, want at the end of the grace period in %s", result.RequeueAfter, remaining)
+	}
+}
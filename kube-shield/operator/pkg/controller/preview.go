@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/listing"
+	"github.com/kubeshield/operator/pkg/protection"
+)
+
+const (
+	// maxPreviewBody caps the size of a candidate policy posted to PreviewHandler
+	maxPreviewBody = 1 << 20
+
+	// maxConcurrentPreviews bounds the previews evaluated at once, as each one
+	// evaluates every pod in the cluster twice. Further requests are turned away.
+	maxConcurrentPreviews = 2
+)
+
+// PolicyPreview is the difference in violations between two versions of a policy
+// over the pods currently in the cluster
+type PolicyPreview struct {
+	Policy        string `json:"policy"`
+	GeneratedAt   string `json:"generatedAt"`
+	PodsEvaluated int64  `json:"podsEvaluated"`
+
+	// ViolatingBefore and ViolatingAfter count the pods violating each version
+	ViolatingBefore int64 `json:"violatingBefore"`
+	ViolatingAfter  int64 `json:"violatingAfter"`
+
+	// NewlyViolating are the pods that only the new version flags
+	NewlyViolating []PodPreview `json:"newlyViolating,omitempty"`
+
+	// NoLongerViolating are the pods that only the old version flags
+	NoLongerViolating []PodPreview `json:"noLongerViolating,omitempty"`
+
+	// Changed are the pods both versions flag, for different checks
+	Changed []PodPreview `json:"changed,omitempty"`
+}
+
+// PodPreview is how the violations of one pod change
+type PodPreview struct {
+	Pod     string   `json:"pod"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// PreviewPolicyChange evaluates every running pod against both versions of a policy
+// with the shared evaluator and reports the pods whose violations change. oldPolicy
+// may be nil for a policy that does not exist yet. Pods are listed through reader;
+// namespaces, nodes and exemptions are read through c, like the pod controller does.
+// Pods in namespaces system skips are left out and active ShieldExemptions apply.
+func PreviewPolicyChange(
+	ctx context.Context,
+	logger logr.Logger,
+	c client.Reader,
+	reader client.Reader,
+	podEvaluator *evaluator.Evaluator,
+	system *protection.SystemNamespaces,
+	oldPolicy, newPolicy *shieldv1alpha1.ShieldPolicy,
+) (*PolicyPreview, error) {
+	preview := &PolicyPreview{Policy: newPolicy.Name}
+	policies := []shieldv1alpha1.ShieldPolicy{*newPolicy}
+	if oldPolicy != nil {
+		policies = append(policies, *oldPolicy)
+	}
+
+	accounts := newServiceAccountCache(reader, logger)
+	owners := newOwnerResolver(reader)
+	namespaces := make(map[string]map[string]string)
+	exemptions := make(map[string][]shieldv1alpha1.ShieldExemption)
+	now := time.Now()
+	pods := &corev1.PodList{}
+	err := listing.Paginate(ctx, reader, pods, listing.DefaultPageSize, func() error {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			if system.Skip(pod.Namespace) {
+				continue
+			}
+			if _, ok := exemptUntil(logger, pod); ok {
+				continue
+			}
+
+			active, ok := exemptions[pod.Namespace]
+			if !ok {
+				var err error
+				if active, err = listActiveExemptions(ctx, c, pod.Namespace, now); err != nil {
+					return err
+				}
+				exemptions[pod.Namespace] = active
+			}
+
+			nsLabels, ok := namespaces[pod.Namespace]
+			if !ok && needsNamespaceLabels(policies) {
+				var err error
+				if nsLabels, err = namespaceLabels(ctx, c, pod.Namespace); err != nil {
+					return err
+				}
+				namespaces[pod.Namespace] = nsLabels
+			}
+
+			var ownerKind string
+			if needsOwnerKind(policies) {
+				var err error
+				if ownerKind, err = owners.topLevelKind(ctx, pod); err != nil {
+					return err
+				}
+			}
+
+			before, err := previewChecks(ctx, c, podEvaluator, oldPolicy, pod, nsLabels, ownerKind, accounts, active)
+			if err != nil {
+				return err
+			}
+			after, err := previewChecks(ctx, c, podEvaluator, newPolicy, pod, nsLabels, ownerKind, accounts, active)
+			if err != nil {
+				return err
+			}
+
+			preview.PodsEvaluated++
+			if len(before) > 0 {
+				preview.ViolatingBefore++
+			}
+			if len(after) > 0 {
+				preview.ViolatingAfter++
+			}
+			entry := PodPreview{
+				Pod:     pod.Namespace + "/" + pod.Name,
+				Added:   checksOnlyIn(after, before),
+				Removed: checksOnlyIn(before, after),
+			}
+			switch {
+			case len(before) == 0 && len(after) > 0:
+				preview.NewlyViolating = append(preview.NewlyViolating, entry)
+			case len(before) > 0 && len(after) == 0:
+				preview.NoLongerViolating = append(preview.NoLongerViolating, entry)
+			case len(entry.Added) > 0 || len(entry.Removed) > 0:
+				preview.Changed = append(preview.Changed, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("evaluating pods: %w", err)
+	}
+
+	preview.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	return preview, nil
+}
+
+// previewChecks returns the checks a pod violates under a policy and not covered by
+// an exemption, or none when the policy is nil, disabled or does not apply to the pod
+func previewChecks(
+	ctx context.Context,
+	c client.Reader,
+	podEvaluator *evaluator.Evaluator,
+	policy *shieldv1alpha1.ShieldPolicy,
+	pod *corev1.Pod,
+	nsLabels map[string]string,
+	ownerKind string,
+	accounts evaluator.PullSecretLookup,
+	exemptions []shieldv1alpha1.ShieldExemption,
+) (map[string]bool, error) {
+	if policy == nil || policy.IsDisabled() || !policy.ShouldApplyToNamespace(pod.Namespace, nsLabels) ||
+		!policy.ShouldApplyToOwnerKind(ownerKind) || !policy.ShouldEvaluatePod(pod) {
+		return nil, nil
+	}
+	if len(policy.Spec.NodeSelector) > 0 {
+		node, err := scheduledNode(ctx, c, pod)
+		if err != nil {
+			return nil, err
+		}
+		if node == nil || !policy.ShouldApplyToNode(node.Labels) {
+			return nil, nil
+		}
+	}
+
+	checks := make(map[string]bool)
+	violations, _ := evaluator.ApplyRegistryOverrides(pod, policy, podEvaluator.EvaluateWith(ctx, pod, policy, accounts))
+	violations, _, _ = applyExemptions(pod, violations, exemptions)
+	for _, violation := range violations {
+		check := violation.EventType
+		if violation.Rule != "" {
+			check = shieldv1alpha1.CustomRuleCheck(violation.Rule)
+		}
+		checks[check] = true
+	}
+	return checks, nil
+}
+
+// checksOnlyIn returns the checks of a that b lacks, sorted
+func checksOnlyIn(a, b map[string]bool) []string {
+	var only []string
+	for check := range a {
+		if !b[check] {
+			only = append(only, check)
+		}
+	}
+	sort.Strings(only)
+	return only
+}
+
+// PreviewHandler serves PreviewPolicyChange for a candidate ShieldPolicy POSTed as
+// YAML or JSON. The candidate is compared with the policy of the same name in the
+// cluster, if any, so CI can see which pods a change would newly flag before it is
+// applied. At most maxConcurrentPreviews requests are evaluated at once, others
+// are answered with 429.
+func PreviewHandler(c client.Reader, reader client.Reader, podEvaluator *evaluator.Evaluator, system *protection.SystemNamespaces) http.Handler {
+	logger := ctrl.Log.WithName("policy-preview")
+	slots := make(chan struct{}, maxConcurrentPreviews)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		default:
+			w.Header().Set("Retry-After", "10")
+			http.Error(w, "too many previews in progress", http.StatusTooManyRequests)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxPreviewBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		candidate := &shieldv1alpha1.ShieldPolicy{}
+		if err := yaml.UnmarshalStrict(body, candidate); err != nil {
+			http.Error(w, fmt.Sprintf("invalid ShieldPolicy: %v", err), http.StatusBadRequest)
+			return
+		}
+		if candidate.Name == "" {
+			http.Error(w, "invalid ShieldPolicy: metadata.name is required", http.StatusBadRequest)
+			return
+		}
+
+		current := &shieldv1alpha1.ShieldPolicy{}
+		if err := reader.Get(req.Context(), client.ObjectKey{Name: candidate.Name}, current); err != nil {
+			if !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to read current ShieldPolicy", "policy", candidate.Name)
+				http.Error(w, "failed to read current policy", http.StatusInternalServerError)
+				return
+			}
+			current = nil
+		}
+
+		preview, err := PreviewPolicyChange(req.Context(), logger, c, reader, podEvaluator, system, current, candidate)
+		if err != nil {
+			logger.Error(err, "Failed to preview ShieldPolicy change", "policy", candidate.Name)
+			http.Error(w, "failed to preview policy change", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			logger.Error(err, "Failed to write policy preview")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// serviceAccountCache memoizes ServiceAccount lookups for the duration of one reconcile,
+// so a pod evaluated against several policies fetches its ServiceAccount at most once.
+// It is safe for concurrent use, since an evaluation that overran its timeout may still
+// be reading from it.
+type serviceAccountCache struct {
+	reader client.Reader
+	logger logr.Logger
+
+	mu       sync.Mutex
+	accounts map[types.NamespacedName]*corev1.ServiceAccount
+}
+
+// newServiceAccountCache creates an empty serviceAccountCache reading through reader
+func newServiceAccountCache(reader client.Reader, logger logr.Logger) *serviceAccountCache {
+	return &serviceAccountCache{
+		reader:   reader,
+		logger:   logger,
+		accounts: make(map[types.NamespacedName]*corev1.ServiceAccount),
+	}
+}
+
+// PullSecrets returns the imagePullSecrets of a ServiceAccount. Lookup failures are
+// logged and treated as an account without pull secrets.
+func (c *serviceAccountCache) PullSecrets(ctx context.Context, namespace, name string) []corev1.LocalObjectReference {
+	if name == "" {
+		name = "default"
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	account, ok := c.accounts[key]
+	if !ok {
+		account = &corev1.ServiceAccount{}
+		if err := c.reader.Get(ctx, key, account); err != nil {
+			c.logger.Info("Failed to fetch ServiceAccount for pull secret check",
+				"serviceAccount", key.String(),
+				"error", err.Error(),
+			)
+			account = nil
+		}
+		c.accounts[key] = account
+	}
+
+	if account == nil {
+		return nil
+	}
+	return account.ImagePullSecrets
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+const (
+	// quarantineNetworkPolicy is the NetworkPolicy isolating quarantined pods, one per namespace
+	quarantineNetworkPolicy = "kube-shield-quarantine"
+
+	// manualQuarantinePolicy is the policy name reported for quarantines requested on a pod
+	manualQuarantinePolicy = "manual"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;create
+
+// quarantineRequested returns true if responders asked for the pod to be isolated
+// and it is not isolated yet
+func quarantineRequested(pod *corev1.Pod) bool {
+	return pod.Annotations[shieldv1alpha1.QuarantineNowAnnotation] == "true" &&
+		pod.Labels[shieldv1alpha1.QuarantinedLabel] != "true"
+}
+
+// quarantine isolates the pod from the network: it makes sure the namespace has the
+// deny-all NetworkPolicy selecting quarantined pods and labels the pod with
+// QuarantinedLabel. The pod keeps running so it can be investigated.
+func (r *PodReconciler) quarantine(ctx context.Context, pod *corev1.Pod) error {
+	if err := r.ensureQuarantinePolicy(ctx, pod.Namespace); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[shieldv1alpha1.QuarantinedLabel] = "true"
+	if err := r.Patch(ctx, pod, patch); err != nil {
+		return fmt.Errorf("labeling pod as quarantined: %w", err)
+	}
+	return nil
+}
+
+// ensureQuarantinePolicy creates the namespace's deny-all NetworkPolicy for
+// quarantined pods unless it already exists
+func (r *PodReconciler) ensureQuarantinePolicy(ctx context.Context, namespace string) error {
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: quarantineNetworkPolicy}, existing)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("reading quarantine NetworkPolicy: %w", err)
+	}
+
+	// Listing both policy types without any rule denies all ingress and egress
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quarantineNetworkPolicy,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "kube-shield"},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{shieldv1alpha1.QuarantinedLabel: "true"},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+	if err := r.Create(ctx, policy); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating quarantine NetworkPolicy: %w", err)
+	}
+	return nil
+}
+
+// handleQuarantineRequest isolates a pod carrying QuarantineNowAnnotation, whatever
+// the policies say, and reports it as MANUAL_QUARANTINE
+func (r *PodReconciler) handleQuarantineRequest(ctx context.Context, logger logr.Logger, pod *corev1.Pod) error {
+	if err := r.quarantine(ctx, pod); err != nil {
+		return err
+	}
+
+	logger.Info("Quarantined pod on request", "annotation", shieldv1alpha1.QuarantineNowAnnotation)
+	r.Recorder.Eventf(pod, corev1.EventTypeWarning, "Quarantined",
+		"Pod isolated from the network by NetworkPolicy %s on request", quarantineNetworkPolicy)
+	event := audit.SecurityEvent{
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		EventType:   "MANUAL_QUARANTINE",
+		Severity:    "HIGH",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Reason:      "Quarantine requested on the pod",
+		Action:      audit.ActionQuarantined,
+		PolicyName:  manualQuarantinePolicy,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Pod '%s' was isolated from the network with NetworkPolicy '%s' after %s was set", pod.Name, quarantineNetworkPolicy, shieldv1alpha1.QuarantineNowAnnotation),
+	}
+	r.sendSecurityEvent(ctx, logger, event)
+	r.recordEnforcement(ctx, logger, pod, event)
+	return nil
+}
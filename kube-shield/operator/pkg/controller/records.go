@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/engine"
+)
+
+// recordTimeout bounds the creation of one ShieldEnforcementRecord, so a slow API
+// server delays enforcement as little as possible
+const recordTimeout = 5 * time.Second
+
+// +kubebuilder:rbac:groups=shield.kubeshield.io,resources=shieldenforcementrecords,verbs=list;create;delete
+
+// recordEnforcement keeps a ShieldEnforcementRecord of an action taken on the pod in
+// its namespace when EnforcementRecords is set. Recording is best-effort: failures
+// are logged and never affect enforcement.
+func (r *PodReconciler) recordEnforcement(ctx context.Context, logger logr.Logger, pod *corev1.Pod, event audit.SecurityEvent) {
+	if !r.Options.EnforcementRecords {
+		return
+	}
+	engine.Classify(&event)
+
+	ctx, cancel := context.WithTimeout(ctx, recordTimeout)
+	defer cancel()
+
+	record := &shieldv1alpha1.ShieldEnforcementRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: pod.Name + "-",
+			Namespace:    pod.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by":   "kube-shield",
+				shieldv1alpha1.RecordPolicyLabel: event.PolicyName,
+			},
+		},
+		Spec: shieldv1alpha1.ShieldEnforcementRecordSpec{
+			Timestamp:      metav1.Now(),
+			PodName:        pod.Name,
+			PodUID:         string(pod.UID),
+			NodeName:       pod.Spec.NodeName,
+			Policy:         event.PolicyName,
+			EventType:      event.EventType,
+			Rule:           event.Rule,
+			ReasonCode:     event.ReasonCode,
+			Container:      event.Container,
+			Image:          r.Options.Sanitizer.Text(event.Image),
+			ResolvedDigest: event.ResolvedDigest,
+			Reason:         r.Options.Sanitizer.Text(event.Reason),
+			Outcome:        event.Action,
+		},
+	}
+	if err := r.Create(ctx, record); err != nil {
+		logger.Error(err, "Failed to record enforcement", "outcome", event.Action)
+	}
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/engine"
+)
+
+// reevaluationJitter spreads periodic re-evaluations of pods created together over
+// this fraction of the interval, so they do not all run at once
+const reevaluationJitter = 0.1
+
+// reevaluationInterval returns the shortest ReevaluationInterval of the policies
+// applying to the pod's namespace, or 0 if none sets one
+func reevaluationInterval(pod *corev1.Pod, policies []shieldv1alpha1.ShieldPolicy, nsLabels map[string]string) time.Duration {
+	var interval time.Duration
+	for i := range policies {
+		policy := &policies[i]
+		if policy.Spec.ReevaluationInterval == nil || policy.Spec.ReevaluationInterval.Duration <= 0 {
+			continue
+		}
+		if !engine.AppliesToNamespace(policy, pod.Namespace, nsLabels) {
+			continue
+		}
+		if interval == 0 || policy.Spec.ReevaluationInterval.Duration < interval {
+			interval = policy.Spec.ReevaluationInterval.Duration
+		}
+	}
+	return interval
+}
+
+// nextReevaluation returns how long to wait before re-checking a pod evaluated at
+// evaluatedAt, with jitter
+func nextReevaluation(interval time.Duration, evaluatedAt, now time.Time) time.Duration {
+	delay := wait.Jitter(interval, reevaluationJitter) - now.Sub(evaluatedAt)
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/evaluator"
+)
+
+const (
+	// retryJitter spreads the requeues of pods that failed together, so a mass violation
+	// during an API server outage does not come back as a thundering herd
+	retryJitter = 0.2
+
+	// forbiddenRetry is how long a pod whose reconcile the operator's RBAC forbids
+	// waits before it is tried again, in case the permission was granted meanwhile
+	forbiddenRetry = 10 * time.Minute
+)
+
+// errorClass decides how a reconcile reacts to an API error
+type errorClass int
+
+const (
+	// errorBackoff is a transient error, retried after a jittered exponential backoff
+	errorBackoff errorClass = iota
+
+	// errorDone means the object is gone and there is nothing left to do
+	errorDone
+
+	// errorRetry is an optimistic concurrency conflict, retried right away
+	errorRetry
+
+	// errorForbidden will not go away by retrying, it needs RBAC to be fixed
+	errorForbidden
+)
+
+// classifyError maps an API error to the way it is handled
+func classifyError(err error) errorClass {
+	switch {
+	case errors.IsNotFound(err):
+		return errorDone
+	case errors.IsConflict(err):
+		return errorRetry
+	case errors.IsForbidden(err):
+		return errorForbidden
+	default:
+		return errorBackoff
+	}
+}
+
+// jitteredBackoff returns the exponential requeue delay after the given number of
+// consecutive failures, stretched by up to retryJitter
+func jitteredBackoff(failures int) time.Duration {
+	return wait.Jitter(enforcementBackoff(failures), retryJitter)
+}
+
+// requeueOnError turns an API error from reconciling a pod into a Result instead of
+// returning it, which would have the rate limiter retry within milliseconds. A
+// forbidden request is retried after forbiddenRetry and marks the enforcing policies
+// degraded, as they cannot act on the pod until the operator's RBAC is fixed.
+func (r *PodReconciler) requeueOnError(ctx context.Context, logger logr.Logger, pod types.NamespacedName, err error, msg string) ctrl.Result {
+	switch classifyError(err) {
+	case errorDone:
+		return ctrl.Result{}
+	case errorRetry:
+		return ctrl.Result{Requeue: true}
+	case errorForbidden:
+		backoff := wait.Jitter(forbiddenRetry, retryJitter)
+		logger.Error(err, msg+", retrying once the operator's RBAC may allow it", "requeueAfter", backoff)
+		r.markAccessForbidden(ctx, logger, msg, err)
+		return ctrl.Result{RequeueAfter: backoff}
+	}
+
+	attempts := r.retries.record(pod)
+	backoff := jitteredBackoff(attempts)
+	logger.Error(err, msg, "attempt", attempts, "requeueAfter", backoff)
+	return ctrl.Result{RequeueAfter: backoff}
+}
+
+// markAccessForbidden sets EnforcementDegraded on the enforcing policies when a read
+// the pod controller needs is forbidden. The condition is cleared by the next
+// successful termination, like other degradations.
+func (r *PodReconciler) markAccessForbidden(ctx context.Context, logger logr.Logger, msg string, err error) {
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if listErr := r.List(ctx, policies); listErr != nil {
+		logger.Error(listErr, "Failed to list ShieldPolicies to mark them degraded")
+		return
+	}
+	message := fmt.Sprintf("%s, the operator's RBAC does not allow it: %v", msg, err)
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if policy.IsDisabled() || evaluator.ActionFor(policy) != audit.ActionTerminated {
+			continue
+		}
+		writeErr := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+				Type:               conditionEnforcementDegraded,
+				Status:             metav1.ConditionTrue,
+				Reason:             "AccessForbidden",
+				Message:            message,
+				ObservedGeneration: policy.Generation,
+			})
+		})
+		if writeErr != nil {
+			logger.Error(writeErr, "Failed to mark ShieldPolicy as degraded", "policy", policy.Name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClassifyError(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	forbidden := errors.NewForbidden(pods, "web", fmt.Errorf("no RBAC"))
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{name: "not found", err: errors.NewNotFound(pods, "web"), want: errorDone},
+		{name: "conflict", err: errors.NewConflict(pods, "web", fmt.Errorf("stale")), want: errorRetry},
+		{name: "forbidden", err: forbidden, want: errorForbidden},
+		{name: "wrapped forbidden", err: fmt.Errorf("deleting pod: %w", forbidden), want: errorForbidden},
+		{name: "server timeout", err: errors.NewServerTimeout(pods, "delete", 1), want: errorBackoff},
+		{name: "generic", err: fmt.Errorf("connection refused"), want: errorBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJitteredBackoffBounds(t *testing.T) {
+	for failures := 1; failures <= 12; failures++ {
+		base := enforcementBackoff(failures)
+		if base < enforcementBackoffBase || base > enforcementBackoffMax {
+			t.Fatalf("enforcementBackoff(%d) = %s, want within [%s, %s]", failures, base, enforcementBackoffBase, enforcementBackoffMax)
+		}
+		for i := 0; i < 20; i++ {
+			if got := jitteredBackoff(failures); got < base || got > base+time.Duration(float64(base)*retryJitter) {
+				t.Fatalf("jitteredBackoff(%d) = %s, want within %s plus %v jitter", failures, got, base, retryJitter)
+			}
+		}
+	}
+}
+
+func TestRequeueOnError(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+	r, _ := newTestPodReconciler(t)
+	ctx := context.Background()
+
+	if result := r.requeueOnError(ctx, logr.Discard(), pod, errors.NewNotFound(pods, "web"), "Failed"); result.Requeue || result.RequeueAfter != 0 {
+		t.Errorf("not found: result = %+v, want no requeue", result)
+	}
+	if result := r.requeueOnError(ctx, logr.Discard(), pod, errors.NewConflict(pods, "web", fmt.Errorf("stale")), "Failed"); !result.Requeue {
+		t.Errorf("conflict: result = %+v, want an immediate requeue", result)
+	}
+	forbidden := errors.NewForbidden(pods, "web", fmt.Errorf("no RBAC"))
+	if result := r.requeueOnError(ctx, logr.Discard(), pod, forbidden, "Failed"); result.RequeueAfter < forbiddenRetry ||
+		result.RequeueAfter > forbiddenRetry+time.Duration(float64(forbiddenRetry)*retryJitter) {
+		t.Errorf("forbidden: requeued after %s, want %s plus jitter", result.RequeueAfter, forbiddenRetry)
+	}
+
+	// Transient errors back off further with every consecutive failure of the pod
+	for failures := 1; failures <= 3; failures++ {
+		base := enforcementBackoff(failures)
+		result := r.requeueOnError(ctx, logr.Discard(), pod, fmt.Errorf("connection refused"), "Failed")
+		if result.RequeueAfter < base || result.RequeueAfter > base+time.Duration(float64(base)*retryJitter) {
+			t.Errorf("failure %d: requeued after %s, want %s plus jitter", failures, result.RequeueAfter, base)
+		}
+	}
+}
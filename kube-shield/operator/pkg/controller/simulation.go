@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/listing"
+)
+
+const (
+	// phaseSimulated is the status phase of a policy carrying SimulateAnnotation
+	phaseSimulated = "Simulated"
+
+	// simulationPodsPerSecond limits how fast a simulation evaluates pods, so a dry run
+	// on a large cluster does not compete with live enforcement
+	simulationPodsPerSecond = 50
+
+	// simulationExamples caps the violating pods named in a simulation summary
+	simulationExamples = 20
+)
+
+// simulationRuns tracks the simulations in progress, one per policy. A newer
+// generation of a policy cancels the run of the previous one.
+type simulationRuns struct {
+	mu      sync.Mutex
+	running map[types.UID]simulationRun
+}
+
+// simulationRun is a simulation in progress
+type simulationRun struct {
+	generation int64
+	cancel     context.CancelFunc
+}
+
+// newSimulationRuns creates an empty simulationRuns
+func newSimulationRuns() *simulationRuns {
+	return &simulationRuns{running: make(map[types.UID]simulationRun)}
+}
+
+// begin registers a run for the policy's generation and returns its context, or
+// false when that generation is already being simulated
+func (s *simulationRuns) begin(ctx context.Context, policy *shieldv1alpha1.ShieldPolicy) (context.Context, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, ok := s.running[policy.UID]; ok {
+		if run.generation == policy.Generation {
+			return nil, false
+		}
+		run.cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.running[policy.UID] = simulationRun{generation: policy.Generation, cancel: cancel}
+	return ctx, true
+}
+
+// end unregisters the run of the policy's generation, unless a newer one replaced it
+func (s *simulationRuns) end(policy *shieldv1alpha1.ShieldPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, ok := s.running[policy.UID]; ok && run.generation == policy.Generation {
+		run.cancel()
+		delete(s.running, policy.UID)
+	}
+}
+
+// stop cancels the run of a policy that is no longer simulated
+func (s *simulationRuns) stop(uid types.UID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run, ok := s.running[uid]; ok {
+		run.cancel()
+		delete(s.running, uid)
+	}
+}
+
+// simulate marks the policy as simulated and, unless its current generation was
+// already summarized, evaluates the existing pods in the background so other
+// policies keep being reconciled meanwhile
+func (r *ShieldPolicyReconciler) simulate(ctx context.Context, logger logr.Logger, policy *shieldv1alpha1.ShieldPolicy) error {
+	err := writePolicyStatus(ctx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+		policy.Status.Phase = phaseSimulated
+		if summary := policy.Status.Simulation; summary == nil || summary.ObservedGeneration != policy.Generation {
+			policy.Status.Message = "Simulating the policy against existing pods"
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if summary := policy.Status.Simulation; summary != nil && summary.ObservedGeneration == policy.Generation {
+		return nil
+	}
+	runCtx, ok := r.simulations.begin(ctx, policy)
+	if !ok {
+		return nil
+	}
+
+	policy = policy.DeepCopy()
+	go func() {
+		defer r.simulations.end(policy)
+		logger.Info("Simulating ShieldPolicy against existing pods", "generation", policy.Generation)
+
+		summary, err := r.simulatePods(runCtx, logger, policy)
+		if err != nil {
+			if runCtx.Err() == nil {
+				logger.Error(err, "ShieldPolicy simulation failed")
+			}
+			return
+		}
+
+		err = writePolicyStatus(runCtx, r.Client, r.APIReader, policy, func(policy *shieldv1alpha1.ShieldPolicy) {
+			// The policy changed or was activated while the pods were evaluated
+			if !policy.IsSimulated() || policy.Generation != summary.ObservedGeneration {
+				return
+			}
+			policy.Status.Simulation = summary
+			policy.Status.Message = fmt.Sprintf("Simulation: %d of %d pods in scope would violate the policy",
+				summary.ViolatingPods, summary.PodsEvaluated)
+		})
+		if err != nil && runCtx.Err() == nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to record ShieldPolicy simulation")
+			return
+		}
+		logger.Info("ShieldPolicy simulation finished", "podsEvaluated", summary.PodsEvaluated, "violatingPods", summary.ViolatingPods)
+	}()
+	return nil
+}
+
+// simulatePods evaluates every pod in the policy's scope, at most
+// simulationPodsPerSecond of them per second. Like the pod controller, it leaves
+// out skipped system namespaces and exempt pods, and applies active ShieldExemptions.
+func (r *ShieldPolicyReconciler) simulatePods(ctx context.Context, logger logr.Logger, policy *shieldv1alpha1.ShieldPolicy) (*shieldv1alpha1.SimulationSummary, error) {
+	summary := &shieldv1alpha1.SimulationSummary{
+		ObservedGeneration: policy.Generation,
+		ViolationsByType:   make(map[string]int64),
+	}
+
+	ticker := time.NewTicker(time.Second / simulationPodsPerSecond)
+	defer ticker.Stop()
+
+	accounts := newServiceAccountCache(r.APIReader, logger)
+	owners := newOwnerResolver(r.APIReader)
+	namespaces := make(map[string]map[string]string)
+	exemptions := make(map[string][]shieldv1alpha1.ShieldExemption)
+	now := time.Now()
+	pods := &corev1.PodList{}
+	err := listing.Paginate(ctx, r.APIReader, pods, listing.DefaultPageSize, func() error {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			// Leave out what the pod controller would never look at
+			if r.System != nil && r.System.Skip(pod.Namespace) {
+				continue
+			}
+			if _, ok := exemptUntil(logger, pod); ok {
+				continue
+			}
+
+			nsLabels, ok := namespaces[pod.Namespace]
+			if !ok && needsNamespaceLabels([]shieldv1alpha1.ShieldPolicy{*policy}) {
+				var err error
+				if nsLabels, err = namespaceLabels(ctx, r.Client, pod.Namespace); err != nil {
+					return err
+				}
+				namespaces[pod.Namespace] = nsLabels
+			}
+			if !policy.ShouldApplyToNamespace(pod.Namespace, nsLabels) {
+				continue
+			}
+
+			if len(policy.Spec.NodeSelector) > 0 {
+				node, err := scheduledNode(ctx, r.Client, pod)
+				if err != nil {
+					return err
+				}
+				if node == nil || !policy.ShouldApplyToNode(node.Labels) {
+					continue
+				}
+			}
+
+			if len(policy.Spec.MatchOwnerKinds) > 0 {
+				kind, err := owners.topLevelKind(ctx, pod)
+				if err != nil {
+					return err
+				}
+				if !policy.ShouldApplyToOwnerKind(kind) {
+					continue
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			active, ok := exemptions[pod.Namespace]
+			if !ok {
+				var err error
+				if active, err = listActiveExemptions(ctx, r.Client, pod.Namespace, now); err != nil {
+					return err
+				}
+				exemptions[pod.Namespace] = active
+			}
+
+			violations := r.Evaluator.EvaluateWith(ctx, pod, policy, accounts)
+			violations, _ = evaluator.ApplyRegistryOverrides(pod, policy, violations)
+			violations, _, _ = applyExemptions(pod, violations, active)
+			summary.PodsEvaluated++
+			if len(violations) == 0 {
+				continue
+			}
+			summary.ViolatingPods++
+			for _, violation := range violations {
+				summary.ViolationsByType[violation.EventType]++
+			}
+			if len(summary.ExamplePods) < simulationExamples {
+				summary.ExamplePods = append(summary.ExamplePods, pod.Namespace+"/"+pod.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("evaluating pods: %w", err)
+	}
+
+	summary.Time = metav1.Now()
+	return summary, nil
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DaemonSetMarker is added to violations of DaemonSet pods that were only audited
+// because the policy does not set EnforceDaemonSetPods
+const DaemonSetMarker = "DAEMONSET_POD"
+
+// isMirrorPod reports whether the pod is the API server's mirror of a static pod.
+// The kubelet runs static pods from its manifest directory and recreates them
+// whatever happens to the mirror, so deleting one terminates nothing.
+func isMirrorPod(pod *corev1.Pod) bool {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return true
+	}
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "Node" && owner.APIVersion == "v1"
+}
+
+// isDaemonSetPod reports whether the pod belongs to a DaemonSet, which recreates a
+// deleted pod on the same node straight away
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "DaemonSet"
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// statusLocks serializes the status writes of each policy across the operator's
+// controllers, so concurrent reconciles wait their turn instead of racing each other
+// through their conflict retries
+var statusLocks = newPolicyLocks()
+
+// policyLocks hands out one mutex per policy. Entries only live while a write holds
+// or waits for them, so deleted policies leave nothing behind.
+type policyLocks struct {
+	mu    sync.Mutex
+	locks map[types.NamespacedName]*policyLock
+}
+
+// policyLock is the mutex of one policy and the number of writers using it
+type policyLock struct {
+	sync.Mutex
+	users int
+}
+
+// newPolicyLocks creates an empty policyLocks
+func newPolicyLocks() *policyLocks {
+	return &policyLocks{locks: make(map[types.NamespacedName]*policyLock)}
+}
+
+// lock acquires the mutex of a policy and returns the function releasing it
+func (l *policyLocks) lock(policy types.NamespacedName) func() {
+	l.mu.Lock()
+	m, ok := l.locks[policy]
+	if !ok {
+		m = &policyLock{}
+		l.locks[policy] = m
+	}
+	m.users++
+	l.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		l.mu.Lock()
+		if m.users--; m.users == 0 {
+			delete(l.locks, policy)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// writePolicyStatus applies mutate to the policy and writes its status, skipping the
+// write when mutate left the status unchanged. When the write conflicts with a
+// concurrent update, the policy is re-read from reader and mutate is applied again to
+// the fresh copy, so increments made by other reconciles are never overwritten.
+// mutate must therefore be safe to run more than once. The policy's lock is only
+// held during an attempt, not while backing off between them.
+func writePolicyStatus(
+	ctx context.Context,
+	c client.Client,
+	reader client.Reader,
+	policy *shieldv1alpha1.ShieldPolicy,
+	mutate func(*shieldv1alpha1.ShieldPolicy),
+) error {
+	if reader == nil {
+		reader = c
+	}
+	refetch := false
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		defer statusLocks.lock(client.ObjectKeyFromObject(policy))()
+		if refetch {
+			if err := reader.Get(ctx, client.ObjectKeyFromObject(policy), policy); err != nil {
+				return err
+			}
+		}
+		refetch = true
+
+		before := policy.Status.DeepCopy()
+		mutate(policy)
+		if equality.Semantic.DeepEqual(before, &policy.Status) {
+			return nil
+		}
+		return c.Status().Update(ctx, policy)
+	})
+}
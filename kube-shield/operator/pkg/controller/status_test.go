@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// attemptsKey carries a writer's count of status updates through its context
+type attemptsKey struct{}
+
+func TestPolicyLocksDropIdleEntries(t *testing.T) {
+	locks := newPolicyLocks()
+	policy := types.NamespacedName{Name: "restricted"}
+
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer locks.lock(policy)()
+			counter++
+		}()
+	}
+	wg.Wait()
+
+	if counter != 50 {
+		t.Errorf("counter = %d, want 50", counter)
+	}
+	if len(locks.locks) != 0 {
+		t.Errorf("%d locks left after all writers released them, want 0", len(locks.locks))
+	}
+}
+
+func TestPolicyLocksKeepEntryWhileWaiting(t *testing.T) {
+	locks := newPolicyLocks()
+	policy := types.NamespacedName{Name: "restricted"}
+
+	unlock := locks.lock(policy)
+	acquired := make(chan func())
+	go func() { acquired <- locks.lock(policy) }()
+
+	// Wait for the second writer to register before releasing the first
+	for {
+		locks.mu.Lock()
+		users := locks.locks[policy].users
+		locks.mu.Unlock()
+		if users == 2 {
+			break
+		}
+	}
+	unlock()
+
+	(<-acquired)()
+	if len(locks.locks) != 0 {
+		t.Errorf("%d locks left after all writers released them, want 0", len(locks.locks))
+	}
+}
+
+// Concurrent increments from stale copies of the policy, some of them rejected with a
+// conflict, must all land in ViolationsCount
+func TestWritePolicyStatusConcurrentIncrements(t *testing.T) {
+	const writers = 20
+	policy := &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}}
+	c := fake.NewClientBuilder().
+		WithScheme(newTestScheme(t)).
+		WithObjects(policy).
+		WithStatusSubresource(&shieldv1alpha1.ShieldPolicy{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// A writer whose first update conflicted is rejected once more after re-reading
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				attempts := ctx.Value(attemptsKey{}).(*int)
+				*attempts++
+				if *attempts == 2 {
+					return errors.NewConflict(shieldv1alpha1.Resource("shieldpolicies"), obj.GetName(), nil)
+				}
+				return c.SubResource(subResource).Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	// Every writer starts from the same copy, so all but one first write is stale
+	stale := &shieldv1alpha1.ShieldPolicy{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(policy), stale); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.WithValue(context.Background(), attemptsKey{}, new(int))
+			errs <- writePolicyStatus(ctx, c, c, stale.DeepCopy(), func(policy *shieldv1alpha1.ShieldPolicy) {
+				policy.Status.ViolationsCount++
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(policy), policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Status.ViolationsCount != writers {
+		t.Errorf("ViolationsCount = %d, want %d", policy.Status.ViolationsCount, writers)
+	}
+}
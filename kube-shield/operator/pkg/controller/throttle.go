@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// ThrottledMarker is added to violations whose termination was skipped because the
+// pod's owner already had MaxTerminationsPerOwner pods terminated in the window
+const ThrottledMarker = "TERMINATION_THROTTLED"
+
+// ownerCounterKey names the termination counter of the pod's controller, or "" for
+// pods without one, which are never throttled
+func ownerCounterKey(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return ""
+	}
+	return fmt.Sprintf("terminations.%s.%s.%s", pod.Namespace, owner.Kind, owner.Name)
+}
+
+// reserveTermination counts a termination against the pod's owner unless the owner
+// used up its terminations for the current window, in which case allowed is false. A
+// replacement pod recreated by its controller would otherwise be terminated again
+// and again. Checking and counting is one step, so concurrent reconciles and replicas
+// cannot both take the last termination. reserved reports whether a termination was
+// counted, to be given back with releaseTermination if the pod is not terminated
+// after all. When the counters cannot be updated, terminations go ahead.
+func (r *PodReconciler) reserveTermination(ctx context.Context, logger logr.Logger, pod *corev1.Pod) (allowed, reserved bool) {
+	key := ownerCounterKey(pod)
+	if r.Counters == nil || r.Options.MaxTerminationsPerOwner <= 0 || key == "" {
+		return true, false
+	}
+	reserved, err := r.Counters.Reserve(ctx, key, int64(r.Options.MaxTerminationsPerOwner), r.Options.TerminationThrottleWindow)
+	if err != nil {
+		logger.Error(err, "Failed to update termination counter, not throttling")
+		return true, false
+	}
+	return reserved, reserved
+}
+
+// releaseTermination gives back a termination reserveTermination counted for a pod
+// that was not terminated
+func (r *PodReconciler) releaseTermination(ctx context.Context, logger logr.Logger, pod *corev1.Pod) {
+	if _, err := r.Counters.Add(ctx, ownerCounterKey(pod), -1, r.Options.TerminationThrottleWindow); err != nil {
+		logger.Error(err, "Failed to release termination counter")
+	}
+}
+
+// throttle downgrades a termination to an audit
+func throttle(violation audit.SecurityEvent) audit.SecurityEvent {
+	violation.Action = audit.ActionAudit
+	violation.Markers = append(violation.Markers, ThrottledMarker)
+	return violation
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// recordSpans routes the operator's spans to an in-memory exporter for the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestReconcileSpanHierarchy(t *testing.T) {
+	exporter := recordSpans(t)
+
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: shieldv1alpha1.EnforcementModeEnforce},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "uid-web"},
+		Spec: corev1.PodSpec{
+			HostNetwork: true,
+			Containers:  []corev1.Container{{Name: "app", Image: "nginx:1.25"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, _ := newTestPodReconciler(t, policy, pod)
+
+	if _, err := r.Reconcile(context.Background(), podRequest(pod)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if _, ok := byName[span.Name]; !ok {
+			byName[span.Name] = span
+		}
+	}
+	root, ok := byName["PodReconciler.Reconcile"]
+	if !ok {
+		t.Fatalf("no PodReconciler.Reconcile span in %d spans", len(spans))
+	}
+	if root.Parent.IsValid() {
+		t.Errorf("PodReconciler.Reconcile has parent %s, want a root span", root.Parent.SpanID())
+	}
+	for _, name := range []string{"EvaluatePolicy", "AuditSink.Deliver", "DeletePod"} {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("no %s span recorded", name)
+			continue
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("%s parent = %s, want PodReconciler.Reconcile %s", name, span.Parent.SpanID(), root.SpanContext.SpanID())
+		}
+	}
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("%s is in trace %s, want %s", span.Name, span.SpanContext.TraceID(), root.SpanContext.TraceID())
+		}
+	}
+	if attrs := byName["EvaluatePolicy"].Attributes; len(attrs) == 0 {
+		t.Error("EvaluatePolicy span has no attributes")
+	}
+}
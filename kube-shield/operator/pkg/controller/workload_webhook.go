@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// WorkloadWebhookPath is where the validating admission webhook for workload
+// controllers is served
+const WorkloadWebhookPath = "/validate-workloads"
+
+// WorkloadValidator is the validating admission webhook for Deployments,
+// StatefulSets, DaemonSets and CronJobs. It checks only the image rules of the
+// PodValidator, registries and repositories, against the workload's pod template,
+// so a bad image is refused once instead of every pod the controller keeps
+// recreating. Other checks are left to the pods themselves.
+type WorkloadValidator struct {
+	pods *PodValidator
+}
+
+// NewWorkloadValidator creates a WorkloadValidator sharing the PodReconciler's configuration
+func NewWorkloadValidator(reconciler *PodReconciler) *WorkloadValidator {
+	return &WorkloadValidator{pods: NewPodValidator(reconciler)}
+}
+
+// +kubebuilder:webhook:path=/validate-workloads,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps;batch,resources=deployments;statefulsets;daemonsets;cronjobs,verbs=create;update,versions=v1,name=vworkload.kubeshield.io,admissionReviewVersions=v1
+
+// Handle implements admission.Handler
+func (v *WorkloadValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	kind := req.Kind.Kind
+	logger := log.FromContext(ctx).WithValues("kind", kind, "workload", req.Namespace+"/"+req.Name)
+
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	template, err := podTemplate(kind, req.Object.Raw)
+	if err != nil {
+		metrics.WorkloadAdmissionDecisions.WithLabelValues(kind, admissionError).Inc()
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if template == nil {
+		return admission.Allowed("")
+	}
+
+	// Scaling or relabeling a workload that already runs its images must keep
+	// working, even when a policy was tightened since
+	if req.Operation == admissionv1.Update {
+		old, err := podTemplate(kind, req.OldObject.Raw)
+		if err == nil && old != nil && reflect.DeepEqual(templateImages(old), templateImages(template)) {
+			return admission.Allowed("")
+		}
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	pod.Namespace = req.Namespace
+	pod.Name = req.Name
+
+	denials, warnings, err := v.pods.review(ctx, pod, engine.IsTemplateCheck, true)
+	if err != nil {
+		logger.Error(err, "Failed to review pod template")
+		metrics.WorkloadAdmissionDecisions.WithLabelValues(kind, admissionError).Inc()
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	switch {
+	case len(denials) > 0:
+		logger.Info("Denying workload whose pod template violates ShieldPolicies", "violations", denials)
+		metrics.WorkloadAdmissionDecisions.WithLabelValues(kind, admissionDenied).Inc()
+		return admission.Denied(strings.Join(denials, "; ")).WithWarnings(warnings...)
+	case len(warnings) > 0:
+		metrics.WorkloadAdmissionDecisions.WithLabelValues(kind, admissionWarned).Inc()
+		return admission.Allowed("").WithWarnings(warnings...)
+	default:
+		metrics.WorkloadAdmissionDecisions.WithLabelValues(kind, admissionAllowed).Inc()
+		return admission.Allowed("")
+	}
+}
+
+// podTemplate decodes a workload of the given kind and returns its pod template, or
+// nil for kinds without one
+func podTemplate(kind string, raw []byte) (*corev1.PodTemplateSpec, error) {
+	var (
+		workload interface{}
+		template func() *corev1.PodTemplateSpec
+	)
+	switch kind {
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		workload, template = deployment, func() *corev1.PodTemplateSpec { return &deployment.Spec.Template }
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		workload, template = statefulSet, func() *corev1.PodTemplateSpec { return &statefulSet.Spec.Template }
+	case "DaemonSet":
+		daemonSet := &appsv1.DaemonSet{}
+		workload, template = daemonSet, func() *corev1.PodTemplateSpec { return &daemonSet.Spec.Template }
+	case "CronJob":
+		cronJob := &batchv1.CronJob{}
+		workload, template = cronJob, func() *corev1.PodTemplateSpec { return &cronJob.Spec.JobTemplate.Spec.Template }
+	default:
+		return nil, nil
+	}
+	if err := json.Unmarshal(raw, workload); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", kind, err)
+	}
+	return template(), nil
+}
+
+// templateImages returns the images of a pod template's containers, in order
+func templateImages(template *corev1.PodTemplateSpec) []string {
+	var images []string
+	for _, container := range template.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server
+func (v *WorkloadValidator) SetupWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(WorkloadWebhookPath, &webhook.Admission{Handler: v})
+}
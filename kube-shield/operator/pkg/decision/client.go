@@ -0,0 +1,149 @@
+// Package decision lets an external policy decision point have the final say
+// before the operator terminates a pod.
+package decision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// Decisions an external decision point can return
+const (
+	// Allow keeps the pod running and records the violation as allowed
+	Allow = "allow"
+	// Deny lets the operator terminate the pod
+	Deny = "deny"
+	// Audit keeps the pod running and reports the violation as audit-only
+	Audit = "audit"
+)
+
+// Marker is added to security events whose action was changed by the external decision point
+const Marker = "EXTERNAL_DECISION"
+
+// Request is the body POSTed to the decision endpoint
+type Request struct {
+	// Event is the security event that would cause the termination
+	Event interface{} `json:"event"`
+
+	// Pod describes the pod about to be terminated
+	Pod PodContext `json:"pod"`
+}
+
+// PodContext is the subset of the pod a decision point typically needs
+type PodContext struct {
+	Name            string                  `json:"name"`
+	Namespace       string                  `json:"namespace"`
+	UID             string                  `json:"uid"`
+	Labels          map[string]string       `json:"labels,omitempty"`
+	Annotations     map[string]string       `json:"annotations,omitempty"`
+	ServiceAccount  string                  `json:"serviceAccount,omitempty"`
+	NodeName        string                  `json:"nodeName,omitempty"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences,omitempty"`
+}
+
+// Response is the body returned by the decision endpoint
+type Response struct {
+	// Decision is one of allow, deny or audit
+	Decision string `json:"decision"`
+
+	// Reason optionally explains the decision
+	Reason string `json:"reason,omitempty"`
+}
+
+// Client asks an external decision endpoint whether a pod may be terminated
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+
+	// FailOpen keeps the pod running (audit) when the endpoint cannot be reached
+	// or answers with garbage; otherwise the operator terminates it (deny)
+	FailOpen bool
+}
+
+// NewClient creates a Client posting to url. The request timeout is taken from httpClient.
+func NewClient(url string, httpClient *http.Client, failOpen bool) *Client {
+	return &Client{
+		URL:        url,
+		HTTPClient: httpClient,
+		FailOpen:   failOpen,
+	}
+}
+
+// NewPodContext extracts the PodContext sent to the decision endpoint. Annotations
+// holding credentials are left out as sanitizer dictates, the endpoint is outside
+// the operator like any audit sink.
+func NewPodContext(pod *corev1.Pod, sanitizer audit.Sanitizer) PodContext {
+	return PodContext{
+		Name:            pod.Name,
+		Namespace:       pod.Namespace,
+		UID:             string(pod.UID),
+		Labels:          pod.Labels,
+		Annotations:     sanitizer.Annotations(pod.Annotations),
+		ServiceAccount:  pod.Spec.ServiceAccountName,
+		NodeName:        pod.Spec.NodeName,
+		OwnerReferences: pod.OwnerReferences,
+	}
+}
+
+// Decide asks the endpoint for a decision. When the endpoint fails, the fallback
+// decision is returned together with the error so callers can report it.
+func (c *Client) Decide(ctx context.Context, req Request) (Response, error) {
+	resp, err := c.post(ctx, req)
+	if err != nil {
+		return c.fallback(), err
+	}
+	switch resp.Decision {
+	case Allow, Deny, Audit:
+		return resp, nil
+	default:
+		return c.fallback(), fmt.Errorf("decision endpoint returned unknown decision %q", resp.Decision)
+	}
+}
+
+// fallback returns the decision applied when the endpoint gives no usable answer
+func (c *Client) fallback() Response {
+	if c.FailOpen {
+		return Response{Decision: Audit, Reason: "decision endpoint unavailable, failing open"}
+	}
+	return Response{Decision: Deny, Reason: "decision endpoint unavailable, failing closed"}
+}
+
+// post sends req to the endpoint and decodes its answer
+func (c *Client) post(ctx context.Context, req Request) (Response, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, httpResp.Body)
+		return Response{}, fmt.Errorf("decision endpoint returned status %d", httpResp.StatusCode)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(io.LimitReader(httpResp.Body, 1<<20)).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("decoding decision response: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,111 @@
+package decision
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// answer returns a handler replying with status and body
+func answer(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		failOpen bool
+		want     string
+		wantErr  bool
+	}{
+		{name: "allow", handler: answer(http.StatusOK, `{"decision":"allow","reason":"break-glass"}`), want: Allow},
+		{name: "deny", handler: answer(http.StatusOK, `{"decision":"deny"}`), failOpen: true, want: Deny},
+		{name: "audit", handler: answer(http.StatusOK, `{"decision":"audit"}`), want: Audit},
+		{name: "unknown decision failing open", handler: answer(http.StatusOK, `{"decision":"maybe"}`), failOpen: true, want: Audit, wantErr: true},
+		{name: "unknown decision failing closed", handler: answer(http.StatusOK, `{"decision":"maybe"}`), want: Deny, wantErr: true},
+		{name: "garbage", handler: answer(http.StatusOK, `not json`), failOpen: true, want: Audit, wantErr: true},
+		{name: "server error", handler: answer(http.StatusInternalServerError, ""), failOpen: true, want: Audit, wantErr: true},
+		{name: "redirect status", handler: answer(http.StatusNotModified, ""), want: Deny, wantErr: true},
+		{name: "no content", handler: answer(http.StatusNoContent, ""), failOpen: true, want: Audit, wantErr: true},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				answer(http.StatusOK, `{"decision":"allow"}`)(w, r)
+			},
+			want:    Deny,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			c := NewClient(server.URL, &http.Client{Timeout: 50 * time.Millisecond}, tt.failOpen)
+
+			resp, err := c.Decide(context.Background(), Request{Event: audit.SecurityEvent{EventType: "HOST_NETWORK"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decide error = %v, want error %v", err, tt.wantErr)
+			}
+			if resp.Decision != tt.want {
+				t.Errorf("Decision = %q, want %q", resp.Decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecideSendsPodContext(t *testing.T) {
+	var got Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		answer(http.StatusOK, `{"decision":"deny"}`)(w, r)
+	}))
+	defer server.Close()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "payments",
+			Name:      "web",
+			Labels:    map[string]string{"app": "web"},
+			Annotations: map[string]string{
+				"team":                    "payments",
+				"example.com/db-password": "hunter2",
+			},
+		},
+		Spec: corev1.PodSpec{ServiceAccountName: "web", NodeName: "node-1"},
+	}
+	c := NewClient(server.URL, &http.Client{Timeout: time.Second}, false)
+	if _, err := c.Decide(context.Background(), Request{Pod: NewPodContext(pod, audit.Sanitizer{Redact: true})}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Pod.Namespace != "payments" || got.Pod.Name != "web" || got.Pod.ServiceAccount != "web" || got.Pod.NodeName != "node-1" {
+		t.Errorf("pod = %+v, want payments/web on node-1 with service account web", got.Pod)
+	}
+	if got.Pod.Labels["app"] != "web" {
+		t.Errorf("labels = %v, want app=web", got.Pod.Labels)
+	}
+	if len(got.Pod.Annotations) != 1 || got.Pod.Annotations["team"] != "payments" {
+		t.Errorf("annotations = %v, want only team=payments", got.Pod.Annotations)
+	}
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// Rule is the catalog entry of an event type
+type Rule struct {
+	// EventType is the SecurityEvent's EventType
+	EventType string `json:"eventType"`
+
+	// Code is the stable ReasonCode of the event type. Codes are part of the API:
+	// tickets and dashboards key on them, so a released code is never changed or
+	// reused for another event type.
+	Code string `json:"code"`
+
+	// Severity is the event's default severity, before severityOverrides. Profile
+	// checks raise some of them at a lower severity under the restricted profile.
+	Severity string `json:"severity"`
+
+	// Remediation is a short hint on how to fix the violation, e.g. the spec field
+	// to set
+	Remediation string `json:"remediation"`
+}
+
+// catalog lists every event type the operator raises. New event types get the next
+// free code of their family.
+var catalog = []Rule{
+	{"PRIVILEGED_CONTAINER", "KS-PRIV-001", "CRITICAL", "set securityContext.privileged=false"},
+	{"PRIVILEGED_INIT_CONTAINER", "KS-PRIV-002", "CRITICAL", "set securityContext.privileged=false on the init container"},
+	{"PRIVILEGE_ESCALATION", "KS-PRIV-003", "MEDIUM", "set securityContext.allowPrivilegeEscalation=false"},
+	{"ROOT_USER", "KS-PRIV-004", "HIGH", "set securityContext.runAsUser to a non-zero UID"},
+	{"RUN_AS_NON_ROOT", "KS-PRIV-005", "MEDIUM", "set securityContext.runAsNonRoot=true"},
+	{"HOST_PROCESS", "KS-PRIV-006", "CRITICAL", "set securityContext.windowsOptions.hostProcess=false"},
+
+	{"CAPABILITIES_NOT_DROPPED", "KS-CAP-001", "MEDIUM", "set securityContext.capabilities.drop=[ALL]"},
+	{"ADDED_CAPABILITIES", "KS-CAP-002", "HIGH", "remove the capability from securityContext.capabilities.add"},
+
+	{"HOST_NETWORK", "KS-HOST-001", "HIGH", "set spec.hostNetwork=false"},
+	{"HOST_PID", "KS-HOST-002", "HIGH", "set spec.hostPID=false"},
+	{"HOST_IPC", "KS-HOST-003", "HIGH", "set spec.hostIPC=false"},
+	{"HOST_PATH_VOLUME", "KS-HOST-004", "HIGH", "replace the hostPath volume with a PersistentVolumeClaim or emptyDir"},
+	{"HOST_PORT", "KS-HOST-005", "MEDIUM", "remove ports[].hostPort and expose the port through a Service"},
+	{"SHARED_PROCESS_NAMESPACE", "KS-HOST-006", "MEDIUM", "set spec.shareProcessNamespace=false"},
+
+	{"SECCOMP_PROFILE", "KS-SEC-001", "HIGH", "set securityContext.seccompProfile.type=RuntimeDefault"},
+	{"APPARMOR_PROFILE", "KS-SEC-002", "HIGH", "set securityContext.appArmorProfile.type=RuntimeDefault"},
+	{"SELINUX_OPTIONS", "KS-SEC-003", "HIGH", "remove securityContext.seLinuxOptions.user and role, and use an allowed type"},
+	{"PROC_MOUNT", "KS-SEC-004", "HIGH", "set securityContext.procMount=Default"},
+	{"UNSAFE_SYSCTL", "KS-SEC-005", "HIGH", "remove the sysctl from spec.securityContext.sysctls"},
+
+	{"RESTRICTED_VOLUME_TYPE", "KS-VOL-001", "MEDIUM", "use configMap, secret, emptyDir, projected, downwardAPI, csi, ephemeral or persistentVolumeClaim volumes"},
+	{"UNBOUNDED_TMPFS", "KS-VOL-002", "MEDIUM", "set emptyDir.sizeLimit on medium=Memory volumes"},
+
+	{"DISALLOWED_REGISTRY", "KS-IMG-001", "HIGH", "pull the image from a registry in allowedRegistries"},
+	{"DISALLOWED_REPOSITORY", "KS-IMG-002", "HIGH", "pull the image from a repository in allowedImageRepositories"},
+	{"MISSING_PULL_SECRET", "KS-IMG-003", "LOW", "add spec.imagePullSecrets or attach them to the service account"},
+	{"IMAGE_DRIFT", "KS-IMG-004", "HIGH", "pin the image by digest and check mutating webhooks and registry mirrors"},
+	{"PUBLIC_REGISTRY_IMAGE", "KS-IMG-005", "MEDIUM", "push the image to a private registry and pull it from there"},
+	{"UNAPPROVED_IMAGE", "KS-IMG-006", "HIGH", "deploy an image built by CI whose digest is in the policy's allowlist"},
+
+	{"MISSING_RESOURCE_REQUESTS", "KS-RES-001", "LOW", "set resources.requests.cpu and resources.requests.memory"},
+	{"MISSING_RESOURCE_LIMITS", "KS-RES-002", "MEDIUM", "set resources.limits.cpu and resources.limits.memory"},
+
+	{"POD_LIFETIME_EXCEEDED", "KS-LIFE-001", "MEDIUM", "delete the pod or run it as a Job with activeDeadlineSeconds"},
+	{"POD_TOO_OLD", "KS-LIFE-002", "LOW", "restart the workload, e.g. kubectl rollout restart"},
+
+	{"MISSING_REQUIRED_LABEL", "KS-META-001", "LOW", "add the missing labels to the pod template"},
+	{"MISSING_ANTIAFFINITY", "KS-META-002", "LOW", "add a podAntiAffinity or topologySpreadConstraints on kubernetes.io/hostname"},
+	{"POD_MUTATED", "KS-META-003", "CRITICAL", "find the mutating webhook that changed the pod and remove the change"},
+
+	{"CUSTOM_RULE_VIOLATION", "KS-RULE-001", "MEDIUM", "change the pod so the policy's custom rule no longer matches"},
+
+	{"EXEMPTION_APPLIED", "KS-OPS-001", "INFO", "fix the exempted violation before the exemption expires"},
+	{"ENFORCEMENT_FAILED", "KS-OPS-002", "CRITICAL", "check the operator's permissions and the pod's finalizers"},
+	{"MANUAL_QUARANTINE", "KS-OPS-003", "HIGH", "investigate the pod, then delete it or remove the quarantine"},
+	{"UNCOVERED_NAMESPACE", "KS-OPS-004", "LOW", "add the namespace to a ShieldPolicy's targetNamespaces or selector"},
+	{"PDB_BLOCKED", "KS-OPS-005", "MEDIUM", "scale up the workload or relax its PodDisruptionBudget so the pod can be terminated"},
+	{"REGISTRY_OVERRIDE_APPLIED", "KS-OPS-006", "INFO", "add the registry to the policy's allowedRegistries or stop pulling from it"},
+	{"AUDIT_EVENTS_SUPPRESSED", "KS-OPS-007", "INFO", "fix the pods repeatedly violating the policy, or raise AUDIT_RATE_LIMIT"},
+}
+
+// rulesByEventType indexes the catalog by event type
+var rulesByEventType = func() map[string]Rule {
+	index := make(map[string]Rule, len(catalog))
+	for _, rule := range catalog {
+		index[rule.EventType] = rule
+	}
+	return index
+}()
+
+// Catalog returns every known rule, sorted by code
+func Catalog() []Rule {
+	rules := make([]Rule, len(catalog))
+	copy(rules, catalog)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Code < rules[j].Code })
+	return rules
+}
+
+// LookupRule returns the catalog entry of an event type
+func LookupRule(eventType string) (Rule, bool) {
+	rule, ok := rulesByEventType[eventType]
+	return rule, ok
+}
+
+// Classify sets the event's ReasonCode and Remediation from the catalog. Events of
+// unknown types are left as they are.
+func Classify(event *audit.SecurityEvent) {
+	rule, ok := rulesByEventType[event.EventType]
+	if !ok {
+		return
+	}
+	event.ReasonCode = rule.Code
+	event.Remediation = rule.Remediation
+}
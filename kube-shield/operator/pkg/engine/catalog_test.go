@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// releasedCodes pins the ReasonCode of every released event type. Codes are part of
+// the API, so an entry here is only ever added, never changed or removed.
+var releasedCodes = map[string]string{
+	"PRIVILEGED_CONTAINER":      "KS-PRIV-001",
+	"PRIVILEGED_INIT_CONTAINER": "KS-PRIV-002",
+	"PRIVILEGE_ESCALATION":      "KS-PRIV-003",
+	"ROOT_USER":                 "KS-PRIV-004",
+	"RUN_AS_NON_ROOT":           "KS-PRIV-005",
+	"HOST_PROCESS":              "KS-PRIV-006",
+	"CAPABILITIES_NOT_DROPPED":  "KS-CAP-001",
+	"ADDED_CAPABILITIES":        "KS-CAP-002",
+	"HOST_NETWORK":              "KS-HOST-001",
+	"HOST_PID":                  "KS-HOST-002",
+	"HOST_IPC":                  "KS-HOST-003",
+	"HOST_PATH_VOLUME":          "KS-HOST-004",
+	"HOST_PORT":                 "KS-HOST-005",
+	"SHARED_PROCESS_NAMESPACE":  "KS-HOST-006",
+	"SECCOMP_PROFILE":           "KS-SEC-001",
+	"APPARMOR_PROFILE":          "KS-SEC-002",
+	"SELINUX_OPTIONS":           "KS-SEC-003",
+	"PROC_MOUNT":                "KS-SEC-004",
+	"UNSAFE_SYSCTL":             "KS-SEC-005",
+	"RESTRICTED_VOLUME_TYPE":    "KS-VOL-001",
+	"UNBOUNDED_TMPFS":           "KS-VOL-002",
+	"DISALLOWED_REGISTRY":       "KS-IMG-001",
+	"DISALLOWED_REPOSITORY":     "KS-IMG-002",
+	"MISSING_PULL_SECRET":       "KS-IMG-003",
+	"IMAGE_DRIFT":               "KS-IMG-004",
+	"PUBLIC_REGISTRY_IMAGE":     "KS-IMG-005",
+	"UNAPPROVED_IMAGE":          "KS-IMG-006",
+	"MISSING_RESOURCE_REQUESTS": "KS-RES-001",
+	"MISSING_RESOURCE_LIMITS":   "KS-RES-002",
+	"POD_LIFETIME_EXCEEDED":     "KS-LIFE-001",
+	"POD_TOO_OLD":               "KS-LIFE-002",
+	"MISSING_REQUIRED_LABEL":    "KS-META-001",
+	"MISSING_ANTIAFFINITY":      "KS-META-002",
+	"POD_MUTATED":               "KS-META-003",
+	"CUSTOM_RULE_VIOLATION":     "KS-RULE-001",
+	"EXEMPTION_APPLIED":         "KS-OPS-001",
+	"ENFORCEMENT_FAILED":        "KS-OPS-002",
+	"MANUAL_QUARANTINE":         "KS-OPS-003",
+	"UNCOVERED_NAMESPACE":       "KS-OPS-004",
+	"PDB_BLOCKED":               "KS-OPS-005",
+	"REGISTRY_OVERRIDE_APPLIED": "KS-OPS-006",
+	"AUDIT_EVENTS_SUPPRESSED":   "KS-OPS-007",
+}
+
+func TestCatalogKeepsReleasedCodes(t *testing.T) {
+	for eventType, code := range releasedCodes {
+		rule, ok := LookupRule(eventType)
+		if !ok {
+			t.Errorf("released event type %s was removed from the catalog", eventType)
+			continue
+		}
+		if rule.Code != code {
+			t.Errorf("code of %s changed from %s to %s", eventType, code, rule.Code)
+		}
+	}
+	for _, rule := range catalog {
+		if _, ok := releasedCodes[rule.EventType]; !ok {
+			t.Errorf("event type %s is not pinned in releasedCodes", rule.EventType)
+		}
+	}
+}
+
+func TestCatalogEntriesAreUnique(t *testing.T) {
+	codePattern := regexp.MustCompile(`^KS-[A-Z]+-[0-9]{3}$`)
+	severities := map[string]bool{"CRITICAL": true, "HIGH": true, "MEDIUM": true, "LOW": true, "INFO": true}
+	eventTypes := make(map[string]bool)
+	codes := make(map[string]string)
+	for _, rule := range catalog {
+		if eventTypes[rule.EventType] {
+			t.Errorf("event type %s is listed twice", rule.EventType)
+		}
+		eventTypes[rule.EventType] = true
+		if other, ok := codes[rule.Code]; ok {
+			t.Errorf("code %s is used by both %s and %s", rule.Code, other, rule.EventType)
+		}
+		codes[rule.Code] = rule.EventType
+		if !codePattern.MatchString(rule.Code) {
+			t.Errorf("code %s of %s does not match %s", rule.Code, rule.EventType, codePattern)
+		}
+		if !severities[rule.Severity] {
+			t.Errorf("%s has unknown severity %q", rule.EventType, rule.Severity)
+		}
+		if rule.Remediation == "" {
+			t.Errorf("%s has no remediation", rule.EventType)
+		}
+	}
+}
+
+func TestCatalogSortedByCode(t *testing.T) {
+	rules := Catalog()
+	if len(rules) != len(catalog) {
+		t.Fatalf("Catalog returned %d rules, want %d", len(rules), len(catalog))
+	}
+	if !sort.SliceIsSorted(rules, func(i, j int) bool { return rules[i].Code < rules[j].Code }) {
+		t.Error("Catalog is not sorted by code")
+	}
+	rules[0].Code = "changed"
+	if Catalog()[0].Code == "changed" {
+		t.Error("Catalog returned the catalog itself instead of a copy")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	event := audit.SecurityEvent{EventType: audit.EventTypePDBBlocked}
+	Classify(&event)
+	if event.ReasonCode != "KS-OPS-005" || event.Remediation == "" {
+		t.Errorf("Classify = %q, %q, want KS-OPS-005 and a remediation", event.ReasonCode, event.Remediation)
+	}
+
+	unknown := audit.SecurityEvent{EventType: "NOT_A_RULE"}
+	Classify(&unknown)
+	if unknown.ReasonCode != "" || unknown.Remediation != "" {
+		t.Errorf("Classify of an unknown event type set %q, %q", unknown.ReasonCode, unknown.Remediation)
+	}
+}
@@ -0,0 +1,202 @@
+// Package engine decides which ShieldPolicies apply to the pods of a namespace. The
+// Pod controller selects policies through it, and the PolicyReport writer and
+// kubeshieldctl summarize them through it, so what they show is what is enforced.
+package engine
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/evaluator"
+	"github.com/kubeshield/operator/pkg/protection"
+)
+
+// actionStrictness orders the actions of violations, the strictest last
+var actionStrictness = map[string]int{
+	audit.ActionAudit:      1,
+	audit.ActionWarn:       2,
+	audit.ActionTerminated: 3,
+}
+
+// AppliesToNamespace reports whether the Pod controller evaluates the pods of
+// namespace against policy. A policy's node selector, owner kinds and evaluation phase narrow
+// this down further for each pod.
+func AppliesToNamespace(policy *shieldv1alpha1.ShieldPolicy, namespace string, namespaceLabels map[string]string) bool {
+	// Simulated policies are only evaluated by the ShieldPolicy controller
+	if policy.IsDisabled() || policy.IsSimulated() {
+		return false
+	}
+	return policy.ShouldApplyToNamespace(namespace, namespaceLabels)
+}
+
+// Effective is the rule set that applies to the pods of a namespace
+type Effective struct {
+	Namespace string `json:"namespace"`
+
+	// Skipped explains why pods of the namespace are not evaluated at all
+	Skipped string `json:"skipped,omitempty"`
+
+	// AuditOnly is set for system namespaces whose violations are only audited
+	AuditOnly bool `json:"auditOnly,omitempty"`
+
+	Policies   []AppliedPolicy    `json:"policies,omitempty"`
+	Checks     []EffectiveCheck   `json:"checks,omitempty"`
+	Exemptions []AppliedExemption `json:"exemptions,omitempty"`
+}
+
+// AppliedPolicy is a policy applying to the namespace
+type AppliedPolicy struct {
+	Name string `json:"name"`
+
+	// Mode is the effective enforcement mode, after DEFAULT_ENFORCEMENT_MODE and
+	// the grace period after creation
+	Mode string `json:"mode"`
+
+	// EnforcesAfter is when an Enforce policy's grace period after creation ends
+	EnforcesAfter *metav1.Time `json:"enforcesAfter,omitempty"`
+
+	EvaluationPhase string `json:"evaluationPhase"`
+
+	// NodeSelector limits the policy to pods on matching nodes
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// MatchOwnerKinds limits the policy to pods of these top-level owner kinds
+	MatchOwnerKinds []string `json:"matchOwnerKinds,omitempty"`
+}
+
+// EffectiveCheck is a check enabled by at least one applying policy
+type EffectiveCheck struct {
+	Check string `json:"check"`
+
+	// Action is the strictest action of the policies enabling the check
+	Action string `json:"action"`
+
+	Policies []string `json:"policies"`
+
+	// ExemptedBy are the active exemptions waiving the check for matching pods
+	ExemptedBy []string `json:"exemptedBy,omitempty"`
+}
+
+// AppliedExemption is an active exemption in the namespace
+type AppliedExemption struct {
+	Name          string                        `json:"name"`
+	Rules         []string                      `json:"rules"`
+	Match         shieldv1alpha1.ExemptionMatch `json:"match"`
+	ExpiresAt     metav1.Time                   `json:"expiresAt"`
+	Justification string                        `json:"justification"`
+}
+
+// Compute returns the effective rule set of a namespace: the policies applying to
+// it with their effective modes, the checks they enable with the strictest action
+// among them, and the active exemptions that waive checks for some of its pods.
+// exemptions are the namespace's ShieldExemptions.
+func Compute(
+	namespace *corev1.Namespace,
+	policies []shieldv1alpha1.ShieldPolicy,
+	exemptions []shieldv1alpha1.ShieldExemption,
+	system *protection.SystemNamespaces,
+	now time.Time,
+) Effective {
+	effective := Effective{Namespace: namespace.Name}
+	switch {
+	case system.IsBypassed(namespace.Name):
+		effective.Skipped = "namespace is listed in BYPASS_NAMESPACES"
+		return effective
+	case system.Skip(namespace.Name):
+		effective.Skipped = "system namespace skipped by SYSTEM_NAMESPACE_MODE=skip"
+		return effective
+	}
+	effective.AuditOnly = system.AuditOnly(namespace.Name)
+
+	var active []*shieldv1alpha1.ShieldExemption
+	for i := range exemptions {
+		exemption := &exemptions[i]
+		if !exemption.IsActive(now) {
+			continue
+		}
+		active = append(active, exemption)
+		effective.Exemptions = append(effective.Exemptions, AppliedExemption{
+			Name:          exemption.Name,
+			Rules:         exemption.Spec.Rules,
+			Match:         exemption.Spec.Match,
+			ExpiresAt:     exemption.Spec.ExpiresAt,
+			Justification: exemption.Spec.Justification,
+		})
+	}
+
+	checks := make(map[string]*EffectiveCheck)
+	for i := range policies {
+		policy := &policies[i]
+		if !AppliesToNamespace(policy, namespace.Name, namespace.Labels) {
+			continue
+		}
+
+		applied := AppliedPolicy{
+			Name:            policy.Name,
+			Mode:            policy.EffectiveEnforcementMode(),
+			EvaluationPhase: policy.EffectiveEvaluationPhase(),
+			NodeSelector:    policy.Spec.NodeSelector,
+			MatchOwnerKinds: policy.Spec.MatchOwnerKinds,
+		}
+		if grace := policy.GraceRemaining(now); grace > 0 {
+			applied.EnforcesAfter = &metav1.Time{Time: now.Add(grace)}
+		}
+		effective.Policies = append(effective.Policies, applied)
+
+		action := evaluator.ActionFor(policy)
+		if effective.AuditOnly {
+			action = audit.ActionAudit
+		}
+		for _, name := range policy.EnabledChecks() {
+			check, ok := checks[name]
+			if !ok {
+				check = &EffectiveCheck{Check: name, Action: action, ExemptedBy: exemptedBy(name, active)}
+				checks[name] = check
+			}
+			if actionStrictness[action] > actionStrictness[check.Action] {
+				check.Action = action
+			}
+			check.Policies = append(check.Policies, policy.Name)
+		}
+	}
+
+	for _, check := range checks {
+		effective.Checks = append(effective.Checks, *check)
+	}
+	sort.Slice(effective.Policies, func(i, j int) bool { return effective.Policies[i].Name < effective.Policies[j].Name })
+	sort.Slice(effective.Checks, func(i, j int) bool { return effective.Checks[i].Check < effective.Checks[j].Check })
+	return effective
+}
+
+// exemptedBy returns the exemptions whose rules cover the check
+func exemptedBy(check string, exemptions []*shieldv1alpha1.ShieldExemption) []string {
+	var names []string
+	for _, exemption := range exemptions {
+		if exemption.CoversRule(check) {
+			names = append(names, exemption.Name)
+		}
+	}
+	return names
+}
+
+// Summary returns the applying policies and their effective modes as
+// "name=Mode,...", or why the namespace is skipped
+func (e Effective) Summary() string {
+	if e.Skipped != "" {
+		return "skipped: " + e.Skipped
+	}
+	parts := make([]string, 0, len(e.Policies))
+	for _, policy := range e.Policies {
+		parts = append(parts, policy.Name+"="+policy.Mode)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,16 @@
+package engine
+
+// templateChecks are the checks that only depend on a pod's images, so a workload
+// template violating them can only ever produce violating pods
+var templateChecks = map[string]bool{
+	"DISALLOWED_REGISTRY":   true,
+	"DISALLOWED_REPOSITORY": true,
+	"PUBLIC_REGISTRY_IMAGE": true,
+	"UNAPPROVED_IMAGE":      true,
+}
+
+// IsTemplateCheck reports whether violations of eventType are checked on the pod
+// templates of workload controllers at admission, before any pod exists
+func IsTemplateCheck(eventType string) bool {
+	return templateChecks[eventType]
+}
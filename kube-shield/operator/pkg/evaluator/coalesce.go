@@ -0,0 +1,111 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// coalescedDescriptionLimit caps the per-container detail kept in the Description
+// of a coalesced event
+const coalescedDescriptionLimit = 2048
+
+// severityRank orders severities, the most severe highest
+var severityRank = map[string]int{
+	"INFO":     0,
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// coalesceKey groups the container events of one rule under one policy
+type coalesceKey struct {
+	policy    string
+	eventType string
+	rule      string
+}
+
+// Coalesce replaces the container events of a pod that share a policy, event type
+// and rule with a single event listing the containers, once more than threshold
+// containers are involved. Pods with dozens of containers would otherwise raise one
+// near-identical event, status increment and audit delivery per container. The
+// coalesced event takes the place of the group's first event, lists the containers
+// sorted by name in Containers and keeps each container's description in its
+// Description up to a size cap, so the same violations always coalesce the same
+// way. A threshold of 0 disables coalescing.
+func Coalesce(events []audit.SecurityEvent, threshold int) []audit.SecurityEvent {
+	if threshold <= 0 || len(events) <= threshold {
+		return events
+	}
+
+	groups := make(map[coalesceKey][]audit.SecurityEvent)
+	for _, event := range events {
+		if event.Container == "" {
+			continue
+		}
+		key := coalesceKey{policy: event.PolicyName, eventType: event.EventType, rule: event.Rule}
+		groups[key] = append(groups[key], event)
+	}
+
+	result := make([]audit.SecurityEvent, 0, len(events))
+	done := make(map[coalesceKey]bool)
+	for _, event := range events {
+		key := coalesceKey{policy: event.PolicyName, eventType: event.EventType, rule: event.Rule}
+		group := groups[key]
+		if event.Container == "" || len(group) <= threshold {
+			result = append(result, event)
+			continue
+		}
+		if !done[key] {
+			result = append(result, coalesce(group))
+			done[key] = true
+		}
+	}
+	return result
+}
+
+// coalesce merges the events of one group into a single event
+func coalesce(group []audit.SecurityEvent) audit.SecurityEvent {
+	sorted := make([]audit.SecurityEvent, len(group))
+	copy(sorted, group)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Container < sorted[j].Container })
+
+	merged := sorted[0]
+	merged.Container = ""
+	merged.Containers = make([]string, 0, len(sorted))
+	var details strings.Builder
+	omitted := 0
+	for _, event := range sorted {
+		merged.Containers = append(merged.Containers, event.Container)
+		if severityRank[event.Severity] > severityRank[merged.Severity] {
+			merged.Severity = event.Severity
+		}
+		// Fields that differ between containers are left empty
+		if event.Image != merged.Image {
+			merged.Image = ""
+		}
+		if event.ResolvedImageID != merged.ResolvedImageID {
+			merged.ResolvedImageID = ""
+		}
+		if event.ResolvedDigest != merged.ResolvedDigest {
+			merged.ResolvedDigest = ""
+		}
+
+		detail := fmt.Sprintf("\n- %s: %s", event.Container, event.Description)
+		if omitted > 0 || details.Len()+len(detail) > coalescedDescriptionLimit {
+			omitted++
+			continue
+		}
+		details.WriteString(detail)
+	}
+	merged.ContainerCount = len(sorted)
+
+	merged.Description = fmt.Sprintf("%d containers violate %s:%s", len(sorted), merged.EventType, details.String())
+	if omitted > 0 {
+		merged.Description += fmt.Sprintf("\n... and %d more", omitted)
+	}
+	return merged
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// ImageAllowlistLookup resolves the digests listed in a policy's
+// AllowedImagesConfigMapRef, e.g. from the manager's cache
+type ImageAllowlistLookup interface {
+	ApprovedDigests(ctx context.Context, ref shieldv1alpha1.ConfigMapReference) (map[string]bool, error)
+}
+
+// hexDigest matches a bare hex digest, as a ConfigMap key cannot contain ":"
+var hexDigest = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// NormalizeDigest turns an allowlist entry into the "algorithm:hex" form runtimes
+// report. Since ConfigMap keys cannot contain ":", "sha256.<hex>", "sha256-<hex>",
+// "sha256_<hex>" and a bare sha256 hex digest are accepted as well.
+func NormalizeDigest(entry string) string {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if strings.Contains(entry, ":") {
+		return entry
+	}
+	for _, separator := range []string{".", "-", "_"} {
+		if algorithm, hex, ok := strings.Cut(entry, separator); ok && algorithm != "" {
+			return algorithm + ":" + hex
+		}
+	}
+	if hexDigest.MatchString(entry) {
+		return "sha256:" + entry
+	}
+	return entry
+}
+
+// ContainerDigest returns the digest a container runs: the one its status reports,
+// or the one its spec image is pinned to until it has started. It is empty while
+// neither is known, e.g. for a pending pod pulling an image by tag.
+func ContainerDigest(pod *corev1.Pod, container corev1.Container) string {
+	if imageID, ok := containerImageIDs(pod)[container.Name]; ok {
+		if _, digest := parseImageID(imageID); digest != "" {
+			return strings.ToLower(digest)
+		}
+	}
+	return strings.ToLower(imageDigest(container.Image))
+}
+
+// checkImageDigests flags containers whose digest is not in the policy's
+// AllowedImageDigests or AllowedImagesConfigMapRef. Containers whose digest is not
+// resolved yet are skipped; the pod is checked again once its status reports it.
+// An allowlist ConfigMap that cannot be read only leaves the inline digests, and
+// the check is skipped while no digest is approved at all, so a missing or emptied
+// ConfigMap never gets a whole namespace terminated. Policies with
+// ImageAllowlistFailurePolicy Fail are checked against the inline digests anyway.
+func checkImageDigests(
+	ctx context.Context,
+	lookup ImageAllowlistLookup,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	containers []corev1.Container,
+	now string,
+) []audit.SecurityEvent {
+	ref := policy.Spec.AllowedImagesConfigMapRef
+	if len(policy.Spec.AllowedImageDigests) == 0 && ref == nil {
+		return nil
+	}
+
+	approved := make(map[string]bool, len(policy.Spec.AllowedImageDigests))
+	for _, digest := range policy.Spec.AllowedImageDigests {
+		approved[NormalizeDigest(digest)] = true
+	}
+	var unavailable error
+	if ref != nil && lookup != nil {
+		// The lookup reports unreadable ConfigMaps itself
+		var listed map[string]bool
+		listed, unavailable = lookup.ApprovedDigests(ctx, *ref)
+		for digest := range listed {
+			approved[digest] = true
+		}
+	}
+	failClosed := unavailable != nil && policy.Spec.ImageAllowlistFailurePolicy == shieldv1alpha1.ImageAllowlistFailureFail
+	if len(approved) == 0 && !failClosed {
+		return nil
+	}
+
+	var violations []audit.SecurityEvent
+	for _, container := range containers {
+		digest := ContainerDigest(pod, container)
+		if digest == "" || approved[digest] {
+			continue
+		}
+		reason := fmt.Sprintf("Image digest %s is not approved", digest)
+		if failClosed {
+			reason += fmt.Sprintf(" (allowlist ConfigMap %s/%s unavailable)", ref.Namespace, ref.Name)
+		}
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "UNAPPROVED_IMAGE",
+			Severity:    "HIGH",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   container.Name,
+			Image:       container.Image,
+			Reason:      reason,
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Container '%s' runs %s with digest %s, which is not in the approved digests of policy '%s'", container.Name, container.Image, digest, policy.Name),
+		})
+	}
+	return violations
+}
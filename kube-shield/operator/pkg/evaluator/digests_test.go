@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// unavailableAllowlist is an ImageAllowlistLookup whose ConfigMap cannot be read
+type unavailableAllowlist struct{}
+
+func (unavailableAllowlist) ApprovedDigests(context.Context, shieldv1alpha1.ConfigMapReference) (map[string]bool, error) {
+	return nil, errors.New("ConfigMap kube-shield/approved-images not found")
+}
+
+func TestCheckImageDigestsUnavailableAllowlist(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "production", Name: "web"}}
+	containers := []corev1.Container{{
+		Name:  "app",
+		Image: "registry.example.com/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}}
+	ref := &shieldv1alpha1.ConfigMapReference{Namespace: "kube-shield", Name: "approved-images"}
+
+	tests := []struct {
+		name          string
+		failurePolicy string
+		wantFlagged   bool
+	}{
+		{name: "default fails open", wantFlagged: false},
+		{name: "ignore fails open", failurePolicy: shieldv1alpha1.ImageAllowlistFailureIgnore, wantFlagged: false},
+		{name: "fail closes", failurePolicy: shieldv1alpha1.ImageAllowlistFailureFail, wantFlagged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &shieldv1alpha1.ShieldPolicy{Spec: shieldv1alpha1.ShieldPolicySpec{
+				AllowedImagesConfigMapRef:   ref,
+				ImageAllowlistFailurePolicy: tt.failurePolicy,
+			}}
+			violations := checkImageDigests(context.Background(), unavailableAllowlist{}, pod, policy, containers, "")
+			if flagged := len(violations) > 0; flagged != tt.wantFlagged {
+				t.Fatalf("flagged = %t, want %t", flagged, tt.wantFlagged)
+			}
+			if tt.wantFlagged && !strings.Contains(violations[0].Reason, "kube-shield/approved-images unavailable") {
+				t.Errorf("Reason = %q, want the unavailable ConfigMap named", violations[0].Reason)
+			}
+		})
+	}
+}
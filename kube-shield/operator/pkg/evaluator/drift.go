@@ -0,0 +1,112 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// containerImageIDs maps container names to the imageID their status reports, the
+// image that actually runs as opposed to the one the spec asked for
+func containerImageIDs(pod *corev1.Pod) map[string]string {
+	ids := make(map[string]string)
+	for _, statuses := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, status := range statuses {
+			if status.ImageID != "" {
+				ids[status.Name] = status.ImageID
+			}
+		}
+	}
+	return ids
+}
+
+// parseImageID splits a container runtime's imageID, such as
+// "docker-pullable://registry/app@sha256:..." or "registry/app@sha256:...", into
+// the image reference and its digest. The reference is empty when the runtime only
+// reports a digest.
+func parseImageID(imageID string) (string, string) {
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		imageID = imageID[i+len("://"):]
+	}
+	if strings.HasPrefix(imageID, "sha256:") {
+		return "", imageID
+	}
+	ref, digest, _ := strings.Cut(imageID, "@")
+	return ref, digest
+}
+
+// imageDigest returns the digest an image reference is pinned to, if any
+func imageDigest(image string) string {
+	_, digest, _ := strings.Cut(image, "@")
+	return digest
+}
+
+// checkImageDrift flags running containers whose imageID does not match their spec
+// image: either it comes from another registry or repository, e.g. after a mutating
+// webhook rewrote the image, or the spec pins a digest and another one runs.
+// Registry mirrors are resolved on both sides. Containers that are not running, and
+// runtimes that report bare digests, are not checked.
+func checkImageDrift(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, containers []corev1.Container, now string) []audit.SecurityEvent {
+	if !policy.Spec.DetectImageDrift {
+		return nil
+	}
+
+	running := make(map[string]corev1.ContainerStatus)
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.State.Running != nil && status.ImageID != "" {
+				running[status.Name] = status
+			}
+		}
+	}
+
+	var violations []audit.SecurityEvent
+	for _, container := range containers {
+		status, ok := running[container.Name]
+		if !ok {
+			continue
+		}
+		ref, digest := parseImageID(status.ImageID)
+		if ref == "" {
+			continue
+		}
+
+		specImage, _ := ResolveMirror(container.Image, policy.Spec.RegistryAliases)
+		runningImage, _ := ResolveMirror(ref, policy.Spec.RegistryAliases)
+		wanted, actual := ExtractRepository(specImage), ExtractRepository(runningImage)
+
+		var reason string
+		switch pinned := imageDigest(container.Image); {
+		case wanted != actual:
+			reason = fmt.Sprintf("Running image from %s, spec asks for %s", actual, wanted)
+		case pinned != "" && digest != "" && pinned != digest:
+			reason = fmt.Sprintf("Running digest %s, spec pins %s", digest, pinned)
+		default:
+			continue
+		}
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "IMAGE_DRIFT",
+			Severity:    "HIGH",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   container.Name,
+			Image:       container.Image,
+			Reason:      reason,
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Container '%s' runs %s although its spec asks for %s, so the image changed after it was requested", container.Name, status.ImageID, container.Image),
+		})
+	}
+	return violations
+}
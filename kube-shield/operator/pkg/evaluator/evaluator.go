@@ -0,0 +1,393 @@
+// Package evaluator checks pods against ShieldPolicies. It only looks at the objects
+// it is given and never talks to the API server, so the same checks can back the pod
+// controller, admission and offline tooling.
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/celrules"
+)
+
+// GrandfatheredMarker is added to violations only audited because the pod was created
+// before a policy with EnforceOnExisting set to false
+const GrandfatheredMarker = "GRANDFATHERED"
+
+// PullSecretLookup resolves the imagePullSecrets of a ServiceAccount
+type PullSecretLookup interface {
+	PullSecrets(ctx context.Context, namespace, name string) []corev1.LocalObjectReference
+}
+
+// Evaluator checks pods against ShieldPolicies
+type Evaluator struct {
+	rules *celrules.Compiler
+
+	// PublicRegistries are the registry globs BlockPublicRegistries flags
+	PublicRegistries []string
+
+	// ImageAllowlists resolves AllowedImagesConfigMapRef. Without it only a policy's
+	// AllowedImageDigests are approved.
+	ImageAllowlists ImageAllowlistLookup
+}
+
+// New creates an Evaluator. Custom CEL rules are skipped when rules is nil.
+func New(rules *celrules.Compiler) *Evaluator {
+	return &Evaluator{rules: rules, PublicRegistries: DefaultPublicRegistries}
+}
+
+// Evaluate checks a pod against a policy and returns any violations. Only the pod's own
+// imagePullSecrets are considered; use EvaluateWith to fall back to its ServiceAccount.
+func (e *Evaluator) Evaluate(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy) []audit.SecurityEvent {
+	return e.EvaluateWith(context.Background(), pod, policy, nil)
+}
+
+// EvaluateWith checks a pod against a policy, resolving ServiceAccount pull secrets
+// through secrets when it is not nil
+func (e *Evaluator) EvaluateWith(
+	ctx context.Context,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	secrets PullSecretLookup,
+) []audit.SecurityEvent {
+	var violations []audit.SecurityEvent
+	evaluatedAt := time.Now()
+	now := evaluatedAt.UTC().Format(time.RFC3339)
+
+	// Linux-only checks are skipped for Windows pods, which are checked for
+	// HostProcess containers instead
+	windows := isWindowsPod(pod)
+
+	// Pod-level checks (host network)
+	if pod.Spec.HostNetwork {
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "HOST_NETWORK",
+			Severity:    "HIGH",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Reason:      "Pod using host network",
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Pod '%s' is using host network which can bypass network policies", pod.Name),
+		})
+	}
+
+	// Pod-level checks (shared process namespace)
+	if !windows && policy.Spec.BlockSharedProcessNamespace && pod.Spec.ShareProcessNamespace != nil && *pod.Spec.ShareProcessNamespace {
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "SHARED_PROCESS_NAMESPACE",
+			Severity:    "MEDIUM",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Reason:      "Pod shares its process namespace between containers",
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Pod '%s' sets shareProcessNamespace, letting its containers see and signal each other's processes", pod.Name),
+		})
+	}
+
+	// Check all containers (including init and ephemeral containers)
+	allContainers := podContainers(pod)
+
+	for _, container := range allContainers {
+		// Check for privileged containers
+		if !windows && policy.ShouldBlockPrivileged() {
+			if container.SecurityContext != nil &&
+				container.SecurityContext.Privileged != nil &&
+				*container.SecurityContext.Privileged {
+
+				violations = append(violations, privilegedViolation(pod, policy, container, now))
+			}
+		}
+
+		// Check that capabilities are dropped, to add back only what is needed
+		if !windows && policy.RequiresDropAllCapabilities() {
+			if container.SecurityContext == nil || !dropsAllCapabilities(container.SecurityContext.Capabilities) {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "CAPABILITIES_NOT_DROPPED",
+					Severity:    "MEDIUM",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      "Capabilities not dropped",
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' does not drop ALL capabilities, it must drop them and add back only what it needs", container.Name),
+				})
+			}
+		}
+
+		// Images pulled through a mirror are checked as the registry they mirror
+		image, mirror := ResolveMirror(container.Image, policy.Spec.RegistryAliases)
+
+		// Check for disallowed registries
+		if len(policy.Spec.AllowedRegistries) > 0 {
+			registry := ExtractRegistry(image)
+			if !policy.IsRegistryAllowed(registry) {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "DISALLOWED_REGISTRY",
+					Severity:    "HIGH",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      fmt.Sprintf("Image from disallowed registry: %s", registry),
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' uses image from registry '%s'%s which is not in the allowed list", container.Name, registry, viaMirror(mirror)),
+				})
+			}
+		}
+
+		// Check for disallowed repositories
+		if len(policy.Spec.AllowedImageRepositories) > 0 {
+			repository := ExtractRepository(image)
+			if !policy.IsRepositoryAllowed(repository) {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "DISALLOWED_REPOSITORY",
+					Severity:    "HIGH",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      fmt.Sprintf("Image from disallowed repository: %s", repository),
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' uses image from repository '%s'%s which is not in the allowed list", container.Name, repository, viaMirror(mirror)),
+				})
+			}
+		}
+
+		// Check for root user
+		if !windows && container.SecurityContext != nil {
+			if container.SecurityContext.RunAsUser != nil && *container.SecurityContext.RunAsUser == 0 {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "ROOT_USER",
+					Severity:    "HIGH",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      "Container running as root user",
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' is configured to run as root (UID 0)", container.Name),
+				})
+			}
+		}
+	}
+
+	if windows {
+		// Check for Windows HostProcess containers
+		violations = append(violations, checkHostProcess(pod, policy, allContainers, now)...)
+	} else {
+		// Check memory-backed emptyDir volumes
+		violations = append(violations, checkEmptyDirVolumes(pod, policy, now)...)
+	}
+
+	// Check that containers declare their resource requests and limits
+	violations = append(violations, checkResources(pod, policy, now)...)
+
+	// Check that the pod carries the ownership and compliance labels
+	violations = append(violations, checkRequiredLabels(pod, policy, now)...)
+
+	// Check that replicas of a workload cannot all land on one node
+	violations = append(violations, checkAntiAffinity(pod, policy, now)...)
+
+	// Check that short-lived pods have not outlived the policy's limit
+	violations = append(violations, checkLifetime(pod, policy, evaluatedAt)...)
+
+	// Check that long-running pods are recycled to pick up patched images
+	violations = append(violations, checkAge(pod, policy, evaluatedAt)...)
+
+	// Apply the policy's Pod Security Standards profile
+	violations = append(violations, checkProfile(pod, policy, allContainers, windows, now)...)
+
+	// Check that internal-only namespaces run private images
+	violations = append(violations, checkPublicRegistries(pod, policy, allContainers, e.PublicRegistries, now)...)
+
+	// Check that private registries come with pull credentials
+	violations = append(violations, checkPullSecrets(ctx, secrets, pod, policy, allContainers, now)...)
+
+	// Check that running containers run the image their spec asks for
+	violations = append(violations, checkImageDrift(pod, policy, allContainers, now)...)
+
+	// Check that locked-down namespaces only run approved image digests
+	violations = append(violations, checkImageDigests(ctx, e.ImageAllowlists, pod, policy, allContainers, now)...)
+
+	// Let the policy re-rank the built-in checks
+	for i := range violations {
+		violations[i].Severity = policy.SeverityFor(violations[i].EventType, violations[i].Severity)
+	}
+
+	// Evaluate the policy's custom CEL rules against the whole pod
+	violations = append(violations, e.checkCustomRules(ctx, pod, policy, now)...)
+
+	// Pods that predate a policy grandfathering them are only audited
+	if policy.Grandfathers(pod) {
+		for i := range violations {
+			if violations[i].Action == audit.ActionTerminated || violations[i].Action == audit.ActionWarn {
+				violations[i].Action = audit.ActionAudit
+				violations[i].Markers = append(violations[i].Markers, GrandfatheredMarker)
+			}
+		}
+	}
+
+	// Record the image each container actually runs next to the requested one. Until
+	// the container has started its status has no imageID and both stay empty.
+	imageIDs := containerImageIDs(pod)
+	for i := range violations {
+		if violations[i].Container != "" {
+			violations[i].ResolvedImageID = imageIDs[violations[i].Container]
+			_, violations[i].ResolvedDigest = parseImageID(violations[i].ResolvedImageID)
+		}
+	}
+
+	return violations
+}
+
+// EvaluateWithin runs EvaluateWith but stops waiting once ctx is done or timeout has
+// elapsed, returning the context's error. A zero timeout only honours ctx. A check
+// that overruns keeps running in the background on its own copy of the pod and
+// policy, and its result is discarded.
+func (e *Evaluator) EvaluateWithin(
+	ctx context.Context,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	secrets PullSecretLookup,
+	timeout time.Duration,
+) ([]audit.SecurityEvent, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	pod, policy = pod.DeepCopy(), policy.DeepCopy()
+	done := make(chan []audit.SecurityEvent, 1)
+	panics := make(chan *PanicError, 1)
+	go func() {
+		// A panic here would take down the process, beyond the reach of the caller
+		defer func() {
+			if value := recover(); value != nil {
+				panics <- &PanicError{Policy: policy.Name, Value: value, Stack: debug.Stack()}
+			}
+		}()
+		done <- e.EvaluateWith(ctx, pod, policy, secrets)
+	}()
+
+	select {
+	case violations := <-done:
+		return violations, nil
+	case err := <-panics:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// PanicError is returned by EvaluateWithin when a check panicked, for example on a
+// malformed spec
+type PanicError struct {
+	Policy string
+	Value  interface{}
+	Stack  []byte
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("evaluating policy %s panicked: %v", e.Policy, e.Value)
+}
+
+// ActionFor returns the action recorded on violations of a policy in its current mode
+func ActionFor(policy *shieldv1alpha1.ShieldPolicy) string {
+	switch {
+	case policy.IsEnforcing():
+		return audit.ActionTerminated
+	case policy.IsWarning():
+		return audit.ActionWarn
+	default:
+		return audit.ActionAudit
+	}
+}
+
+// checkEmptyDirVolumes flags memory-backed emptyDir volumes (tmpfs) that have no
+// size limit or a limit above the policy's MaxEmptyDirMemoryMB, since they count
+// against node memory and can be used to exhaust it
+func checkEmptyDirVolumes(
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	now string,
+) []audit.SecurityEvent {
+	// Terminating pods for their tmpfs volumes is opt-in through MaxEmptyDirMemoryMB,
+	// so running pods with an unbounded tmpfs are only reported after an upgrade
+	action := ActionFor(policy)
+	if action == audit.ActionTerminated && policy.Spec.MaxEmptyDirMemoryMB == 0 {
+		action = audit.ActionAudit
+	}
+
+	var violations []audit.SecurityEvent
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir == nil || volume.EmptyDir.Medium != corev1.StorageMediumMemory {
+			continue
+		}
+
+		var reason string
+		switch sizeLimit := volume.EmptyDir.SizeLimit; {
+		case sizeLimit == nil || sizeLimit.IsZero():
+			reason = "Memory-backed emptyDir without sizeLimit"
+		case policy.Spec.MaxEmptyDirMemoryMB > 0 && sizeLimit.Value() > policy.Spec.MaxEmptyDirMemoryMB*1024*1024:
+			reason = fmt.Sprintf("Memory-backed emptyDir sizeLimit %s exceeds %dMi", sizeLimit.String(), policy.Spec.MaxEmptyDirMemoryMB)
+		default:
+			continue
+		}
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "UNBOUNDED_TMPFS",
+			Severity:    "MEDIUM",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Reason:      reason,
+			Action:      action,
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Volume '%s' is a tmpfs mounted by containers [%s] and can exhaust node memory", volume.Name, strings.Join(mountingContainers(pod, volume.Name), ", ")),
+		})
+	}
+	return violations
+}
+
+// mountingContainers returns the names of the containers that mount the named volume
+func mountingContainers(pod *corev1.Pod, volumeName string) []string {
+	var names []string
+	for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		for _, mount := range container.VolumeMounts {
+			if mount.Name == volumeName {
+				names = append(names, container.Name)
+				break
+			}
+		}
+	}
+	return names
+}
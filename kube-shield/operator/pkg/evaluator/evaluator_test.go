@@ -0,0 +1,326 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// panickingAllowlist is an ImageAllowlistLookup that panics, standing in for a check
+// tripping over a malformed spec
+type panickingAllowlist struct{}
+
+func (panickingAllowlist) ApprovedDigests(context.Context, shieldv1alpha1.ConfigMapReference) (map[string]bool, error) {
+	panic("malformed allowlist")
+}
+
+// blockingAllowlist is an ImageAllowlistLookup that never answers
+type blockingAllowlist struct{ release chan struct{} }
+
+func (b blockingAllowlist) ApprovedDigests(context.Context, shieldv1alpha1.ConfigMapReference) (map[string]bool, error) {
+	<-b.release
+	return nil, nil
+}
+
+func allowlistPolicy() *shieldv1alpha1.ShieldPolicy {
+	return &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			AllowedImagesConfigMapRef: &shieldv1alpha1.ConfigMapReference{Namespace: "kube-shield", Name: "approved-images"},
+		},
+	}
+}
+
+func testPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.25"}}},
+	}
+}
+
+func TestEvaluateWithinRecoversPanics(t *testing.T) {
+	e := New(nil)
+	e.ImageAllowlists = panickingAllowlist{}
+
+	violations, err := e.EvaluateWithin(context.Background(), testPod(), allowlistPolicy(), nil, time.Second)
+	if violations != nil {
+		t.Errorf("violations = %v, want none from a panicking evaluation", violations)
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("err = %v, want a *PanicError", err)
+	}
+	if panicErr.Policy != "restricted" || panicErr.Value != "malformed allowlist" {
+		t.Errorf("PanicError = %q, %v, want the policy and panic value", panicErr.Policy, panicErr.Value)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("PanicError has no stack")
+	}
+}
+
+func TestEvaluateWithinTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	e := New(nil)
+	e.ImageAllowlists = blockingAllowlist{release: release}
+
+	_, err := e.EvaluateWithin(context.Background(), testPod(), allowlistPolicy(), nil, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestEvaluateWithinReturnsViolations(t *testing.T) {
+	pod := testPod()
+	pod.Spec.HostNetwork = true
+	policy := &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}}
+
+	violations, err := New(nil).EvaluateWithin(context.Background(), pod, policy, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, violation := range violations {
+		if violation.EventType == "HOST_NETWORK" {
+			return
+		}
+	}
+	t.Errorf("violations = %v, want HOST_NETWORK", violations)
+}
+
+// eventTypes returns the event types of violations, in order
+func eventTypes(violations []audit.SecurityEvent) []string {
+	var types []string
+	for _, violation := range violations {
+		types = append(types, violation.EventType)
+	}
+	return types
+}
+
+func TestEvaluate(t *testing.T) {
+	yes, root := true, int64(0)
+	tests := []struct {
+		name   string
+		mutate func(*corev1.Pod)
+		spec   shieldv1alpha1.ShieldPolicySpec
+		want   []string
+	}{
+		{
+			name: "compliant pod",
+		},
+		{
+			name:   "host network",
+			mutate: func(pod *corev1.Pod) { pod.Spec.HostNetwork = true },
+			want:   []string{"HOST_NETWORK"},
+		},
+		{
+			name: "privileged container",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &yes}
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{BlockPrivileged: &yes},
+			want: []string{"PRIVILEGED_CONTAINER"},
+		},
+		{
+			name: "privileged container allowed",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &yes}
+			},
+		},
+		{
+			name: "privileged init container",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{{Name: "setup", Image: "busybox:1.36",
+					SecurityContext: &corev1.SecurityContext{Privileged: &yes}}}
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{BlockPrivileged: &yes},
+			want: []string{"PRIVILEGED_INIT_CONTAINER"},
+		},
+		{
+			name: "privileged container alongside an unprivileged init container",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{{Name: "setup", Image: "busybox:1.36"}}
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &yes}
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{BlockPrivileged: &yes},
+			want: []string{"PRIVILEGED_CONTAINER"},
+		},
+		{
+			name: "capabilities not dropped",
+			spec: shieldv1alpha1.ShieldPolicySpec{RequireDropAllCapabilities: &yes},
+			want: []string{"CAPABILITIES_NOT_DROPPED"},
+		},
+		{
+			name: "capabilities dropped",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+					Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				}
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{RequireDropAllCapabilities: &yes},
+		},
+		{
+			name: "shared process namespace",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.ShareProcessNamespace = &yes
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{BlockSharedProcessNamespace: true},
+			want: []string{"SHARED_PROCESS_NAMESPACE"},
+		},
+		{
+			name: "disallowed registry",
+			spec: shieldv1alpha1.ShieldPolicySpec{AllowedRegistries: []string{"registry.example.com"}},
+			want: []string{"DISALLOWED_REGISTRY"},
+		},
+		{
+			name: "allowed registry",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Image = "registry.example.com/web/nginx:1.25"
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{AllowedRegistries: []string{"registry.example.com"}},
+		},
+		{
+			name: "disallowed repository",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Image = "registry.example.com/tools/nginx:1.25"
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{AllowedImageRepositories: []string{"registry.example.com/web/*"}},
+			want: []string{"DISALLOWED_REPOSITORY"},
+		},
+		{
+			name: "root user",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{RunAsUser: &root}
+			},
+			want: []string{"ROOT_USER"},
+		},
+		{
+			name: "windows pod skips linux checks",
+			mutate: func(pod *corev1.Pod) {
+				pod.Spec.OS = &corev1.PodOS{Name: corev1.Windows}
+				pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &yes, RunAsUser: &root}
+			},
+			spec: shieldv1alpha1.ShieldPolicySpec{BlockPrivileged: &yes, RequireDropAllCapabilities: &yes},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod()
+			if tt.mutate != nil {
+				tt.mutate(pod)
+			}
+			policy := &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "restricted"}, Spec: tt.spec}
+
+			got := eventTypes(New(nil).Evaluate(pod, policy))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEvaluateAction checks the action reported for host network and root user
+// violations. These checks reported AUDIT even when the policy deleted the pod
+// until termination started following each violation's action.
+func TestEvaluateAction(t *testing.T) {
+	root := int64(0)
+	checks := map[string]func(pod *corev1.Pod){
+		"HOST_NETWORK": func(pod *corev1.Pod) { pod.Spec.HostNetwork = true },
+		"ROOT_USER": func(pod *corev1.Pod) {
+			pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{RunAsUser: &root}
+		},
+	}
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: shieldv1alpha1.EnforcementModeEnforce, want: audit.ActionTerminated},
+		{mode: shieldv1alpha1.EnforcementModeWarn, want: audit.ActionWarn},
+		{mode: shieldv1alpha1.EnforcementModeAudit, want: audit.ActionAudit},
+	}
+	for eventType, violate := range checks {
+		for _, tt := range tests {
+			t.Run(eventType+"/"+tt.mode, func(t *testing.T) {
+				pod := testPod()
+				violate(pod)
+				policy := &shieldv1alpha1.ShieldPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+					Spec:       shieldv1alpha1.ShieldPolicySpec{EnforcementMode: tt.mode},
+				}
+
+				violations := New(nil).Evaluate(pod, policy)
+				if len(violations) != 1 || violations[0].EventType != eventType {
+					t.Fatalf("violations = %v, want %s", eventTypes(violations), eventType)
+				}
+				if violations[0].Action != tt.want {
+					t.Errorf("Action = %s, want %s", violations[0].Action, tt.want)
+				}
+				if violations[0].PolicyName != policy.Name || violations[0].PodName != pod.Name || violations[0].Namespace != pod.Namespace {
+					t.Errorf("violation = %+v, want it to name the policy and pod", violations[0])
+				}
+			})
+		}
+	}
+}
+
+func TestEvaluateSeverityOverride(t *testing.T) {
+	pod := testPod()
+	pod.Spec.HostNetwork = true
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			SeverityOverrides: map[string]shieldv1alpha1.Severity{"HOST_NETWORK": shieldv1alpha1.SeverityLow},
+		},
+	}
+
+	violations := New(nil).Evaluate(pod, policy)
+	if len(violations) != 1 || violations[0].Severity != string(shieldv1alpha1.SeverityLow) {
+		t.Errorf("violations = %+v, want HOST_NETWORK at LOW", violations)
+	}
+}
+
+func TestEvaluateGrandfathersExistingPods(t *testing.T) {
+	no := false
+	policyCreated := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted", CreationTimestamp: metav1.NewTime(policyCreated)},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			EnforcementMode:   shieldv1alpha1.EnforcementModeEnforce,
+			EnforceOnExisting: &no,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		created    time.Time
+		wantAction string
+	}{
+		{name: "created before the policy", created: policyCreated.Add(-time.Hour), wantAction: audit.ActionAudit},
+		{name: "created after the policy", created: policyCreated.Add(time.Hour), wantAction: audit.ActionTerminated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod()
+			pod.CreationTimestamp = metav1.NewTime(tt.created)
+			pod.Spec.HostNetwork = true
+
+			violations := New(nil).Evaluate(pod, policy)
+			if len(violations) != 1 {
+				t.Fatalf("violations = %v, want HOST_NETWORK", eventTypes(violations))
+			}
+			if violations[0].Action != tt.wantAction {
+				t.Errorf("Action = %s, want %s", violations[0].Action, tt.wantAction)
+			}
+			if grandfathered := slices.Contains(violations[0].Markers, GrandfatheredMarker); grandfathered != (tt.wantAction == audit.ActionAudit) {
+				t.Errorf("Markers = %v, want %s only on the existing pod", violations[0].Markers, GrandfatheredMarker)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// checkRequiredLabels flags pods missing any of the policy's RequiredLabels. A
+// requirement is either a key, which any value satisfies, or key=value. All unmet
+// requirements of a pod are reported in one event.
+func checkRequiredLabels(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, now string) []audit.SecurityEvent {
+	var missing []string
+	for _, requirement := range policy.Spec.RequiredLabels {
+		key, value, hasValue := strings.Cut(requirement, "=")
+		actual, ok := pod.Labels[key]
+		if !ok || hasValue && actual != value {
+			missing = append(missing, requirement)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return []audit.SecurityEvent{{
+		Timestamp:   now,
+		EventType:   "MISSING_REQUIRED_LABEL",
+		Severity:    "LOW",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Reason:      fmt.Sprintf("Required labels missing: %s", strings.Join(missing, ", ")),
+		Action:      ActionFor(policy),
+		PolicyName:  policy.Name,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Pod '%s' does not carry the labels %s required by policy '%s'", pod.Name, strings.Join(missing, ", "), policy.Name),
+	}}
+}
@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// checkLifetime flags pods that have been running for longer than MaxPodLifetime at
+// the given time, which is also the event's timestamp
+func checkLifetime(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, at time.Time) []audit.SecurityEvent {
+	expiry, ok := policy.LifetimeExpiry(pod)
+	if !ok || at.Before(expiry) {
+		return nil
+	}
+
+	age := at.Sub(pod.Status.StartTime.Time).Round(time.Second)
+	return []audit.SecurityEvent{{
+		Timestamp:   at.UTC().Format(time.RFC3339),
+		EventType:   "POD_LIFETIME_EXCEEDED",
+		Severity:    "MEDIUM",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Reason:      fmt.Sprintf("Pod running for %s, longer than %s", age, policy.Spec.MaxPodLifetime.Duration),
+		Action:      ActionFor(policy),
+		PolicyName:  policy.Name,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Pod '%s' started at %s and has outlived the maximum lifetime of %s set by policy '%s'", pod.Name, pod.Status.StartTime.UTC().Format(time.RFC3339), policy.Spec.MaxPodLifetime.Duration, policy.Name),
+	}}
+}
+
+// PodTooOld is the event type of pods older than MaxPodAge. The Pod controller
+// evicts these pods rather than deleting them.
+const PodTooOld = "POD_TOO_OLD"
+
+// checkAge flags pods that have existed for longer than MaxPodAge. Pods of
+// DaemonSets and pods without a controller are only audited: evicting them either
+// recreates them from the same image on the same node or removes them for good.
+func checkAge(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, at time.Time) []audit.SecurityEvent {
+	expiry, ok := policy.AgeExpiry(pod)
+	if !ok || at.Before(expiry) {
+		return nil
+	}
+
+	action := ActionFor(policy)
+	owner := metav1.GetControllerOf(pod)
+	if action == audit.ActionTerminated && (owner == nil || owner.Kind == "DaemonSet") {
+		action = audit.ActionAudit
+	}
+
+	age := at.Sub(pod.CreationTimestamp.Time).Round(time.Second)
+	return []audit.SecurityEvent{{
+		Timestamp:   at.UTC().Format(time.RFC3339),
+		EventType:   PodTooOld,
+		Severity:    "LOW",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Reason:      fmt.Sprintf("Pod is %s old, older than %s", age, policy.Spec.MaxPodAge.Duration),
+		Action:      action,
+		PolicyName:  policy.Name,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Pod '%s' was created at %s and is older than the maximum age of %s set by policy '%s'", pod.Name, pod.CreationTimestamp.UTC().Format(time.RFC3339), policy.Spec.MaxPodAge.Duration, policy.Name),
+	}}
+}
@@ -0,0 +1,52 @@
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestCheckLifetimeAndAgeAtExpiry(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "job", CreationTimestamp: metav1.NewTime(created)},
+		Status:     corev1.PodStatus{StartTime: &metav1.Time{Time: created}},
+	}
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "recycle"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			MaxPodLifetime: &metav1.Duration{Duration: time.Hour},
+			MaxPodAge:      &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "just before", at: created.Add(time.Hour - time.Nanosecond)},
+		{name: "at expiry", at: created.Add(time.Hour), want: true},
+		{name: "after", at: created.Add(2 * time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lifetime := checkLifetime(pod, policy, tt.at)
+			age := checkAge(pod, policy, tt.at)
+			if (len(lifetime) == 1) != tt.want || (len(age) == 1) != tt.want {
+				t.Fatalf("lifetime violations = %d, age violations = %d, want flagged %v", len(lifetime), len(age), tt.want)
+			}
+			// Both are reported at the time they were checked at
+			want := tt.at.UTC().Format(time.RFC3339)
+			for _, violation := range append(lifetime, age...) {
+				if violation.Timestamp != want {
+					t.Errorf("%s timestamp = %s, want %s", violation.EventType, violation.Timestamp, want)
+				}
+			}
+		})
+	}
+}
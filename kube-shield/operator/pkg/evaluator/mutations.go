@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// podContainers returns the pod's containers, init containers and ephemeral
+// containers. Ephemeral containers are converted, they share every checked field.
+func podContainers(pod *corev1.Pod) []corev1.Container {
+	containers := append(append([]corev1.Container{}, pod.Spec.Containers...), pod.Spec.InitContainers...)
+	for _, ephemeral := range pod.Spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(ephemeral.EphemeralContainerCommon))
+	}
+	return containers
+}
+
+// ContainerNames returns the names of all the pod's containers, including init and
+// ephemeral containers
+func ContainerNames(pod *corev1.Pod) []string {
+	containers := podContainers(pod)
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names
+}
+
+// AttachedContainers returns the pod's ephemeral containers that are not in known,
+// the containers it had when it was last evaluated. Ephemeral containers can only be
+// attached to an existing pod, so all of them are returned when nothing is known,
+// e.g. after an operator restart.
+func AttachedContainers(pod *corev1.Pod, known []string) []string {
+	seen := make(map[string]bool, len(known))
+	for _, name := range known {
+		seen[name] = true
+	}
+	var attached []string
+	for _, container := range pod.Spec.EphemeralContainers {
+		if !seen[container.Name] {
+			attached = append(attached, container.Name)
+		}
+	}
+	return attached
+}
+
+// MutationViolations reports the containers attached to a pod after admission, such
+// as ephemeral debug containers added with kubectl debug. They are raised whether or
+// not the new container violates any other check, since attaching to a workload may
+// be a live intrusion. They are only reported, never enforced: a debug session is
+// not a reason to terminate the workload.
+func MutationViolations(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, attached []string) []audit.SecurityEvent {
+	if len(attached) == 0 {
+		return nil
+	}
+
+	images := make(map[string]string)
+	for _, container := range podContainers(pod) {
+		images[container.Name] = container.Image
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var violations []audit.SecurityEvent
+	for _, name := range attached {
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "POD_MUTATED",
+			Severity:    policy.SeverityFor("POD_MUTATED", "CRITICAL"),
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   name,
+			Image:       images[name],
+			Reason:      "Ephemeral container added after admission",
+			Action:      audit.ActionAudit,
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Ephemeral container '%s' (%s) was attached to pod '%s' after admission, a possible live intrusion", name, images[name], pod.Name),
+		})
+	}
+	return violations
+}
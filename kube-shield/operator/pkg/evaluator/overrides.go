@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// RegistryOverrideApplied is reported for every DISALLOWED_REGISTRY violation a
+// pod's AllowedRegistriesAnnotation overrode
+const RegistryOverrideApplied = "REGISTRY_OVERRIDE_APPLIED"
+
+// AnnotatedRegistries returns the registries a pod allows itself through
+// AllowedRegistriesAnnotation. "*" and entries that could never match are ignored,
+// so the annotation cannot lift the restriction altogether.
+func AnnotatedRegistries(pod *corev1.Pod) []string {
+	var registries []string
+	for _, entry := range strings.Split(pod.Annotations[shieldv1alpha1.AllowedRegistriesAnnotation], ",") {
+		entry = strings.TrimRight(strings.TrimSpace(entry), "/")
+		if entry == "" || entry == "*" || shieldv1alpha1.RegistryEntryProblem(entry) != "" {
+			continue
+		}
+		registries = append(registries, entry)
+	}
+	return registries
+}
+
+// ApplyRegistryOverrides merges the registries the pod's AllowedRegistriesAnnotation
+// lists with the policy's AllowedRegistries, for policies with
+// AllowAnnotationRegistryOverrides. It returns the violations left and, so the
+// override is never a silent bypass, a REGISTRY_OVERRIDE_APPLIED event for every
+// DISALLOWED_REGISTRY violation the annotation lifted.
+func ApplyRegistryOverrides(
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	violations []audit.SecurityEvent,
+) ([]audit.SecurityEvent, []audit.SecurityEvent) {
+	if !policy.Spec.AllowAnnotationRegistryOverrides {
+		return violations, nil
+	}
+	annotated := AnnotatedRegistries(pod)
+	if len(annotated) == 0 {
+		return violations, nil
+	}
+
+	var remaining, applied []audit.SecurityEvent
+	for _, violation := range violations {
+		if violation.EventType != "DISALLOWED_REGISTRY" {
+			remaining = append(remaining, violation)
+			continue
+		}
+		image, _ := ResolveMirror(violation.Image, policy.Spec.RegistryAliases)
+		registry := ExtractRegistry(image)
+		if !containsRegistry(annotated, registry) {
+			remaining = append(remaining, violation)
+			continue
+		}
+
+		event := violation
+		event.EventType = RegistryOverrideApplied
+		event.Severity = "INFO"
+		event.Action = audit.ActionExempted
+		event.ExemptedCheck = violation.EventType
+		event.Reason = fmt.Sprintf("Registry %s allowed by pod annotation %s", registry, shieldv1alpha1.AllowedRegistriesAnnotation)
+		event.Description = fmt.Sprintf("Container '%s' uses image from registry '%s', which policy '%s' does not allow but the pod allows itself through %s",
+			violation.Container, registry, policy.Name, shieldv1alpha1.AllowedRegistriesAnnotation)
+		applied = append(applied, event)
+	}
+	return remaining, applied
+}
+
+// containsRegistry reports whether registry is one of registries
+func containsRegistry(registries []string, registry string) bool {
+	for _, candidate := range registries {
+		if candidate == registry {
+			return true
+		}
+	}
+	return false
+}
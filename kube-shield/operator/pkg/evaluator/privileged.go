@@ -0,0 +1,62 @@
+package evaluator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// privilegedViolation reports a privileged container. Init containers that run to
+// completion get their own event type: they can prepare the host and exit, leaving
+// main containers that look clean, so they are called out for triage.
+func privilegedViolation(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, container corev1.Container, now string) audit.SecurityEvent {
+	event := audit.SecurityEvent{
+		Timestamp:   now,
+		EventType:   "PRIVILEGED_CONTAINER",
+		Severity:    "CRITICAL",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Container:   container.Name,
+		Image:       container.Image,
+		Reason:      "Privileged container detected",
+		Action:      ActionFor(policy),
+		PolicyName:  policy.Name,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Container '%s' is running in privileged mode which violates policy '%s'", container.Name, policy.Name),
+	}
+	if !isInitContainer(pod, container.Name) {
+		return event
+	}
+
+	event.EventType = "PRIVILEGED_INIT_CONTAINER"
+	event.Reason = "Privileged init container detected"
+	event.Description = fmt.Sprintf("Init container '%s' is running in privileged mode which violates policy '%s'; it can change host state before exiting", container.Name, policy.Name)
+	if !hasPrivilegedMainContainer(pod) {
+		event.Description += ", and none of the main containers is privileged"
+	}
+	return event
+}
+
+// isInitContainer returns true if the named container is an init container that runs
+// to completion. Sidecars (restartPolicy: Always) keep running like main containers.
+func isInitContainer(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name {
+			return container.RestartPolicy == nil || *container.RestartPolicy != corev1.ContainerRestartPolicyAlways
+		}
+	}
+	return false
+}
+
+// hasPrivilegedMainContainer returns true if any of the pod's main containers is privileged
+func hasPrivilegedMainContainer(pod *corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.SecurityContext != nil && container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+			return true
+		}
+	}
+	return false
+}
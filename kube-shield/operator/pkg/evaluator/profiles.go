@@ -0,0 +1,295 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// baselineCapabilities are the capabilities the baseline profile lets containers add
+var baselineCapabilities = map[corev1.Capability]bool{
+	"AUDIT_WRITE":      true,
+	"CHOWN":            true,
+	"DAC_OVERRIDE":     true,
+	"FOWNER":           true,
+	"FSETID":           true,
+	"KILL":             true,
+	"MKNOD":            true,
+	"NET_BIND_SERVICE": true,
+	"SETFCAP":          true,
+	"SETGID":           true,
+	"SETPCAP":          true,
+	"SETUID":           true,
+	"SYS_CHROOT":       true,
+}
+
+// safeSysctls are the namespaced sysctls the baseline profile allows
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_fin_timeout":            true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+}
+
+// baselineSELinuxTypes are the SELinux types the baseline profile allows
+var baselineSELinuxTypes = map[string]bool{
+	"":                 true,
+	"container_t":      true,
+	"container_init_t": true,
+	"container_kvm_t":  true,
+}
+
+// appArmorAnnotationPrefix is the legacy per-container AppArmor annotation
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// profileChecker collects the violations of a policy's security profile
+type profileChecker struct {
+	pod        *corev1.Pod
+	policy     *shieldv1alpha1.ShieldPolicy
+	now        string
+	violations []audit.SecurityEvent
+}
+
+// add records a violation, for the pod as a whole when container is nil
+func (c *profileChecker) add(container *corev1.Container, eventType, severity, reason, description string) {
+	event := audit.SecurityEvent{
+		Timestamp:   c.now,
+		EventType:   eventType,
+		Severity:    severity,
+		PodName:     c.pod.Name,
+		Namespace:   c.pod.Namespace,
+		Reason:      reason,
+		Action:      ActionFor(c.policy),
+		PolicyName:  c.policy.Name,
+		NodeName:    c.pod.Spec.NodeName,
+		Description: description,
+	}
+	if container != nil {
+		event.Container = container.Name
+		event.Image = container.Image
+	}
+	c.violations = append(c.violations, event)
+}
+
+// checkProfile runs the checks of the policy's Pod Security Standards profile that
+// are not covered by the policy's own fields. Linux-only controls are skipped for
+// Windows pods.
+func checkProfile(
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	containers []corev1.Container,
+	windows bool,
+	now string,
+) []audit.SecurityEvent {
+	if !policy.HasBaselineProfile() {
+		return nil
+	}
+
+	c := &profileChecker{pod: pod, policy: policy, now: now}
+	c.checkBaseline(containers, windows)
+	if policy.HasRestrictedProfile() {
+		c.checkRestricted(containers, windows)
+	}
+	return c.violations
+}
+
+// checkBaseline applies the baseline profile
+func (c *profileChecker) checkBaseline(containers []corev1.Container, windows bool) {
+	pod := c.pod
+
+	if pod.Spec.HostPID {
+		c.add(nil, "HOST_PID", "HIGH", "Pod using host PID namespace",
+			fmt.Sprintf("Pod '%s' shares the host's process namespace and can see every process on the node", pod.Name))
+	}
+	if pod.Spec.HostIPC {
+		c.add(nil, "HOST_IPC", "HIGH", "Pod using host IPC namespace",
+			fmt.Sprintf("Pod '%s' shares the host's IPC namespace", pod.Name))
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.HostPath != nil {
+			c.add(nil, "HOST_PATH_VOLUME", "HIGH", fmt.Sprintf("hostPath volume %s", volume.HostPath.Path),
+				fmt.Sprintf("Volume '%s' mounts host path '%s' into the pod", volume.Name, volume.HostPath.Path))
+		}
+	}
+
+	for i := range containers {
+		container := &containers[i]
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				c.add(container, "HOST_PORT", "MEDIUM", fmt.Sprintf("Host port %d", port.HostPort),
+					fmt.Sprintf("Container '%s' binds host port %d", container.Name, port.HostPort))
+			}
+		}
+	}
+
+	if windows {
+		return
+	}
+
+	podSC := pod.Spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+	if podSC.SeccompProfile != nil && podSC.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		c.add(nil, "SECCOMP_PROFILE", "HIGH", "Unconfined seccomp profile",
+			fmt.Sprintf("Pod '%s' disables seccomp filtering", pod.Name))
+	}
+	if podSC.AppArmorProfile != nil && podSC.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		c.add(nil, "APPARMOR_PROFILE", "HIGH", "Unconfined AppArmor profile",
+			fmt.Sprintf("Pod '%s' disables AppArmor confinement", pod.Name))
+	}
+	if reason := seLinuxViolation(podSC.SELinuxOptions); reason != "" {
+		c.add(nil, "SELINUX_OPTIONS", "HIGH", reason,
+			fmt.Sprintf("Pod '%s' sets SELinux options the baseline profile forbids", pod.Name))
+	}
+	for _, sysctl := range podSC.Sysctls {
+		if !safeSysctls[sysctl.Name] {
+			c.add(nil, "UNSAFE_SYSCTL", "HIGH", fmt.Sprintf("Unsafe sysctl %s", sysctl.Name),
+				fmt.Sprintf("Pod '%s' sets sysctl '%s' which is not in the safe set", pod.Name, sysctl.Name))
+		}
+	}
+
+	for i := range containers {
+		container := &containers[i]
+		if value, ok := pod.Annotations[appArmorAnnotationPrefix+container.Name]; ok &&
+			value != "runtime/default" && !strings.HasPrefix(value, "localhost/") {
+			c.add(container, "APPARMOR_PROFILE", "HIGH", fmt.Sprintf("AppArmor profile %s", value),
+				fmt.Sprintf("Container '%s' runs with AppArmor profile '%s'", container.Name, value))
+		}
+
+		sc := container.SecurityContext
+		if sc == nil {
+			continue
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			c.add(container, "SECCOMP_PROFILE", "HIGH", "Unconfined seccomp profile",
+				fmt.Sprintf("Container '%s' disables seccomp filtering", container.Name))
+		}
+		if sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			c.add(container, "APPARMOR_PROFILE", "HIGH", "Unconfined AppArmor profile",
+				fmt.Sprintf("Container '%s' disables AppArmor confinement", container.Name))
+		}
+		if reason := seLinuxViolation(sc.SELinuxOptions); reason != "" {
+			c.add(container, "SELINUX_OPTIONS", "HIGH", reason,
+				fmt.Sprintf("Container '%s' sets SELinux options the baseline profile forbids", container.Name))
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			c.add(container, "PROC_MOUNT", "HIGH", fmt.Sprintf("procMount %s", *sc.ProcMount),
+				fmt.Sprintf("Container '%s' unmasks /proc", container.Name))
+		}
+		if sc.Capabilities != nil {
+			var added []string
+			for _, capability := range sc.Capabilities.Add {
+				if !baselineCapabilities[capability] {
+					added = append(added, string(capability))
+				}
+			}
+			if len(added) > 0 {
+				c.add(container, "ADDED_CAPABILITIES", "HIGH", fmt.Sprintf("Capabilities added: %s", strings.Join(added, ", ")),
+					fmt.Sprintf("Container '%s' adds capabilities beyond the baseline set: %s", container.Name, strings.Join(added, ", ")))
+			}
+		}
+	}
+}
+
+// checkRestricted applies the controls the restricted profile adds to baseline
+func (c *profileChecker) checkRestricted(containers []corev1.Container, windows bool) {
+	pod := c.pod
+
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.ConfigMap != nil, volume.CSI != nil, volume.DownwardAPI != nil, volume.EmptyDir != nil,
+			volume.Ephemeral != nil, volume.PersistentVolumeClaim != nil, volume.Projected != nil, volume.Secret != nil:
+		default:
+			c.add(nil, "RESTRICTED_VOLUME_TYPE", "MEDIUM", "Volume type not allowed by the restricted profile",
+				fmt.Sprintf("Volume '%s' uses a volume type outside the restricted profile's allowed set", volume.Name))
+		}
+	}
+
+	if windows {
+		return
+	}
+
+	podSC := pod.Spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+	podRunAsNonRoot := podSC.RunAsNonRoot != nil && *podSC.RunAsNonRoot
+	podSeccomp := podSC.SeccompProfile != nil
+
+	for i := range containers {
+		container := &containers[i]
+		sc := container.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			c.add(container, "PRIVILEGE_ESCALATION", "MEDIUM", "allowPrivilegeEscalation not set to false",
+				fmt.Sprintf("Container '%s' can gain more privileges than its parent process", container.Name))
+		}
+
+		runAsNonRoot := podRunAsNonRoot
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = *sc.RunAsNonRoot
+		}
+		if !runAsNonRoot {
+			c.add(container, "RUN_AS_NON_ROOT", "MEDIUM", "runAsNonRoot not set to true",
+				fmt.Sprintf("Container '%s' is not required to run as a non-root user", container.Name))
+		}
+
+		if sc.SeccompProfile == nil && !podSeccomp {
+			c.add(container, "SECCOMP_PROFILE", "MEDIUM", "No seccomp profile",
+				fmt.Sprintf("Container '%s' must use the RuntimeDefault or a Localhost seccomp profile", container.Name))
+		}
+
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" && baselineCapabilities[capability] {
+					c.add(container, "ADDED_CAPABILITIES", "MEDIUM", fmt.Sprintf("Capability added: %s", capability),
+						fmt.Sprintf("Container '%s' adds capability %s, only NET_BIND_SERVICE is allowed", container.Name, capability))
+				}
+			}
+		}
+	}
+}
+
+// seLinuxViolation explains why SELinux options are not allowed by the baseline
+// profile, or returns an empty string when they are
+func seLinuxViolation(options *corev1.SELinuxOptions) string {
+	switch {
+	case options == nil:
+		return ""
+	case !baselineSELinuxTypes[options.Type]:
+		return fmt.Sprintf("SELinux type %s", options.Type)
+	case options.User != "":
+		return fmt.Sprintf("SELinux user %s", options.User)
+	case options.Role != "":
+		return fmt.Sprintf("SELinux role %s", options.Role)
+	default:
+		return ""
+	}
+}
+
+// dropsAllCapabilities returns true if the capabilities drop ALL
+func dropsAllCapabilities(capabilities *corev1.Capabilities) bool {
+	if capabilities == nil {
+		return false
+	}
+	for _, capability := range capabilities.Drop {
+		if strings.EqualFold(string(capability), "ALL") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package evaluator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// PublicRegistryImage is raised for images from a public registry under a policy
+// with BlockPublicRegistries
+const PublicRegistryImage = "PUBLIC_REGISTRY_IMAGE"
+
+// DefaultPublicRegistries are the registry globs BlockPublicRegistries treats as
+// public unless the operator is configured with others
+var DefaultPublicRegistries = []string{
+	"docker.io",
+	"ghcr.io",
+	"quay.io",
+	"gcr.io",
+	"*.gcr.io",
+	"registry.k8s.io",
+	"k8s.gcr.io",
+	"public.ecr.aws",
+	"mcr.microsoft.com",
+	"registry.gitlab.com",
+	"nvcr.io",
+}
+
+// checkPublicRegistries flags containers whose image comes from a public registry,
+// for namespaces that must only run privately built images. Images pulled through a
+// mirror are checked as the registry they mirror.
+func checkPublicRegistries(
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	containers []corev1.Container,
+	public []string,
+	now string,
+) []audit.SecurityEvent {
+	if !policy.Spec.BlockPublicRegistries {
+		return nil
+	}
+
+	var violations []audit.SecurityEvent
+	for _, container := range containers {
+		image, mirror := ResolveMirror(container.Image, policy.Spec.RegistryAliases)
+		registry := ExtractRegistry(image)
+		pattern, ok := matchRegistryPattern(public, registry)
+		if !ok {
+			continue
+		}
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   PublicRegistryImage,
+			Severity:    "MEDIUM",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   container.Name,
+			Image:       container.Image,
+			Reason:      fmt.Sprintf("Image from public registry: %s", registry),
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Container '%s' uses image from public registry '%s'%s (matches '%s'), only private images are allowed", container.Name, registry, viaMirror(mirror), pattern),
+		})
+	}
+	return violations
+}
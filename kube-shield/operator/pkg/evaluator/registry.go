@@ -0,0 +1,149 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// checkPullSecrets flags containers pulling from a registry listed in
+// RequireImagePullSecretFor when neither the pod nor its ServiceAccount provides
+// an imagePullSecret. The ServiceAccount is only consulted when secrets is not nil.
+func checkPullSecrets(
+	ctx context.Context,
+	secrets PullSecretLookup,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	containers []corev1.Container,
+	now string,
+) []audit.SecurityEvent {
+	if len(policy.Spec.RequireImagePullSecretFor) == 0 || len(pod.Spec.ImagePullSecrets) > 0 {
+		return nil
+	}
+
+	var violations []audit.SecurityEvent
+	resolved := false
+	for _, container := range containers {
+		registry := ExtractRegistry(container.Image)
+		pattern, ok := matchRegistryPattern(policy.Spec.RequireImagePullSecretFor, registry)
+		if !ok {
+			continue
+		}
+
+		// Only fetch the ServiceAccount once a container actually needs credentials
+		if !resolved && secrets != nil {
+			if len(secrets.PullSecrets(ctx, pod.Namespace, pod.Spec.ServiceAccountName)) > 0 {
+				return nil
+			}
+		}
+		resolved = true
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "MISSING_PULL_SECRET",
+			Severity:    "LOW",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   container.Name,
+			Image:       container.Image,
+			Reason:      fmt.Sprintf("No imagePullSecret for private registry: %s", registry),
+			Action:      audit.ActionAudit,
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Container '%s' pulls from registry '%s' (matches '%s') but neither the pod nor its service account has an imagePullSecret", container.Name, registry, pattern),
+		})
+	}
+
+	return violations
+}
+
+// matchRegistryPattern returns the first glob pattern that matches registry
+func matchRegistryPattern(patterns []string, registry string) (string, bool) {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, registry); err == nil && matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// ResolveMirror rewrites an image pulled through a mirror listed in aliases to the
+// registry it mirrors, so "mirror.internal:5000/docker.io/library/nginx" with the
+// alias "mirror.internal:5000/docker.io" -> "docker.io" becomes
+// "docker.io/library/nginx". Prefixes match whole path segments and the longest one
+// wins. It also returns the mirror prefix that was applied, empty when none was.
+func ResolveMirror(image string, aliases map[string]string) (string, string) {
+	var mirror string
+	for prefix := range aliases {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix == "" || !strings.HasPrefix(image, prefix+"/") {
+			continue
+		}
+		if len(prefix) > len(mirror) {
+			mirror = prefix
+		}
+	}
+	if mirror == "" {
+		return image, ""
+	}
+
+	registry := aliases[mirror]
+	if registry == "" {
+		registry = aliases[mirror+"/"]
+	}
+	return strings.TrimSuffix(registry, "/") + strings.TrimPrefix(image, mirror), mirror
+}
+
+// viaMirror describes the mirror an image was resolved through, for event descriptions
+func viaMirror(mirror string) string {
+	if mirror == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (via mirror %s)", mirror)
+}
+
+// ExtractRepository returns the registry and repository path of a container image,
+// without tag or digest. Docker Hub images are expanded, so "nginx:1.25" becomes
+// "docker.io/library/nginx" and "team/app" becomes "docker.io/team/app".
+func ExtractRepository(image string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	// A tag follows the last colon, unless that colon belongs to a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+
+	registry := ExtractRegistry(image)
+	name = strings.TrimPrefix(name, registry+"/")
+	if registry == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	return registry + "/" + name
+}
+
+// ExtractRegistry extracts the registry from a container image
+func ExtractRegistry(image string) string {
+	// Handle images without explicit registry (default to docker.io)
+	if !strings.Contains(image, "/") {
+		return "docker.io"
+	}
+
+	parts := strings.Split(image, "/")
+	firstPart := parts[0]
+
+	// Check if first part looks like a registry (contains . or :)
+	if strings.Contains(firstPart, ".") || strings.Contains(firstPart, ":") {
+		return firstPart
+	}
+
+	// Otherwise, it's a docker.io library image
+	return "docker.io"
+}
@@ -0,0 +1,144 @@
+package evaluator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func TestExtractRepository(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx", want: "docker.io/library/nginx"},
+		{image: "nginx:1.25", want: "docker.io/library/nginx"},
+		{image: "team/app:v1", want: "docker.io/team/app"},
+		{image: "docker.io/nginx", want: "docker.io/library/nginx"},
+		{image: "docker.io/library/nginx:1.25", want: "docker.io/library/nginx"},
+		{image: "gcr.io/myproject/app:v1", want: "gcr.io/myproject/app"},
+		{image: "gcr.io/myproject/team/app:v1", want: "gcr.io/myproject/team/app"},
+		{image: "gcr.io/myproject/team/sub/app", want: "gcr.io/myproject/team/sub/app"},
+		{image: "registry.example.com:5000/org/sub/app", want: "registry.example.com:5000/org/sub/app"},
+		{image: "registry.example.com:5000/org/sub/app:1.0", want: "registry.example.com:5000/org/sub/app"},
+		{image: "registry.example.com/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", want: "registry.example.com/org/app"},
+		{image: "registry.example.com:5000/org/app:1.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", want: "registry.example.com:5000/org/app"},
+		{image: "localhost:5000/app", want: "localhost:5000/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := ExtractRepository(tt.image); got != tt.want {
+				t.Errorf("ExtractRepository(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx:1.25", want: "docker.io"},
+		{image: "team/app", want: "docker.io"},
+		{image: "gcr.io/myproject/team/app", want: "gcr.io"},
+		{image: "registry.example.com:5000/org/app", want: "registry.example.com:5000"},
+		{image: "localhost:5000/app", want: "localhost:5000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := ExtractRegistry(tt.image); got != tt.want {
+				t.Errorf("ExtractRegistry(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMirror(t *testing.T) {
+	aliases := map[string]string{
+		"mirror.internal:5000":                "registry.example.com",
+		"mirror.internal:5000/docker.io":      "docker.io",
+		"mirror.internal:5000/docker.io/team": "registry.example.com/team/",
+		"proxy.internal/quay/":                "quay.io",
+	}
+	tests := []struct {
+		image      string
+		want       string
+		wantMirror string
+	}{
+		{image: "nginx:1.25", want: "nginx:1.25"},
+		{image: "gcr.io/myproject/app:v1", want: "gcr.io/myproject/app:v1"},
+		{image: "mirror.internal:5000/app:1.0", want: "registry.example.com/app:1.0", wantMirror: "mirror.internal:5000"},
+		// The longest matching prefix wins
+		{image: "mirror.internal:5000/docker.io/library/nginx:1.25", want: "docker.io/library/nginx:1.25", wantMirror: "mirror.internal:5000/docker.io"},
+		{image: "mirror.internal:5000/docker.io/team/app:v1", want: "registry.example.com/team/app:v1", wantMirror: "mirror.internal:5000/docker.io/team"},
+		// Prefixes match whole path segments only
+		{image: "mirror.internal:5000/docker.iox/app", want: "registry.example.com/docker.iox/app", wantMirror: "mirror.internal:5000"},
+		{image: "mirror.internal:50000/app", want: "mirror.internal:50000/app"},
+		{image: "mirror.internal:5000", want: "mirror.internal:5000"},
+		// Trailing slashes on either side are ignored
+		{image: "proxy.internal/quay/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", want: "quay.io/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", wantMirror: "proxy.internal/quay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, mirror := ResolveMirror(tt.image, aliases)
+			if got != tt.want || mirror != tt.wantMirror {
+				t.Errorf("ResolveMirror(%q) = %q, %q, want %q, %q", tt.image, got, mirror, tt.want, tt.wantMirror)
+			}
+		})
+	}
+}
+
+func TestResolveMirrorWithoutAliases(t *testing.T) {
+	if got, mirror := ResolveMirror("mirror.internal:5000/app", nil); got != "mirror.internal:5000/app" || mirror != "" {
+		t.Errorf("ResolveMirror without aliases = %q, %q, want the image unchanged", got, mirror)
+	}
+}
+
+func TestEvaluateRegistryAliases(t *testing.T) {
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			AllowedRegistries:        []string{"docker.io", "registry.example.com"},
+			AllowedImageRepositories: []string{"docker.io/library/*", "registry.example.com/team/*"},
+			RegistryAliases: map[string]string{
+				"mirror.internal:5000/docker.io":      "docker.io",
+				"mirror.internal:5000/docker.io/team": "registry.example.com/team",
+			},
+		},
+	}
+	tests := []struct {
+		image string
+		want  []string
+	}{
+		{image: "mirror.internal:5000/docker.io/library/nginx:1.25"},
+		{image: "mirror.internal:5000/docker.io/team/app:v1"},
+		{image: "mirror.internal:5000/docker.io/other/app:v1", want: []string{"DISALLOWED_REPOSITORY"}},
+		{image: "mirror.internal:5000/app:v1", want: []string{"DISALLOWED_REGISTRY", "DISALLOWED_REPOSITORY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			pod := testPod()
+			pod.Spec.Containers[0].Image = tt.image
+
+			violations := New(nil).Evaluate(pod, policy)
+			if got := eventTypes(violations); !slices.Equal(got, tt.want) {
+				t.Fatalf("violations = %v, want %v", got, tt.want)
+			}
+			// Reports name the image as pulled, and the mirror when one applied
+			_, mirror := ResolveMirror(tt.image, policy.Spec.RegistryAliases)
+			for _, violation := range violations {
+				if violation.Image != tt.image {
+					t.Errorf("Image = %q, want %q", violation.Image, tt.image)
+				}
+				if named := strings.Contains(violation.Description, "via mirror "+mirror); named != (mirror != "") {
+					t.Errorf("Description = %q, want the mirror named only for resolved images", violation.Description)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// requiredResources are the resources RequireResourceRequests and
+// RequireResourceLimits expect every container to declare
+var requiredResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+// checkResources flags containers that leave the CPU or memory request or limit
+// unset. Ephemeral containers cannot declare resources and are not checked.
+func checkResources(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, now string) []audit.SecurityEvent {
+	if !policy.Spec.RequireResourceRequests && !policy.Spec.RequireResourceLimits {
+		return nil
+	}
+
+	var violations []audit.SecurityEvent
+	containers := append(append([]corev1.Container{}, pod.Spec.Containers...), pod.Spec.InitContainers...)
+	for _, container := range containers {
+		if policy.Spec.RequireResourceRequests {
+			if missing := missingResources(container.Resources.Requests); len(missing) > 0 {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "MISSING_RESOURCE_REQUESTS",
+					Severity:    "LOW",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      fmt.Sprintf("Resource requests not set: %s", strings.Join(missing, ", ")),
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' does not request %s, so the scheduler cannot account for it", container.Name, strings.Join(missing, " or ")),
+				})
+			}
+		}
+
+		if policy.Spec.RequireResourceLimits {
+			if missing := missingResources(container.Resources.Limits); len(missing) > 0 {
+				violations = append(violations, audit.SecurityEvent{
+					Timestamp:   now,
+					EventType:   "MISSING_RESOURCE_LIMITS",
+					Severity:    "MEDIUM",
+					PodName:     pod.Name,
+					Namespace:   pod.Namespace,
+					Container:   container.Name,
+					Image:       container.Image,
+					Reason:      fmt.Sprintf("Resource limits not set: %s", strings.Join(missing, ", ")),
+					Action:      ActionFor(policy),
+					PolicyName:  policy.Name,
+					NodeName:    pod.Spec.NodeName,
+					Description: fmt.Sprintf("Container '%s' has no %s limit and can starve other workloads on its node", container.Name, strings.Join(missing, " or ")),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// missingResources lists the requiredResources absent from a container's requests or limits
+func missingResources(resources corev1.ResourceList) []string {
+	var missing []string
+	for _, name := range requiredResources {
+		if _, ok := resources[name]; !ok {
+			missing = append(missing, string(name))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/celrules"
+)
+
+// checkCustomRules evaluates a policy's CEL rules and returns a violation for every rule that matches
+func (e *Evaluator) checkCustomRules(
+	ctx context.Context,
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	now string,
+) []audit.SecurityEvent {
+	if len(policy.Spec.Rules) == 0 || e.rules == nil {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+	input, err := celrules.PodInput(pod)
+	if err != nil {
+		logger.Error(err, "Failed to convert pod for CEL evaluation")
+		return nil
+	}
+
+	var violations []audit.SecurityEvent
+	for _, rule := range policy.Spec.Rules {
+		program, err := e.rules.Compile(policy.Name, policy.Generation, rule.Expression)
+		if err != nil {
+			// Compile errors are surfaced on the policy status by the policy controller
+			continue
+		}
+
+		matched, err := celrules.Evaluate(program, input)
+		if err != nil {
+			logger.V(1).Info("Failed to evaluate CEL rule", "policy", policy.Name, "rule", rule.Name, "error", err.Error())
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		severity := rule.Severity
+		if severity == "" {
+			severity = "MEDIUM"
+		}
+		action := audit.ActionAudit
+		if rule.Action == "Enforce" {
+			action = ActionFor(policy)
+		}
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "CUSTOM_RULE_VIOLATION",
+			Rule:        rule.Name,
+			Severity:    severity,
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Reason:      fmt.Sprintf("Custom rule violated: %s", rule.Name),
+			Action:      action,
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Pod '%s' matches custom rule '%s' (%s) of policy '%s'", pod.Name, rule.Name, rule.Expression, policy.Name),
+		})
+	}
+
+	return violations
+}
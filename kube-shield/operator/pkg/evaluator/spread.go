@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// replicatedOwnerKinds are the controllers whose pods are replicas of one workload.
+// DaemonSets already place one pod per node and Jobs are not long-running replicas.
+var replicatedOwnerKinds = map[string]bool{
+	"ReplicaSet":            true,
+	"StatefulSet":           true,
+	"ReplicationController": true,
+}
+
+// checkAntiAffinity flags replicas that the scheduler may pack onto a single node.
+// The operator sees pods, not workloads, so the workload is judged by its pod: a
+// pod owned by a ReplicaSet, StatefulSet or ReplicationController carries its
+// template's affinity and spread constraints. Only hard requirements count, i.e.
+// required pod anti-affinity or a topology spread constraint with DoNotSchedule.
+// The check is advisory and never terminates pods.
+func checkAntiAffinity(pod *corev1.Pod, policy *shieldv1alpha1.ShieldPolicy, now string) []audit.SecurityEvent {
+	if !policy.Spec.RequireAntiAffinity {
+		return nil
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || !replicatedOwnerKinds[owner.Kind] || spreadsReplicas(pod) {
+		return nil
+	}
+
+	action := ActionFor(policy)
+	if action == audit.ActionTerminated {
+		action = audit.ActionAudit
+	}
+	return []audit.SecurityEvent{{
+		Timestamp:   now,
+		EventType:   "MISSING_ANTIAFFINITY",
+		Severity:    "LOW",
+		PodName:     pod.Name,
+		Namespace:   pod.Namespace,
+		Reason:      fmt.Sprintf("%s %s does not require its replicas to be spread", owner.Kind, owner.Name),
+		Action:      action,
+		PolicyName:  policy.Name,
+		NodeName:    pod.Spec.NodeName,
+		Description: fmt.Sprintf("Pod '%s' of %s '%s' has neither required pod anti-affinity nor a DoNotSchedule topology spread constraint, so all replicas may land on one node", pod.Name, owner.Kind, owner.Name),
+	}}
+}
+
+// spreadsReplicas returns true if the pod requires its replicas to be spread out
+func spreadsReplicas(pod *corev1.Pod) bool {
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.PodAntiAffinity != nil &&
+		len(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 {
+		return true
+	}
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		if constraint.WhenUnsatisfiable == corev1.DoNotSchedule {
+			return true
+		}
+	}
+	return false
+}
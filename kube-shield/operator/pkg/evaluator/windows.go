@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// osLabel is the well-known node label pods select their operating system with
+const osLabel = "kubernetes.io/os"
+
+// isWindowsPod reports whether a pod targets Windows nodes. Linux security context
+// fields such as privileged and runAsUser are ignored on Windows.
+func isWindowsPod(pod *corev1.Pod) bool {
+	return PodOS(pod) == corev1.Windows
+}
+
+// PodOS returns the operating system a pod declares, or "" if it does not say.
+// spec.os.name wins; otherwise the OS is taken from a kubernetes.io/os nodeSelector
+// or required node affinity, and HostProcess containers, which only exist on
+// Windows, mark the pod as a Windows pod.
+func PodOS(pod *corev1.Pod) corev1.OSName {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name
+	}
+	if os := pod.Spec.NodeSelector[osLabel]; os != "" {
+		return corev1.OSName(os)
+	}
+	if os := affinityOS(pod); os != "" {
+		return os
+	}
+	if hasHostProcess(pod) {
+		return corev1.Windows
+	}
+	return ""
+}
+
+// affinityOS returns the OS every required node affinity term pins the pod to, or
+// "" if any term allows another or none
+func affinityOS(pod *corev1.Pod) corev1.OSName {
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+
+	var os corev1.OSName
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		var termOS corev1.OSName
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == osLabel && expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) == 1 {
+				termOS = corev1.OSName(expr.Values[0])
+			}
+		}
+		// Terms are alternatives, each has to agree
+		if termOS == "" || os != "" && termOS != os {
+			return ""
+		}
+		os = termOS
+	}
+	return os
+}
+
+// hasHostProcess reports whether the pod or any of its containers asks for
+// hostProcess, which the API server only admits for Windows pods
+func hasHostProcess(pod *corev1.Pod) bool {
+	if sc := pod.Spec.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+		return true
+	}
+	containers := append(append([]corev1.Container{}, pod.Spec.Containers...), pod.Spec.InitContainers...)
+	for _, container := range containers {
+		if sc := container.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			return true
+		}
+	}
+	return false
+}
+
+// WithNodeOS returns the pod, or a copy of it with spec.os set from the labels of
+// the node it runs on when the pod does not declare its OS itself. Pods created
+// without any OS hint still get the Windows checks once they land on a Windows node.
+func WithNodeOS(pod *corev1.Pod, nodeLabels map[string]string) *corev1.Pod {
+	os := nodeLabels[osLabel]
+	if os == "" || PodOS(pod) != "" {
+		return pod
+	}
+	pod = pod.DeepCopy()
+	pod.Spec.OS = &corev1.PodOS{Name: corev1.OSName(os)}
+	return pod
+}
+
+// checkHostProcess flags Windows HostProcess containers, the Windows equivalent of
+// privileged containers. A container inherits hostProcess from the pod's
+// windowsOptions unless it sets its own.
+func checkHostProcess(
+	pod *corev1.Pod,
+	policy *shieldv1alpha1.ShieldPolicy,
+	containers []corev1.Container,
+	now string,
+) []audit.SecurityEvent {
+	if !policy.ShouldBlockPrivileged() {
+		return nil
+	}
+
+	podHostProcess := false
+	if sc := pod.Spec.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil {
+		podHostProcess = *sc.WindowsOptions.HostProcess
+	}
+
+	var violations []audit.SecurityEvent
+	for _, container := range containers {
+		hostProcess := podHostProcess
+		if sc := container.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil {
+			hostProcess = *sc.WindowsOptions.HostProcess
+		}
+		if !hostProcess {
+			continue
+		}
+
+		violations = append(violations, audit.SecurityEvent{
+			Timestamp:   now,
+			EventType:   "HOST_PROCESS",
+			Severity:    "CRITICAL",
+			PodName:     pod.Name,
+			Namespace:   pod.Namespace,
+			Container:   container.Name,
+			Image:       container.Image,
+			Reason:      "Windows HostProcess container detected",
+			Action:      ActionFor(policy),
+			PolicyName:  policy.Name,
+			NodeName:    pod.Spec.NodeName,
+			Description: fmt.Sprintf("Container '%s' runs as a Windows HostProcess container with full access to the node, which violates policy '%s'", container.Name, policy.Name),
+		})
+	}
+	return violations
+}
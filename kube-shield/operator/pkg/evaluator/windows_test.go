@@ -0,0 +1,202 @@
+package evaluator
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+// riskyPod is a pod asking for everything the Linux checks flag: a privileged root
+// container keeping its capabilities in a shared process namespace
+func riskyPod(name string) *corev1.Pod {
+	yes, root := true, int64(0)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: corev1.PodSpec{
+			ShareProcessNamespace: &yes,
+			Containers: []corev1.Container{{
+				Name:            "app",
+				Image:           "registry.example.com/app:1.0",
+				SecurityContext: &corev1.SecurityContext{Privileged: &yes, RunAsUser: &root},
+			}},
+		},
+	}
+}
+
+// linuxPod is riskyPod scheduled on Linux nodes
+func linuxPod() *corev1.Pod {
+	pod := riskyPod("linux-app")
+	pod.Spec.OS = &corev1.PodOS{Name: corev1.Linux}
+	return pod
+}
+
+// windowsPod is riskyPod scheduled on Windows nodes through a nodeSelector
+func windowsPod() *corev1.Pod {
+	pod := riskyPod("windows-app")
+	pod.Spec.NodeSelector = map[string]string{osLabel: string(corev1.Windows)}
+	return pod
+}
+
+// hostProcessPod is a Windows pod whose containers inherit hostProcess from the pod,
+// except for a sidecar opting out
+func hostProcessPod() *corev1.Pod {
+	yes, no := true, false
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "node-agent"},
+		Spec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &yes},
+			},
+			HostNetwork: true,
+			Containers: []corev1.Container{
+				{Name: "agent", Image: "registry.example.com/agent:1.0"},
+				{Name: "sidecar", Image: "registry.example.com/sidecar:1.0", SecurityContext: &corev1.SecurityContext{
+					WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &no},
+				}},
+			},
+		},
+	}
+}
+
+func strictPolicy() *shieldv1alpha1.ShieldPolicy {
+	yes := true
+	return &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+		Spec: shieldv1alpha1.ShieldPolicySpec{
+			BlockPrivileged:             &yes,
+			RequireDropAllCapabilities:  &yes,
+			BlockSharedProcessNamespace: true,
+		},
+	}
+}
+
+func TestPodOS(t *testing.T) {
+	affinity := func(terms ...string) *corev1.Affinity {
+		selector := &corev1.NodeSelector{}
+		for _, os := range terms {
+			selector.NodeSelectorTerms = append(selector.NodeSelectorTerms, corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{Key: osLabel, Operator: corev1.NodeSelectorOpIn, Values: []string{os}}},
+			})
+		}
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: selector}}
+	}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want corev1.OSName
+	}{
+		{name: "spec.os", pod: linuxPod(), want: corev1.Linux},
+		{name: "node selector", pod: windowsPod(), want: corev1.Windows},
+		{name: "host process", pod: hostProcessPod(), want: corev1.Windows},
+		{name: "no hint", pod: riskyPod("app")},
+		{
+			name: "spec.os wins over the node selector",
+			pod: func() *corev1.Pod {
+				pod := windowsPod()
+				pod.Spec.OS = &corev1.PodOS{Name: corev1.Linux}
+				return pod
+			}(),
+			want: corev1.Linux,
+		},
+		{
+			name: "required node affinity",
+			pod: func() *corev1.Pod {
+				pod := riskyPod("app")
+				pod.Spec.Affinity = affinity("windows", "windows")
+				return pod
+			}(),
+			want: corev1.Windows,
+		},
+		{
+			name: "node affinity allowing both",
+			pod: func() *corev1.Pod {
+				pod := riskyPod("app")
+				pod.Spec.Affinity = affinity("windows", "linux")
+				return pod
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PodOS(tt.pod); got != tt.want {
+				t.Errorf("PodOS = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNodeOS(t *testing.T) {
+	windowsNode := map[string]string{osLabel: string(corev1.Windows)}
+
+	pod := riskyPod("app")
+	got := WithNodeOS(pod, windowsNode)
+	if PodOS(got) != corev1.Windows {
+		t.Errorf("PodOS on a Windows node = %q, want windows", PodOS(got))
+	}
+	if pod.Spec.OS != nil {
+		t.Error("WithNodeOS modified the pod it was given")
+	}
+
+	if linux := linuxPod(); WithNodeOS(linux, windowsNode) != linux {
+		t.Error("WithNodeOS replaced the OS a pod declares")
+	}
+	if got := WithNodeOS(pod, nil); got != pod {
+		t.Error("WithNodeOS changed a pod without node labels")
+	}
+}
+
+func TestEvaluateMixedOS(t *testing.T) {
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		policy *shieldv1alpha1.ShieldPolicy
+		want   []string
+	}{
+		{
+			name:   "linux pod",
+			pod:    linuxPod(),
+			policy: strictPolicy(),
+			want:   []string{"SHARED_PROCESS_NAMESPACE", "PRIVILEGED_CONTAINER", "CAPABILITIES_NOT_DROPPED", "ROOT_USER"},
+		},
+		{
+			name:   "windows pod skips linux-only checks",
+			pod:    windowsPod(),
+			policy: strictPolicy(),
+		},
+		{
+			name:   "windows pod on a windows node",
+			pod:    WithNodeOS(riskyPod("app"), map[string]string{osLabel: string(corev1.Windows)}),
+			policy: strictPolicy(),
+		},
+		{
+			name:   "host process containers",
+			pod:    hostProcessPod(),
+			policy: strictPolicy(),
+			want:   []string{"HOST_NETWORK", "HOST_PROCESS"},
+		},
+		{
+			name:   "host process allowed",
+			pod:    hostProcessPod(),
+			policy: &shieldv1alpha1.ShieldPolicy{ObjectMeta: metav1.ObjectMeta{Name: "permissive"}},
+			want:   []string{"HOST_NETWORK"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := New(nil).Evaluate(tt.pod, tt.policy)
+			if got := eventTypes(violations); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+			for _, violation := range violations {
+				if violation.EventType == "HOST_PROCESS" && violation.Container != "agent" {
+					t.Errorf("HOST_PROCESS reported for container %q, want only the one inheriting it", violation.Container)
+				}
+			}
+		})
+	}
+}
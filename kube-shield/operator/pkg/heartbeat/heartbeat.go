@@ -0,0 +1,204 @@
+// Package heartbeat periodically tells the audit service that the operator is alive,
+// so that silence can be told apart from a cluster without violations.
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+const (
+	// podsEvaluatedMetric is the counter the pods evaluated since the last heartbeat
+	// are derived from
+	podsEvaluatedMetric = "kubeshield_pods_evaluated_total"
+
+	// queueDepthMetric is controller-runtime's gauge of each controller's work queue
+	queueDepthMetric = "workqueue_depth"
+
+	// modeSimulated counts simulated policies apart from their enforcement mode
+	modeSimulated = "Simulated"
+)
+
+// Heartbeat is the liveness and inventory report posted to the audit service
+type Heartbeat struct {
+	Time            string         `json:"time"`
+	Interval        string         `json:"interval"`
+	OperatorVersion string         `json:"operatorVersion"`
+	Leader          string         `json:"leader"`
+	Policies        map[string]int `json:"policies"`
+	PodsEvaluated   int64          `json:"podsEvaluated"`
+	QueueDepths     map[string]int `json:"queueDepths"`
+	BufferedEvents  map[string]int `json:"bufferedEvents"`
+}
+
+// bufferedSink is a Sink that holds events before delivering them
+type bufferedSink interface {
+	audit.Sink
+	Buffered() int
+}
+
+// Sender posts a Heartbeat to the audit service's /heartbeat endpoint every
+// Interval. It runs as a Job on the leader.
+type Sender struct {
+	Reader     client.Reader
+	HTTPClient *http.Client
+	URL        string
+	Interval   time.Duration
+	Identity   string
+	Sinks      []audit.Sink
+
+	evaluated int64
+}
+
+// NewSender creates a Sender. url is the audit service base URL, httpClient the one
+// security events are sent with, identity names this replica, and the buffer depths
+// of sinks are included in every heartbeat.
+func NewSender(reader client.Reader, httpClient *http.Client, url string, interval time.Duration, identity string, sinks []audit.Sink) *Sender {
+	return &Sender{
+		Reader:     reader,
+		HTTPClient: httpClient,
+		URL:        url,
+		Interval:   interval,
+		Identity:   identity,
+		Sinks:      sinks,
+	}
+}
+
+// Job returns the job sending the heartbeats. Only the leader reports, so the audit
+// service sees which replica is active. A heartbeat is sent right away on becoming
+// leader and then on every interval.
+func (s *Sender) Job() schedule.Job {
+	return schedule.Job{
+		Name:      "heartbeat",
+		Interval:  s.Interval,
+		Immediate: true,
+		Run:       s.beat,
+	}
+}
+
+// beat builds and sends a single heartbeat. Failures never stop the operator, they
+// are logged and counted.
+func (s *Sender) beat(ctx context.Context, logger logr.Logger) {
+	heartbeat, err := s.Build(ctx, time.Now())
+	if err != nil {
+		// The heartbeat is still useful without the policy inventory
+		logger.V(1).Info("Failed to count ShieldPolicies for heartbeat", "error", err.Error())
+	}
+
+	if err := s.post(ctx, heartbeat); err != nil {
+		metrics.HeartbeatFailures.Inc()
+		logger.V(1).Info("Failed to send heartbeat", "error", err.Error())
+		return
+	}
+	logger.V(1).Info("Sent heartbeat", "podsEvaluated", heartbeat.PodsEvaluated)
+}
+
+// Build gathers the current inventory. The pods evaluated are counted since the
+// previous call.
+func (s *Sender) Build(ctx context.Context, now time.Time) (Heartbeat, error) {
+	heartbeat := Heartbeat{
+		Time:            now.UTC().Format(time.RFC3339),
+		Interval:        s.Interval.String(),
+		OperatorVersion: version.Version,
+		Leader:          s.Identity,
+		Policies:        make(map[string]int),
+		BufferedEvents:  make(map[string]int),
+	}
+
+	for _, sink := range s.Sinks {
+		if buffered, ok := sink.(bufferedSink); ok {
+			heartbeat.BufferedEvents[buffered.Name()] = buffered.Buffered()
+		}
+	}
+
+	evaluated, depths := gatherMetrics()
+	heartbeat.PodsEvaluated = evaluated - s.evaluated
+	s.evaluated = evaluated
+	heartbeat.QueueDepths = depths
+
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := s.Reader.List(ctx, policies); err != nil {
+		return heartbeat, fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		mode := policy.EffectiveEnforcementMode()
+		if policy.IsSimulated() {
+			mode = modeSimulated
+		}
+		heartbeat.Policies[mode]++
+	}
+	return heartbeat, nil
+}
+
+// gatherMetrics reads the pods evaluated so far and the depth of every controller's
+// work queue from the metrics registry
+func gatherMetrics() (int64, map[string]int) {
+	depths := make(map[string]int)
+	families, err := crmetrics.Registry.Gather()
+	if err != nil {
+		// Gather returns what it could collect along with the error
+		ctrl.Log.WithName("heartbeat").V(1).Info("Incomplete metrics for heartbeat", "error", err.Error())
+	}
+
+	var evaluated int64
+	for _, family := range families {
+		switch family.GetName() {
+		case podsEvaluatedMetric:
+			for _, metric := range family.GetMetric() {
+				evaluated += int64(metric.GetCounter().GetValue())
+			}
+		case queueDepthMetric:
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "name" {
+						depths[label.GetValue()] = int(metric.GetGauge().GetValue())
+					}
+				}
+			}
+		}
+	}
+	return evaluated, depths
+}
+
+// post sends the heartbeat as JSON to the audit service
+func (s *Sender) post(ctx context.Context, heartbeat Heartbeat) error {
+	payload, err := json.Marshal(heartbeat)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/heartbeat", s.URL), bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("audit service returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
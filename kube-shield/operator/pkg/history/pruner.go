@@ -0,0 +1,115 @@
+// Package history retains the ShieldEnforcementRecords the operator writes, so the
+// in-cluster history of enforcement actions stays bounded.
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/schedule"
+)
+
+// Pruner deletes ShieldEnforcementRecords every Interval:
+// records older than MaxAge, and in every namespace the oldest records beyond
+// MaxPerNamespace. Records are read from the API server, since the manager does not
+// cache them. It runs as a Job on the leader.
+type Pruner struct {
+	Client          client.Client
+	Reader          client.Reader
+	MaxAge          time.Duration
+	MaxPerNamespace int
+	Interval        time.Duration
+}
+
+// NewPruner creates a Pruner. A zero maxAge or maxPerNamespace disables that limit.
+func NewPruner(c client.Client, reader client.Reader, maxAge time.Duration, maxPerNamespace int, interval time.Duration) *Pruner {
+	return &Pruner{
+		Client:          c,
+		Reader:          reader,
+		MaxAge:          maxAge,
+		MaxPerNamespace: maxPerNamespace,
+		Interval:        interval,
+	}
+}
+
+// Job returns the leader-only job pruning the records every Interval
+func (p *Pruner) Job() schedule.Job {
+	return schedule.Job{
+		Name:      "history",
+		Interval:  p.Interval,
+		Immediate: true,
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if err := p.prune(ctx, logger); err != nil {
+				logger.Error(err, "Failed to prune enforcement records")
+			}
+		},
+	}
+}
+
+// prune deletes the records Expired selects
+func (p *Pruner) prune(ctx context.Context, logger logr.Logger) error {
+	records := &shieldv1alpha1.ShieldEnforcementRecordList{}
+	if err := p.Reader.List(ctx, records); err != nil {
+		return fmt.Errorf("listing enforcement records: %w", err)
+	}
+
+	expired := Expired(records.Items, time.Now(), p.MaxAge, p.MaxPerNamespace)
+	deleted := 0
+	for i := range expired {
+		if err := p.Client.Delete(ctx, &expired[i]); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete enforcement record", "namespace", expired[i].Namespace, "name", expired[i].Name)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		logger.Info("Pruned enforcement records", "deleted", deleted, "remaining", len(records.Items)-deleted)
+	}
+	return nil
+}
+
+// Expired returns the records to delete at now: those older than maxAge, and per
+// namespace the oldest of the remaining records beyond maxPerNamespace. A zero
+// maxAge or maxPerNamespace disables that limit.
+func Expired(records []shieldv1alpha1.ShieldEnforcementRecord, now time.Time, maxAge time.Duration, maxPerNamespace int) []shieldv1alpha1.ShieldEnforcementRecord {
+	var expired []shieldv1alpha1.ShieldEnforcementRecord
+	kept := make(map[string][]shieldv1alpha1.ShieldEnforcementRecord)
+	for _, record := range records {
+		if maxAge > 0 && now.Sub(recordTime(&record)) > maxAge {
+			expired = append(expired, record)
+			continue
+		}
+		kept[record.Namespace] = append(kept[record.Namespace], record)
+	}
+	if maxPerNamespace <= 0 {
+		return expired
+	}
+
+	for _, namespaced := range kept {
+		if len(namespaced) <= maxPerNamespace {
+			continue
+		}
+		// Newest first, so everything past the limit is the oldest
+		sort.Slice(namespaced, func(i, j int) bool {
+			return recordTime(&namespaced[i]).After(recordTime(&namespaced[j]))
+		})
+		expired = append(expired, namespaced[maxPerNamespace:]...)
+	}
+	return expired
+}
+
+// recordTime is when the recorded action was taken, falling back to the record's
+// creation for records without a timestamp
+func recordTime(record *shieldv1alpha1.ShieldEnforcementRecord) time.Time {
+	if !record.Spec.Timestamp.IsZero() {
+		return record.Spec.Timestamp.Time
+	}
+	return record.CreationTimestamp.Time
+}
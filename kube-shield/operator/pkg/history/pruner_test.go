@@ -0,0 +1,104 @@
+package history
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+)
+
+func testRecord(namespace, name string, age time.Duration, now time.Time) shieldv1alpha1.ShieldEnforcementRecord {
+	return shieldv1alpha1.ShieldEnforcementRecord{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       shieldv1alpha1.ShieldEnforcementRecordSpec{Timestamp: metav1.NewTime(now.Add(-age))},
+	}
+}
+
+func expiredNames(records []shieldv1alpha1.ShieldEnforcementRecord) []string {
+	names := make([]string, 0, len(records))
+	for i := range records {
+		names = append(names, records[i].Namespace+"/"+records[i].Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestExpiredByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []shieldv1alpha1.ShieldEnforcementRecord{
+		testRecord("default", "fresh", time.Hour, now),
+		testRecord("default", "old", 48*time.Hour, now),
+		testRecord("team-a", "old", 25*time.Hour, now),
+	}
+	// Records without a timestamp fall back to their creation
+	created := testRecord("team-a", "untimed", 0, now)
+	created.Spec.Timestamp = metav1.Time{}
+	created.CreationTimestamp = metav1.NewTime(now.Add(-72 * time.Hour))
+	records = append(records, created)
+
+	got := expiredNames(Expired(records, now, 24*time.Hour, 0))
+	want := []string{"default/old", "team-a/old", "team-a/untimed"}
+	if len(got) != len(want) {
+		t.Fatalf("Expired = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expired = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExpiredByCount(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []shieldv1alpha1.ShieldEnforcementRecord{
+		testRecord("default", "third", 3*time.Hour, now),
+		testRecord("default", "first", time.Hour, now),
+		testRecord("default", "fourth", 4*time.Hour, now),
+		testRecord("default", "second", 2*time.Hour, now),
+		testRecord("team-a", "only", 10*time.Hour, now),
+	}
+
+	got := expiredNames(Expired(records, now, 0, 2))
+	want := []string{"default/fourth", "default/third"}
+	if len(got) != len(want) {
+		t.Fatalf("Expired = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expired = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExpiredByAgeAndCount(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []shieldv1alpha1.ShieldEnforcementRecord{
+		testRecord("default", "first", time.Hour, now),
+		testRecord("default", "second", 2*time.Hour, now),
+		testRecord("default", "third", 3*time.Hour, now),
+		testRecord("default", "ancient", 100*time.Hour, now),
+	}
+
+	// The record expired by age does not count against the namespace limit
+	got := expiredNames(Expired(records, now, 24*time.Hour, 2))
+	want := []string{"default/ancient", "default/third"}
+	if len(got) != len(want) {
+		t.Fatalf("Expired = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expired = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExpiredWithoutLimits(t *testing.T) {
+	now := time.Now()
+	records := []shieldv1alpha1.ShieldEnforcementRecord{testRecord("default", "old", 1000*time.Hour, now)}
+	if expired := Expired(records, now, 0, 0); len(expired) != 0 {
+		t.Errorf("Expired without limits = %v, want none", expiredNames(expired))
+	}
+}
@@ -0,0 +1,184 @@
+// Package interop recognizes violations another policy engine already reports, so
+// that during a migration from Gatekeeper or Kyverno the same problem is not raised
+// twice at full severity.
+package interop
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	wgpolicyv1alpha2 "github.com/kubeshield/operator/pkg/apis/wgpolicyk8s/v1alpha2"
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+const (
+	// reportTTL is how long the PolicyReports of a namespace are reused before they
+	// are read again
+	reportTTL = time.Minute
+
+	// missingCRDTTL is how long the Detector stops asking for PolicyReports after
+	// finding their CRD is not installed
+	missingCRDTTL = 10 * time.Minute
+
+	// ownSource is the source of the PolicyReports the operator writes itself
+	ownSource = "kubeshield"
+
+	// duplicateSeverity is the severity of violations another engine already reports
+	duplicateSeverity = "LOW"
+)
+
+// Detector finds the violations of a pod that Gatekeeper or Kyverno already report in
+// the PolicyReports of the pod's namespace
+type Detector struct {
+	Reader client.Reader
+
+	mu      sync.Mutex
+	reports map[string]cachedReports
+
+	// pruned is when expired namespaces were last dropped from reports
+	pruned time.Time
+
+	// missingUntil is set while the PolicyReport CRD is known not to be installed
+	missingUntil time.Time
+}
+
+// cachedReports are the PolicyReports of one namespace
+type cachedReports struct {
+	results []scopedResult
+	read    time.Time
+}
+
+// scopedResult is a failing result of another engine together with the report scope
+type scopedResult struct {
+	result wgpolicyv1alpha2.PolicyReportResult
+	scope  *corev1.ObjectReference
+}
+
+// NewDetector creates a Detector. reader should not be cache-backed, so the operator
+// does not need to watch PolicyReports.
+func NewDetector(reader client.Reader) *Detector {
+	return &Detector{
+		Reader:  reader,
+		reports: make(map[string]cachedReports),
+	}
+}
+
+// Duplicates returns, per event type, the "source/policy" of another engine that
+// already reports an equivalent failure for the pod
+func (d *Detector) Duplicates(ctx context.Context, pod *corev1.Pod) (map[string]string, error) {
+	results, err := d.results(ctx, pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make(map[string]string)
+	for _, scoped := range results {
+		if !referencesPod(scoped, pod) {
+			continue
+		}
+		for _, name := range []string{scoped.result.Policy, scoped.result.Rule} {
+			for _, eventType := range EventTypesFor(name) {
+				if _, ok := duplicates[eventType]; !ok {
+					duplicates[eventType] = scoped.result.Source + "/" + scoped.result.Policy
+				}
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+// results returns the failing results of other engines in a namespace. Without the
+// PolicyReport CRD there are none, and the API server is not asked again for
+// missingCRDTTL.
+func (d *Detector) results(ctx context.Context, namespace string) ([]scopedResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(d.missingUntil) {
+		return nil, nil
+	}
+	if cached, ok := d.reports[namespace]; ok && now.Sub(cached.read) < reportTTL {
+		return cached.results, nil
+	}
+	d.prune(now)
+
+	reports := &wgpolicyv1alpha2.PolicyReportList{}
+	if err := d.Reader.List(ctx, reports, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			d.missingUntil = now.Add(missingCRDTTL)
+			d.reports = make(map[string]cachedReports)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing PolicyReports: %w", err)
+	}
+
+	var results []scopedResult
+	for i := range reports.Items {
+		report := &reports.Items[i]
+		for _, result := range report.Results {
+			if strings.EqualFold(result.Source, ownSource) {
+				continue
+			}
+			if result.Result != wgpolicyv1alpha2.StatusFail && result.Result != wgpolicyv1alpha2.StatusError {
+				continue
+			}
+			results = append(results, scopedResult{result: result, scope: report.Scope})
+		}
+	}
+	d.reports[namespace] = cachedReports{results: results, read: now}
+	return results, nil
+}
+
+// prune drops the namespaces whose reports expired, at most once per reportTTL, so
+// namespaces that are gone do not stay in memory
+func (d *Detector) prune(now time.Time) {
+	if now.Sub(d.pruned) < reportTTL {
+		return
+	}
+	d.pruned = now
+	for namespace, cached := range d.reports {
+		if now.Sub(cached.read) >= reportTTL {
+			delete(d.reports, namespace)
+		}
+	}
+}
+
+// referencesPod returns true if a result is about the pod, through its resources or,
+// for per-resource reports, the report's scope
+func referencesPod(scoped scopedResult, pod *corev1.Pod) bool {
+	references := scoped.result.Resources
+	if scoped.scope != nil {
+		references = append(references[:len(references):len(references)], *scoped.scope)
+	}
+	for _, ref := range references {
+		if ref.Kind != "Pod" {
+			continue
+		}
+		if ref.UID != "" && ref.UID == pod.UID {
+			return true
+		}
+		if ref.UID == "" && ref.Name == pod.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Downgrade lowers the severity of violations another engine already reports to LOW
+// and records which engine in DuplicatedBy. The violations are still reported.
+func Downgrade(violations []audit.SecurityEvent, duplicates map[string]string) {
+	for i := range violations {
+		if by, ok := duplicates[violations[i].EventType]; ok {
+			violations[i].Severity = duplicateSeverity
+			violations[i].DuplicatedBy = by
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package interop
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	wgpolicyv1alpha2 "github.com/kubeshield/operator/pkg/apis/wgpolicyk8s/v1alpha2"
+)
+
+func newReportClient(t *testing.T, reports ...client.Object) *fake.ClientBuilder {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := wgpolicyv1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(reports...)
+}
+
+func testPod() *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-web"}}
+}
+
+func TestDuplicates(t *testing.T) {
+	podRef := corev1.ObjectReference{Kind: "Pod", Name: "web", Namespace: "default", UID: "uid-web"}
+	otherRef := corev1.ObjectReference{Kind: "Pod", Name: "db", Namespace: "default", UID: "uid-db"}
+	reports := []client.Object{
+		&wgpolicyv1alpha2.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{Name: "gatekeeper", Namespace: "default"},
+			Results: []wgpolicyv1alpha2.PolicyReportResult{
+				{Source: "gatekeeper", Policy: "K8sPSPPrivilegedContainer", Result: wgpolicyv1alpha2.StatusFail, Resources: []corev1.ObjectReference{podRef}},
+				{Source: "gatekeeper", Policy: "K8sPSPHostFilesystem", Result: wgpolicyv1alpha2.StatusPass, Resources: []corev1.ObjectReference{podRef}},
+				{Source: "gatekeeper", Policy: "K8sAllowedRepos", Result: wgpolicyv1alpha2.StatusFail, Resources: []corev1.ObjectReference{otherRef}},
+				{Source: "kubeshield", Policy: "K8sRequiredLabels", Result: wgpolicyv1alpha2.StatusFail, Resources: []corev1.ObjectReference{podRef}},
+			},
+		},
+		// Kyverno writes one report per resource and names the pod in the scope
+		&wgpolicyv1alpha2.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{Name: "kyverno-web", Namespace: "default"},
+			Scope:      &podRef,
+			Results: []wgpolicyv1alpha2.PolicyReportResult{
+				{Source: "kyverno", Policy: "disallow-host-namespaces", Rule: "host-namespaces", Result: wgpolicyv1alpha2.StatusFail},
+			},
+		},
+	}
+	detector := NewDetector(newReportClient(t, reports...).Build())
+
+	duplicates, err := detector.Duplicates(context.Background(), testPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PRIVILEGED_CONTAINER":      "gatekeeper/K8sPSPPrivilegedContainer",
+		"PRIVILEGED_INIT_CONTAINER": "gatekeeper/K8sPSPPrivilegedContainer",
+		"HOST_NETWORK":              "kyverno/disallow-host-namespaces",
+		"HOST_PID":                  "kyverno/disallow-host-namespaces",
+		"HOST_IPC":                  "kyverno/disallow-host-namespaces",
+	}
+	if len(duplicates) != len(want) {
+		t.Errorf("Duplicates = %v, want %v", duplicates, want)
+	}
+	for eventType, by := range want {
+		if duplicates[eventType] != by {
+			t.Errorf("Duplicates[%s] = %q, want %q", eventType, duplicates[eventType], by)
+		}
+	}
+}
+
+func TestDuplicatesWithoutPolicyReportCRD(t *testing.T) {
+	lists := 0
+	c := newReportClient(t).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			lists++
+			return &meta.NoKindMatchError{
+				GroupKind:        schema.GroupKind{Group: "wgpolicyk8s.io", Kind: "PolicyReport"},
+				SearchedVersions: []string{"v1alpha2"},
+			}
+		},
+	}).Build()
+	detector := NewDetector(c)
+
+	for _, namespace := range []string{"default", "default", "team-a"} {
+		pod := testPod()
+		pod.Namespace = namespace
+		duplicates, err := detector.Duplicates(context.Background(), pod)
+		if err != nil {
+			t.Fatalf("Duplicates without the CRD returned %v, want no error", err)
+		}
+		if len(duplicates) != 0 {
+			t.Errorf("Duplicates without the CRD = %v, want none", duplicates)
+		}
+	}
+	if lists != 1 {
+		t.Errorf("PolicyReports listed %d times without the CRD, want 1", lists)
+	}
+
+	// The CRD may be installed later
+	detector.missingUntil = time.Now().Add(-time.Second)
+	if _, err := detector.Duplicates(context.Background(), testPod()); err != nil {
+		t.Fatal(err)
+	}
+	if lists != 2 {
+		t.Errorf("PolicyReports listed %d times after missingCRDTTL, want 2", lists)
+	}
+}
+
+func TestDuplicatesCachesReports(t *testing.T) {
+	lists := 0
+	c := newReportClient(t).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			lists++
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+	detector := NewDetector(c)
+
+	for i := 0; i < 3; i++ {
+		if _, err := detector.Duplicates(context.Background(), testPod()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lists != 1 {
+		t.Errorf("PolicyReports listed %d times within reportTTL, want 1", lists)
+	}
+}
+
+func TestDetectorPrunesExpiredNamespaces(t *testing.T) {
+	detector := NewDetector(newReportClient(t).Build())
+	now := time.Now()
+	detector.reports["deleted"] = cachedReports{read: now.Add(-2 * reportTTL)}
+	detector.reports["recent"] = cachedReports{read: now}
+
+	if _, err := detector.Duplicates(context.Background(), testPod()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := detector.reports["deleted"]; ok {
+		t.Error("expired namespace was not pruned")
+	}
+	for _, namespace := range []string{"recent", "default"} {
+		if _, ok := detector.reports[namespace]; !ok {
+			t.Errorf("namespace %s was pruned, want it kept", namespace)
+		}
+	}
+}
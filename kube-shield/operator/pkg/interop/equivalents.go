@@ -0,0 +1,49 @@
+package interop
+
+import "strings"
+
+// equivalents maps the operator's event types to the Gatekeeper constraint kinds
+// (from the gatekeeper-library) and Kyverno policies (from the kyverno/policies
+// pod-security and best-practices sets) that detect the same problem
+var equivalents = map[string][]string{
+	"PRIVILEGED_CONTAINER":      {"K8sPSPPrivilegedContainer", "disallow-privileged-containers"},
+	"PRIVILEGED_INIT_CONTAINER": {"K8sPSPPrivilegedContainer", "disallow-privileged-containers"},
+	"HOST_NETWORK":              {"K8sPSPHostNetworkingPorts", "disallow-host-namespaces"},
+	"HOST_PORT":                 {"K8sPSPHostNetworkingPorts", "disallow-host-ports"},
+	"HOST_PID":                  {"K8sPSPHostNamespace", "disallow-host-namespaces"},
+	"HOST_IPC":                  {"K8sPSPHostNamespace", "disallow-host-namespaces"},
+	"HOST_PATH_VOLUME":          {"K8sPSPHostFilesystem", "disallow-host-path"},
+	"HOST_PROCESS":              {"K8sPSPWindowsHostProcess", "disallow-host-process"},
+	"ROOT_USER":                 {"K8sPSPAllowedUsers", "require-run-as-non-root-user"},
+	"RUN_AS_NON_ROOT":           {"K8sPSPAllowedUsers", "require-run-as-nonroot"},
+	"PRIVILEGE_ESCALATION":      {"K8sPSPAllowPrivilegeEscalationContainer", "disallow-privilege-escalation"},
+	"ADDED_CAPABILITIES":        {"K8sPSPCapabilities", "disallow-capabilities", "disallow-capabilities-strict"},
+	"CAPABILITIES_NOT_DROPPED":  {"K8sPSPCapabilities", "disallow-capabilities-strict"},
+	"SECCOMP_PROFILE":           {"K8sPSPSeccomp", "restrict-seccomp", "restrict-seccomp-strict"},
+	"APPARMOR_PROFILE":          {"K8sPSPAppArmor", "restrict-apparmor-profiles"},
+	"SELINUX_OPTIONS":           {"K8sPSPSELinuxV2", "disallow-selinux"},
+	"PROC_MOUNT":                {"K8sPSPProcMount", "disallow-proc-mount"},
+	"UNSAFE_SYSCTL":             {"K8sPSPForbiddenSysctls", "restrict-sysctls"},
+	"RESTRICTED_VOLUME_TYPE":    {"K8sPSPVolumeTypes", "restrict-volume-types"},
+	"DISALLOWED_REGISTRY":       {"K8sAllowedRepos", "restrict-image-registries"},
+	"DISALLOWED_REPOSITORY":     {"K8sAllowedRepos", "restrict-image-registries"},
+	"MISSING_REQUIRED_LABEL":    {"K8sRequiredLabels", "require-labels"},
+}
+
+// eventTypesByName is the reverse of equivalents, keyed by lower-cased name
+var eventTypesByName = func() map[string][]string {
+	index := make(map[string][]string)
+	for eventType, names := range equivalents {
+		for _, name := range names {
+			key := strings.ToLower(name)
+			index[key] = append(index[key], eventType)
+		}
+	}
+	return index
+}()
+
+// EventTypesFor returns the event types equivalent to a Gatekeeper constraint kind or
+// Kyverno policy name. Names are matched case-insensitively.
+func EventTypesFor(name string) []string {
+	return eventTypesByName[strings.ToLower(name)]
+}
@@ -0,0 +1,66 @@
+package interop
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/engine"
+)
+
+func TestEquivalentsUseKnownEventTypes(t *testing.T) {
+	for eventType := range equivalents {
+		if _, ok := engine.LookupRule(eventType); !ok {
+			t.Errorf("equivalents maps %s, which is not in the rule catalog", eventType)
+		}
+	}
+}
+
+func TestEventTypesFor(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "K8sPSPPrivilegedContainer", want: []string{"PRIVILEGED_CONTAINER", "PRIVILEGED_INIT_CONTAINER"}},
+		{name: "k8spspprivilegedcontainer", want: []string{"PRIVILEGED_CONTAINER", "PRIVILEGED_INIT_CONTAINER"}},
+		{name: "disallow-privileged-containers", want: []string{"PRIVILEGED_CONTAINER", "PRIVILEGED_INIT_CONTAINER"}},
+		{name: "disallow-host-namespaces", want: []string{"HOST_IPC", "HOST_NETWORK", "HOST_PID"}},
+		{name: "K8sPSPHostNetworkingPorts", want: []string{"HOST_NETWORK", "HOST_PORT"}},
+		{name: "K8sPSPCapabilities", want: []string{"ADDED_CAPABILITIES", "CAPABILITIES_NOT_DROPPED"}},
+		{name: "disallow-capabilities", want: []string{"ADDED_CAPABILITIES"}},
+		{name: "K8sAllowedRepos", want: []string{"DISALLOWED_REGISTRY", "DISALLOWED_REPOSITORY"}},
+		{name: "restrict-seccomp-strict", want: []string{"SECCOMP_PROFILE"}},
+		{name: "require-labels", want: []string{"MISSING_REQUIRED_LABEL"}},
+		{name: "K8sUnknownConstraint", want: nil},
+		{name: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]string(nil), EventTypesFor(tt.name)...)
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("EventTypesFor(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("EventTypesFor(%q) = %v, want %v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	violations := []audit.SecurityEvent{
+		{EventType: "PRIVILEGED_CONTAINER", Severity: "CRITICAL"},
+		{EventType: "HOST_PATH_VOLUME", Severity: "HIGH"},
+	}
+	Downgrade(violations, map[string]string{"PRIVILEGED_CONTAINER": "gatekeeper/K8sPSPPrivilegedContainer"})
+
+	if violations[0].Severity != duplicateSeverity || violations[0].DuplicatedBy != "gatekeeper/K8sPSPPrivilegedContainer" {
+		t.Errorf("duplicated violation = %+v, want LOW and DuplicatedBy set", violations[0])
+	}
+	if violations[1].Severity != "HIGH" || violations[1].DuplicatedBy != "" {
+		t.Errorf("other violation = %+v, want it unchanged", violations[1])
+	}
+}
@@ -0,0 +1,48 @@
+// Package listing provides paginated List calls for code paths that need a full
+// listing straight from the API server. Hot paths should keep using the
+// informer-backed cached client instead.
+package listing
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPageSize is used when a caller passes a non-positive page size
+const DefaultPageSize int64 = 500
+
+// Paginate lists objects into list one page at a time, calling fn after each
+// page has been loaded. list is overwritten on every page, so fn must consume
+// its items before returning. reader should be an uncached reader (such as the
+// manager's API reader), since the cache does not honor continuation tokens.
+func Paginate(
+	ctx context.Context,
+	reader client.Reader,
+	list client.ObjectList,
+	pageSize int64,
+	fn func() error,
+	opts ...client.ListOption,
+) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	continueToken := ""
+	for {
+		pageOpts := append([]client.ListOption{}, opts...)
+		pageOpts = append(pageOpts, client.Limit(pageSize), client.Continue(continueToken))
+
+		if err := reader.List(ctx, list, pageOpts...); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+
+		continueToken = list.GetContinue()
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
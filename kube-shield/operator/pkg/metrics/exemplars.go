@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// OpenMetricsPath serves the metrics in the OpenMetrics format, the only one that
+// carries exemplars. controller-runtime's /metrics handler does not negotiate it.
+const OpenMetricsPath = "/metrics/openmetrics"
+
+// exemplarTraceID is the exemplar label holding the trace of the reconcile
+const exemplarTraceID = "trace_id"
+
+// exemplars is set by EnableExemplars
+var exemplars atomic.Bool
+
+// EnableExemplars makes Inc attach the trace ID of the current span as an exemplar,
+// so a spike of a violation counter links to the reconcile that raised it
+func EnableExemplars() {
+	exemplars.Store(true)
+}
+
+// Inc increments counter. With exemplars enabled and a sampled span in ctx, the
+// increment carries the span's trace ID as exemplar.
+func Inc(ctx context.Context, counter prometheus.Counter) {
+	if exemplars.Load() {
+		spanContext := trace.SpanContextFromContext(ctx)
+		if adder, ok := counter.(prometheus.ExemplarAdder); ok && spanContext.IsSampled() {
+			adder.AddWithExemplar(1, prometheus.Labels{exemplarTraceID: spanContext.TraceID().String()})
+			return
+		}
+	}
+	counter.Inc()
+}
+
+// OpenMetricsHandler serves the operator's metrics, with exemplars, to scrapers
+// asking for OpenMetrics
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
+}
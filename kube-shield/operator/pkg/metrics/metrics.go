@@ -0,0 +1,445 @@
+// Package metrics defines the Prometheus metrics exported by the operator.
+// Metrics are registered with controller-runtime's registry so they are served
+// from the manager's metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/kubeshield/operator/pkg/state"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+const namespace = "kubeshield"
+
+var (
+	// BuildInfo is always 1 and carries the operator build details as labels
+	BuildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "build_info",
+			Help:      "Build information of the running operator.",
+		},
+		[]string{"version", "commit", "build_date", "go_version"},
+	)
+
+	// EvaluationCacheLookups counts evaluation cache lookups by result (hit or miss)
+	EvaluationCacheLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "evaluation_cache_lookups_total",
+			Help:      "Number of pod evaluation cache lookups, by result.",
+		},
+		[]string{"result"},
+	)
+
+	// Violations counts the violations the pod controller reports, by policy, event
+	// type and action
+	Violations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "violations_total",
+			Help:      "Total violations reported by the pod controller, by policy, event type and action.",
+		},
+		[]string{"policy", "event_type", "action"},
+	)
+
+	// EnforcementFailures counts failed attempts to terminate violating pods
+	EnforcementFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "enforcement_failures_total",
+			Help:      "Number of failed attempts to terminate violating pods, by policy and namespace.",
+		},
+		[]string{"policy", "namespace"},
+	)
+
+	// AdmissionDecisions counts the pods the admission webhook reviewed, by decision
+	AdmissionDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "admission_decisions_total",
+			Help:      "Number of pods reviewed by the admission webhook, by decision (allowed, warned, error).",
+		},
+		[]string{"decision"},
+	)
+
+	// WorkloadAdmissionDecisions counts the workload pod templates the admission
+	// webhook reviewed, by kind and decision
+	WorkloadAdmissionDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "workload_admission_decisions_total",
+			Help:      "Number of workload pod templates reviewed by the admission webhook, by kind and decision (allowed, warned, denied, error).",
+		},
+		[]string{"kind", "decision"},
+	)
+
+	// PodsEvaluated counts pod reconciles that evaluated the pod against the policies
+	PodsEvaluated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pods_evaluated_total",
+			Help:      "Total pods evaluated against the ShieldPolicies, not counting evaluation cache hits.",
+		},
+	)
+
+	// HeartbeatFailures counts heartbeats the audit service did not accept
+	HeartbeatFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "heartbeat_failures_total",
+			Help:      "Total heartbeats that could not be delivered to the audit service.",
+		},
+	)
+
+	// AlertWebhookFailures counts violations a policy's alert webhook did not accept
+	AlertWebhookFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alert_webhook_failures_total",
+			Help:      "Total violations that could not be delivered to a policy's alert webhook, by destination host.",
+		},
+		[]string{"destination"},
+	)
+
+	// AlertsSkipped counts alerts not posted to a policy's alert webhook
+	AlertsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_skipped_total",
+			Help:      "Total alerts not posted to a policy's alert webhook, by destination host and reason (duplicate, queue_full, invalid_url).",
+		},
+		[]string{"destination", "reason"},
+	)
+
+	// ImageAllowlistReadFailures counts failed reads of policies' allowlist ConfigMaps
+	ImageAllowlistReadFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "image_allowlist_read_failures_total",
+			Help:      "Total failed reads of image allowlist ConfigMaps referenced by allowedImagesConfigMapRef, by ConfigMap.",
+		},
+		[]string{"configmap"},
+	)
+
+	// AuditReachable is the result of the startup connectivity check of the audit service
+	AuditReachable = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "audit_service_reachable",
+			Help:      "Whether the audit service answered the startup connectivity check (1) or not (0).",
+		},
+	)
+
+	// SampledOutEvents counts security events dropped by AUDIT_SAMPLE_RATES
+	SampledOutEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_events_sampled_out_total",
+			Help:      "Total security events not delivered because of audit sampling, by severity.",
+		},
+		[]string{"severity"},
+	)
+
+	// SuppressedAuditEvents counts audit events dropped by AUDIT_RATE_LIMIT
+	SuppressedAuditEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_events_suppressed_total",
+			Help:      "Total audit events not delivered because their policy exceeded the rate limit in the namespace.",
+		},
+		[]string{"policy", "namespace"},
+	)
+
+	// ReconcileDuration observes how long a Pod reconcile takes
+	ReconcileDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of Pod reconciles in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		},
+	)
+
+	// AuditPostDuration observes the latency of security event POSTs to the audit
+	// service, by outcome (success, error or retryable)
+	AuditPostDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "audit_post_duration_seconds",
+			Help:      "Latency of security event deliveries to the audit service in seconds, by outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		},
+		[]string{"outcome"},
+	)
+
+	// EvaluationTimeouts counts policy evaluations abandoned because they overran
+	// their own timeout (scope "policy") or the reconcile's budget (scope "reconcile")
+	EvaluationTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "evaluation_timeouts_total",
+			Help:      "Total policy evaluations abandoned after a timeout, by policy and scope (policy or reconcile).",
+		},
+		[]string{"policy", "scope"},
+	)
+
+	// PeriodicReevaluations counts evaluations of unchanged pods that were due under
+	// a policy's ReevaluationInterval
+	PeriodicReevaluations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "periodic_reevaluations_total",
+			Help:      "Total evaluations of unchanged pods triggered by a policy's reevaluationInterval.",
+		},
+	)
+
+	// NodeReevaluationsDropped counts pods not re-queued after their node's labels
+	// changed because the node had more than NODE_REEVALUATION_LIMIT pods
+	NodeReevaluationsDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "node_reevaluations_dropped_total",
+			Help:      "Total pods not re-evaluated after a node label change because of NODE_REEVALUATION_LIMIT.",
+		},
+	)
+
+	// ReconcilePanics counts reconciles that panicked and were turned into errors
+	ReconcilePanics = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconcile_panics_total",
+			Help:      "Total reconciles that panicked, by controller.",
+		},
+		[]string{"controller"},
+	)
+
+	// BackOffSkips counts terminations skipped because the pod was stuck in a back-off loop
+	BackOffSkips = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backoff_skips_total",
+			Help:      "Total terminations skipped because the pod was in ImagePullBackOff or CrashLoopBackOff, by policy.",
+		},
+		[]string{"policy"},
+	)
+
+	// StaticPodViolations counts violations of static pods, which are never terminated
+	StaticPodViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "static_pod_violations_total",
+			Help:      "Total violations found on static (mirror) pods, which are reported but never terminated, by policy and event type.",
+		},
+		[]string{"policy", "event_type"},
+	)
+
+	// DrainingNodeSkips counts terminations skipped because the pod's node was draining
+	DrainingNodeSkips = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "draining_node_skips_total",
+			Help:      "Total terminations skipped because the pod's node was cordoned or being removed, by policy.",
+		},
+		[]string{"policy"},
+	)
+
+	// UpgradeSkips counts terminations skipped because a cluster upgrade was suspected
+	UpgradeSkips = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upgrade_skips_total",
+			Help:      "Total terminations skipped because the cluster or the pod's node seemed to be upgrading, by policy.",
+		},
+		[]string{"policy"},
+	)
+
+	// ClusterUpgradeSuspected is 1 while a cluster-wide upgrade is suspected
+	ClusterUpgradeSuspected = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cluster_upgrade_suspected",
+			Help:      "1 while enough nodes are NotReady or cordoned to suspect a rolling cluster upgrade, 0 otherwise.",
+		},
+	)
+
+	// SkewedNodes is the number of nodes whose kubelet version is too far from the control plane
+	SkewedNodes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "skewed_nodes",
+			Help:      "Number of nodes whose kubelet minor version is more than UPGRADE_VERSION_SKEW away from the control plane.",
+		},
+	)
+
+	// PDBBlockedTerminations counts terminations deferred by a PodDisruptionBudget
+	PDBBlockedTerminations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pdb_blocked_terminations_total",
+			Help:      "Total terminations deferred because a PodDisruptionBudget allowed no disruption, by policy.",
+		},
+		[]string{"policy"},
+	)
+
+	// ActiveExemptions is the number of unexpired ShieldExemptions per namespace
+	ActiveExemptions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_exemptions",
+			Help:      "Number of ShieldExemptions that have not expired, by namespace.",
+		},
+		[]string{"namespace"},
+	)
+
+	// NamespaceCovered is 1 for namespaces at least one ShieldPolicy applies to, 0 otherwise
+	NamespaceCovered = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "namespace_covered",
+			Help:      "Whether at least one ShieldPolicy applies to the namespace (1) or none (0).",
+		},
+		[]string{"namespace"},
+	)
+
+	// UncoveredNamespaces is the number of namespaces no ShieldPolicy applies to
+	UncoveredNamespaces = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "uncovered_namespaces",
+			Help:      "Number of namespaces, system namespaces aside, that no ShieldPolicy applies to.",
+		},
+	)
+
+	// RBACMissingPermissions is the number of permissions the operator needs but was not granted
+	RBACMissingPermissions = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "rbac_missing_permissions",
+			Help:      "Number of RBAC permissions the operator needs that SelfSubjectAccessReviews report as not granted.",
+		},
+	)
+
+	// ComplianceScore is the severity-weighted compliance score (0-100) of a policy or namespace
+	ComplianceScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "compliance_score",
+			Help:      "Severity-weighted compliance score from 0 to 100, by scope (policy or namespace) and name.",
+		},
+		[]string{"scope", "name"},
+	)
+)
+
+// Evaluation timeout scopes
+const (
+	// TimeoutScopePolicy is a policy that overran its own evaluation timeout
+	TimeoutScopePolicy = "policy"
+	// TimeoutScopeReconcile is a policy cut short by the reconcile's evaluation budget
+	TimeoutScopeReconcile = "reconcile"
+)
+
+// Audit POST outcomes
+const (
+	// OutcomeSuccess is a POST the audit service accepted
+	OutcomeSuccess = "success"
+	// OutcomeError is a POST that failed and is not worth retrying
+	OutcomeError = "error"
+	// OutcomeRetryable is a POST rejected with a status worth retrying (429 or 5xx).
+	// The sink does not retry it itself.
+	OutcomeRetryable = "retryable"
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		BuildInfo,
+		EvaluationCacheLookups,
+		EnforcementFailures,
+		PodsEvaluated,
+		HeartbeatFailures,
+		AuditReachable,
+		AlertWebhookFailures,
+		AlertsSkipped,
+		ImageAllowlistReadFailures,
+		Violations,
+		SampledOutEvents,
+		SuppressedAuditEvents,
+		ReconcileDuration,
+		AuditPostDuration,
+		EvaluationTimeouts,
+		ReconcilePanics,
+		PeriodicReevaluations,
+		NodeReevaluationsDropped,
+		DrainingNodeSkips,
+		PDBBlockedTerminations,
+		UpgradeSkips,
+		ClusterUpgradeSuspected,
+		SkewedNodes,
+		StaticPodViolations,
+		BackOffSkips,
+		AdmissionDecisions,
+		WorkloadAdmissionDecisions,
+		ActiveExemptions,
+		NamespaceCovered,
+		UncoveredNamespaces,
+		RBACMissingPermissions,
+		ComplianceScore,
+	)
+
+	BuildInfo.WithLabelValues(version.Version, version.Commit, version.BuildDate, version.GoVersion()).Set(1)
+}
+
+// violatingPodsDesc describes the kubeshield_violating_pods gauge
+var violatingPodsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "violating_pods"),
+	"Number of pods currently violating a rule, by namespace, event type and policy.",
+	[]string{"namespace", "event_type", "policy"},
+	nil,
+)
+
+// violationCollector computes the violating pods gauge from a state.Store at scrape time
+type violationCollector struct {
+	store state.Store
+}
+
+// Describe implements prometheus.Collector
+func (c *violationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- violatingPodsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *violationCollector) Collect(ch chan<- prometheus.Metric) {
+	type labels struct {
+		namespace string
+		eventType string
+		policy    string
+	}
+
+	// A pod failing several custom rules has one violation per rule, so count it once
+	pods := make(map[labels]map[types.UID]struct{})
+	for _, v := range c.store.List() {
+		l := labels{namespace: v.Pod.Namespace, eventType: v.EventType, policy: v.Policy}
+		if pods[l] == nil {
+			pods[l] = make(map[types.UID]struct{})
+		}
+		pods[l][v.PodUID] = struct{}{}
+	}
+
+	for l, uids := range pods {
+		ch <- prometheus.MustNewConstMetric(
+			violatingPodsDesc,
+			prometheus.GaugeValue,
+			float64(len(uids)),
+			l.namespace, l.eventType, l.policy,
+		)
+	}
+}
+
+// RegisterViolationStore exports the current contents of store as the
+// kubeshield_violating_pods gauge
+func RegisterViolationStore(store state.Store) error {
+	return metrics.Registry.Register(&violationCollector{store: store})
+}
@@ -0,0 +1,143 @@
+// Package nscoverage finds namespaces no ShieldPolicy applies to, so gaps in policy
+// coverage are visible before they are exploited.
+package nscoverage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+const (
+	// uncoveredEventType is the event raised for a new namespace without coverage
+	uncoveredEventType = "UNCOVERED_NAMESPACE"
+
+	// coveragePolicy is the policy name reported with coverage events
+	coveragePolicy = "coverage"
+)
+
+// Tracker recomputes every Interval which namespaces have
+// at least one policy applying to them, from the manager's cache. It publishes the
+// kubeshield_namespace_covered and kubeshield_uncovered_namespaces gauges and, when
+// Sinks are set, reports namespaces that appear without coverage. It runs as a Job
+// on the leader.
+type Tracker struct {
+	Client   client.Client
+	System   *protection.SystemNamespaces
+	Sinks    []audit.Sink
+	Interval time.Duration
+
+	// Sanitizer is applied to coverage events like to every other event
+	Sanitizer audit.Sanitizer
+
+	// known holds the namespaces seen so far, nil until the first pass
+	known map[string]bool
+}
+
+// NewTracker creates a Tracker. sinks may be empty to only publish metrics.
+func NewTracker(c client.Client, system *protection.SystemNamespaces, sinks []audit.Sink, interval time.Duration) *Tracker {
+	return &Tracker{
+		Client:   c,
+		System:   system,
+		Sinks:    sinks,
+		Interval: interval,
+	}
+}
+
+// Job returns the leader-only job recomputing the coverage every Interval
+func (t *Tracker) Job() schedule.Job {
+	return schedule.Job{
+		Name:      "coverage",
+		Interval:  t.Interval,
+		Immediate: true,
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if err := t.update(ctx, logger); err != nil {
+				logger.Error(err, "Failed to compute namespace coverage")
+			}
+		},
+	}
+}
+
+// update recomputes coverage and publishes it
+func (t *Tracker) update(ctx context.Context, logger logr.Logger) error {
+	namespaces := &corev1.NamespaceList{}
+	if err := t.Client.List(ctx, namespaces); err != nil {
+		return fmt.Errorf("listing namespaces: %w", err)
+	}
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := t.Client.List(ctx, policies); err != nil {
+		return fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+
+	first := t.known == nil
+	seen := make(map[string]bool, len(namespaces.Items))
+	uncovered := 0
+	metrics.NamespaceCovered.Reset()
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if t.System.IsSystem(ns.Name) || t.System.IsBypassed(ns.Name) {
+			continue
+		}
+		seen[ns.Name] = true
+
+		if Covered(ns, policies.Items) {
+			metrics.NamespaceCovered.WithLabelValues(ns.Name).Set(1)
+			continue
+		}
+		metrics.NamespaceCovered.WithLabelValues(ns.Name).Set(0)
+		uncovered++
+
+		// Namespaces that existed before the operator started are only counted
+		if !first && !t.known[ns.Name] {
+			logger.Info("New namespace has no applicable ShieldPolicy", "namespace", ns.Name)
+			t.report(ctx, logger, ns.Name)
+		}
+	}
+	metrics.UncoveredNamespaces.Set(float64(uncovered))
+	t.known = seen
+	return nil
+}
+
+// Covered returns true if at least one enabled policy applies to the namespace
+func Covered(ns *corev1.Namespace, policies []shieldv1alpha1.ShieldPolicy) bool {
+	for i := range policies {
+		if engine.AppliesToNamespace(&policies[i], ns.Name, ns.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// report sends an UNCOVERED_NAMESPACE event to every sink
+func (t *Tracker) report(ctx context.Context, logger logr.Logger, namespace string) {
+	event := audit.SecurityEvent{
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		EventType:       uncoveredEventType,
+		Severity:        "LOW",
+		Namespace:       namespace,
+		Reason:          "No ShieldPolicy applies to the namespace",
+		Action:          audit.ActionAudit,
+		PolicyName:      coveragePolicy,
+		Description:     fmt.Sprintf("Namespace '%s' was created but none of the ShieldPolicies apply to it, so its pods are not checked", namespace),
+		OperatorVersion: version.Version,
+	}
+	t.Sanitizer.Apply(&event)
+	engine.Classify(&event)
+	for _, sink := range t.Sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			logger.Info("Failed to deliver coverage event", "sink", sink.Name(), "error", err.Error())
+		}
+	}
+}
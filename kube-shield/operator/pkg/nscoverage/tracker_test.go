@@ -0,0 +1,154 @@
+package nscoverage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/audit"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/protection"
+)
+
+// recordingSink keeps the events sent to it
+type recordingSink struct {
+	mu     sync.Mutex
+	events []audit.SecurityEvent
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, event audit.SecurityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func testNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+// coveredGauges returns the kubeshield_namespace_covered value of every namespace
+func coveredGauges(t *testing.T) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	go func() {
+		metrics.NamespaceCovered.Collect(ch)
+		close(ch)
+	}()
+
+	gauges := make(map[string]float64)
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		gauges[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	return gauges
+}
+
+func TestCovered(t *testing.T) {
+	policies := []shieldv1alpha1.ShieldPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec:       shieldv1alpha1.ShieldPolicySpec{TargetNamespaces: []string{"payments"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+			Spec: shieldv1alpha1.ShieldPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "restricted"}},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		ns       *corev1.Namespace
+		policies []shieldv1alpha1.ShieldPolicy
+		want     bool
+	}{
+		{name: "targeted by name", ns: testNamespace("payments", nil), policies: policies, want: true},
+		{name: "selected by label", ns: testNamespace("web", map[string]string{"tier": "restricted"}), policies: policies, want: true},
+		{name: "neither", ns: testNamespace("web", map[string]string{"tier": "public"}), policies: policies},
+		{name: "no policies", ns: testNamespace("payments", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Covered(tt.ns, tt.policies); got != tt.want {
+				t.Errorf("Covered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerUpdate(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := shieldv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	system, err := protection.NewSystemNamespaces([]string{"kube-*"}, protection.SystemNamespaceModeSkip, []string{"monitoring"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &shieldv1alpha1.ShieldPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+		Spec:       shieldv1alpha1.ShieldPolicySpec{TargetNamespaces: []string{"payments"}},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(policy, testNamespace("kube-system", nil), testNamespace("monitoring", nil),
+			testNamespace("payments", nil), testNamespace("web", nil)).
+		Build()
+	sink := &recordingSink{}
+	tracker := NewTracker(c, system, []audit.Sink{sink}, time.Minute)
+
+	// Namespaces that existed before the first pass are counted but not reported
+	if err := tracker.update(ctx, logr.Discard()); err != nil {
+		t.Fatal(err)
+	}
+	gauges := coveredGauges(t)
+	if len(gauges) != 2 || gauges["payments"] != 1 || gauges["web"] != 0 {
+		t.Errorf("namespace_covered = %v, want payments=1 and web=0 without system or bypassed namespaces", gauges)
+	}
+	m := &dto.Metric{}
+	if err := metrics.UncoveredNamespaces.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetGauge().GetValue(); got != 1 {
+		t.Errorf("uncovered_namespaces = %v, want 1", got)
+	}
+	if len(sink.events) != 0 {
+		t.Errorf("first pass reported %d events, want 0", len(sink.events))
+	}
+
+	// A new uncovered namespace is reported once, a new system namespace never
+	for _, ns := range []*corev1.Namespace{testNamespace("batch", nil), testNamespace("kube-node-lease", nil)} {
+		if err := c.Create(ctx, ns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := tracker.update(ctx, logr.Discard()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.events) != 1 || sink.events[0].Namespace != "batch" || sink.events[0].EventType != uncoveredEventType {
+		t.Errorf("events = %+v, want one %s event for batch", sink.events, uncoveredEventType)
+	}
+}
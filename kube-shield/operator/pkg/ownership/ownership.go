@@ -0,0 +1,101 @@
+// Package ownership resolves the top-level controller of a pod, e.g. the Deployment
+// behind the ReplicaSet that created it.
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxCache bounds the owners remembered by a Resolver. The cache is simply dropped
+// when full, since every entry is cheap to resolve again.
+const maxCache = 10000
+
+// intermediateOwners are the kinds followed to their own controller when resolving
+// a pod's top-level owner. Other kinds are taken as the top-level owner.
+var intermediateOwners = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "ReplicaSet"}: true,
+	{Group: "batch", Kind: "Job"}:       true,
+}
+
+// Resolver finds a pod's top-level owner. Owner chains never change, so the
+// top-level owner of every intermediate owner is remembered by UID.
+type Resolver struct {
+	reader client.Reader
+
+	mu     sync.Mutex
+	owners map[types.UID]metav1.OwnerReference
+}
+
+// NewResolver creates a Resolver reading owners as metadata through reader
+func NewResolver(reader client.Reader) *Resolver {
+	return &Resolver{reader: reader, owners: make(map[types.UID]metav1.OwnerReference)}
+}
+
+// TopLevel returns the pod's top-level controller, or nil when the pod has none.
+// An intermediate owner that no longer exists is taken as the top-level owner.
+func (r *Resolver) TopLevel(ctx context.Context, pod *corev1.Pod) (*metav1.OwnerReference, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil
+	}
+
+	var walked []types.UID
+	for {
+		gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+		if !intermediateOwners[gvk.GroupKind()] {
+			break
+		}
+		if cached, ok := r.cached(ref.UID); ok {
+			ref = &cached
+			break
+		}
+		walked = append(walked, ref.UID)
+
+		owner := &metav1.PartialObjectMetadata{}
+		owner.SetGroupVersionKind(gvk)
+		err := r.reader.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, owner)
+		if errors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s %s: %w", ref.Kind, ref.Name, err)
+		}
+		next := metav1.GetControllerOf(owner)
+		if next == nil {
+			break
+		}
+		ref = next
+	}
+
+	r.remember(walked, *ref)
+	return ref, nil
+}
+
+// cached returns the remembered top-level owner of an owner
+func (r *Resolver) cached(uid types.UID) (metav1.OwnerReference, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref, ok := r.owners[uid]
+	return ref, ok
+}
+
+// remember records the top-level owner of the owners walked to reach it
+func (r *Resolver) remember(uids []types.UID, ref metav1.OwnerReference) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.owners)+len(uids) > maxCache {
+		r.owners = make(map[types.UID]metav1.OwnerReference)
+	}
+	for _, uid := range uids {
+		r.owners[uid] = ref
+	}
+}
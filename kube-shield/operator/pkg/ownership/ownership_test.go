@@ -0,0 +1,97 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func controllerRef(apiVersion, kind, name string, uid types.UID) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid, Controller: &controller}}
+}
+
+func podOwnedBy(refs []metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod", OwnerReferences: refs}}
+}
+
+func TestTopLevel(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "backup-28391040", UID: "job-uid",
+		OwnerReferences: controllerRef("batch/v1", "CronJob", "backup", "cronjob-uid"),
+	}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "web-5d4f8c", UID: "rs-uid",
+		OwnerReferences: controllerRef("apps/v1", "Deployment", "web", "deploy-uid"),
+	}}
+	bareRS := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bare", UID: "bare-uid"}}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(job, rs, bareRS).Build()
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		wantKind string
+		wantName string
+	}{
+		{name: "job of a cronjob", pod: podOwnedBy(controllerRef("batch/v1", "Job", job.Name, job.UID)), wantKind: "CronJob", wantName: "backup"},
+		{name: "replicaset of a deployment", pod: podOwnedBy(controllerRef("apps/v1", "ReplicaSet", rs.Name, rs.UID)), wantKind: "Deployment", wantName: "web"},
+		{name: "bare replicaset", pod: podOwnedBy(controllerRef("apps/v1", "ReplicaSet", bareRS.Name, bareRS.UID)), wantKind: "ReplicaSet", wantName: "bare"},
+		{name: "deleted job", pod: podOwnedBy(controllerRef("batch/v1", "Job", "gone", "gone-uid")), wantKind: "Job", wantName: "gone"},
+		{name: "statefulset", pod: podOwnedBy(controllerRef("apps/v1", "StatefulSet", "db", "sts-uid")), wantKind: "StatefulSet", wantName: "db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := NewResolver(c).TopLevel(context.Background(), tt.pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref == nil || ref.Kind != tt.wantKind || ref.Name != tt.wantName {
+				t.Fatalf("TopLevel = %v, want %s %s", ref, tt.wantKind, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestTopLevelWithoutController(t *testing.T) {
+	ref, err := NewResolver(fake.NewClientBuilder().Build()).TopLevel(context.Background(), podOwnedBy(nil))
+	if err != nil || ref != nil {
+		t.Fatalf("TopLevel of a bare pod = %v, %v, want nil", ref, err)
+	}
+}
+
+func TestTopLevelRemembersOwners(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default", Name: "backup-28391040", UID: "job-uid",
+		OwnerReferences: controllerRef("batch/v1", "CronJob", "backup", "cronjob-uid"),
+	}}
+	gets := 0
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(job).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			gets++
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+	resolver := NewResolver(c)
+
+	for i := 0; i < 3; i++ {
+		ref, err := resolver.TopLevel(context.Background(), podOwnedBy(controllerRef("batch/v1", "Job", job.Name, job.UID)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref.Kind != "CronJob" {
+			t.Fatalf("TopLevel = %s, want CronJob", ref.Kind)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("owners read %d times, want 1", gets)
+	}
+}
@@ -0,0 +1,195 @@
+// Package policyreport publishes the operator's current violations as wgpolicyk8s.io
+// PolicyReport objects, one per namespace, so they show up in tools such as Policy
+// Reporter next to the results of other policy engines.
+package policyreport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	wgpolicyv1alpha2 "github.com/kubeshield/operator/pkg/apis/wgpolicyk8s/v1alpha2"
+	"github.com/kubeshield/operator/pkg/engine"
+	"github.com/kubeshield/operator/pkg/protection"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+const (
+	// ReportName is the name of the PolicyReport written to every namespace with violations
+	ReportName = "kubeshield"
+
+	// source identifies the operator as the producer of results
+	source = "kubeshield"
+
+	// category groups the operator's results in policy dashboards
+	category = "Pod Security"
+
+	// managedByLabel marks the reports the writer owns, so stale ones can be found
+	managedByLabel = "app.kubernetes.io/managed-by"
+)
+
+// Writer rewrites the PolicyReports every Interval from the
+// violation store. Namespaces without violations have their report removed. Each
+// report is annotated with the policies that apply to its namespace. It runs as a
+// Job on the leader.
+type Writer struct {
+	Store    state.Store
+	Client   client.Client
+	Reader   client.Reader
+	System   *protection.SystemNamespaces
+	Interval time.Duration
+}
+
+// NewWriter creates a Writer. reader is used to find the existing reports and should
+// not be cache-backed, so the operator does not need to watch PolicyReports.
+func NewWriter(store state.Store, c client.Client, reader client.Reader, system *protection.SystemNamespaces, interval time.Duration) *Writer {
+	return &Writer{
+		Store:    store,
+		Client:   c,
+		Reader:   reader,
+		System:   system,
+		Interval: interval,
+	}
+}
+
+// Job returns the leader-only job publishing the PolicyReports every Interval
+func (w *Writer) Job() schedule.Job {
+	return schedule.Job{
+		Name:     "policy-report",
+		Interval: w.Interval,
+		Run: func(ctx context.Context, logger logr.Logger) {
+			if err := w.sync(ctx, logger); err != nil {
+				logger.Error(err, "Failed to publish PolicyReports")
+			}
+		},
+	}
+}
+
+// sync brings the PolicyReports in line with the current violations
+func (w *Writer) sync(ctx context.Context, logger logr.Logger) error {
+	desired := Build(w.Store.List())
+	if err := w.annotate(ctx, desired); err != nil {
+		return err
+	}
+
+	existing := &wgpolicyv1alpha2.PolicyReportList{}
+	if err := w.Reader.List(ctx, existing, client.MatchingLabels{managedByLabel: source}); err != nil {
+		return fmt.Errorf("listing PolicyReports: %w", err)
+	}
+
+	for i := range existing.Items {
+		report := &existing.Items[i]
+		want, ok := desired[report.Namespace]
+		delete(desired, report.Namespace)
+
+		if !ok {
+			if err := w.Client.Delete(ctx, report); err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete PolicyReport", "namespace", report.Namespace)
+			}
+			continue
+		}
+		summary := want.Annotations[shieldv1alpha1.EffectivePoliciesAnnotation]
+		if equality.Semantic.DeepEqual(report.Results, want.Results) && report.Summary == want.Summary &&
+			report.Annotations[shieldv1alpha1.EffectivePoliciesAnnotation] == summary {
+			continue
+		}
+		report.Results, report.Summary = want.Results, want.Summary
+		if report.Annotations == nil {
+			report.Annotations = make(map[string]string)
+		}
+		report.Annotations[shieldv1alpha1.EffectivePoliciesAnnotation] = summary
+		if err := w.Client.Update(ctx, report); err != nil {
+			logger.Error(err, "Failed to update PolicyReport", "namespace", report.Namespace)
+		}
+	}
+
+	for namespace, report := range desired {
+		if err := w.Client.Create(ctx, report); err != nil {
+			logger.Error(err, "Failed to create PolicyReport", "namespace", namespace)
+		}
+	}
+	return nil
+}
+
+// annotate sets EffectivePoliciesAnnotation on each report, from the policies and
+// exemptions in the manager's cache
+func (w *Writer) annotate(ctx context.Context, reports map[string]*wgpolicyv1alpha2.PolicyReport) error {
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := w.Client.List(ctx, policies); err != nil {
+		return fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+
+	now := time.Now()
+	for name, report := range reports {
+		namespace := &corev1.Namespace{}
+		if err := w.Client.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("reading namespace %s: %w", name, err)
+		}
+		exemptions := &shieldv1alpha1.ShieldExemptionList{}
+		if err := w.Client.List(ctx, exemptions, client.InNamespace(name)); err != nil {
+			return fmt.Errorf("listing ShieldExemptions in %s: %w", name, err)
+		}
+		effective := engine.Compute(namespace, policies.Items, exemptions.Items, w.System, now)
+		report.Annotations = map[string]string{shieldv1alpha1.EffectivePoliciesAnnotation: effective.Summary()}
+	}
+	return nil
+}
+
+// Build turns violations into one PolicyReport per namespace, keyed by namespace
+func Build(violations []state.Violation) map[string]*wgpolicyv1alpha2.PolicyReport {
+	reports := make(map[string]*wgpolicyv1alpha2.PolicyReport)
+	for _, v := range violations {
+		report, ok := reports[v.Pod.Namespace]
+		if !ok {
+			report = &wgpolicyv1alpha2.PolicyReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ReportName,
+					Namespace: v.Pod.Namespace,
+					Labels:    map[string]string{managedByLabel: source},
+				},
+			}
+			reports[v.Pod.Namespace] = report
+		}
+
+		// Custom rules share one event type, so name the rule to tell their results apart
+		rule := v.EventType
+		if v.Rule != "" {
+			rule = shieldv1alpha1.CustomRuleCheck(v.Rule)
+		}
+
+		// FirstSeen keeps the result stable across re-evaluations of the pod
+		report.Results = append(report.Results, wgpolicyv1alpha2.PolicyReportResult{
+			Source:    source,
+			Policy:    v.Policy,
+			Rule:      rule,
+			Category:  category,
+			Severity:  wgpolicyv1alpha2.PolicySeverity(strings.ToLower(v.Severity)),
+			Timestamp: metav1.Timestamp{Seconds: v.FirstSeen.Unix()},
+			Result:    wgpolicyv1alpha2.StatusFail,
+			Scored:    true,
+			Resources: []corev1.ObjectReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Namespace:  v.Pod.Namespace,
+				Name:       v.Pod.Name,
+				UID:        v.PodUID,
+			}},
+			Message: fmt.Sprintf("Pod '%s' violates %s of policy '%s'", v.Pod.Name, rule, v.Policy),
+		})
+		report.Summary.Fail++
+	}
+	return reports
+}
@@ -0,0 +1,96 @@
+// Package protection decides which pods the operator must never terminate,
+// regardless of what any ShieldPolicy says. It always covers the operator's
+// own workload so a misconfigured policy cannot disable enforcement.
+package protection
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeshield/operator/pkg/ownership"
+)
+
+// Marker is attached to security events raised on protected pods
+const Marker = "PROTECTED_RESOURCE"
+
+// Protector matches pods against "namespace/name" glob patterns
+type Protector struct {
+	patterns []string
+}
+
+// NewProtector creates a Protector for the given "namespace/name" glob patterns
+func NewProtector(patterns []string) (*Protector, error) {
+	p := &Protector{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if !strings.Contains(pattern, "/") {
+			return nil, fmt.Errorf("protected workload pattern %q must have the form namespace/name", pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid protected workload pattern %q: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	return p, nil
+}
+
+// Patterns returns the patterns the Protector matches
+func (p *Protector) Patterns() []string {
+	return p.patterns
+}
+
+// IsProtected reports whether the pod must never be terminated, and the pattern that matched
+func (p *Protector) IsProtected(pod *corev1.Pod) (bool, string) {
+	name := pod.Namespace + "/" + pod.Name
+	for _, pattern := range p.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
+
+// SelfPatterns returns the patterns that cover the operator's own pods. When the
+// operator pod belongs to a Deployment every replica of its current revision is covered,
+// otherwise only the pod itself. reader must not depend on a started cache.
+func SelfPatterns(ctx context.Context, reader client.Reader, namespace, podName string) ([]string, error) {
+	if namespace == "" || podName == "" {
+		return nil, fmt.Errorf("operator namespace and pod name are not known, set POD_NAMESPACE and POD_NAME via the downward API")
+	}
+	patterns := []string{namespace + "/" + podName}
+
+	pod := &corev1.Pod{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: podName}, pod); err != nil {
+		return patterns, err
+	}
+
+	owner, err := ownership.NewResolver(reader).TopLevel(ctx, pod)
+	if err != nil {
+		return patterns, err
+	}
+
+	// Pods of a Deployment are named <deployment>-<template hash>-<suffix>, and pods
+	// of a bare ReplicaSet <replicaset>-<suffix>. The suffix has a fixed length, so
+	// other workloads whose names merely share the prefix are not covered.
+	switch {
+	case owner == nil:
+	case owner.Kind == "Deployment" && pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] != "":
+		patterns = append(patterns, namespace+"/"+owner.Name+"-"+pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]+"-"+generatedSuffix)
+	case owner.Kind == "ReplicaSet":
+		patterns = append(patterns, namespace+"/"+owner.Name+"-"+generatedSuffix)
+	}
+	return patterns, nil
+}
+
+// generatedSuffix matches the random suffix the API server appends to a generateName
+const generatedSuffix = "?????"
@@ -0,0 +1,64 @@
+package protection
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSelfPatternsOfDeployment(t *testing.T) {
+	controller := true
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "kube-shield", Name: "kube-shield-operator-5d4f8c", UID: "rs-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "kube-shield-operator", UID: "deploy-uid", Controller: &controller}},
+	}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "kube-shield", Name: "kube-shield-operator-5d4f8c-x7k2p",
+		Labels:          map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5d4f8c"},
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, UID: rs.UID, Controller: &controller}},
+	}}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(rs, pod).Build()
+
+	patterns, err := SelfPatterns(context.Background(), c, pod.Namespace, pod.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"kube-shield/kube-shield-operator-5d4f8c-x7k2p", "kube-shield/kube-shield-operator-5d4f8c-?????"}
+	if len(patterns) != len(want) || patterns[0] != want[0] || patterns[1] != want[1] {
+		t.Errorf("SelfPatterns = %v, want %v", patterns, want)
+	}
+
+	protector, err := NewProtector(patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "kube-shield-operator-5d4f8c-x7k2p", want: true},
+		{name: "kube-shield-operator-5d4f8c-q9w3z", want: true},
+		{name: "kube-shield-operator-dashboard-7b9c6d-x7k2p"},
+		{name: "kube-shield-operator-5d4f8c-x7k2p-debug"},
+		{name: "kube-shield-operator-canary-x7k2p"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-shield", Name: tt.name}}
+			if got, _ := protector.IsProtected(other); got != tt.want {
+				t.Errorf("IsProtected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelfPatternsWithoutDownwardAPI(t *testing.T) {
+	if _, err := SelfPatterns(context.Background(), fake.NewClientBuilder().Build(), "", ""); err == nil {
+		t.Error("SelfPatterns without a namespace and pod name succeeded")
+	}
+}
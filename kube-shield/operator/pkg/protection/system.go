@@ -0,0 +1,109 @@
+package protection
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// System namespace modes
+const (
+	// SystemNamespaceModeSkip ignores pods in system namespaces entirely
+	SystemNamespaceModeSkip = "skip"
+	// SystemNamespaceModeAuditOnly evaluates pods in system namespaces but never acts on them
+	SystemNamespaceModeAuditOnly = "audit-only"
+)
+
+// SystemNamespaceMarker is attached to security events downgraded because the pod
+// runs in a system namespace
+const SystemNamespaceMarker = "SYSTEM_NAMESPACE"
+
+// SystemNamespaces is the single place that decides how pods in cluster system
+// namespaces are treated. Patterns are globs such as "kube-system" or "openshift-*".
+// Bypassed namespaces, such as monitoring or logging, are never evaluated whatever
+// the mode and whatever the policies say.
+type SystemNamespaces struct {
+	patterns []string
+	bypass   []string
+	mode     string
+}
+
+// NewSystemNamespaces creates a SystemNamespaces for the given system namespace
+// globs, mode and bypassed namespace globs
+func NewSystemNamespaces(patterns []string, mode string, bypass []string) (*SystemNamespaces, error) {
+	if mode != SystemNamespaceModeSkip && mode != SystemNamespaceModeAuditOnly {
+		return nil, fmt.Errorf("invalid system namespace mode %q, must be %s or %s",
+			mode, SystemNamespaceModeSkip, SystemNamespaceModeAuditOnly)
+	}
+
+	s := &SystemNamespaces{mode: mode}
+	var err error
+	if s.patterns, err = namespacePatterns("system", patterns); err != nil {
+		return nil, err
+	}
+	if s.bypass, err = namespacePatterns("bypassed", bypass); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// namespacePatterns validates namespace globs, dropping empty ones
+func namespacePatterns(kind string, patterns []string) ([]string, error) {
+	var valid []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s namespace pattern %q: %w", kind, pattern, err)
+		}
+		valid = append(valid, pattern)
+	}
+	return valid, nil
+}
+
+// matchesAny reports whether namespace matches one of the globs
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Patterns returns the system namespace globs
+func (s *SystemNamespaces) Patterns() []string {
+	return s.patterns
+}
+
+// BypassPatterns returns the bypassed namespace globs
+func (s *SystemNamespaces) BypassPatterns() []string {
+	return s.bypass
+}
+
+// Mode returns how pods in system namespaces are treated
+func (s *SystemNamespaces) Mode() string {
+	return s.mode
+}
+
+// IsSystem reports whether namespace is a system namespace
+func (s *SystemNamespaces) IsSystem(namespace string) bool {
+	return matchesAny(s.patterns, namespace)
+}
+
+// IsBypassed reports whether namespace is exempt from all checks
+func (s *SystemNamespaces) IsBypassed(namespace string) bool {
+	return matchesAny(s.bypass, namespace)
+}
+
+// Skip reports whether pods in namespace must not be evaluated at all
+func (s *SystemNamespaces) Skip(namespace string) bool {
+	return s.IsBypassed(namespace) || s.mode == SystemNamespaceModeSkip && s.IsSystem(namespace)
+}
+
+// AuditOnly reports whether pods in namespace are evaluated but only ever audited
+func (s *SystemNamespaces) AuditOnly(namespace string) bool {
+	return s.mode == SystemNamespaceModeAuditOnly && s.IsSystem(namespace)
+}
@@ -0,0 +1,232 @@
+package reporter
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/scoring"
+	"github.com/kubeshield/operator/pkg/state"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+// ComplianceReport is a point-in-time view of the policy set and the pods
+// currently violating it
+type ComplianceReport struct {
+	GeneratedAt     string           `json:"generatedAt"`
+	OperatorVersion string           `json:"operatorVersion"`
+	Policies        []PolicyEntry    `json:"policies"`
+	Namespaces      []NamespaceScore `json:"namespaces"`
+	Violations      []ViolationEntry `json:"violations"`
+}
+
+// NamespaceScore is the compliance score of a namespace with active violations
+type NamespaceScore struct {
+	Namespace  string `json:"namespace"`
+	Score      int32  `json:"score"`
+	Violations int    `json:"violations"`
+}
+
+// PolicyEntry describes one ShieldPolicy in a ComplianceReport
+type PolicyEntry struct {
+	Name                string   `json:"name"`
+	Mode                string   `json:"mode"`
+	Phase               string   `json:"phase,omitempty"`
+	Checks              []string `json:"checks"`
+	TargetNamespaces    []string `json:"targetNamespaces,omitempty"`
+	ViolationsCount     int64    `json:"violationsCount"`
+	TerminationsCount   int64    `json:"terminationsCount"`
+	ViolationsLast24h   int64    `json:"violationsLast24h"`
+	TerminationsLast24h int64    `json:"terminationsLast24h"`
+	Score               int32    `json:"score"`
+}
+
+// ViolationEntry is a violation currently present on a pod
+type ViolationEntry struct {
+	PodName   string `json:"podName"`
+	Namespace string `json:"namespace"`
+	Policy    string `json:"policy"`
+	EventType string `json:"eventType"`
+	Severity  string `json:"severity"`
+	FirstSeen string `json:"firstSeen"`
+}
+
+// BuildComplianceReport lists every ShieldPolicy through reader and combines
+// them with the violations currently held in store, scored with weights
+func BuildComplianceReport(
+	ctx context.Context,
+	reader client.Reader,
+	store state.Store,
+	weights scoring.Weights,
+	now time.Time,
+) (ComplianceReport, error) {
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := reader.List(ctx, policies); err != nil {
+		return ComplianceReport{}, fmt.Errorf("listing ShieldPolicies: %w", err)
+	}
+
+	violations := store.List()
+	scores := weights.Compute(violations)
+
+	report := ComplianceReport{
+		GeneratedAt:     now.UTC().Format(time.RFC3339),
+		OperatorVersion: version.Version,
+		Policies:        make([]PolicyEntry, 0, len(policies.Items)),
+		Namespaces:      make([]NamespaceScore, 0, len(scores.Namespaces)),
+		Violations:      []ViolationEntry{},
+	}
+
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		report.Policies = append(report.Policies, PolicyEntry{
+			Name:                policy.Name,
+			Mode:                policy.EffectiveEnforcementMode(),
+			Phase:               policy.Status.Phase,
+			Checks:              policy.EnabledChecks(),
+			TargetNamespaces:    policy.Spec.TargetNamespaces,
+			ViolationsCount:     policy.Status.ViolationsCount,
+			TerminationsCount:   policy.Status.TerminationsCount,
+			ViolationsLast24h:   policy.Status.ViolationsLast24h,
+			TerminationsLast24h: policy.Status.TerminationsLast24h,
+			Score:               scores.Policy(policy.Name),
+		})
+	}
+	sort.Slice(report.Policies, func(i, j int) bool {
+		return report.Policies[i].Name < report.Policies[j].Name
+	})
+
+	perNamespace := make(map[string]int)
+	for _, v := range violations {
+		perNamespace[v.Pod.Namespace]++
+		report.Violations = append(report.Violations, ViolationEntry{
+			PodName:   v.Pod.Name,
+			Namespace: v.Pod.Namespace,
+			Policy:    v.Policy,
+			EventType: v.EventType,
+			Severity:  v.Severity,
+			FirstSeen: v.FirstSeen.UTC().Format(time.RFC3339),
+		})
+	}
+
+	for ns, count := range perNamespace {
+		report.Namespaces = append(report.Namespaces, NamespaceScore{
+			Namespace:  ns,
+			Score:      scores.Namespace(ns),
+			Violations: count,
+		})
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+
+	return report, nil
+}
+
+// WriteCSV writes the report as CSV: one row per policy followed by one row per violation
+func (r ComplianceReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	rows := [][]string{{"kind", "name", "namespace", "policy", "mode", "checks", "violations", "terminations", "score", "eventType", "severity", "firstSeen"}}
+	for _, p := range r.Policies {
+		rows = append(rows, []string{
+			"policy", p.Name, strings.Join(p.TargetNamespaces, ";"), p.Name, p.Mode, strings.Join(p.Checks, ";"),
+			strconv.FormatInt(p.ViolationsCount, 10), strconv.FormatInt(p.TerminationsCount, 10),
+			strconv.Itoa(int(p.Score)), "", "", "",
+		})
+	}
+	for _, n := range r.Namespaces {
+		rows = append(rows, []string{
+			"namespace", n.Namespace, n.Namespace, "", "", "", strconv.Itoa(n.Violations), "", strconv.Itoa(int(n.Score)), "", "", "",
+		})
+	}
+	for _, v := range r.Violations {
+		rows = append(rows, []string{
+			"violation", v.PodName, v.Namespace, v.Policy, "", "", "", "", "", v.EventType, v.Severity, v.FirstSeen,
+		})
+	}
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+// complianceHTML renders a ComplianceReport as a standalone HTML page
+var complianceHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Kube-Shield compliance report</title></head>
+<body>
+<h1>Kube-Shield compliance report</h1>
+<p>Generated at {{.GeneratedAt}} by operator {{.OperatorVersion}}</p>
+<h2>Policies</h2>
+<table border="1">
+<tr><th>Name</th><th>Mode</th><th>Phase</th><th>Checks</th><th>Namespaces</th><th>Violations</th><th>Terminations</th><th>Violations 24h</th><th>Score</th></tr>
+{{range .Policies}}<tr><td>{{.Name}}</td><td>{{.Mode}}</td><td>{{.Phase}}</td><td>{{range $i, $c := .Checks}}{{if $i}}, {{end}}{{$c}}{{end}}</td><td>{{range $i, $n := .TargetNamespaces}}{{if $i}}, {{end}}{{$n}}{{else}}all{{end}}</td><td>{{.ViolationsCount}}</td><td>{{.TerminationsCount}}</td><td>{{.ViolationsLast24h}}</td><td>{{.Score}}</td></tr>
+{{end}}</table>
+<h2>Namespaces with violations</h2>
+<table border="1">
+<tr><th>Namespace</th><th>Violations</th><th>Score</th></tr>
+{{range .Namespaces}}<tr><td>{{.Namespace}}</td><td>{{.Violations}}</td><td>{{.Score}}</td></tr>
+{{end}}</table>
+<h2>Current violations</h2>
+<table border="1">
+<tr><th>Namespace</th><th>Pod</th><th>Policy</th><th>Event type</th><th>Severity</th><th>First seen</th></tr>
+{{range .Violations}}<tr><td>{{.Namespace}}</td><td>{{.PodName}}</td><td>{{.Policy}}</td><td>{{.EventType}}</td><td>{{.Severity}}</td><td>{{.FirstSeen}}</td></tr>
+{{else}}<tr><td colspan="6">No pods are currently violating a policy</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as an HTML page
+func (r ComplianceReport) WriteHTML(w io.Writer) error {
+	return complianceHTML.Execute(w, r)
+}
+
+// ReportHandler serves a freshly built ComplianceReport. The format query
+// parameter selects json (default), csv or html.
+func ReportHandler(reader client.Reader, store state.Store, weights scoring.Weights) http.Handler {
+	logger := ctrl.Log.WithName("compliance-report")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report, err := BuildComplianceReport(req.Context(), reader, store, weights, time.Now())
+		if err != nil {
+			logger.Error(err, "Failed to build compliance report")
+			http.Error(w, "failed to build compliance report", http.StatusInternalServerError)
+			return
+		}
+
+		switch format := req.URL.Query().Get("format"); format {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			err = json.NewEncoder(w).Encode(report)
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="kubeshield-compliance.csv"`)
+			err = report.WriteCSV(w)
+		case "html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			err = report.WriteHTML(w)
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q, use json, csv or html", format), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.Error(err, "Failed to write compliance report")
+		}
+	})
+}
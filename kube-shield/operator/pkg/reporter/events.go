@@ -0,0 +1,84 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kubeshield/operator/pkg/audit"
+)
+
+// RecentEventsResponse is the body served by EventsHandler
+type RecentEventsResponse struct {
+	Count  int                   `json:"count"`
+	Events []audit.SecurityEvent `json:"events"`
+}
+
+// EventsHandler serves the security events held by recent, newest first. The
+// namespace, policy and severity query parameters filter them, since and until bound
+// their time as RFC 3339 timestamps or durations before now (e.g. since=15m), and
+// limit caps how many are returned. Captured container logs are left out as they
+// may hold data the requester is not allowed to read; they reach the audit sinks.
+func EventsHandler(recent *audit.RecentEvents) http.Handler {
+	logger := ctrl.Log.WithName("recent-events")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query, err := parseEventQuery(req, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		events := recent.Query(query)
+		for i := range events {
+			events[i].CapturedLogs = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(RecentEventsResponse{Count: len(events), Events: events}); err != nil {
+			logger.Error(err, "Failed to write recent events")
+		}
+	})
+}
+
+// parseEventQuery reads an EventQuery from the request's query parameters
+func parseEventQuery(req *http.Request, now time.Time) (audit.EventQuery, error) {
+	params := req.URL.Query()
+	query := audit.EventQuery{
+		Namespace: params.Get("namespace"),
+		Policy:    params.Get("policy"),
+		Severity:  params.Get("severity"),
+	}
+
+	var err error
+	if query.Since, err = parseQueryTime(params.Get("since"), now); err != nil {
+		return query, fmt.Errorf("invalid since: %w", err)
+	}
+	if query.Until, err = parseQueryTime(params.Get("until"), now); err != nil {
+		return query, fmt.Errorf("invalid until: %w", err)
+	}
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit < 0 {
+			return query, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+	return query, nil
+}
+
+// parseQueryTime accepts an RFC 3339 timestamp or a duration before now
+func parseQueryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
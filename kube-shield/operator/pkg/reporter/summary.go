@@ -0,0 +1,289 @@
+// Package reporter periodically sends a digest of the current violations to the
+// audit service or Slack, for teams that prefer a summary over per-event noise.
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeshield/operator/pkg/listing"
+	"github.com/kubeshield/operator/pkg/schedule"
+	"github.com/kubeshield/operator/pkg/state"
+	"github.com/kubeshield/operator/pkg/version"
+)
+
+// Supported summary destinations
+const (
+	// DestinationAudit posts the summary as JSON to the audit service's /summary endpoint
+	DestinationAudit = "audit"
+	// DestinationSlack posts the summary as a message to a Slack incoming webhook
+	DestinationSlack = "slack"
+)
+
+// slackWebhookHost is the only host Slack serves incoming webhooks from
+const slackWebhookHost = "hooks.slack.com"
+
+// ValidateSlackWebhookURL checks that raw is an https Slack incoming webhook, so a
+// mistyped or substituted URL cannot send the cluster's violations elsewhere
+func ValidateSlackWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" {
+		return errors.New("webhook URL must use https")
+	}
+	if parsed.Hostname() != slackWebhookHost || parsed.Port() != "" {
+		return fmt.Errorf("webhook URL must point at %s", slackWebhookHost)
+	}
+	if parsed.User != nil {
+		return errors.New("webhook URL must not carry credentials")
+	}
+	return nil
+}
+
+// Summary is the digest of current violations sent on every interval
+type Summary struct {
+	GeneratedAt      string        `json:"generatedAt"`
+	Interval         string        `json:"interval"`
+	OperatorVersion  string        `json:"operatorVersion"`
+	TotalViolations  int           `json:"totalViolations"`
+	ViolatingPods    int           `json:"violatingPods"`
+	TotalPods        int           `json:"totalPods"`
+	Counts           []Count       `json:"counts"`
+	Namespaces       []Namespace   `json:"namespaces"`
+	TopOffendingPods []PodOffender `json:"topOffendingPods"`
+}
+
+// Namespace compares the number of violating pods with all pods in a namespace
+type Namespace struct {
+	Namespace     string `json:"namespace"`
+	Pods          int    `json:"pods"`
+	ViolatingPods int    `json:"violatingPods"`
+}
+
+// Count is the number of violations for one policy, namespace and event type
+type Count struct {
+	Policy    string `json:"policy"`
+	Namespace string `json:"namespace"`
+	EventType string `json:"eventType"`
+	Count     int    `json:"count"`
+}
+
+// PodOffender is a pod ranked by its number of current violations
+type PodOffender struct {
+	PodName    string `json:"podName"`
+	Namespace  string `json:"namespace"`
+	Violations int    `json:"violations"`
+}
+
+// SummaryReporter sends a Summary every Interval. It runs as a Job on the leader, so
+// replicas do not send duplicate summaries.
+type SummaryReporter struct {
+	Store       state.Store
+	Reader      client.Reader
+	PageSize    int64
+	HTTPClient  *http.Client
+	Interval    time.Duration
+	Destination string
+	URL         string
+	TopN        int
+}
+
+// NewSummaryReporter creates a SummaryReporter. For DestinationAudit, url is the
+// audit service base URL; for DestinationSlack it is the incoming webhook URL.
+// reader is used for paginated pod counts and should not be cache-backed.
+func NewSummaryReporter(
+	store state.Store,
+	reader client.Reader,
+	pageSize int64,
+	httpClient *http.Client,
+	interval time.Duration,
+	destination string,
+	url string,
+	topN int,
+) *SummaryReporter {
+	return &SummaryReporter{
+		Store:       store,
+		Reader:      reader,
+		PageSize:    pageSize,
+		HTTPClient:  httpClient,
+		Interval:    interval,
+		Destination: destination,
+		URL:         url,
+		TopN:        topN,
+	}
+}
+
+// Job returns the leader-only job sending a summary every Interval
+func (r *SummaryReporter) Job() schedule.Job {
+	return schedule.Job{
+		Name:     "summary-reporter",
+		Interval: r.Interval,
+		Run:      r.report,
+	}
+}
+
+// report builds and sends a single summary
+func (r *SummaryReporter) report(ctx context.Context, logger logr.Logger) {
+	podCounts, err := r.countPods(ctx)
+	if err != nil {
+		// The summary is still useful without pod totals
+		logger.Error(err, "Failed to count pods for summary report")
+	}
+	summary := r.Build(time.Now(), podCounts)
+
+	switch r.Destination {
+	case DestinationSlack:
+		err = r.post(ctx, r.URL, map[string]string{"text": summary.Text()})
+	default:
+		err = r.post(ctx, fmt.Sprintf("%s/summary", r.URL), summary)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to send summary report", "destination", r.Destination)
+		return
+	}
+
+	logger.V(1).Info("Sent summary report",
+		"violations", summary.TotalViolations,
+		"violatingPods", summary.ViolatingPods,
+	)
+}
+
+// countPods returns the number of pods per namespace, listed page by page
+func (r *SummaryReporter) countPods(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	pods := &corev1.PodList{}
+	err := listing.Paginate(ctx, r.Reader, pods, r.PageSize, func() error {
+		for i := range pods.Items {
+			counts[pods.Items[i].Namespace]++
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// Build aggregates the store's current violations into a Summary. podCounts
+// holds the number of pods per namespace and may be nil.
+func (r *SummaryReporter) Build(now time.Time, podCounts map[string]int) Summary {
+	violations := r.Store.List()
+
+	counts := make(map[Count]int)
+	perPod := make(map[PodOffender]int)
+	violatingPerNamespace := make(map[string]int)
+	for _, v := range violations {
+		counts[Count{Policy: v.Policy, Namespace: v.Pod.Namespace, EventType: v.EventType}]++
+		perPod[PodOffender{PodName: v.Pod.Name, Namespace: v.Pod.Namespace}]++
+	}
+
+	summary := Summary{
+		GeneratedAt:      now.UTC().Format(time.RFC3339),
+		Interval:         r.Interval.String(),
+		OperatorVersion:  version.Version,
+		TotalViolations:  len(violations),
+		ViolatingPods:    len(perPod),
+		Counts:           []Count{},
+		Namespaces:       []Namespace{},
+		TopOffendingPods: []PodOffender{},
+	}
+
+	for key, n := range counts {
+		key.Count = n
+		summary.Counts = append(summary.Counts, key)
+	}
+	sort.Slice(summary.Counts, func(i, j int) bool {
+		a, b := summary.Counts[i], summary.Counts[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Policy+a.Namespace+a.EventType < b.Policy+b.Namespace+b.EventType
+	})
+
+	for pod := range perPod {
+		violatingPerNamespace[pod.Namespace]++
+	}
+	for ns, pods := range podCounts {
+		summary.TotalPods += pods
+		summary.Namespaces = append(summary.Namespaces, Namespace{
+			Namespace:     ns,
+			Pods:          pods,
+			ViolatingPods: violatingPerNamespace[ns],
+		})
+	}
+	sort.Slice(summary.Namespaces, func(i, j int) bool {
+		return summary.Namespaces[i].Namespace < summary.Namespaces[j].Namespace
+	})
+
+	for pod, n := range perPod {
+		pod.Violations = n
+		summary.TopOffendingPods = append(summary.TopOffendingPods, pod)
+	}
+	sort.Slice(summary.TopOffendingPods, func(i, j int) bool {
+		a, b := summary.TopOffendingPods[i], summary.TopOffendingPods[j]
+		if a.Violations != b.Violations {
+			return a.Violations > b.Violations
+		}
+		return a.Namespace+"/"+a.PodName < b.Namespace+"/"+b.PodName
+	})
+	if r.TopN > 0 && len(summary.TopOffendingPods) > r.TopN {
+		summary.TopOffendingPods = summary.TopOffendingPods[:r.TopN]
+	}
+
+	return summary
+}
+
+// Text renders the summary as a short plain-text message
+func (s Summary) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Kube-Shield summary* (%s): %d active violations across %d pods\n",
+		s.GeneratedAt, s.TotalViolations, s.ViolatingPods)
+	for _, c := range s.Counts {
+		fmt.Fprintf(&b, "• %s / %s / %s: %d\n", c.Policy, c.Namespace, c.EventType, c.Count)
+	}
+	if len(s.TopOffendingPods) > 0 {
+		b.WriteString("Top offending pods:\n")
+		for _, p := range s.TopOffendingPods {
+			fmt.Fprintf(&b, "• %s/%s: %d\n", p.Namespace, p.PodName, p.Violations)
+		}
+	}
+	return b.String()
+}
+
+// post sends body as JSON to url
+func (r *SummaryReporter) post(ctx context.Context, url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("summary destination returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package schedule runs the operator's background jobs, such as scheduled reports and
+// startup scans. Jobs only run while this replica holds leadership, so a highly
+// available deployment does not repeat them on every replica.
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Job is a background task run by the leader
+type Job struct {
+	// Name names the job's logger
+	Name string
+
+	// Interval is the time between runs. A zero Interval runs the job once.
+	Interval time.Duration
+
+	// Immediate runs the job as soon as leadership is acquired instead of
+	// after the first Interval
+	Immediate bool
+
+	// Run performs a single run. It handles its own errors, a failed run
+	// does not stop the job.
+	Run func(ctx context.Context, logger logr.Logger)
+}
+
+// Add registers job with mgr. It starts once this replica is elected leader and
+// stops with the manager; replicas that are not the leader stay passive.
+func Add(mgr manager.Manager, job Job) error {
+	return mgr.Add(&leaderJob{job: job})
+}
+
+// AddRunnable registers r with mgr so that it only runs on the leader, even if r
+// asks to run on every replica
+func AddRunnable(mgr manager.Manager, r manager.Runnable) error {
+	return mgr.Add(leaderRunnable{Runnable: r})
+}
+
+// leaderJob is the manager Runnable running a Job
+type leaderJob struct {
+	job Job
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (j *leaderJob) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable
+func (j *leaderJob) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName(j.job.Name)
+	if j.job.Interval <= 0 {
+		j.job.Run(ctx, logger)
+		return nil
+	}
+
+	logger.V(1).Info("Starting background job", "interval", j.job.Interval)
+	ticker := time.NewTicker(j.job.Interval)
+	defer ticker.Stop()
+
+	if j.job.Immediate {
+		j.job.Run(ctx, logger)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			j.job.Run(ctx, logger)
+		}
+	}
+}
+
+// leaderRunnable overrides the leader election preference of a Runnable
+type leaderRunnable struct {
+	manager.Runnable
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (leaderRunnable) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,122 @@
+// Package scoring turns the violations currently present in the cluster into a
+// single severity-weighted compliance score per policy and per namespace.
+package scoring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+// The compliance score of a scope (a policy or a namespace) is
+//
+//	score = max(MinScore, MaxScore - Σ weight(severity of each active violation in scope))
+//
+// so a scope without active violations scores MaxScore and the score recovers as
+// soon as violations clear.
+const (
+	// MaxScore is the score of a scope without active violations
+	MaxScore = 100
+	// MinScore is the lowest possible score
+	MinScore = 0
+)
+
+// Scopes of a compliance score
+const (
+	// ScopePolicy scores the violations raised by one ShieldPolicy
+	ScopePolicy = "policy"
+	// ScopeNamespace scores the violations of pods in one namespace
+	ScopeNamespace = "namespace"
+)
+
+// DefaultWeights are the per-severity penalties used unless configured otherwise
+var DefaultWeights = Weights{
+	"CRITICAL": 10,
+	"HIGH":     5,
+	"MEDIUM":   2,
+	"LOW":      1,
+	"INFO":     0,
+}
+
+// Weights maps a severity to the penalty of one active violation of that severity
+type Weights map[string]int
+
+// ParseWeights parses "SEVERITY=weight" pairs. Severities that are not listed
+// keep their DefaultWeights value.
+func ParseWeights(pairs []string) (Weights, error) {
+	weights := make(Weights, len(DefaultWeights))
+	for severity, weight := range DefaultWeights {
+		weights[severity] = weight
+	}
+	for _, pair := range pairs {
+		severity, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid score weight %q, expected SEVERITY=weight", pair)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid score weight %q, weight must be a non-negative integer", pair)
+		}
+		weights[strings.ToUpper(strings.TrimSpace(severity))] = weight
+	}
+	return weights, nil
+}
+
+// Score computes the compliance score of a set of violations
+func (w Weights) Score(violations []state.Violation) int32 {
+	score := MaxScore
+	for _, v := range violations {
+		score -= w[v.Severity]
+		if score <= MinScore {
+			return MinScore
+		}
+	}
+	return int32(score)
+}
+
+// Scores holds the compliance scores of every policy and namespace with active violations
+type Scores struct {
+	Policies   map[string]int32
+	Namespaces map[string]int32
+}
+
+// Compute groups violations by policy and by namespace and scores each group.
+// Scopes without violations are absent and score MaxScore.
+func (w Weights) Compute(violations []state.Violation) Scores {
+	byPolicy := make(map[string][]state.Violation)
+	byNamespace := make(map[string][]state.Violation)
+	for _, v := range violations {
+		byPolicy[v.Policy] = append(byPolicy[v.Policy], v)
+		byNamespace[v.Pod.Namespace] = append(byNamespace[v.Pod.Namespace], v)
+	}
+
+	scores := Scores{
+		Policies:   make(map[string]int32, len(byPolicy)),
+		Namespaces: make(map[string]int32, len(byNamespace)),
+	}
+	for policy, vs := range byPolicy {
+		scores.Policies[policy] = w.Score(vs)
+	}
+	for ns, vs := range byNamespace {
+		scores.Namespaces[ns] = w.Score(vs)
+	}
+	return scores
+}
+
+// Policy returns the score of a policy
+func (s Scores) Policy(name string) int32 {
+	if score, ok := s.Policies[name]; ok {
+		return score
+	}
+	return MaxScore
+}
+
+// Namespace returns the score of a namespace
+func (s Scores) Namespace(name string) int32 {
+	if score, ok := s.Namespaces[name]; ok {
+		return score
+	}
+	return MaxScore
+}
@@ -0,0 +1,118 @@
+package scoring
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+func violation(namespace, policy, severity string) state.Violation {
+	return state.Violation{
+		Key:      state.Key{Policy: policy, EventType: "HOST_NETWORK"},
+		Pod:      types.NamespacedName{Namespace: namespace, Name: "web"},
+		Severity: severity,
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities []string
+		want       int32
+	}{
+		{name: "no violations", want: MaxScore},
+		{name: "weighted", severities: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFO"}, want: 82},
+		{name: "unknown severity", severities: []string{"UNKNOWN", "HIGH"}, want: 95},
+		{name: "exactly MinScore", severities: []string{"CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL",
+			"CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL"}, want: MinScore},
+		{name: "clamped at MinScore", severities: []string{"CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL",
+			"CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "CRITICAL", "HIGH"}, want: MinScore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []state.Violation
+			for _, severity := range tt.severities {
+				violations = append(violations, violation("default", "restricted", severity))
+			}
+			if got := DefaultWeights.Score(violations); got != tt.want {
+				t.Errorf("Score = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    Weights
+		wantErr bool
+	}{
+		{name: "defaults", want: DefaultWeights},
+		{
+			name:  "override",
+			pairs: []string{" critical = 20", "INFO=1"},
+			want:  Weights{"CRITICAL": 20, "HIGH": 5, "MEDIUM": 2, "LOW": 1, "INFO": 1},
+		},
+		{
+			name:  "new severity",
+			pairs: []string{"SEVERE=7"},
+			want:  Weights{"CRITICAL": 10, "HIGH": 5, "MEDIUM": 2, "LOW": 1, "INFO": 0, "SEVERE": 7},
+		},
+		{name: "missing weight", pairs: []string{"CRITICAL"}, wantErr: true},
+		{name: "not a number", pairs: []string{"CRITICAL=ten"}, wantErr: true},
+		{name: "negative weight", pairs: []string{"CRITICAL=-1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWeights(tt.pairs)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseWeights = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseWeights = %v, want %v", got, tt.want)
+			}
+			for severity, weight := range tt.want {
+				if got[severity] != weight {
+					t.Errorf("ParseWeights = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+	if DefaultWeights["CRITICAL"] != 10 {
+		t.Errorf("ParseWeights changed DefaultWeights to %v", DefaultWeights)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	scores := DefaultWeights.Compute([]state.Violation{
+		violation("payments", "restricted", "CRITICAL"),
+		violation("payments", "baseline", "HIGH"),
+		violation("web", "restricted", "LOW"),
+	})
+
+	policies := map[string]int32{"restricted": 89, "baseline": 95, "unused": MaxScore}
+	for policy, want := range policies {
+		if got := scores.Policy(policy); got != want {
+			t.Errorf("Policy(%s) = %d, want %d", policy, got, want)
+		}
+	}
+	namespaces := map[string]int32{"payments": 85, "web": 99, "clean": MaxScore}
+	for ns, want := range namespaces {
+		if got := scores.Namespace(ns); got != want {
+			t.Errorf("Namespace(%s) = %d, want %d", ns, got, want)
+		}
+	}
+	if len(scores.Policies) != 2 || len(scores.Namespaces) != 2 {
+		t.Errorf("scores = %+v, want only the scopes with violations", scores)
+	}
+}
@@ -0,0 +1,127 @@
+package scoring
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+const (
+	// debounce batches bursts of store changes into one recalculation
+	debounce = 5 * time.Second
+
+	// resyncInterval picks up policies created since the last recalculation
+	resyncInterval = time.Minute
+)
+
+// Updater is a manager Runnable that recalculates compliance scores whenever the
+// violation store changes, writing them to ShieldPolicy status and the
+// kubeshield_compliance_score gauge. It only runs on the leader.
+type Updater struct {
+	Store   state.Store
+	Client  client.Client
+	Weights Weights
+
+	// namespaces remembers every existing namespace that was scored, so its gauge
+	// goes back to MaxScore once its violations clear
+	namespaces map[string]bool
+}
+
+// NewUpdater creates an Updater
+func NewUpdater(store state.Store, c client.Client, weights Weights) *Updater {
+	return &Updater{
+		Store:      store,
+		Client:     c,
+		Weights:    weights,
+		namespaces: make(map[string]bool),
+	}
+}
+
+// Start implements manager.Runnable
+func (u *Updater) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("compliance-score")
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	changed, unsubscribe := u.Store.Subscribe()
+	defer unsubscribe()
+
+	u.update(ctx, logger)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-changed:
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(debounce):
+			}
+		}
+		u.update(ctx, logger)
+	}
+}
+
+// update recalculates all scores and publishes them
+func (u *Updater) update(ctx context.Context, logger logr.Logger) {
+	scores := u.Weights.Compute(u.Store.List())
+
+	for ns := range scores.Namespaces {
+		u.namespaces[ns] = true
+	}
+	u.forgetDeletedNamespaces(ctx, logger)
+	for ns := range u.namespaces {
+		metrics.ComplianceScore.WithLabelValues(ScopeNamespace, ns).Set(float64(scores.Namespace(ns)))
+	}
+
+	policies := &shieldv1alpha1.ShieldPolicyList{}
+	if err := u.Client.List(ctx, policies); err != nil {
+		logger.Error(err, "Failed to list ShieldPolicies for compliance scores")
+		return
+	}
+
+	metrics.ComplianceScore.DeletePartialMatch(map[string]string{"scope": ScopePolicy})
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		score := scores.Policy(policy.Name)
+		metrics.ComplianceScore.WithLabelValues(ScopePolicy, policy.Name).Set(float64(score))
+
+		if policy.Status.ComplianceScore != nil && *policy.Status.ComplianceScore == score {
+			continue
+		}
+		// A merge patch of the score alone does not conflict with the other status writers
+		patch := client.MergeFrom(policy.DeepCopy())
+		policy.Status.ComplianceScore = &score
+		if err := u.Client.Status().Patch(ctx, policy, patch); err != nil {
+			logger.Error(err, "Failed to update compliance score", "policy", policy.Name)
+		}
+	}
+}
+
+// forgetDeletedNamespaces stops scoring namespaces that no longer exist and drops
+// their gauges. When namespaces cannot be listed, they are all kept until next time.
+func (u *Updater) forgetDeletedNamespaces(ctx context.Context, logger logr.Logger) {
+	namespaces := &corev1.NamespaceList{}
+	if err := u.Client.List(ctx, namespaces); err != nil {
+		logger.Error(err, "Failed to list namespaces for compliance scores")
+		return
+	}
+	existing := make(map[string]bool, len(namespaces.Items))
+	for i := range namespaces.Items {
+		existing[namespaces.Items[i].Name] = true
+	}
+	for ns := range u.namespaces {
+		if !existing[ns] {
+			delete(u.namespaces, ns)
+			metrics.ComplianceScore.DeleteLabelValues(ScopeNamespace, ns)
+		}
+	}
+}
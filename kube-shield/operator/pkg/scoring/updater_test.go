@@ -0,0 +1,89 @@
+package scoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	shieldv1alpha1 "github.com/kubeshield/operator/pkg/apis/shield/v1alpha1"
+	"github.com/kubeshield/operator/pkg/metrics"
+	"github.com/kubeshield/operator/pkg/state"
+)
+
+// namespaceScores returns the kubeshield_compliance_score value of every namespace
+func namespaceScores(t *testing.T) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric)
+	go func() {
+		metrics.ComplianceScore.Collect(ch)
+		close(ch)
+	}()
+
+	scores := make(map[string]float64)
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		labels := make(map[string]string)
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if labels["scope"] == ScopeNamespace {
+			scores[labels["name"]] = m.GetGauge().GetValue()
+		}
+	}
+	return scores
+}
+
+func TestUpdaterForgetsDeletedNamespaces(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := shieldv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	payments := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}}
+	web := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(payments, web).Build()
+	store := state.NewMemoryStore()
+	updater := NewUpdater(store, c, DefaultWeights)
+
+	paymentsPod := types.NamespacedName{Namespace: "payments", Name: "web"}
+	webPod := types.NamespacedName{Namespace: "web", Name: "web"}
+	store.Record(paymentsPod, "uid-payments", []state.Violation{violation("payments", "restricted", "CRITICAL")})
+	store.Record(webPod, "uid-web", []state.Violation{violation("web", "restricted", "LOW")})
+	updater.update(ctx, logr.Discard())
+	if scores := namespaceScores(t); scores["payments"] != 90 || scores["web"] != 99 {
+		t.Fatalf("namespace scores = %v, want payments=90 and web=99", scores)
+	}
+
+	// A namespace whose violations cleared recovers, a deleted one disappears
+	store.Record(paymentsPod, "uid-payments", nil)
+	store.Record(webPod, "uid-web", nil)
+	if err := c.Delete(ctx, web); err != nil {
+		t.Fatal(err)
+	}
+	updater.update(ctx, logr.Discard())
+	scores := namespaceScores(t)
+	if scores["payments"] != MaxScore {
+		t.Errorf("payments score = %v, want %d once its violations cleared", scores["payments"], MaxScore)
+	}
+	if _, ok := scores["web"]; ok {
+		t.Errorf("namespace scores = %v, want no gauge for the deleted namespace", scores)
+	}
+	if updater.namespaces["web"] {
+		t.Error("deleted namespace is still tracked")
+	}
+}
@@ -0,0 +1,282 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Counter backends accepted in STATE_BACKEND
+const (
+	// BackendMemory keeps counters in the replica's memory
+	BackendMemory = "memory"
+
+	// BackendConfigMap shares counters between replicas through a ConfigMap
+	BackendConfigMap = "configmap"
+)
+
+// counterKeyPattern is what ConfigMap data keys allow
+var counterKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// Counters are named counts over fixed time windows, such as the pods of one owner
+// terminated in the current hour. Unlike the rest of the operator's state they can
+// be shared, so replicas and a new leader after failover agree on them. Counters
+// kept in one store should use the same window, which must be positive.
+//
+// Only counters are shared. With STATE_BACKEND=configmap the termination limit per
+// owner holds across replicas and failovers, but the rest of the state stays in each
+// replica's memory and starts over on a new leader: the violation store behind
+// /report and the current-violation gauges, the evaluation cache, the alert and
+// audit deduplication, and enforcement failure and retry tracking. A new leader
+// re-evaluates every pod, so it may report violations again that its predecessor
+// already reported.
+type Counters interface {
+	// Add adds delta to the key's count in the window containing now and returns the
+	// new count. A count from an earlier window starts over, and counts never drop
+	// below zero.
+	Add(ctx context.Context, key string, delta int64, window time.Duration) (int64, error)
+
+	// Reserve adds one to the key's count in the window containing now, unless it
+	// already reached limit, as a single atomic step. It returns whether it did.
+	Reserve(ctx context.Context, key string, limit int64, window time.Duration) (bool, error)
+
+	// Get returns the key's count in the window containing now
+	Get(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// counterEntry is a count and the start of the window it belongs to
+type counterEntry struct {
+	start time.Time
+	count int64
+}
+
+// checkWindow rejects windows that are not positive. Truncating to such a window
+// leaves the time unchanged, so every call would start a new window and counts
+// would never go above one.
+func checkWindow(window time.Duration) error {
+	if window <= 0 {
+		return fmt.Errorf("counter window must be positive, got %s", window)
+	}
+	return nil
+}
+
+// current returns the entry's count if it belongs to the window containing now
+func (e counterEntry) current(now time.Time, window time.Duration) int64 {
+	if !e.start.Equal(now.Truncate(window)) {
+		return 0
+	}
+	return e.count
+}
+
+// MemoryCounters are Counters held by a single replica. They are lost on restart
+// and not seen by other replicas.
+type MemoryCounters struct {
+	mu      sync.Mutex
+	entries map[string]counterEntry
+}
+
+// NewMemoryCounters creates empty MemoryCounters
+func NewMemoryCounters() *MemoryCounters {
+	return &MemoryCounters{entries: make(map[string]counterEntry)}
+}
+
+// Add implements Counters
+func (m *MemoryCounters) Add(_ context.Context, key string, delta int64, window time.Duration) (int64, error) {
+	if err := checkWindow(window); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	count := max(m.entries[key].current(now, window)+delta, 0)
+	m.entries[key] = counterEntry{start: now.Truncate(window), count: count}
+	m.prune(now, window)
+	return count, nil
+}
+
+// Reserve implements Counters
+func (m *MemoryCounters) Reserve(_ context.Context, key string, limit int64, window time.Duration) (bool, error) {
+	if err := checkWindow(window); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	count := m.entries[key].current(now, window)
+	if count >= limit {
+		return false, nil
+	}
+	m.entries[key] = counterEntry{start: now.Truncate(window), count: count + 1}
+	m.prune(now, window)
+	return true, nil
+}
+
+// Get implements Counters
+func (m *MemoryCounters) Get(_ context.Context, key string, window time.Duration) (int64, error) {
+	if err := checkWindow(window); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries[key].current(time.Now(), window), nil
+}
+
+// prune drops the entries of past windows
+func (m *MemoryCounters) prune(now time.Time, window time.Duration) {
+	for key, entry := range m.entries {
+		if entry.current(now, window) == 0 {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// ConfigMapCounters are Counters stored in a ConfigMap, one data key per counter
+// holding "<window start unix>:<count>". Updates rely on the ConfigMap's
+// resourceVersion: a write based on a stale read is rejected by the API server and
+// retried on a fresh copy, so concurrent increments from several replicas are never
+// lost. Keys must be valid ConfigMap keys.
+type ConfigMapCounters struct {
+	Client    client.Client
+	Reader    client.Reader
+	Namespace string
+	Name      string
+}
+
+// NewConfigMapCounters creates ConfigMapCounters in the ConfigMap namespace/name,
+// which is created on first use. reader should not be cache-backed, so reads see the
+// latest writes of other replicas.
+func NewConfigMapCounters(c client.Client, reader client.Reader, namespace, name string) *ConfigMapCounters {
+	return &ConfigMapCounters{Client: c, Reader: reader, Namespace: namespace, Name: name}
+}
+
+// Add implements Counters
+func (s *ConfigMapCounters) Add(ctx context.Context, key string, delta int64, window time.Duration) (int64, error) {
+	var count int64
+	err := s.update(ctx, key, window, func(current int64) (int64, bool) {
+		count = max(current+delta, 0)
+		return count, true
+	})
+	return count, err
+}
+
+// Reserve implements Counters
+func (s *ConfigMapCounters) Reserve(ctx context.Context, key string, limit int64, window time.Duration) (bool, error) {
+	var reserved bool
+	err := s.update(ctx, key, window, func(current int64) (int64, bool) {
+		reserved = current < limit
+		return current + 1, reserved
+	})
+	return reserved, err
+}
+
+// update replaces the key's count in the window containing now with what next
+// returns for the current one, unless next declines. Writes based on a stale read
+// are retried on a fresh copy, running next again.
+func (s *ConfigMapCounters) update(ctx context.Context, key string, window time.Duration, next func(current int64) (int64, bool)) error {
+	if !counterKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid counter key %q", key)
+	}
+	if err := checkWindow(window); err != nil {
+		return err
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm, err := s.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		count, ok := next(parseCounter(cm.Data[key]).current(now, window))
+		if !ok {
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		// Drop past windows so the ConfigMap stays small
+		for k, value := range cm.Data {
+			if parseCounter(value).current(now, window) == 0 {
+				delete(cm.Data, k)
+			}
+		}
+		cm.Data[key] = formatCounter(counterEntry{start: now.Truncate(window), count: count})
+
+		if cm.ResourceVersion == "" {
+			err = s.Client.Create(ctx, cm)
+			if errors.IsAlreadyExists(err) {
+				// Another replica created it first, retry on its copy
+				return errors.NewConflict(corev1.Resource("configmaps"), s.Name, err)
+			}
+			return err
+		}
+		return s.Client.Update(ctx, cm)
+	})
+	if err != nil {
+		return fmt.Errorf("updating counter %s in ConfigMap %s/%s: %w", key, s.Namespace, s.Name, err)
+	}
+	return nil
+}
+
+// Get implements Counters
+func (s *ConfigMapCounters) Get(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if err := checkWindow(window); err != nil {
+		return 0, err
+	}
+	cm, err := s.read(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("reading ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+	return parseCounter(cm.Data[key]).current(time.Now(), window), nil
+}
+
+// read returns the ConfigMap, or a new unsaved one if it does not exist yet
+func (s *ConfigMapCounters) read(ctx context.Context) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	err := s.Reader.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: s.Name}, cm)
+	if errors.IsNotFound(err) {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.Namespace,
+				Name:      s.Name,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "kube-shield"},
+			},
+		}, nil
+	}
+	return cm, err
+}
+
+// parseCounter decodes a "<window start unix>:<count>" value, treating malformed
+// values as empty
+func parseCounter(value string) counterEntry {
+	start, count, ok := strings.Cut(value, ":")
+	if !ok {
+		return counterEntry{}
+	}
+	unix, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return counterEntry{}
+	}
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return counterEntry{}
+	}
+	return counterEntry{start: time.Unix(unix, 0), count: n}
+}
+
+// formatCounter encodes an entry as "<window start unix>:<count>"
+func formatCounter(entry counterEntry) string {
+	return fmt.Sprintf("%d:%d", entry.start.Unix(), entry.count)
+}
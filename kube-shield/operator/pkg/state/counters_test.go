@@ -0,0 +1,139 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMemoryCountersReserve(t *testing.T) {
+	ctx := context.Background()
+	counters := NewMemoryCounters()
+
+	for i := 0; i < 3; i++ {
+		if reserved, err := counters.Reserve(ctx, "owner", 3, time.Hour); err != nil || !reserved {
+			t.Fatalf("Reserve #%d = %t, %v, want true", i+1, reserved, err)
+		}
+	}
+	if reserved, _ := counters.Reserve(ctx, "owner", 3, time.Hour); reserved {
+		t.Fatal("Reserve past the limit = true, want false")
+	}
+
+	// A termination that did not happen frees its slot again
+	if _, err := counters.Add(ctx, "owner", -1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if reserved, _ := counters.Reserve(ctx, "owner", 3, time.Hour); !reserved {
+		t.Fatal("Reserve after a release = false, want true")
+	}
+}
+
+func TestMemoryCountersNeverNegative(t *testing.T) {
+	ctx := context.Background()
+	counters := NewMemoryCounters()
+
+	if count, _ := counters.Add(ctx, "owner", -1, time.Hour); count != 0 {
+		t.Fatalf("Add(-1) on an empty counter = %d, want 0", count)
+	}
+}
+
+func TestCountersRejectNonPositiveWindow(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	backends := map[string]Counters{
+		BackendMemory:    NewMemoryCounters(),
+		BackendConfigMap: NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state"),
+	}
+
+	for name, counters := range backends {
+		for _, window := range []time.Duration{0, -time.Minute} {
+			// Every reservation would start a new window and the limit would never apply
+			if reserved, err := counters.Reserve(ctx, "owner", 1, window); err == nil || reserved {
+				t.Errorf("%s: Reserve with window %s = %t, %v, want an error", name, window, reserved, err)
+			}
+			if _, err := counters.Add(ctx, "owner", 1, window); err == nil {
+				t.Errorf("%s: Add with window %s succeeded", name, window)
+			}
+			if _, err := counters.Get(ctx, "owner", window); err == nil {
+				t.Errorf("%s: Get with window %s succeeded", name, window)
+			}
+		}
+	}
+}
+
+func TestConfigMapCountersReserveAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	replicas := []*ConfigMapCounters{
+		NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state"),
+		NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state"),
+	}
+
+	const limit = 5
+	var mu sync.Mutex
+	granted := 0
+	var wg sync.WaitGroup
+	for _, counters := range replicas {
+		wg.Add(1)
+		go func(counters *ConfigMapCounters) {
+			defer wg.Done()
+			for i := 0; i < limit; i++ {
+				reserved, err := counters.Reserve(ctx, "terminations.default.ReplicaSet.web", limit, time.Hour)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if reserved {
+					mu.Lock()
+					granted++
+					mu.Unlock()
+				}
+			}
+		}(counters)
+	}
+	wg.Wait()
+
+	if granted != limit {
+		t.Errorf("%d terminations granted across replicas, want %d", granted, limit)
+	}
+}
+
+func TestConfigMapCountersSurviveFailover(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	leader := NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state")
+
+	for i := 0; i < 2; i++ {
+		if _, err := leader.Reserve(ctx, "terminations.default.ReplicaSet.web", 3, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The new leader starts with nothing in memory and reads the shared counts
+	successor := NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state")
+	count, err := successor.Get(ctx, "terminations.default.ReplicaSet.web", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("count after failover = %d, want 2", count)
+	}
+	if reserved, _ := successor.Reserve(ctx, "terminations.default.ReplicaSet.web", 3, time.Hour); !reserved {
+		t.Fatal("successor could not take the last termination")
+	}
+	if reserved, _ := successor.Reserve(ctx, "terminations.default.ReplicaSet.web", 3, time.Hour); reserved {
+		t.Fatal("successor went past the limit set before the failover")
+	}
+}
+
+func TestConfigMapCountersRejectInvalidKey(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	counters := NewConfigMapCounters(c, c, "kube-shield", "kube-shield-state")
+
+	if _, err := counters.Reserve(context.Background(), "not a key", 1, time.Hour); err == nil {
+		t.Fatal("Reserve with an invalid key succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// EvaluationCache remembers which version of a pod was last evaluated against
+// which version of the policy set, so reconciles of unchanged pods can skip
+// the checks entirely.
+type EvaluationCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]evaluationEntry
+}
+
+// evaluationEntry is the last evaluation recorded for a pod
+type evaluationEntry struct {
+	uid             types.UID
+	resourceVersion string
+	policyVersion   string
+	containers      []string
+	evaluatedAt     time.Time
+}
+
+// NewEvaluationCache creates an empty EvaluationCache
+func NewEvaluationCache() *EvaluationCache {
+	return &EvaluationCache{
+		entries: make(map[types.NamespacedName]evaluationEntry),
+	}
+}
+
+// Fresh reports whether the pod was already evaluated at this resourceVersion
+// against the same policy set. policyVersion identifies the policy set; entries
+// recorded under a different policy version are treated as stale.
+func (c *EvaluationCache) Fresh(pod types.NamespacedName, uid types.UID, resourceVersion, policyVersion string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[pod]
+	return ok &&
+		entry.uid == uid &&
+		entry.resourceVersion == resourceVersion &&
+		entry.policyVersion == policyVersion
+}
+
+// Remember records that the pod was evaluated at resourceVersion against policyVersion,
+// along with the names of the containers it had at the time
+func (c *EvaluationCache) Remember(pod types.NamespacedName, uid types.UID, resourceVersion, policyVersion string, containers []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[pod] = evaluationEntry{
+		uid:             uid,
+		resourceVersion: resourceVersion,
+		policyVersion:   policyVersion,
+		containers:      containers,
+		evaluatedAt:     time.Now(),
+	}
+}
+
+// EvaluatedAt returns when this pod instance was last evaluated, or the zero time
+// if it never was
+func (c *EvaluationCache) EvaluatedAt(pod types.NamespacedName, uid types.UID) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[pod]
+	if !ok || entry.uid != uid {
+		return time.Time{}
+	}
+	return entry.evaluatedAt
+}
+
+// Containers returns the names of the containers the pod had when it was last
+// evaluated. It returns nil when this pod instance was never evaluated.
+func (c *EvaluationCache) Containers(pod types.NamespacedName, uid types.UID) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[pod]
+	if !ok || entry.uid != uid {
+		return nil
+	}
+	return entry.containers
+}
+
+// Forget drops the cached evaluation of a pod
+func (c *EvaluationCache) Forget(pod types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, pod)
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEvaluationCacheFresh(t *testing.T) {
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+	cache := NewEvaluationCache()
+	if cache.Fresh(pod, "uid-1", "10", "v1") {
+		t.Fatal("Fresh before any evaluation = true, want false")
+	}
+	cache.Remember(pod, "uid-1", "10", "v1", []string{"app"})
+
+	tests := []struct {
+		name            string
+		uid             types.UID
+		resourceVersion string
+		policyVersion   string
+		want            bool
+	}{
+		{name: "unchanged", uid: "uid-1", resourceVersion: "10", policyVersion: "v1", want: true},
+		{name: "pod updated", uid: "uid-1", resourceVersion: "11", policyVersion: "v1"},
+		{name: "policies changed", uid: "uid-1", resourceVersion: "10", policyVersion: "v2"},
+		{name: "pod recreated", uid: "uid-2", resourceVersion: "10", policyVersion: "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.Fresh(pod, tt.uid, tt.resourceVersion, tt.policyVersion); got != tt.want {
+				t.Errorf("Fresh = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluationCacheForget(t *testing.T) {
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+	cache := NewEvaluationCache()
+	cache.Remember(pod, "uid-1", "10", "v1", []string{"app"})
+
+	if got := cache.Containers(pod, "uid-1"); !slices.Equal(got, []string{"app"}) {
+		t.Errorf("Containers = %v, want [app]", got)
+	}
+	if got := cache.Containers(pod, "uid-2"); got != nil {
+		t.Errorf("Containers of another pod instance = %v, want nil", got)
+	}
+	if cache.EvaluatedAt(pod, "uid-1").IsZero() {
+		t.Error("EvaluatedAt is zero after Remember")
+	}
+
+	cache.Forget(pod)
+	if cache.Fresh(pod, "uid-1", "10", "v1") {
+		t.Error("Fresh after Forget = true, want false")
+	}
+	if !cache.EvaluatedAt(pod, "uid-1").IsZero() {
+		t.Error("EvaluatedAt after Forget is not zero")
+	}
+}
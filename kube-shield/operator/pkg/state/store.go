@@ -0,0 +1,248 @@
+// Package state holds the operator's in-memory view of pods that are currently
+// out of compliance. Unlike the counters kept in ShieldPolicyStatus, entries are
+// removed again when a pod becomes compliant or is deleted.
+package state
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Key identifies a single rule violation on a single pod
+type Key struct {
+	// PodUID is the UID of the violating pod
+	PodUID types.UID
+
+	// Policy is the name of the ShieldPolicy that raised the violation
+	Policy string
+
+	// EventType is the rule that was violated (e.g. PRIVILEGED_CONTAINER)
+	EventType string
+
+	// Rule is the name of the custom rule that was violated, empty for built-in checks
+	Rule string
+}
+
+// Violation is a currently active violation of a rule on a pod
+type Violation struct {
+	Key
+
+	// Pod is the namespaced name of the violating pod
+	Pod types.NamespacedName
+
+	// Severity is the severity reported for the violation
+	Severity string
+
+	// FirstSeen is when the violation was first recorded
+	FirstSeen time.Time
+
+	// LastSeen is when the violation was last confirmed by an evaluation
+	LastSeen time.Time
+}
+
+// Store tracks the violations currently present in the cluster
+type Store interface {
+	// Record replaces the set of violations held for a pod with the given set.
+	// Recording an empty set marks the pod as compliant.
+	Record(pod types.NamespacedName, uid types.UID, violations []Violation)
+
+	// Forget removes every violation held for a pod, typically after it was deleted
+	Forget(pod types.NamespacedName)
+
+	// Get returns the violation stored under key, if any
+	Get(key Key) (Violation, bool)
+
+	// Pod returns the violations held for the pod instance with the given UID
+	Pod(pod types.NamespacedName, uid types.UID) []Violation
+
+	// List returns a snapshot of all current violations
+	List() []Violation
+
+	// Subscribe returns a channel that receives a value after the set of violations
+	// changed, and a function ending the subscription. Every subscriber is told of
+	// every change. Notifications are coalesced per subscriber, so it sees at least
+	// one value after any number of changes.
+	Subscribe() (<-chan struct{}, func())
+}
+
+// MemoryStore is a Store backed by a map guarded by a RWMutex
+type MemoryStore struct {
+	mu          sync.RWMutex
+	pods        map[types.NamespacedName]podEntry
+	uids        map[types.UID]types.NamespacedName
+	subscribers map[chan struct{}]bool
+}
+
+// podEntry holds the violations of one pod instance
+type podEntry struct {
+	uid        types.UID
+	violations map[Key]Violation
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pods:        make(map[types.NamespacedName]podEntry),
+		uids:        make(map[types.UID]types.NamespacedName),
+		subscribers: make(map[chan struct{}]bool),
+	}
+}
+
+// Record implements Store
+func (s *MemoryStore) Record(pod types.NamespacedName, uid types.UID, violations []Violation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.pods[pod]
+	s.remove(pod)
+	if len(violations) == 0 {
+		if len(previous.violations) > 0 {
+			s.notify()
+		}
+		return
+	}
+
+	// A pod recreated under the same name starts with a clean slate
+	if previous.uid != uid {
+		previous.violations = nil
+	}
+
+	now := time.Now()
+	entry := podEntry{
+		uid:        uid,
+		violations: make(map[Key]Violation, len(violations)),
+	}
+	for _, v := range violations {
+		v.Pod = pod
+		v.PodUID = uid
+		v.LastSeen = now
+		if old, ok := previous.violations[v.Key]; ok {
+			v.FirstSeen = old.FirstSeen
+		} else if v.FirstSeen.IsZero() {
+			v.FirstSeen = now
+		}
+		entry.violations[v.Key] = v
+	}
+	s.pods[pod] = entry
+	s.uids[uid] = pod
+
+	if !sameKeys(previous.violations, entry.violations) {
+		s.notify()
+	}
+}
+
+// sameKeys reports whether two violation sets hold the same keys
+func sameKeys(a, b map[Key]Violation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, va := range a {
+		if vb, ok := b[key]; !ok || va.Severity != vb.Severity {
+			return false
+		}
+	}
+	return true
+}
+
+// notify signals a change to every subscriber, skipping those that already have a
+// notification pending. Callers must hold the write lock.
+func (s *MemoryStore) notify() {
+	for changed := range s.subscribers {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe implements Store
+func (s *MemoryStore) Subscribe() (<-chan struct{}, func()) {
+	changed := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.subscribers[changed] = true
+	s.mu.Unlock()
+
+	return changed, func() {
+		s.mu.Lock()
+		delete(s.subscribers, changed)
+		s.mu.Unlock()
+	}
+}
+
+// Forget implements Store
+func (s *MemoryStore) Forget(pod types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.pods[pod]; ok && len(entry.violations) > 0 {
+		s.notify()
+	}
+	s.remove(pod)
+}
+
+// remove drops a pod and its UID index entry. Callers must hold the write lock.
+func (s *MemoryStore) remove(pod types.NamespacedName) {
+	if entry, ok := s.pods[pod]; ok {
+		delete(s.uids, entry.uid)
+		delete(s.pods, pod)
+	}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(key Key) (Violation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pod, ok := s.uids[key.PodUID]
+	if !ok {
+		return Violation{}, false
+	}
+	v, ok := s.pods[pod].violations[key]
+	return v, ok
+}
+
+// Pod implements Store
+func (s *MemoryStore) Pod(pod types.NamespacedName, uid types.UID) []Violation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.pods[pod]
+	if !ok || entry.uid != uid {
+		return nil
+	}
+	result := make([]Violation, 0, len(entry.violations))
+	for _, v := range entry.violations {
+		result = append(result, v)
+	}
+	return result
+}
+
+// List implements Store. Violations are ordered by pod, policy, event type and
+// rule so callers get a stable view.
+func (s *MemoryStore) List() []Violation {
+	s.mu.RLock()
+	result := make([]Violation, 0, len(s.pods))
+	for _, entry := range s.pods {
+		for _, v := range entry.violations {
+			result = append(result, v)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Pod != b.Pod {
+			return a.Pod.String() < b.Pod.String()
+		}
+		if a.Policy != b.Policy {
+			return a.Policy < b.Policy
+		}
+		if a.EventType != b.EventType {
+			return a.EventType < b.EventType
+		}
+		return a.Rule < b.Rule
+	})
+	return result
+}
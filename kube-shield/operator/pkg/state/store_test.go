@@ -0,0 +1,173 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func violation(policy, eventType string) Violation {
+	return Violation{Key: Key{Policy: policy, EventType: eventType}, Severity: "HIGH"}
+}
+
+func TestMemoryStoreRecord(t *testing.T) {
+	store := NewMemoryStore()
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+
+	store.Record(pod, "uid-1", []Violation{violation("restricted", "HOST_NETWORK")})
+	first, ok := store.Get(Key{PodUID: "uid-1", Policy: "restricted", EventType: "HOST_NETWORK"})
+	if !ok {
+		t.Fatal("recorded violation not found")
+	}
+
+	// Confirming the violation keeps when it was first seen
+	store.Record(pod, "uid-1", []Violation{violation("restricted", "HOST_NETWORK")})
+	again, _ := store.Get(first.Key)
+	if !again.FirstSeen.Equal(first.FirstSeen) {
+		t.Errorf("FirstSeen = %v after a re-evaluation, want %v", again.FirstSeen, first.FirstSeen)
+	}
+
+	// A pod recreated under the same name starts over
+	store.Record(pod, "uid-2", []Violation{violation("restricted", "HOST_NETWORK")})
+	if _, ok := store.Get(first.Key); ok {
+		t.Error("violation of the previous pod instance is still stored")
+	}
+	if violations := store.Pod(pod, "uid-1"); len(violations) != 0 {
+		t.Errorf("Pod for the previous UID = %v, want none", violations)
+	}
+
+	store.Record(pod, "uid-2", nil)
+	if violations := store.List(); len(violations) != 0 {
+		t.Errorf("List after the pod became compliant = %v, want none", violations)
+	}
+}
+
+func TestMemoryStoreConcurrentAccess(t *testing.T) {
+	store := NewMemoryStore()
+	const writers, pods = 8, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < pods; i++ {
+				pod := types.NamespacedName{Namespace: fmt.Sprintf("ns-%d", w), Name: fmt.Sprintf("pod-%d", i)}
+				uid := types.UID(pod.String())
+				store.Record(pod, uid, []Violation{violation("restricted", "HOST_NETWORK"), violation("restricted", "ROOT_USER")})
+				store.Get(Key{PodUID: uid, Policy: "restricted", EventType: "HOST_NETWORK"})
+				store.Pod(pod, uid)
+				// Every other pod becomes compliant or is deleted again
+				switch i % 4 {
+				case 1:
+					store.Record(pod, uid, nil)
+				case 3:
+					store.Forget(pod)
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < pods; i++ {
+				for _, v := range store.List() {
+					if v.Pod.Name == "" || v.PodUID == "" {
+						t.Errorf("List returned an incomplete violation %+v", v)
+						return
+					}
+				}
+			}
+		}()
+	}
+	// Drain notifications while writers run, as the status updaters do
+	done := make(chan struct{})
+	changed, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+	go func() {
+		for {
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+
+	if got, want := len(store.List()), writers*pods/2*2; got != want {
+		t.Errorf("%d violations left, want %d", got, want)
+	}
+	if len(store.uids) != writers*pods/2 {
+		t.Errorf("%d UIDs indexed, want %d", len(store.uids), writers*pods/2)
+	}
+}
+
+func TestMemoryStoreSubscribeCoalesces(t *testing.T) {
+	store := NewMemoryStore()
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+	changed, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < 3; i++ {
+		store.Record(pod, "uid", []Violation{violation("restricted", fmt.Sprintf("RULE_%d", i))})
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("no change notification after recording violations")
+	}
+	select {
+	case <-changed:
+		t.Fatal("changes were not coalesced into one notification")
+	default:
+	}
+
+	// Re-recording the same violations is not a change
+	store.Record(pod, "uid", []Violation{violation("restricted", "RULE_2")})
+	select {
+	case <-changed:
+		t.Error("notification for an unchanged set of violations")
+	default:
+	}
+}
+
+func TestMemoryStoreSubscribeFansOut(t *testing.T) {
+	store := NewMemoryStore()
+	pod := types.NamespacedName{Namespace: "default", Name: "web"}
+	first, unsubscribeFirst := store.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := store.Subscribe()
+	third, unsubscribeThird := store.Subscribe()
+	defer unsubscribeThird()
+
+	store.Record(pod, "uid", []Violation{violation("restricted", "HOST_NETWORK")})
+	for i, changed := range []<-chan struct{}{first, second, third} {
+		select {
+		case <-changed:
+		default:
+			t.Errorf("subscriber %d was not notified", i+1)
+		}
+	}
+
+	// Ended subscriptions are not notified, the others still are
+	unsubscribeSecond()
+	unsubscribeSecond()
+	store.Forget(pod)
+	select {
+	case <-second:
+		t.Error("notification after unsubscribing")
+	default:
+	}
+	for _, changed := range []<-chan struct{}{first, third} {
+		select {
+		case <-changed:
+		default:
+			t.Error("remaining subscriber was not notified of the second change")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package status serves the operator's read-only status endpoints, such as the
+// compliance report, on their own HTTP listener next to metrics and probes.
+package status
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take once the manager stops
+const shutdownTimeout = 5 * time.Second
+
+// Server is a manager Runnable serving status endpoints. It runs on every replica,
+// not only the leader, since it only reads state. Endpoints backed by state only
+// the leader holds are registered with HandleLeaderOnly.
+type Server struct {
+	Addr string
+
+	// Filter wraps every endpoint, e.g. to authenticate and authorize requests the
+	// way the secure metrics endpoint does. Nil serves the endpoints unprotected.
+	Filter metricsserver.Filter
+
+	mux *http.ServeMux
+}
+
+// NewServer creates a Server listening on addr
+func NewServer(addr string) *Server {
+	return &Server{
+		Addr: addr,
+		mux:  http.NewServeMux(),
+	}
+}
+
+// Handle registers handler for pattern
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleLeaderOnly registers handler for pattern on the leader. Until this replica
+// is elected, which elected is closed on, requests are answered with 503 so clients
+// are not served the empty state of a passive replica.
+func (s *Server) HandleLeaderOnly(pattern string, elected <-chan struct{}, handler http.Handler) {
+	s.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-elected:
+			handler.ServeHTTP(w, r)
+		default:
+			http.Error(w, "not the leader, query the replica holding the leader election lease", http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable
+func (s *Server) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("status-server")
+	var handler http.Handler = s.mux
+	if s.Filter != nil {
+		filtered, err := s.Filter(logger, handler)
+		if err != nil {
+			return err
+		}
+		handler = filtered
+	}
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Serving status endpoints", "addr", s.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
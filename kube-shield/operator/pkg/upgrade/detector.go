@@ -0,0 +1,242 @@
+// Package upgrade suspects rolling cluster upgrades from the state of the nodes, so
+// enforcement can step aside while system agents briefly run unexpected images.
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeshield/operator/pkg/metrics"
+)
+
+// detectionInterval is how often the nodes are looked at again
+const detectionInterval = 15 * time.Second
+
+// ServerVersion returns the control plane's version, e.g. a discovery client
+type ServerVersion func() (string, error)
+
+// Detector is a manager Runnable that suspects single nodes of being upgraded: nodes
+// whose kubelet is more than VersionSkew minor versions away from the control plane,
+// and, while more than UnavailablePercent of the nodes are NotReady or cordoned, the
+// nodes that are. Nodes are read from the manager's cache.
+//
+// A cluster-wide upgrade is only lifted once the nodes have stayed below the
+// threshold for Cooldown, and a node stays suspected for Cooldown after it recovers,
+// so enforcement does not flap while nodes are upgraded one after the other. No node
+// is suspected for longer than MaxNodeSuspicion, so a node that never recovers does
+// not keep enforcement off for good.
+type Detector struct {
+	Client             client.Client
+	ControlPlane       ServerVersion
+	UnavailablePercent int
+	VersionSkew        int
+	Cooldown           time.Duration
+	MaxNodeSuspicion   time.Duration
+
+	mu sync.RWMutex
+	// upgrading is set while a cluster-wide upgrade is suspected
+	upgrading bool
+	// calmSince is when the nodes last went below the threshold during an upgrade
+	calmSince time.Time
+	// suspects holds the nodes suspected of being upgraded
+	suspects map[string]Suspicion
+}
+
+// Suspicion is the time window during which a node was suspected of being upgraded
+type Suspicion struct {
+	// Since is when the node was first suspected
+	Since time.Time
+	// Seen is when the node last looked like it was being upgraded
+	Seen time.Time
+}
+
+// NewDetector creates a Detector. A zero unavailablePercent or versionSkew disables
+// that heuristic.
+func NewDetector(c client.Client, controlPlane ServerVersion, unavailablePercent, versionSkew int, cooldown, maxNodeSuspicion time.Duration) *Detector {
+	return &Detector{
+		Client:             c,
+		ControlPlane:       controlPlane,
+		UnavailablePercent: unavailablePercent,
+		VersionSkew:        versionSkew,
+		Cooldown:           cooldown,
+		MaxNodeSuspicion:   maxNodeSuspicion,
+	}
+}
+
+// Suspected reports whether pods on the node should be left alone because the node
+// seems to be upgrading
+func (d *Detector) Suspected(nodeName string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	suspicion, ok := d.suspects[nodeName]
+	return ok && time.Since(suspicion.Since) < d.MaxNodeSuspicion
+}
+
+// Start implements manager.Runnable
+func (d *Detector) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("upgrade")
+	ticker := time.NewTicker(detectionInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.update(ctx, logger, time.Now()); err != nil {
+			logger.Error(err, "Failed to check nodes for a cluster upgrade")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// update recomputes the suspicions from the current nodes
+func (d *Detector) update(ctx context.Context, logger logr.Logger, now time.Time) error {
+	nodes := &corev1.NodeList{}
+	if err := d.Client.List(ctx, nodes); err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	skewed := map[string]bool{}
+	if d.VersionSkew > 0 {
+		controlPlane, err := d.ControlPlane()
+		if err != nil {
+			return fmt.Errorf("reading control plane version: %w", err)
+		}
+		if skewed, err = SkewedNodes(nodes.Items, controlPlane, d.VersionSkew); err != nil {
+			return err
+		}
+	}
+	over := d.UnavailablePercent > 0 && UnavailablePercent(nodes.Items) > float64(d.UnavailablePercent)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	was := d.upgrading
+	d.upgrading, d.calmSince = nextState(d.upgrading, d.calmSince, over, now, d.Cooldown)
+
+	// During a cluster-wide upgrade, only the nodes being replaced are left alone
+	candidates := skewed
+	if d.upgrading {
+		candidates = make(map[string]bool, len(skewed))
+		for name := range skewed {
+			candidates[name] = true
+		}
+		for i := range nodes.Items {
+			if !nodeAvailable(&nodes.Items[i]) {
+				candidates[nodes.Items[i].Name] = true
+			}
+		}
+	}
+	d.suspects = NextSuspects(d.suspects, candidates, now, d.Cooldown)
+
+	if d.upgrading != was {
+		logger.Info("Cluster upgrade suspicion changed", "suspected", d.upgrading, "unavailablePercent", UnavailablePercent(nodes.Items))
+	}
+	if d.upgrading {
+		metrics.ClusterUpgradeSuspected.Set(1)
+	} else {
+		metrics.ClusterUpgradeSuspected.Set(0)
+	}
+	metrics.SkewedNodes.Set(float64(len(skewed)))
+	return nil
+}
+
+// nextState applies the hysteresis: an upgrade is suspected as soon as the nodes go
+// over the threshold, and only cleared after they stayed under it for cooldown
+func nextState(upgrading bool, calmSince time.Time, over bool, now time.Time, cooldown time.Duration) (bool, time.Time) {
+	switch {
+	case over:
+		return true, time.Time{}
+	case !upgrading:
+		return false, time.Time{}
+	case calmSince.IsZero():
+		return true, now
+	case now.Sub(calmSince) >= cooldown:
+		return false, time.Time{}
+	default:
+		return true, calmSince
+	}
+}
+
+// NextSuspects updates the suspected nodes from the nodes that currently look like
+// they are being upgraded. A node keeps the time it was first suspected, and is
+// dropped once it no longer looked like it for cooldown.
+func NextSuspects(previous map[string]Suspicion, candidates map[string]bool, now time.Time, cooldown time.Duration) map[string]Suspicion {
+	next := make(map[string]Suspicion, len(candidates))
+	for name := range candidates {
+		suspicion, ok := previous[name]
+		if !ok {
+			suspicion.Since = now
+		}
+		suspicion.Seen = now
+		next[name] = suspicion
+	}
+	for name, suspicion := range previous {
+		if !candidates[name] && now.Sub(suspicion.Seen) < cooldown {
+			next[name] = suspicion
+		}
+	}
+	return next
+}
+
+// UnavailablePercent returns the percentage of nodes that are NotReady or cordoned
+func UnavailablePercent(nodes []corev1.Node) float64 {
+	if len(nodes) == 0 {
+		return 0
+	}
+	unavailable := 0
+	for i := range nodes {
+		if !nodeAvailable(&nodes[i]) {
+			unavailable++
+		}
+	}
+	return 100 * float64(unavailable) / float64(len(nodes))
+}
+
+// nodeAvailable returns true if the node is Ready and not cordoned
+func nodeAvailable(node *corev1.Node) bool {
+	return !node.Spec.Unschedulable && nodeReady(node)
+}
+
+// nodeReady returns true if the node's Ready condition is true
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// SkewedNodes returns the nodes whose kubelet is more than skew minor versions away
+// from the control plane. Nodes reporting no parsable version are left out.
+func SkewedNodes(nodes []corev1.Node, controlPlane string, skew int) (map[string]bool, error) {
+	plane, err := version.ParseGeneric(controlPlane)
+	if err != nil {
+		return nil, fmt.Errorf("parsing control plane version %q: %w", controlPlane, err)
+	}
+
+	skewed := make(map[string]bool)
+	for i := range nodes {
+		kubelet, err := version.ParseGeneric(nodes[i].Status.NodeInfo.KubeletVersion)
+		if err != nil {
+			continue
+		}
+		distance := int(plane.Minor()) - int(kubelet.Minor())
+		if distance < 0 {
+			distance = -distance
+		}
+		if kubelet.Major() != plane.Major() || distance > skew {
+			skewed[nodes[i].Name] = true
+		}
+	}
+	return skewed, nil
+}
@@ -0,0 +1,151 @@
+package upgrade
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name string, ready, cordoned bool, kubelet string) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: cordoned},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: kubelet},
+		},
+	}
+}
+
+func TestUnavailablePercent(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []corev1.Node
+		want  float64
+	}{
+		{name: "no nodes", want: 0},
+		{name: "all ready", nodes: []corev1.Node{testNode("a", true, false, ""), testNode("b", true, false, "")}, want: 0},
+		{
+			name:  "one not ready one cordoned",
+			nodes: []corev1.Node{testNode("a", false, false, ""), testNode("b", true, true, ""), testNode("c", true, false, ""), testNode("d", true, false, "")},
+			want:  50,
+		},
+		{name: "no ready condition", nodes: []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "a"}}}, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnavailablePercent(tt.nodes); got != tt.want {
+				t.Errorf("UnavailablePercent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkewedNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		testNode("current", true, false, "v1.30.1"),
+		testNode("one-behind", true, false, "v1.29.4"),
+		testNode("two-behind", true, false, "v1.28.9"),
+		testNode("ahead", true, false, "v1.32.0"),
+		testNode("other-major", true, false, "v2.30.0"),
+		testNode("managed", true, false, "v1.28.9-eks-036c24b"),
+		testNode("unknown", true, false, ""),
+	}
+
+	skewed, err := SkewedNodes(nodes, "v1.30.2", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"two-behind": true, "ahead": true, "other-major": true, "managed": true}
+	if len(skewed) != len(want) {
+		t.Errorf("SkewedNodes = %v, want %v", skewed, want)
+	}
+	for name := range want {
+		if !skewed[name] {
+			t.Errorf("node %s is not skewed, want it skewed", name)
+		}
+	}
+
+	if _, err := SkewedNodes(nodes, "not-a-version", 1); err == nil {
+		t.Error("SkewedNodes with an unparsable control plane version succeeded")
+	}
+}
+
+func TestNextState(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cooldown := 10 * time.Minute
+	tests := []struct {
+		name          string
+		upgrading     bool
+		calmSince     time.Time
+		over          bool
+		wantUpgrading bool
+		wantCalmSince time.Time
+	}{
+		{name: "goes over", over: true, wantUpgrading: true},
+		{name: "stays over resets calm", upgrading: true, calmSince: now.Add(-time.Minute), over: true, wantUpgrading: true},
+		{name: "stays under", wantUpgrading: false},
+		{name: "goes under", upgrading: true, wantUpgrading: true, wantCalmSince: now},
+		{name: "calm within cooldown", upgrading: true, calmSince: now.Add(-5 * time.Minute), wantUpgrading: true, wantCalmSince: now.Add(-5 * time.Minute)},
+		{name: "calm for cooldown", upgrading: true, calmSince: now.Add(-cooldown), wantUpgrading: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrading, calmSince := nextState(tt.upgrading, tt.calmSince, tt.over, now, cooldown)
+			if upgrading != tt.wantUpgrading || !calmSince.Equal(tt.wantCalmSince) {
+				t.Errorf("nextState = %t, %v, want %t, %v", upgrading, calmSince, tt.wantUpgrading, tt.wantCalmSince)
+			}
+		})
+	}
+}
+
+func TestNextSuspects(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cooldown := 10 * time.Minute
+	previous := map[string]Suspicion{
+		"still-upgrading": {Since: now.Add(-time.Hour), Seen: now.Add(-time.Minute)},
+		"recovering":      {Since: now.Add(-time.Hour), Seen: now.Add(-5 * time.Minute)},
+		"recovered":       {Since: now.Add(-time.Hour), Seen: now.Add(-cooldown)},
+	}
+	candidates := map[string]bool{"still-upgrading": true, "new": true}
+
+	next := NextSuspects(previous, candidates, now, cooldown)
+
+	want := map[string]Suspicion{
+		"still-upgrading": {Since: now.Add(-time.Hour), Seen: now},
+		"new":             {Since: now, Seen: now},
+		"recovering":      {Since: now.Add(-time.Hour), Seen: now.Add(-5 * time.Minute)},
+	}
+	if len(next) != len(want) {
+		t.Errorf("NextSuspects = %v, want %v", next, want)
+	}
+	for name, suspicion := range want {
+		got, ok := next[name]
+		if !ok || !got.Since.Equal(suspicion.Since) || !got.Seen.Equal(suspicion.Seen) {
+			t.Errorf("suspicion of %s = %+v, want %+v", name, got, suspicion)
+		}
+	}
+}
+
+func TestSuspectedExpires(t *testing.T) {
+	d := NewDetector(nil, nil, 0, 0, time.Minute, time.Hour)
+	d.suspects = map[string]Suspicion{
+		"recent": {Since: time.Now(), Seen: time.Now()},
+		"stuck":  {Since: time.Now().Add(-2 * time.Hour), Seen: time.Now()},
+	}
+	if !d.Suspected("recent") {
+		t.Error("recently suspected node is not suspected")
+	}
+	if d.Suspected("stuck") {
+		t.Error("node suspected for longer than MaxNodeSuspicion is still suspected")
+	}
+	if d.Suspected("healthy") {
+		t.Error("unknown node is suspected")
+	}
+}
@@ -0,0 +1,36 @@
+// Package version reports the version of the operator build. The variables are
+// set at build time via ldflags, e.g.
+//
+//	-X github.com/kubeshield/operator/pkg/version.Version=v1.2.0
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var (
+	// Version is the operator release
+	Version = "dev"
+
+	// Commit is the git commit the binary was built from
+	Commit = "unknown"
+
+	// BuildDate is when the binary was built, in RFC3339
+	BuildDate = "unknown"
+)
+
+// GoVersion returns the Go toolchain the binary was built with
+func GoVersion() string {
+	return runtime.Version()
+}
+
+// String returns a human readable summary of the build
+func String() string {
+	return fmt.Sprintf("kubeshield-operator %s (commit %s, built %s, %s)", Version, Commit, BuildDate, GoVersion())
+}
+
+// UserAgent returns the User-Agent sent on the operator's outbound HTTP requests
+func UserAgent() string {
+	return "kubeshield-operator/" + Version
+}
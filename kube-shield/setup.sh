@@ -183,7 +183,11 @@ build_images() {
     # Build Operator
     log_info "Building Go Operator image..."
     cd "${SCRIPT_DIR}/operator"
-    docker build -t kubeshield-operator:latest .
+    docker build \
+        --build-arg VERSION="$(git describe --tags --always --dirty 2>/dev/null || echo dev)" \
+        --build-arg COMMIT="$(git rev-parse --short HEAD 2>/dev/null || echo unknown)" \
+        --build-arg BUILD_DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+        -t kubeshield-operator:latest .
     log_success "Operator image built"
 
     # Build Audit Service